@@ -29,8 +29,19 @@ func main() {
 	engine := serverConfig.Gin
 	// Initialize routes
 	routes.PasswordEntryRoutes(engine, serverConfig.Middleware, serverConfig.Controller.PasswordEntryController)
+	routes.AttachmentRoutes(engine, serverConfig.Middleware, serverConfig.Controller.AttachmentController)
+	routes.SSHKeyRoutes(engine, serverConfig.Middleware, serverConfig.Controller.SSHKeyController)
+	routes.PasskeyRoutes(engine, serverConfig.Middleware, serverConfig.Controller.PasskeyController)
 	routes.PasswordGroupRoutes(engine, serverConfig.Middleware, serverConfig.Controller.PasswordGroupController)
 	routes.PasswordTagRoutes(engine, serverConfig.Middleware, serverConfig.Controller.PasswordTagController)
+	routes.EquivalentDomainRoutes(engine, serverConfig.Middleware, serverConfig.Controller.EquivalentDomainController)
+	routes.ServiceAccountRoutes(engine, serverConfig.Middleware, serverConfig.Controller.ServiceAccountController)
+	routes.UserDeviceRoutes(engine, serverConfig.Middleware, serverConfig.Controller.UserDeviceController)
+	routes.WebAuthnRoutes(engine, serverConfig.Middleware, serverConfig.Controller.WebAuthnController)
+	routes.VaultRoutes(engine, serverConfig.Middleware, serverConfig.Controller.VaultController)
+	routes.PasswordPolicyRoutes(engine, serverConfig.Middleware, serverConfig.Controller.PasswordPolicyController)
+	routes.ReportRoutes(engine, serverConfig.Middleware, serverConfig.Controller.ReportController)
+	routes.InternalRoutes(engine, serverConfig.Middleware, serverConfig.Controller.InternalController)
 
 	// Run server
 	log.Println("Starting server on :8082")
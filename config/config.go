@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
@@ -11,11 +12,15 @@ import (
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 	"log"
+	"password-management-service/internal/utils/blob"
+	"password-management-service/internal/utils/breach"
+	"password-management-service/internal/utils/jwt"
 	"time"
 )
 
 // Config holds application-wide configurations
 type Config struct {
+	AppEnv     string `envconfig:"APP_ENV" default:"production"`
 	AppPort    string `envconfig:"APP_PORT" default:"8082"`
 	JWTSecret  string `envconfig:"JWT_SECRET" default:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6q7r8s9t0u1v2w3x4y5z6"`
 	RedisHost  string `envconfig:"REDIS_HOST" default:"localhost"`
@@ -30,8 +35,81 @@ type Config struct {
 	DBSchema   string `envconfig:"DB_SCHEMA" default:"public"`
 	DBSSLMode  string `envconfig:"DB_SSLMODE" default:"disable"`
 	NatsUrl    string `envconfig:"NATS_URL" default:"nats://localhost:4222"`
+
+	StepUpActions string        `envconfig:"STEP_UP_ACTIONS" default:"reveal,share,delete,rotate_key,ssh_sign,passkey"`
+	StepUpMaxAge  time.Duration `envconfig:"STEP_UP_MAX_AGE" default:"5m"`
+
+	RateLimitEnabled   bool          `envconfig:"RATE_LIMIT_ENABLED" default:"true"`
+	RateLimitWindow    time.Duration `envconfig:"RATE_LIMIT_WINDOW" default:"1m"`
+	RateLimitRead      int           `envconfig:"RATE_LIMIT_READ" default:"120"`
+	RateLimitReveal    int           `envconfig:"RATE_LIMIT_REVEAL" default:"20"`
+	RateLimitExport    int           `envconfig:"RATE_LIMIT_EXPORT" default:"5"`
+	RateLimitPinVerify int           `envconfig:"RATE_LIMIT_PIN_VERIFY" default:"10"`
+	PinMaxAttempts     int           `envconfig:"PIN_MAX_ATTEMPTS" default:"5"`
+	PinLockoutDuration time.Duration `envconfig:"PIN_LOCKOUT_DURATION" default:"15m"`
+
+	VaultIdleTimeout time.Duration `envconfig:"VAULT_IDLE_TIMEOUT" default:"5m"`
+	VaultMaxAge      time.Duration `envconfig:"VAULT_MAX_AGE" default:"1h"`
+
+	// DeviceSeenInterval is how often a device's last-seen time is written; 0 writes it on every request
+	DeviceSeenInterval time.Duration `envconfig:"DEVICE_SEEN_INTERVAL" default:"5m"`
+
+	WebAuthnRPID             string        `envconfig:"WEBAUTHN_RP_ID" default:"localhost"`
+	WebAuthnRPName           string        `envconfig:"WEBAUTHN_RP_NAME" default:"Password Manager"`
+	WebAuthnOrigins          string        `envconfig:"WEBAUTHN_ORIGINS" default:"http://localhost:3000"`
+	WebAuthnTimeout          time.Duration `envconfig:"WEBAUTHN_TIMEOUT" default:"2m"`
+	WebAuthnUserVerification string        `envconfig:"WEBAUTHN_USER_VERIFICATION" default:"preferred"`
+
+	JWTInternalSecret   string        `envconfig:"JWT_INTERNAL_SECRET" default:""`
+	JWTJWKSSource       string        `envconfig:"JWT_JWKS_SOURCE" default:""`
+	JWTJWKSCacheTTL     time.Duration `envconfig:"JWT_JWKS_CACHE_TTL" default:"10m"`
+	JWTUserIssuer       string        `envconfig:"JWT_USER_ISSUER" default:""`
+	JWTUserAudience     string        `envconfig:"JWT_USER_AUDIENCE" default:""`
+	JWTInternalIssuer   string        `envconfig:"JWT_INTERNAL_ISSUER" default:"auth-service"`
+	JWTInternalAudience string        `envconfig:"JWT_INTERNAL_AUDIENCE" default:""`
+
+	InternalAllowedServices string `envconfig:"INTERNAL_ALLOWED_SERVICES" default:"auth"`
+
+	NatsEnabled                bool          `envconfig:"NATS_ENABLED" default:"true"`
+	NatsQueue                  string        `envconfig:"NATS_QUEUE" default:"password-management-service"`
+	NatsSubjectUserCreated     string        `envconfig:"NATS_SUBJECT_USER_CREATED" default:"user.created"`
+	NatsSubjectUserDeleted     string        `envconfig:"NATS_SUBJECT_USER_DELETED" default:"user.deleted"`
+	NatsSubjectUserRoleChanged string        `envconfig:"NATS_SUBJECT_USER_ROLE_CHANGED" default:"user.role_changed"`
+	NatsDeadLetterSubject      string        `envconfig:"NATS_DEAD_LETTER_SUBJECT" default:"password.dead_letter"`
+	NatsMaxDeliver             int           `envconfig:"NATS_MAX_DELIVER" default:"5"`
+	NatsRetryBackoff           time.Duration `envconfig:"NATS_RETRY_BACKOFF" default:"2s"`
+	NatsEventSubjectPrefix     string        `envconfig:"NATS_EVENT_SUBJECT_PREFIX" default:"vault."`
+	NatsPublishTimeout         time.Duration `envconfig:"NATS_PUBLISH_TIMEOUT" default:"5s"`
+
+	OutboxRelaySchedule   string        `envconfig:"OUTBOX_RELAY_SCHEDULE" default:"@every 5s"`
+	OutboxCleanupSchedule string        `envconfig:"OUTBOX_CLEANUP_SCHEDULE" default:"@daily"`
+	OutboxBatchSize       int           `envconfig:"OUTBOX_BATCH_SIZE" default:"100"`
+	OutboxRetention       time.Duration `envconfig:"OUTBOX_RETENTION" default:"168h"`
+	ExpiryScanSchedule    string        `envconfig:"EXPIRY_SCAN_SCHEDULE" default:"@every 15m"`
+
+	BreachRangeDir        string        `envconfig:"BREACH_RANGE_DIR" default:""`
+	BreachRangeAPIURL     string        `envconfig:"BREACH_RANGE_API_URL" default:""`
+	BreachRangeTimeout    time.Duration `envconfig:"BREACH_RANGE_TIMEOUT" default:"10s"`
+	BreachRecheckInterval time.Duration `envconfig:"BREACH_RECHECK_INTERVAL" default:"24h"`
+	BreachRecheckSchedule string        `envconfig:"BREACH_RECHECK_SCHEDULE" default:"@every 1h"`
+	BreachBatchSize       int           `envconfig:"BREACH_BATCH_SIZE" default:"500"`
+	BreachWorkers         int           `envconfig:"BREACH_WORKERS" default:"2"`
+	BreachQueueSize       int           `envconfig:"BREACH_QUEUE_SIZE" default:"1000"`
+
+	AttachmentBackend  string `envconfig:"ATTACHMENT_BACKEND" default:"postgres"`
+	AttachmentMaxSize  int64  `envconfig:"ATTACHMENT_MAX_SIZE" default:"10485760"`
+	AttachmentDir      string `envconfig:"ATTACHMENT_DIR" default:"./data/attachments"`
+	AttachmentS3URL    string `envconfig:"ATTACHMENT_S3_ENDPOINT" default:""`
+	AttachmentS3Bucket string `envconfig:"ATTACHMENT_S3_BUCKET" default:""`
+	AttachmentS3Region string `envconfig:"ATTACHMENT_S3_REGION" default:"us-east-1"`
+	AttachmentS3Access string `envconfig:"ATTACHMENT_S3_ACCESS_KEY" default:""`
+	AttachmentS3Secret string `envconfig:"ATTACHMENT_S3_SECRET_KEY" default:""`
+	AttachmentS3Prefix string `envconfig:"ATTACHMENT_S3_PREFIX" default:""`
 }
 
+// defaultJWTSecret is the development secret baked into the JWT_SECRET default
+const defaultJWTSecret = "a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6q7r8s9t0u1v2w3x4y5z6"
+
 // LoadConfig loads environment variables into the Config struct
 func LoadConfig() *Config {
 	var cfg Config
@@ -39,7 +117,21 @@ func LoadConfig() *Config {
 		log.Fatalf("❌ Failed to load config: %v", err)
 	}
 
+	// APP_ENV defaults to production, so the built-in secret only works when development is set explicitly
+	if cfg.AppEnv != "development" {
+		if cfg.JWTSecret == defaultJWTSecret || cfg.JWTInternalSecret == defaultJWTSecret {
+			log.Fatalf("❌ Refusing to start: default JWT secret is not allowed when APP_ENV=%s, set APP_ENV=development for local use", cfg.AppEnv)
+		}
+	}
+	if cfg.JWTSecret == "" && cfg.JWTJWKSSource == "" {
+		log.Fatalf("❌ Failed to load config: either JWT_SECRET or JWT_JWKS_SOURCE is required")
+	}
+	if cfg.JWTInternalSecret == "" {
+		log.Fatalf("❌ Failed to load config: JWT_INTERNAL_SECRET is required")
+	}
+
 	logrus.WithFields(logrus.Fields{
+		"AppEnv":    cfg.AppEnv,
 		"AppPort":   cfg.AppPort,
 		"DBHost":    cfg.DBHost,
 		"DBName":    cfg.DBName,
@@ -132,6 +224,99 @@ func InitRedis(cfg *Config) *redis.Client {
 	return nil
 }
 
+// InitNats connects to NATS. The client keeps reconnecting in the background, so a broker that is
+// down at startup does not block the HTTP API.
+func InitNats(cfg *Config) *nats.Conn {
+	if !cfg.NatsEnabled {
+		logrus.Info("NATS disabled")
+		return nil
+	}
+
+	conn, err := nats.Connect(cfg.NatsUrl,
+		nats.Name(cfg.NatsQueue),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2*time.Second),
+	)
+	if err != nil {
+		logrus.WithError(err).Error("❌ Failed to connect to NATS")
+		return nil
+	}
+
+	logrus.Info("✅ NATS client initialized")
+	return conn
+}
+
+func CloseNats(conn *nats.Conn) {
+	if conn == nil {
+		return
+	}
+	if err := conn.Drain(); err != nil {
+		logrus.WithError(err).Error("❌ Failed to drain NATS connection")
+		return
+	}
+	logrus.Info("✅ NATS connection closed")
+}
+
+// InitJWT builds the token verifier, loading the JWKS document when one is configured. Next to a JWKS, HMAC user
+// tokens are only accepted with a JWT_SECRET that was changed from the default.
+func InitJWT(cfg *Config) jwt.Service {
+	options := jwt.Options{
+		Secret:           cfg.JWTSecret,
+		InternalSecret:   cfg.JWTInternalSecret,
+		SecretWithKeySet: cfg.JWTSecret != defaultJWTSecret,
+		UserIssuer:       cfg.JWTUserIssuer,
+		UserAudience:     cfg.JWTUserAudience,
+		InternalIssuer:   cfg.JWTInternalIssuer,
+		InternalAudience: cfg.JWTInternalAudience,
+	}
+
+	if cfg.JWTJWKSSource != "" {
+		keySet, err := jwt.NewKeySet(cfg.JWTJWKSSource, cfg.JWTJWKSCacheTTL)
+		if err != nil {
+			logrus.Fatalf("❌ Failed to load JWKS from %s: %v", cfg.JWTJWKSSource, err)
+		}
+		options.KeySet = keySet
+		logrus.Info("✅ JWKS loaded")
+	}
+
+	jwtService, err := jwt.NewJWTService(options)
+	if err != nil {
+		logrus.Fatalf("❌ Failed to initialize JWT: %v", err)
+	}
+	return jwtService
+}
+
+// InitBreachChecker selects the breach range source; nil disables breach detection
+func InitBreachChecker(cfg *Config) breach.Checker {
+	checker := breach.NewChecker(cfg.BreachRangeDir, cfg.BreachRangeAPIURL, cfg.BreachRangeTimeout)
+	if checker == nil {
+		logrus.Info("Breach detection disabled")
+		return nil
+	}
+
+	logrus.Info("✅ Breach checker initialized")
+	return checker
+}
+
+// InitBlobStore opens the attachment backend; an unusable backend is fatal because uploads would be lost
+func InitBlobStore(cfg *Config, db *gorm.DB) blob.Store {
+	store, err := blob.NewStore(cfg.AttachmentBackend, *db, cfg.AttachmentDir, blob.S3Options{
+		Endpoint:  cfg.AttachmentS3URL,
+		Bucket:    cfg.AttachmentS3Bucket,
+		Region:    cfg.AttachmentS3Region,
+		AccessKey: cfg.AttachmentS3Access,
+		SecretKey: cfg.AttachmentS3Secret,
+		Prefix:    cfg.AttachmentS3Prefix,
+	})
+	if err != nil {
+		logrus.Fatalf("❌ Failed to initialize attachment storage: %v", err)
+	}
+
+	logrus.WithField("backend", store.Backend()).Info("✅ Attachment storage initialized")
+	return store
+}
+
 func InitCron(cfg *Config) {
 
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog"
 	"github.com/sirupsen/logrus"
 	"log"
@@ -11,9 +12,12 @@ import (
 	"password-management-service/internal/middleware"
 	"password-management-service/internal/repository"
 	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
 	"password-management-service/internal/utils/encryption"
-	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/event"
 	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/webauthn"
+	"strings"
 	"syscall"
 )
 
@@ -28,23 +32,12 @@ func NewServerConfig() (*ServerConfig, error) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
-	go func() {
-		<-quit
-		log.Println("🛑 Shutting down gracefully...")
-
-		// Close database and Redis before exiting
-		CloseDatabase(db)
-		CloseRedis(redisClient)
-
-		os.Exit(0)
-	}()
-
 	server := &ServerConfig{
 		Gin:        engine,
 		Config:     cfg,
 		DB:         db,
 		Redis:      redisService,
-		JWTService: jwt.NewJWTService(cfg.JWTSecret),
+		JWTService: InitJWT(cfg),
 	}
 
 	server.initEncryption()
@@ -54,6 +47,24 @@ func NewServerConfig() (*ServerConfig, error) {
 	server.initController()
 	server.initMiddleware()
 	server.initCron()
+	server.initEventConsumer()
+
+	go func() {
+		<-quit
+		log.Println("🛑 Shutting down gracefully...")
+
+		// Stop jobs, event consumption and breach checks, then close NATS, database and Redis before exiting
+		<-server.Cron.CronService.Stop().Done()
+		if server.Nats.Subscriber != nil {
+			_ = server.Nats.Subscriber.Stop()
+		}
+		server.Services.BreachService.Stop()
+		CloseNats(server.Nats.Conn)
+		CloseDatabase(db)
+		CloseRedis(redisClient)
+
+		os.Exit(0)
+	}()
 	return server, nil
 }
 
@@ -100,23 +111,93 @@ func (s *ServerConfig) initEncryption() {
 
 // initNats initializes the application services
 func (s *ServerConfig) initNats() {
-	s.Nats = Nats{}
+	s.Nats = Nats{
+		Conn: InitNats(s.Config),
+	}
+}
+
+// initEventConsumer subscribes to user lifecycle events once the services it dispatches to exist
+func (s *ServerConfig) initEventConsumer() {
+	if s.Nats.Conn == nil {
+		return
+	}
+
+	s.Nats.Subscriber = event.NewSubscriber(s.Nats.Conn, event.Options{
+		Subjects: []string{
+			s.Config.NatsSubjectUserCreated,
+			s.Config.NatsSubjectUserDeleted,
+			s.Config.NatsSubjectUserRoleChanged,
+		},
+		Queue:        s.Config.NatsQueue,
+		DeadLetter:   s.Config.NatsDeadLetterSubject,
+		MaxDeliver:   s.Config.NatsMaxDeliver,
+		RetryBackoff: s.Config.NatsRetryBackoff,
+	}, s.Services.UserEventService.HandleUserEvent)
+
+	if err := s.Nats.Subscriber.Start(); err != nil {
+		logrus.WithError(err).Error("❌ Failed to start NATS event consumer")
+	}
 }
 
 func (s *ServerConfig) initRepository() {
 	s.Repository = Repository{
-		UserRepository:              repository.NewUserRepository(*s.DB),
-		UserKeysRepository:          repository.NewUserKeysRepository(*s.DB),
-		PasswordEntryRepository:     repository.NewPasswordEntryRepository(*s.DB),
-		PasswordEntryKeysRepository: repository.NewPasswordEntryKeysRepository(*s.DB),
-		PasswordTagRepository:       repository.NewPasswordTagRepository(*s.DB),
-		PasswordGroupRepository:     repository.NewPasswordGroupRepository(*s.DB),
-		PasswordHistoryRepository:   repository.NewPasswordHistoryRepository(*s.DB),
-		SharedPasswordRepository:    repository.NewSharedPasswordRepository(*s.DB),
+		UserRepository:               repository.NewUserRepository(*s.DB),
+		UserKeysRepository:           repository.NewUserKeysRepository(*s.DB),
+		PasswordEntryRepository:      repository.NewPasswordEntryRepository(*s.DB),
+		PasswordEntryKeysRepository:  repository.NewPasswordEntryKeysRepository(*s.DB),
+		PasswordTagRepository:        repository.NewPasswordTagRepository(*s.DB),
+		PasswordGroupRepository:      repository.NewPasswordGroupRepository(*s.DB),
+		PasswordEntryGroupRepository: repository.NewPasswordEntryGroupRepository(*s.DB),
+		PasswordEntryURLRepository:   repository.NewPasswordEntryURLRepository(*s.DB),
+		PasswordHistoryRepository:    repository.NewPasswordHistoryRepository(*s.DB),
+		SharedPasswordRepository:     repository.NewSharedPasswordRepository(*s.DB),
+		PasswordAccessLogRepository:  repository.NewPasswordAccessLogRepository(*s.DB),
+		ServiceAccountRepository:     repository.NewServiceAccountRepository(*s.DB),
+		UserDeviceRepository:         repository.NewUserDeviceRepository(*s.DB),
+		UserAuthenticatorRepository:  repository.NewUserAuthenticatorRepository(*s.DB),
+		VaultRepository:              repository.NewVaultRepository(*s.DB),
+		OutboxRepository:             repository.NewOutboxRepository(*s.DB),
+		PasswordPolicyRepository:     repository.NewPasswordPolicyRepository(*s.DB),
+		PasswordAttachmentRepository: repository.NewPasswordAttachmentRepository(*s.DB),
+		EquivalentDomainRepository:   repository.NewEquivalentDomainRepository(*s.DB),
 	}
 }
 
 func (s *ServerConfig) initServices() {
+	breachService := services.NewBreachService(
+		s.Repository.PasswordEntryRepository,
+		s.Repository.PasswordEntryKeysRepository,
+		s.Repository.UserKeysRepository,
+		s.Encryption.EncryptionService,
+		InitBreachChecker(s.Config),
+		s.Config.BreachRecheckInterval,
+		s.Config.BreachBatchSize,
+		s.Config.BreachWorkers,
+		s.Config.BreachQueueSize)
+	passwordPolicyService := services.NewPasswordPolicyService(
+		s.Repository.PasswordPolicyRepository,
+		s.Repository.PasswordGroupRepository)
+	attachmentService := services.NewAttachmentService(
+		s.Repository.UserRepository,
+		s.Repository.UserKeysRepository,
+		s.Repository.PasswordEntryRepository,
+		s.Repository.PasswordEntryKeysRepository,
+		s.Repository.PasswordAttachmentRepository,
+		s.Repository.PasswordAccessLogRepository,
+		s.Encryption.EncryptionService,
+		InitBlobStore(s.Config, s.DB),
+		s.Redis,
+		s.Config.AttachmentMaxSize)
+	equivalentDomainService := services.NewEquivalentDomainService(
+		s.Repository.UserRepository,
+		s.Repository.EquivalentDomainRepository,
+		s.Redis)
+	serviceAccountKeyService := services.NewServiceAccountKeyService(
+		s.Repository.ServiceAccountRepository,
+		s.Repository.UserKeysRepository,
+		s.Repository.PasswordEntryKeysRepository,
+		s.Encryption.EncryptionService)
+
 	s.Services = Services{
 		PasswordEntryService: services.NewPasswordEntryService(
 			s.Repository.UserRepository,
@@ -125,6 +206,14 @@ func (s *ServerConfig) initServices() {
 			s.Repository.PasswordEntryKeysRepository,
 			s.Repository.PasswordTagRepository,
 			s.Repository.PasswordGroupRepository,
+			s.Repository.PasswordEntryGroupRepository,
+			s.Repository.PasswordEntryURLRepository,
+			s.Repository.PasswordAccessLogRepository,
+			breachService,
+			passwordPolicyService,
+			attachmentService,
+			equivalentDomainService,
+			serviceAccountKeyService,
 			s.Encryption.EncryptionService,
 			s.Redis),
 		PasswordGroupService: services.NewPasswordGroupService(
@@ -135,25 +224,163 @@ func (s *ServerConfig) initServices() {
 		PasswordTagService: services.NewPasswordTagService(
 			s.Repository.UserRepository,
 			s.Repository.PasswordTagRepository,
+			serviceAccountKeyService,
+			s.Redis),
+		ServiceAccountService: services.NewServiceAccountService(
+			s.Repository.UserRepository,
+			s.Repository.UserKeysRepository,
+			s.Repository.ServiceAccountRepository,
+			s.Repository.PasswordEntryRepository,
+			s.Repository.PasswordEntryKeysRepository,
+			s.Repository.PasswordGroupRepository,
+			s.Repository.PasswordTagRepository,
+			s.Repository.PasswordAccessLogRepository,
+			s.Encryption.EncryptionService,
+			s.Redis),
+		UserDeviceService: services.NewUserDeviceService(
+			s.Repository.UserRepository,
+			s.Repository.UserDeviceRepository,
+			s.Redis),
+		WebAuthnService: services.NewWebAuthnService(
+			s.Repository.UserRepository,
+			s.Repository.UserAuthenticatorRepository,
+			s.Redis,
+			webauthn.RelyingParty{
+				ID:               s.Config.WebAuthnRPID,
+				Name:             s.Config.WebAuthnRPName,
+				Origins:          strings.Split(s.Config.WebAuthnOrigins, ","),
+				UserVerification: strings.ToLower(strings.TrimSpace(s.Config.WebAuthnUserVerification)),
+			},
+			s.Config.WebAuthnTimeout,
+			s.Config.StepUpMaxAge),
+		VaultService: services.NewVaultService(
+			s.Repository.UserRepository,
+			s.Repository.UserKeysRepository,
+			s.Encryption.EncryptionService,
+			breachService,
+			s.Redis,
+			s.Config.VaultIdleTimeout,
+			s.Config.VaultMaxAge),
+		InternalService: services.NewInternalService(
+			s.Repository.UserRepository,
+			s.Repository.UserKeysRepository,
+			s.Repository.VaultRepository,
+			attachmentService,
+			s.Encryption.EncryptionService,
+			s.Redis),
+		BreachService:           breachService,
+		PasswordPolicyService:   passwordPolicyService,
+		AttachmentService:       attachmentService,
+		EquivalentDomainService: equivalentDomainService,
+		SSHKeyService: services.NewSSHKeyService(
+			s.Repository.UserRepository,
+			s.Repository.UserKeysRepository,
+			s.Repository.PasswordEntryRepository,
+			s.Repository.PasswordEntryKeysRepository,
+			s.Repository.PasswordAccessLogRepository,
+			s.Encryption.EncryptionService,
+			s.Redis),
+		PasskeyService: services.NewPasskeyService(
+			s.Repository.UserRepository,
+			s.Repository.UserKeysRepository,
+			s.Repository.PasswordEntryRepository,
+			s.Repository.PasswordEntryKeysRepository,
+			s.Repository.PasswordAccessLogRepository,
+			s.Encryption.EncryptionService,
+			s.Redis),
+		ReportService: services.NewReportService(
+			s.Repository.UserRepository,
+			s.Repository.PasswordEntryRepository,
+			s.Repository.PasswordPolicyRepository,
+			s.Repository.PasswordGroupRepository,
+			s.Repository.PasswordEntryGroupRepository,
 			s.Redis),
 	}
+	s.Services.UserEventService = services.NewUserEventService(
+		s.Repository.UserRepository,
+		s.Services.InternalService,
+		s.Redis,
+		map[string]string{
+			s.Config.NatsSubjectUserCreated:     utils.EventUserCreated,
+			s.Config.NatsSubjectUserDeleted:     utils.EventUserDeleted,
+			s.Config.NatsSubjectUserRoleChanged: utils.EventUserRoleChanged,
+		})
+	publisher := event.NewPublisher(s.Nats.Conn, s.Config.NatsPublishTimeout)
+	if s.Nats.Conn != nil {
+		if err := publisher.CheckStream(s.Config.NatsEventSubjectPrefix + ">"); err != nil {
+			logrus.WithError(err).Error("❌ No JetStream stream captures the outbox subjects, events stay queued in the outbox until one is created")
+		}
+	}
+	s.Services.OutboxService = services.NewOutboxService(
+		s.Repository.OutboxRepository,
+		s.Repository.PasswordEntryRepository,
+		publisher,
+		s.Config.NatsEventSubjectPrefix,
+		s.Config.OutboxBatchSize,
+		s.Config.OutboxRetention)
 }
 
 func (s *ServerConfig) initController() {
 	s.Controller = Controller{
-		PasswordEntryController: controller.NewPasswordEntryController(s.Services.PasswordEntryService, s.JWTService),
-		PasswordGroupController: controller.NewPasswordGroupController(s.Services.PasswordGroupService, s.JWTService),
-		PasswordTagController:   controller.NewPasswordTagController(s.Services.PasswordTagService, s.JWTService),
+		PasswordEntryController:    controller.NewPasswordEntryController(s.Services.PasswordEntryService, s.JWTService),
+		PasswordGroupController:    controller.NewPasswordGroupController(s.Services.PasswordGroupService, s.JWTService),
+		PasswordTagController:      controller.NewPasswordTagController(s.Services.PasswordTagService, s.JWTService),
+		ServiceAccountController:   controller.NewServiceAccountController(s.Services.ServiceAccountService, s.JWTService),
+		UserDeviceController:       controller.NewUserDeviceController(s.Services.UserDeviceService, s.JWTService),
+		VaultController:            controller.NewVaultController(s.Services.VaultService, s.JWTService),
+		InternalController:         controller.NewInternalController(s.Services.InternalService, s.JWTService),
+		ReportController:           controller.NewReportController(s.Services.ReportService, s.JWTService),
+		PasswordPolicyController:   controller.NewPasswordPolicyController(s.Services.PasswordPolicyService, s.JWTService),
+		AttachmentController:       controller.NewAttachmentController(s.Services.AttachmentService, s.JWTService),
+		SSHKeyController:           controller.NewSSHKeyController(s.Services.SSHKeyService, s.JWTService),
+		PasskeyController:          controller.NewPasskeyController(s.Services.PasskeyService, s.JWTService),
+		WebAuthnController:         controller.NewWebAuthnController(s.Services.WebAuthnService, s.JWTService),
+		EquivalentDomainController: controller.NewEquivalentDomainController(s.Services.EquivalentDomainService, s.JWTService),
 	}
 }
 
 func (s *ServerConfig) initMiddleware() {
 	s.Middleware = Middleware{
-		PasswordMiddleware: middleware.NewPasswordMiddleware(s.JWTService),
+		PasswordMiddleware: middleware.NewPasswordMiddleware(s.JWTService, s.Redis, s.Repository.UserDeviceRepository, s.Config.DeviceSeenInterval),
 		AdminMiddleware:    middleware.NewAdminMiddleware(s.JWTService),
+		StepUpMiddleware: middleware.NewStepUpMiddleware(
+			s.Redis,
+			s.Repository.UserAuthenticatorRepository,
+			strings.Split(s.Config.StepUpActions, ","),
+			s.Config.StepUpMaxAge,
+			s.Config.PinMaxAttempts,
+			s.Config.PinLockoutDuration),
+		RateLimitMiddleware: middleware.NewRateLimitMiddleware(
+			s.Redis,
+			s.Config.RateLimitEnabled,
+			s.Config.RateLimitWindow,
+			map[string]int{
+				utils.RateLimitBucketRead:      s.Config.RateLimitRead,
+				utils.RateLimitBucketReveal:    s.Config.RateLimitReveal,
+				utils.RateLimitBucketExport:    s.Config.RateLimitExport,
+				utils.RateLimitBucketPinVerify: s.Config.RateLimitPinVerify,
+			}),
+		ServiceTokenMiddleware: middleware.NewServiceTokenMiddleware(s.Services.ServiceAccountService),
+		VaultMiddleware:        middleware.NewVaultMiddleware(s.Redis, s.Config.VaultIdleTimeout, s.Config.VaultMaxAge),
+		InternalMiddleware:     middleware.NewInternalMiddleware(s.JWTService, strings.Split(s.Config.InternalAllowedServices, ",")),
 	}
 }
 func (s *ServerConfig) initCron() {
-	s.Cron = Cron{}
-	//s.Cron.CronService.Start()
+	s.Cron = Cron{
+		CronService: cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DiscardLogger))),
+	}
+
+	addJob := func(schedule string, job func()) {
+		if _, err := s.Cron.CronService.AddFunc(schedule, job); err != nil {
+			log.Fatalf("❌ Invalid cron schedule %q: %v", schedule, err)
+		}
+	}
+
+	addJob(s.Config.ExpiryScanSchedule, func() { _, _ = s.Services.OutboxService.MarkExpiredEntries() })
+	addJob(s.Config.OutboxCleanupSchedule, func() { _, _ = s.Services.OutboxService.DeletePublishedEvents() })
+	addJob(s.Config.BreachRecheckSchedule, func() { _, _ = s.Services.VaultService.RecheckUnlockedVaults() })
+	if s.Nats.Conn != nil {
+		addJob(s.Config.OutboxRelaySchedule, func() { _, _ = s.Services.OutboxService.RelayEvents() })
+	}
+	s.Cron.CronService.Start()
 }
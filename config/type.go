@@ -2,12 +2,15 @@ package config
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 	"password-management-service/internal/controller"
 	"password-management-service/internal/middleware"
 	"password-management-service/internal/repository"
 	"password-management-service/internal/services"
 	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/event"
 	"password-management-service/internal/utils/jwt"
 	"password-management-service/internal/utils/redis"
 )
@@ -30,38 +33,82 @@ type ServerConfig struct {
 
 // Services holds all service dependencies
 type Services struct {
-	PasswordEntryService services.PasswordEntryService
-	PasswordGroupService services.PasswordGroupService
-	PasswordTagService   services.PasswordTagService
+	PasswordEntryService    services.PasswordEntryService
+	PasswordGroupService    services.PasswordGroupService
+	PasswordTagService      services.PasswordTagService
+	ServiceAccountService   services.ServiceAccountService
+	UserDeviceService       services.UserDeviceService
+	VaultService            services.VaultService
+	InternalService         services.InternalService
+	UserEventService        services.UserEventService
+	OutboxService           services.OutboxService
+	BreachService           services.BreachService
+	ReportService           services.ReportService
+	PasswordPolicyService   services.PasswordPolicyService
+	AttachmentService       services.AttachmentService
+	SSHKeyService           services.SSHKeyService
+	PasskeyService          services.PasskeyService
+	WebAuthnService         services.WebAuthnService
+	EquivalentDomainService services.EquivalentDomainService
 }
 
 // Repository contains repository (database access objects)
 type Repository struct {
-	UserRepository              repository.UserRepository
-	UserKeysRepository          repository.UserKeysRepository
-	PasswordEntryRepository     repository.PasswordEntryRepository
-	PasswordEntryKeysRepository repository.PasswordEntryKeysRepository
-	PasswordTagRepository       repository.PasswordTagRepository
-	PasswordGroupRepository     repository.PasswordGroupRepository
-	PasswordHistoryRepository   repository.PasswordHistoryRepository
-	SharedPasswordRepository    repository.SharedPasswordRepository
+	UserRepository               repository.UserRepository
+	UserKeysRepository           repository.UserKeysRepository
+	PasswordEntryRepository      repository.PasswordEntryRepository
+	PasswordEntryKeysRepository  repository.PasswordEntryKeysRepository
+	PasswordTagRepository        repository.PasswordTagRepository
+	PasswordGroupRepository      repository.PasswordGroupRepository
+	PasswordEntryGroupRepository repository.PasswordEntryGroupRepository
+	PasswordEntryURLRepository   repository.PasswordEntryURLRepository
+	PasswordHistoryRepository    repository.PasswordHistoryRepository
+	SharedPasswordRepository     repository.SharedPasswordRepository
+	PasswordAccessLogRepository  repository.PasswordAccessLogRepository
+	ServiceAccountRepository     repository.ServiceAccountRepository
+	UserDeviceRepository         repository.UserDeviceRepository
+	UserAuthenticatorRepository  repository.UserAuthenticatorRepository
+	VaultRepository              repository.VaultRepository
+	OutboxRepository             repository.OutboxRepository
+	PasswordPolicyRepository     repository.PasswordPolicyRepository
+	PasswordAttachmentRepository repository.PasswordAttachmentRepository
+	EquivalentDomainRepository   repository.EquivalentDomainRepository
 }
 
 type Controller struct {
-	PasswordEntryController controller.PasswordEntryController
-	PasswordGroupController controller.PasswordGroupController
-	PasswordTagController   controller.PasswordTagController
+	PasswordEntryController    controller.PasswordEntryController
+	PasswordGroupController    controller.PasswordGroupController
+	PasswordTagController      controller.PasswordTagController
+	ServiceAccountController   controller.ServiceAccountController
+	UserDeviceController       controller.UserDeviceController
+	VaultController            controller.VaultController
+	InternalController         controller.InternalController
+	ReportController           controller.ReportController
+	PasswordPolicyController   controller.PasswordPolicyController
+	AttachmentController       controller.AttachmentController
+	SSHKeyController           controller.SSHKeyController
+	PasskeyController          controller.PasskeyController
+	WebAuthnController         controller.WebAuthnController
+	EquivalentDomainController controller.EquivalentDomainController
 }
 
 type Middleware struct {
-	PasswordMiddleware middleware.PasswordMiddleware
-	AdminMiddleware    middleware.AdminMiddleware
+	PasswordMiddleware     middleware.PasswordMiddleware
+	AdminMiddleware        middleware.AdminMiddleware
+	StepUpMiddleware       middleware.StepUpMiddleware
+	RateLimitMiddleware    middleware.RateLimitMiddleware
+	ServiceTokenMiddleware middleware.ServiceTokenMiddleware
+	VaultMiddleware        middleware.VaultMiddleware
+	InternalMiddleware     middleware.InternalMiddleware
 }
 
 type Cron struct {
+	CronService *cron.Cron
 }
 
 type Nats struct {
+	Conn       *nats.Conn
+	Subscriber event.Subscriber
 }
 
 type Encryption struct {
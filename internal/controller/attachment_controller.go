@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"net/http"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/vault"
+	"password-management-service/package/response"
+	"strconv"
+)
+
+// multipartOverhead leaves room for the multipart boundaries and headers around the file itself
+const multipartOverhead = 1 << 20
+
+type AttachmentController interface {
+	AddAttachment(context *gin.Context)
+	GetListAttachments(context *gin.Context)
+	DownloadAttachment(context *gin.Context)
+	DeleteAttachment(context *gin.Context)
+}
+
+type attachmentController struct {
+	AttachmentService services.AttachmentService
+	JWTService        jwt.Service
+}
+
+func NewAttachmentController(attachmentService services.AttachmentService, jwtService jwt.Service) AttachmentController {
+	return &attachmentController{
+		AttachmentService: attachmentService,
+		JWTService:        jwtService,
+	}
+}
+
+func (c *attachmentController) AddAttachment(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Entry ID must be a number", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	maxSize := c.AttachmentService.MaxSize()
+	context.Request.Body = http.MaxBytesReader(context.Writer, context.Request.Body, maxSize+multipartOverhead)
+	fileHeader, err := context.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.SendResponse(context, http.StatusRequestEntityTooLarge, "Error", nil, "attachment exceeds the maximum size of "+strconv.FormatInt(maxSize, 10)+" bytes")
+			return
+		}
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+	if fileHeader.Size > maxSize {
+		response.SendResponse(context, http.StatusRequestEntityTooLarge, "Error", nil, "attachment exceeds the maximum size of "+strconv.FormatInt(maxSize, 10)+" bytes")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	attachment, err := c.AttachmentService.AddAttachment(entryID, fileHeader.Filename, fileHeader.Header.Get("Content-Type"), data, token.ClientID, vaultKey)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", attachment, nil)
+}
+
+func (c *attachmentController) GetListAttachments(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Entry ID must be a number", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	attachments, err := c.AttachmentService.GetListAttachments(entryID, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", attachments, nil)
+}
+
+func (c *attachmentController) DownloadAttachment(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Entry ID must be a number", nil, err.Error())
+		return
+	}
+	attachmentID, err := utils.ConvertToUint(context.Param("attachmentId"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Attachment ID must be a number", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	metadata := in.RequestMetadata{
+		RequestID: context.GetHeader(utils.XRequestID),
+		IPAddress: context.ClientIP(),
+		UserAgent: context.Request.UserAgent(),
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	attachment, data, err := c.AttachmentService.DownloadAttachment(entryID, attachmentID, token.ClientID, vaultKey, metadata)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+
+	context.Header("Cache-Control", "no-store")
+	context.Header("X-Content-Type-Options", "nosniff")
+	context.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	context.Data(http.StatusOK, attachment.ContentType, data)
+}
+
+func (c *attachmentController) DeleteAttachment(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Entry ID must be a number", nil, err.Error())
+		return
+	}
+	attachmentID, err := utils.ConvertToUint(context.Param("attachmentId"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Attachment ID must be a number", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := c.AttachmentService.DeleteAttachment(entryID, attachmentID, token.ClientID); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", nil, "Attachment deleted successfully")
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/package/response"
+)
+
+type EquivalentDomainController interface {
+	GetListEquivalentDomainGroups(context *gin.Context)
+	AddEquivalentDomainGroup(context *gin.Context)
+	UpdateEquivalentDomainGroup(context *gin.Context)
+	DeleteEquivalentDomainGroup(context *gin.Context)
+	ExcludeEquivalentDomainGroup(context *gin.Context)
+	IncludeEquivalentDomainGroup(context *gin.Context)
+}
+
+type equivalentDomainController struct {
+	EquivalentDomainService services.EquivalentDomainService
+	JWT                     jwt.Service
+}
+
+func NewEquivalentDomainController(equivalentDomainService services.EquivalentDomainService, JWT jwt.Service) EquivalentDomainController {
+	return &equivalentDomainController{
+		EquivalentDomainService: equivalentDomainService,
+		JWT:                     JWT,
+	}
+}
+
+func (e *equivalentDomainController) GetListEquivalentDomainGroups(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	groups, err := e.EquivalentDomainService.GetListEquivalentDomainGroups(token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Failed to get equivalent domain groups", nil, err.Error())
+		return
+	}
+
+	response.SendResponse(context, http.StatusOK, "Get equivalent domain groups successfully", groups, nil)
+}
+
+func (e *equivalentDomainController) AddEquivalentDomainGroup(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	var req in.EquivalentDomainGroupRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	group, err := e.EquivalentDomainService.AddEquivalentDomainGroup(&req, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Failed to add equivalent domain group", nil, err.Error())
+		return
+	}
+
+	response.SendResponse(context, http.StatusCreated, "Equivalent domain group added successfully", group, nil)
+}
+
+func (e *equivalentDomainController) UpdateEquivalentDomainGroup(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	groupID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	var req in.EquivalentDomainGroupRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	group, err := e.EquivalentDomainService.UpdateEquivalentDomainGroup(groupID, &req, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Failed to update equivalent domain group", nil, err.Error())
+		return
+	}
+
+	response.SendResponse(context, http.StatusOK, "Equivalent domain group updated successfully", group, nil)
+}
+
+func (e *equivalentDomainController) DeleteEquivalentDomainGroup(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	groupID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	if err := e.EquivalentDomainService.DeleteEquivalentDomainGroup(groupID, token.ClientID); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Failed to delete equivalent domain group", nil, err.Error())
+		return
+	}
+
+	response.SendResponse(context, http.StatusOK, "Equivalent domain group deleted successfully", nil, nil)
+}
+
+func (e *equivalentDomainController) ExcludeEquivalentDomainGroup(context *gin.Context) {
+	e.setExcluded(context, true, "Equivalent domain group excluded successfully")
+}
+
+func (e *equivalentDomainController) IncludeEquivalentDomainGroup(context *gin.Context) {
+	e.setExcluded(context, false, "Equivalent domain group included successfully")
+}
+
+func (e *equivalentDomainController) setExcluded(context *gin.Context, excluded bool, message string) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	groupID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	if err := e.EquivalentDomainService.SetEquivalentDomainGroupExcluded(groupID, token.ClientID, excluded); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Failed to update equivalent domain group", nil, err.Error())
+		return
+	}
+
+	response.SendResponse(context, http.StatusOK, message, nil, nil)
+}
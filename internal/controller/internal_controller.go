@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/package/response"
+)
+
+type InternalController interface {
+	BootstrapUserKey(context *gin.Context)
+	DeleteUser(context *gin.Context)
+	GetStatistics(context *gin.Context)
+}
+
+type internalController struct {
+	InternalService services.InternalService
+	JWTService      jwt.Service
+}
+
+func NewInternalController(internalService services.InternalService, jwtService jwt.Service) InternalController {
+	return &internalController{
+		InternalService: internalService,
+		JWTService:      jwtService,
+	}
+}
+
+func (c *internalController) BootstrapUserKey(context *gin.Context) {
+	userID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	claims, exist := jwt.ExtractInternalClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	result, err := c.InternalService.BootstrapUserKey(userID, claims.Service)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "User key bootstrapped successfully", result, nil)
+}
+
+func (c *internalController) DeleteUser(context *gin.Context) {
+	userID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	claims, exist := jwt.ExtractInternalClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	result, err := c.InternalService.DeleteUser(userID, claims.Service)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "User data deleted successfully", result, nil)
+}
+
+func (c *internalController) GetStatistics(context *gin.Context) {
+	var userID *uint
+	if value := context.Query("user_id"); value != "" {
+		id, err := utils.ConvertToUint(value)
+		if err != nil {
+			response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+			return
+		}
+		userID = &id
+	}
+
+	statistics, err := c.InternalService.GetStatistics(userID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", statistics, nil)
+}
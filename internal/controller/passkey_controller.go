@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/vault"
+	"password-management-service/package/response"
+)
+
+type PasskeyController interface {
+	GetAttestation(context *gin.Context)
+	Assert(context *gin.Context)
+}
+
+type passkeyController struct {
+	PasskeyService services.PasskeyService
+	JWTService     jwt.Service
+}
+
+func NewPasskeyController(passkeyService services.PasskeyService, jwtService jwt.Service) PasskeyController {
+	return &passkeyController{
+		PasskeyService: passkeyService,
+		JWTService:     jwtService,
+	}
+}
+
+func (c *passkeyController) GetAttestation(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Entry ID must be a number", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	attestation, err := c.PasskeyService.GetAttestation(entryID, token.ClientID, vaultKey)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", attestation, nil)
+}
+
+func (c *passkeyController) Assert(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Entry ID must be a number", nil, err.Error())
+		return
+	}
+
+	var req in.PasskeyAssertionRequest
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	metadata := in.RequestMetadata{
+		RequestID: context.GetHeader(utils.XRequestID),
+		IPAddress: context.ClientIP(),
+		UserAgent: context.Request.UserAgent(),
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	assertion, err := c.PasskeyService.Assert(entryID, &req, token.ClientID, vaultKey, metadata)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", assertion, nil)
+}
@@ -1,21 +1,38 @@
 package controller
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"net/http"
 	"password-management-service/internal/dto/in"
+	"password-management-service/internal/repository"
 	"password-management-service/internal/services"
 	"password-management-service/internal/utils"
 	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/policy"
+	"password-management-service/internal/utils/vault"
 	"password-management-service/package/response"
+	"strconv"
 )
 
+const defaultRecentEntries = 20
+
 type PasswordEntryController interface {
 	AddPasswordEntry(context *gin.Context)
 	UpdatePasswordEntry(context *gin.Context)
 	AddGroupPasswordEntry(context *gin.Context)
+	BulkPasswordEntries(context *gin.Context)
+	IsBulkDelete(context *gin.Context) bool
 	GetListPasswordEntries(context *gin.Context)
+	GetRecentPasswordEntries(context *gin.Context)
+	GetMatchingPasswordEntries(context *gin.Context)
+	FavoritePasswordEntry(context *gin.Context)
+	UnfavoritePasswordEntry(context *gin.Context)
+	PinPasswordEntry(context *gin.Context)
+	UnpinPasswordEntry(context *gin.Context)
 	GetPasswordEntryByID(context *gin.Context)
+	RevealPasswordEntry(context *gin.Context)
 	DeletePasswordEntry(context *gin.Context)
 }
 
@@ -40,18 +57,15 @@ func (c *passwordEntryController) AddPasswordEntry(context *gin.Context) {
 		return
 	}
 
-	requestID := context.GetHeader(utils.XRequestID)
-	if requestID == "" {
-		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "request id not found")
-		return
-	}
-
 	if err := context.ShouldBindJSON(&req); err != nil {
 		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
 		return
 	}
 
-	if err := c.PasswordEntryService.AddPasswordEntry(&req, token.ClientID, requestID); err != nil {
+	if err := c.PasswordEntryService.AddPasswordEntry(&req, token.ClientID); err != nil {
+		if sendPolicyViolation(context, err) {
+			return
+		}
 		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
 		return
 	}
@@ -78,7 +92,11 @@ func (c *passwordEntryController) UpdatePasswordEntry(context *gin.Context) {
 		return
 	}
 
-	if err := c.PasswordEntryService.UpdatePasswordEntry(entryID, &req, token.ClientID); err != nil {
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	if err := c.PasswordEntryService.UpdatePasswordEntry(entryID, &req, token.ClientID, vaultKey); err != nil {
+		if sendPolicyViolation(context, err) {
+			return
+		}
 		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
 		return
 	}
@@ -102,13 +120,49 @@ func (c *passwordEntryController) AddGroupPasswordEntry(context *gin.Context) {
 		return
 	}
 
-	if err := c.PasswordEntryService.AddGroupPasswordEntry(req, token.ClientID); err != nil {
-		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	if err := c.PasswordEntryService.AddGroupPasswordEntry(req, token.ClientID, vaultKey); err != nil {
+		if sendPolicyViolation(context, err) {
+			return
+		}
+		response.SendResponse(context, errorStatus(err), "Error", nil, err.Error())
 		return
 	}
 	response.SendResponse(context, http.StatusOK, "Success", nil, "Password entry updated successfully")
 }
 
+func (c *passwordEntryController) BulkPasswordEntries(context *gin.Context) {
+	var req in.BulkPasswordEntryRequest
+	if err := context.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	result, err := c.PasswordEntryService.BulkPasswordEntries(&req, token.ClientID, vaultKey)
+	if err != nil {
+		response.SendResponse(context, errorStatus(err), "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", result, nil)
+}
+
+// IsBulkDelete reports whether a bulk request deletes entries, so the route can require the delete step-up. The
+// body is cached for the handler.
+func (c *passwordEntryController) IsBulkDelete(context *gin.Context) bool {
+	var req in.BulkPasswordEntryRequest
+	if err := context.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		return false
+	}
+	return req.Action == utils.BulkActionDelete
+}
+
 func (c *passwordEntryController) GetListPasswordEntries(context *gin.Context) {
 	token, exist := jwt.ExtractTokenClaims(context)
 	if !exist {
@@ -123,8 +177,11 @@ func (c *passwordEntryController) GetListPasswordEntries(context *gin.Context) {
 	}
 
 	tagsParam := context.Query("tags")
+	entryType := context.Query("type")
+	favorite := context.Query("favorite") == "true"
+	sort := context.Query("sort")
 
-	passwordEntries, total, err := c.PasswordEntryService.GetListPasswordEntries(token.ClientID, tagsParam, pageIndex, pageSize)
+	passwordEntries, total, err := c.PasswordEntryService.GetListPasswordEntries(token.ClientID, tagsParam, entryType, favorite, sort, pageIndex, pageSize)
 	if err != nil {
 		response.SendResponseList(context, 500, "Failed to get list password entry", response.PagedData{
 			Total:     total,
@@ -143,6 +200,85 @@ func (c *passwordEntryController) GetListPasswordEntries(context *gin.Context) {
 	}, nil)
 }
 
+func (c *passwordEntryController) GetRecentPasswordEntries(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	limit, err := strconv.Atoi(context.DefaultQuery("limit", strconv.Itoa(defaultRecentEntries)))
+	if err != nil || limit <= 0 {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "limit must be a positive integer")
+		return
+	}
+
+	passwordEntries, err := c.PasswordEntryService.GetRecentPasswordEntries(token.ClientID, limit)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", passwordEntries, nil)
+}
+
+func (c *passwordEntryController) GetMatchingPasswordEntries(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	rawURL := context.Query("url")
+	if rawURL == "" {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "url is required")
+		return
+	}
+
+	passwordEntries, err := c.PasswordEntryService.GetMatchingPasswordEntries(token.ClientID, rawURL)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", passwordEntries, nil)
+}
+
+func (c *passwordEntryController) FavoritePasswordEntry(context *gin.Context) {
+	c.markPasswordEntry(context, c.PasswordEntryService.SetFavorite, true, "Password entry added to favorites")
+}
+
+func (c *passwordEntryController) UnfavoritePasswordEntry(context *gin.Context) {
+	c.markPasswordEntry(context, c.PasswordEntryService.SetFavorite, false, "Password entry removed from favorites")
+}
+
+func (c *passwordEntryController) PinPasswordEntry(context *gin.Context) {
+	c.markPasswordEntry(context, c.PasswordEntryService.SetPinned, true, "Password entry pinned")
+}
+
+func (c *passwordEntryController) UnpinPasswordEntry(context *gin.Context) {
+	c.markPasswordEntry(context, c.PasswordEntryService.SetPinned, false, "Password entry unpinned")
+}
+
+// markPasswordEntry sets or clears a per-entry flag such as favorite or pinned
+func (c *passwordEntryController) markPasswordEntry(context *gin.Context, set func(uint, string, bool) error, value bool, message string) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Resource MaintenanceTypeID must be a number", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := set(entryID, token.ClientID, value); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", nil, message)
+}
+
 func (c *passwordEntryController) GetPasswordEntryByID(context *gin.Context) {
 	entryID, err := utils.ConvertToUint(context.Param("id"))
 	if err != nil {
@@ -156,14 +292,50 @@ func (c *passwordEntryController) GetPasswordEntryByID(context *gin.Context) {
 		return
 	}
 
-	passwordEntry, err := c.PasswordEntryService.GetPasswordEntryByID(entryID, token.ClientID)
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	passwordEntry, err := c.PasswordEntryService.GetPasswordEntryByID(entryID, token.ClientID, vaultKey)
 	if err != nil {
-		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		response.SendResponse(context, errorStatus(err), "Error", nil, err.Error())
 		return
 	}
 	response.SendResponse(context, http.StatusOK, "Success", passwordEntry, nil)
 }
 
+func (c *passwordEntryController) RevealPasswordEntry(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Resource MaintenanceTypeID must be a number", nil, err.Error())
+		return
+	}
+
+	var req in.RevealPasswordEntryRequest
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	metadata := in.RequestMetadata{
+		RequestID: context.GetHeader(utils.XRequestID),
+		IPAddress: context.ClientIP(),
+		UserAgent: context.Request.UserAgent(),
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	revealed, err := c.PasswordEntryService.RevealPasswordEntry(entryID, &req, token.ClientID, vaultKey, metadata)
+	if err != nil {
+		response.SendResponse(context, errorStatus(err), "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", revealed, nil)
+}
+
 func (c *passwordEntryController) DeletePasswordEntry(context *gin.Context) {
 	entryID, err := utils.ConvertToUint(context.Param("id"))
 	if err != nil {
@@ -178,8 +350,32 @@ func (c *passwordEntryController) DeletePasswordEntry(context *gin.Context) {
 	}
 
 	if err := c.PasswordEntryService.DeletePasswordEntry(entryID, token.ClientID); err != nil {
-		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		response.SendResponse(context, errorStatus(err), "Error", nil, err.Error())
 		return
 	}
 	response.SendResponse(context, http.StatusOK, "Success", nil, "Password entry deleted successfully")
 }
+
+// sendPolicyViolation answers 422 with every violated rule when the service rejected the password by policy
+func sendPolicyViolation(context *gin.Context, err error) bool {
+	var violation *policy.ViolationError
+	if !errors.As(err, &violation) {
+		return false
+	}
+	response.SendResponse(context, http.StatusUnprocessableEntity, "Password policy violation", violation.Violations, err.Error())
+	return true
+}
+
+// errorStatus answers client input errors and unknown entries with 4xx, and everything else with 500
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrEntryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrServiceAccountKeyRequired), errors.Is(err, repository.ErrScopeChanged):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
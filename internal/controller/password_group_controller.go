@@ -14,7 +14,9 @@ type PasswordGroupController interface {
 	AddPasswordGroup(context *gin.Context)
 	UpdatePasswordGroup(context *gin.Context)
 	GetListPasswordGroup(context *gin.Context)
+	MovePasswordGroup(context *gin.Context)
 	GetItemListPasswordGroup(context *gin.Context)
+	GetPasswordGroupByPath(context *gin.Context)
 	DeletePasswordGroup(context *gin.Context)
 }
 
@@ -83,6 +85,34 @@ func (c *passwordGroupController) UpdatePasswordGroup(context *gin.Context) {
 	response.SendResponse(context, http.StatusOK, "Success", passwordGroup, nil)
 }
 
+func (c *passwordGroupController) MovePasswordGroup(context *gin.Context) {
+	var req in.MovePasswordGroupRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	passwordGroupID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	passwordGroup, err := c.PasswordGroupService.MovePasswordGroup(passwordGroupID, req, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+
+	response.SendResponse(context, http.StatusOK, "Success", passwordGroup, nil)
+}
+
 func (c *passwordGroupController) GetListPasswordGroup(context *gin.Context) {
 	token, exist := jwt.ExtractTokenClaims(context)
 	if !exist {
@@ -112,7 +142,31 @@ func (c *passwordGroupController) GetItemListPasswordGroup(context *gin.Context)
 		return
 	}
 
-	passwordGroup, err := c.PasswordGroupService.GetItemListPasswordGroup(passwordGroupID, token.ClientID)
+	recursive := context.Query("recursive") == "true"
+
+	passwordGroup, err := c.PasswordGroupService.GetItemListPasswordGroup(passwordGroupID, token.ClientID, recursive)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+
+	response.SendResponse(context, http.StatusOK, "Success", passwordGroup, nil)
+}
+
+func (c *passwordGroupController) GetPasswordGroupByPath(context *gin.Context) {
+	path := context.Query("path")
+	if path == "" {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "path is required")
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	passwordGroup, err := c.PasswordGroupService.GetPasswordGroupByPath(path, token.ClientID)
 	if err != nil {
 		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
 		return
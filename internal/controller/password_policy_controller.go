@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/package/response"
+)
+
+type PasswordPolicyController interface {
+	AddPolicy(context *gin.Context)
+	UpdatePolicy(context *gin.Context)
+	GetListPolicies(context *gin.Context)
+	GetPolicyByID(context *gin.Context)
+	DeletePolicy(context *gin.Context)
+}
+
+type passwordPolicyController struct {
+	PasswordPolicyService services.PasswordPolicyService
+	JWTService            jwt.Service
+}
+
+func NewPasswordPolicyController(passwordPolicyService services.PasswordPolicyService, jwtService jwt.Service) PasswordPolicyController {
+	return &passwordPolicyController{
+		PasswordPolicyService: passwordPolicyService,
+		JWTService:            jwtService,
+	}
+}
+
+func (c *passwordPolicyController) AddPolicy(context *gin.Context) {
+	var req in.PasswordPolicyRequest
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	policy, err := c.PasswordPolicyService.AddPolicy(&req, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusCreated, "Password policy added successfully", policy, nil)
+}
+
+func (c *passwordPolicyController) UpdatePolicy(context *gin.Context) {
+	policyID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	var req in.PasswordPolicyRequest
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	if err := c.PasswordPolicyService.UpdatePolicy(policyID, &req, token.ClientID); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Password policy updated successfully", nil, nil)
+}
+
+func (c *passwordPolicyController) GetListPolicies(context *gin.Context) {
+	policies, err := c.PasswordPolicyService.GetListPolicies()
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", policies, nil)
+}
+
+func (c *passwordPolicyController) GetPolicyByID(context *gin.Context) {
+	policyID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	policy, err := c.PasswordPolicyService.GetPolicyByID(policyID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", policy, nil)
+}
+
+func (c *passwordPolicyController) DeletePolicy(context *gin.Context) {
+	policyID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := c.PasswordPolicyService.DeletePolicy(policyID, token.ClientID); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Password policy deleted successfully", nil, nil)
+}
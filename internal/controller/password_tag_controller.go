@@ -3,15 +3,18 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"password-management-service/internal/dto/in"
 	"password-management-service/internal/services"
 	"password-management-service/internal/utils"
 	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/vault"
 	"password-management-service/package/response"
 )
 
 type PasswordTagController interface {
 	AddPasswordTag(context *gin.Context)
 	UpdatePasswordTag(context *gin.Context)
+	MergePasswordTag(context *gin.Context)
 	GetListPasswordTag(context *gin.Context)
 	DeletePasswordTag(context *gin.Context)
 }
@@ -35,15 +38,13 @@ func (p *passwordTagController) AddPasswordTag(context *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Name string `json:"name" binding:"required"`
-	}
+	var req in.PasswordTagRequest
 	if err := context.ShouldBindJSON(&req); err != nil {
 		response.SendResponse(context, http.StatusBadRequest, "Invalid request", nil, err.Error())
 		return
 	}
 
-	passwordTag, err := p.PasswordTagService.AddPasswordTag(req, token.ClientID)
+	passwordTag, err := p.PasswordTagService.AddPasswordTag(&req, token.ClientID)
 	if err != nil {
 		response.SendResponse(context, http.StatusInternalServerError, "Failed to add password tag", nil, err.Error())
 		return
@@ -65,15 +66,13 @@ func (p *passwordTagController) UpdatePasswordTag(context *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Name string `json:"name" binding:"required"`
-	}
+	var req in.PasswordTagRequest
 	if err := context.ShouldBindJSON(&req); err != nil {
 		response.SendResponse(context, http.StatusBadRequest, "Invalid request", nil, err.Error())
 		return
 	}
 
-	passwordTag, err := p.PasswordTagService.UpdatePasswordTag(tagID, req, token.ClientID)
+	passwordTag, err := p.PasswordTagService.UpdatePasswordTag(tagID, &req, token.ClientID)
 	if err != nil {
 		response.SendResponse(context, http.StatusInternalServerError, "Failed to update password tag", nil, err.Error())
 		return
@@ -82,6 +81,35 @@ func (p *passwordTagController) UpdatePasswordTag(context *gin.Context) {
 	response.SendResponse(context, http.StatusOK, "Password tag updated successfully", passwordTag, nil)
 }
 
+func (p *passwordTagController) MergePasswordTag(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	tagID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	var req in.MergePasswordTagRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	result, err := p.PasswordTagService.MergePasswordTag(tagID, &req, token.ClientID, vaultKey)
+	if err != nil {
+		response.SendResponse(context, errorStatus(err), "Failed to merge password tags", nil, err.Error())
+		return
+	}
+
+	response.SendResponse(context, http.StatusOK, "Password tags merged successfully", result, nil)
+}
+
 func (p *passwordTagController) GetListPasswordTag(context *gin.Context) {
 	token, exist := jwt.ExtractTokenClaims(context)
 	if !exist {
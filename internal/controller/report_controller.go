@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/package/response"
+)
+
+type ReportController interface {
+	GetBreachedEntries(context *gin.Context)
+	GetPolicyViolations(context *gin.Context)
+}
+
+type reportController struct {
+	ReportService services.ReportService
+	JWTService    jwt.Service
+}
+
+func NewReportController(reportService services.ReportService, jwtService jwt.Service) ReportController {
+	return &reportController{
+		ReportService: reportService,
+		JWTService:    jwtService,
+	}
+}
+
+func (c *reportController) GetBreachedEntries(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	entries, err := c.ReportService.GetBreachedEntries(token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", entries, nil)
+}
+
+func (c *reportController) GetPolicyViolations(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	violations, err := c.ReportService.GetPolicyViolations(token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", violations, nil)
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/apitoken"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/vault"
+	"password-management-service/package/response"
+)
+
+type ServiceAccountController interface {
+	AddServiceAccount(context *gin.Context)
+	GetListServiceAccount(context *gin.Context)
+	DeleteServiceAccount(context *gin.Context)
+	AddServiceAccountToken(context *gin.Context)
+	DeleteServiceAccountToken(context *gin.Context)
+	SyncServiceAccount(context *gin.Context)
+	GetListServiceEntries(context *gin.Context)
+	GetServiceSecret(context *gin.Context)
+	RenderSecrets(context *gin.Context)
+}
+
+type serviceAccountController struct {
+	ServiceAccountService services.ServiceAccountService
+	JWTService            jwt.Service
+}
+
+func NewServiceAccountController(serviceAccountService services.ServiceAccountService, jwtService jwt.Service) ServiceAccountController {
+	return &serviceAccountController{
+		ServiceAccountService: serviceAccountService,
+		JWTService:            jwtService,
+	}
+}
+
+func (c *serviceAccountController) AddServiceAccount(context *gin.Context) {
+	var req in.ServiceAccountRequest
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	serviceAccount, err := c.ServiceAccountService.AddServiceAccount(&req, token.ClientID, vaultKey)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusCreated, "Service account created successfully", serviceAccount, nil)
+}
+
+func (c *serviceAccountController) GetListServiceAccount(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	serviceAccounts, err := c.ServiceAccountService.GetListServiceAccount(token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", serviceAccounts, nil)
+}
+
+func (c *serviceAccountController) DeleteServiceAccount(context *gin.Context) {
+	serviceAccountID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := c.ServiceAccountService.DeleteServiceAccount(serviceAccountID, token.ClientID); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Service account deleted successfully", nil, nil)
+}
+
+func (c *serviceAccountController) AddServiceAccountToken(context *gin.Context) {
+	serviceAccountID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	var req in.ServiceAccountTokenRequest
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	serviceAccountToken, err := c.ServiceAccountService.AddServiceAccountToken(serviceAccountID, &req, token.ClientID, vaultKey)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusCreated, "Service account token created successfully", serviceAccountToken, nil)
+}
+
+func (c *serviceAccountController) DeleteServiceAccountToken(context *gin.Context) {
+	serviceAccountID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	tokenID, err := utils.ConvertToUint(context.Param("token_id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := c.ServiceAccountService.DeleteServiceAccountToken(serviceAccountID, tokenID, token.ClientID); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Service account token revoked successfully", nil, nil)
+}
+
+func (c *serviceAccountController) SyncServiceAccount(context *gin.Context) {
+	serviceAccountID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	result, err := c.ServiceAccountService.SyncServiceAccount(serviceAccountID, token.ClientID, vaultKey)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Service account synchronized successfully", result, nil)
+}
+
+func (c *serviceAccountController) GetListServiceEntries(context *gin.Context) {
+	claims, exist := apitoken.ExtractServiceTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusUnauthorized, "Error", nil, "Service token not found")
+		return
+	}
+
+	entries, err := c.ServiceAccountService.GetListServiceEntries(claims)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", entries, nil)
+}
+
+func (c *serviceAccountController) GetServiceSecret(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	claims, exist := apitoken.ExtractServiceTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusUnauthorized, "Error", nil, "Service token not found")
+		return
+	}
+
+	metadata := in.RequestMetadata{
+		RequestID: context.GetHeader(utils.XRequestID),
+		IPAddress: context.ClientIP(),
+		UserAgent: context.Request.UserAgent(),
+	}
+
+	secret, err := c.ServiceAccountService.GetServiceSecret(entryID, claims, metadata)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", secret, nil)
+}
+
+func (c *serviceAccountController) RenderSecrets(context *gin.Context) {
+	var req in.RenderSecretsRequest
+	if err := context.ShouldBindQuery(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	claims, exist := apitoken.ExtractServiceTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusUnauthorized, "Error", nil, "Service token not found")
+		return
+	}
+
+	metadata := in.RequestMetadata{
+		RequestID: context.GetHeader(utils.XRequestID),
+		IPAddress: context.ClientIP(),
+		UserAgent: context.Request.UserAgent(),
+	}
+
+	body, contentType, err := c.ServiceAccountService.RenderSecrets(&req, claims, metadata)
+	if err != nil {
+		response.SendResponse(context, errorStatus(err), "Error", nil, err.Error())
+		return
+	}
+
+	context.Header("Cache-Control", "no-store")
+	context.Data(http.StatusOK, contentType, body)
+}
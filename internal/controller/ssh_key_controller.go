@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/vault"
+	"password-management-service/package/response"
+)
+
+type SSHKeyController interface {
+	Sign(context *gin.Context)
+	GetAuthorizedKey(context *gin.Context)
+}
+
+type sshKeyController struct {
+	SSHKeyService services.SSHKeyService
+	JWTService    jwt.Service
+}
+
+func NewSSHKeyController(sshKeyService services.SSHKeyService, jwtService jwt.Service) SSHKeyController {
+	return &sshKeyController{
+		SSHKeyService: sshKeyService,
+		JWTService:    jwtService,
+	}
+}
+
+func (c *sshKeyController) Sign(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Entry ID must be a number", nil, err.Error())
+		return
+	}
+
+	var req in.SSHSignRequest
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	metadata := in.RequestMetadata{
+		RequestID: context.GetHeader(utils.XRequestID),
+		IPAddress: context.ClientIP(),
+		UserAgent: context.Request.UserAgent(),
+	}
+
+	vaultKey, _ := vault.ExtractVaultKey(context)
+	signature, err := c.SSHKeyService.Sign(entryID, &req, token.ClientID, vaultKey, metadata)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", signature, nil)
+}
+
+// GetAuthorizedKey returns the public key as plain text so it can be appended to authorized_keys directly
+func (c *sshKeyController) GetAuthorizedKey(context *gin.Context) {
+	entryID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Entry ID must be a number", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	authorizedKey, err := c.SSHKeyService.GetAuthorizedKey(entryID, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	context.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(authorizedKey))
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/package/response"
+)
+
+type UserDeviceController interface {
+	GetListDevices(context *gin.Context)
+	RevokeDevice(context *gin.Context)
+	ApproveDevice(context *gin.Context)
+}
+
+type userDeviceController struct {
+	UserDeviceService services.UserDeviceService
+	JWTService        jwt.Service
+}
+
+func NewUserDeviceController(userDeviceService services.UserDeviceService, jwtService jwt.Service) UserDeviceController {
+	return &userDeviceController{
+		UserDeviceService: userDeviceService,
+		JWTService:        jwtService,
+	}
+}
+
+func (c *userDeviceController) GetListDevices(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	devices, err := c.UserDeviceService.GetListDevices(token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", devices, nil)
+}
+
+func (c *userDeviceController) RevokeDevice(context *gin.Context) {
+	deviceID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	err = c.UserDeviceService.RevokeDevice(deviceID, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Device revoked successfully", nil, nil)
+}
+
+func (c *userDeviceController) ApproveDevice(context *gin.Context) {
+	deviceID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	err = c.UserDeviceService.ApproveDevice(deviceID, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Device approved successfully", nil, nil)
+}
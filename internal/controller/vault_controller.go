@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/package/response"
+)
+
+type VaultController interface {
+	UnlockVault(context *gin.Context)
+	LockVault(context *gin.Context)
+}
+
+type vaultController struct {
+	VaultService services.VaultService
+	JWTService   jwt.Service
+}
+
+func NewVaultController(vaultService services.VaultService, jwtService jwt.Service) VaultController {
+	return &vaultController{
+		VaultService: vaultService,
+		JWTService:   jwtService,
+	}
+}
+
+func (c *vaultController) UnlockVault(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	session, err := c.VaultService.UnlockVault(token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	context.Header("Cache-Control", "no-store")
+	response.SendResponse(context, http.StatusOK, "Vault unlocked successfully", session, nil)
+}
+
+func (c *vaultController) LockVault(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := c.VaultService.LockVault(token.ClientID); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Vault locked successfully", nil, nil)
+}
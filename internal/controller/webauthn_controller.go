@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/package/response"
+)
+
+type WebAuthnController interface {
+	GetListAuthenticators(context *gin.Context)
+	BeginRegistration(context *gin.Context)
+	FinishRegistration(context *gin.Context)
+	BeginAuthentication(context *gin.Context)
+	FinishAuthentication(context *gin.Context)
+	DeleteAuthenticator(context *gin.Context)
+	ResetAuthenticators(context *gin.Context)
+}
+
+type webAuthnController struct {
+	WebAuthnService services.WebAuthnService
+	JWTService      jwt.Service
+}
+
+func NewWebAuthnController(webAuthnService services.WebAuthnService, jwtService jwt.Service) WebAuthnController {
+	return &webAuthnController{
+		WebAuthnService: webAuthnService,
+		JWTService:      jwtService,
+	}
+}
+
+func (c *webAuthnController) GetListAuthenticators(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	authenticators, err := c.WebAuthnService.GetListAuthenticators(token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", authenticators, nil)
+}
+
+func (c *webAuthnController) BeginRegistration(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	options, err := c.WebAuthnService.BeginRegistration(token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", options, nil)
+}
+
+func (c *webAuthnController) FinishRegistration(context *gin.Context) {
+	var req in.AuthenticatorRegistrationRequest
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	authenticator, err := c.WebAuthnService.FinishRegistration(&req, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusCreated, "Authenticator registered successfully", authenticator, nil)
+}
+
+func (c *webAuthnController) BeginAuthentication(context *gin.Context) {
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	options, err := c.WebAuthnService.BeginAuthentication(token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", options, nil)
+}
+
+// FinishAuthentication verifies the assertion for the X-REQUEST-ID of this request; the request that needs
+// step-up sends the same header
+func (c *webAuthnController) FinishAuthentication(context *gin.Context) {
+	var req in.AuthenticatorAssertionRequest
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := context.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	verification, err := c.WebAuthnService.FinishAuthentication(&req, token.ClientID, context.GetHeader(utils.XRequestID))
+	if err != nil {
+		response.SendResponse(context, http.StatusUnauthorized, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Success", verification, nil)
+}
+
+func (c *webAuthnController) DeleteAuthenticator(context *gin.Context) {
+	authenticatorID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := c.WebAuthnService.DeleteAuthenticator(authenticatorID, token.ClientID); err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Authenticator deleted successfully", nil, nil)
+}
+
+func (c *webAuthnController) ResetAuthenticators(context *gin.Context) {
+	userID, err := utils.ConvertToUint(context.Param("id"))
+	if err != nil {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, err.Error())
+		return
+	}
+
+	token, exist := jwt.ExtractTokenClaims(context)
+	if !exist {
+		response.SendResponse(context, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	result, err := c.WebAuthnService.ResetAuthenticators(userID, token.ClientID)
+	if err != nil {
+		response.SendResponse(context, http.StatusInternalServerError, "Error", nil, err.Error())
+		return
+	}
+	response.SendResponse(context, http.StatusOK, "Authenticators reset successfully", result, nil)
+}
@@ -0,0 +1,6 @@
+package in
+
+// EquivalentDomainGroupRequest lists domains that share the same accounts; each one is reduced to its base domain
+type EquivalentDomainGroupRequest struct {
+	Domains []string `json:"domains" binding:"required,min=2"`
+}
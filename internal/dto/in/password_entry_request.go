@@ -2,6 +2,8 @@ package in
 
 import (
 	"github.com/lib/pq"
+	"password-management-service/internal/utils/customfield"
+	"password-management-service/internal/utils/urlmatch"
 )
 
 type PasswordEntryRequest struct {
@@ -11,4 +13,44 @@ type PasswordEntryRequest struct {
 	Notes    *string         `json:"notes"`
 	URL      *string         `json:"url"`
 	Tags     *pq.StringArray `json:"tags"`
+	// URLs replaces the entry's URLs on every save; when omitted, URL is the only URL, matched by base domain
+	URLs []urlmatch.Rule `json:"urls"`
+	// EntryType defaults to login; Data holds the fields defined by the type's schema
+	EntryType string            `json:"entry_type"`
+	Data      map[string]string `json:"data"`
+	// CustomFields replaces the entry's custom fields on every save; omitting it clears them
+	CustomFields []customfield.Field `json:"custom_fields"`
+}
+
+// BulkPasswordEntryRequest applies one action to many entries. move, copy and remove need GroupID; tag and untag
+// need Tags.
+type BulkPasswordEntryRequest struct {
+	Action   string   `json:"action" binding:"required,oneof=move copy remove tag untag delete"`
+	EntryIDs []uint   `json:"entry_ids" binding:"required,min=1"`
+	GroupID  *uint    `json:"group_id"`
+	Tags     []string `json:"tags"`
+}
+
+type RevealPasswordEntryRequest struct {
+	Fields []string `json:"fields" binding:"required,min=1"`
+}
+
+// SSHSignRequest carries the base64 encoded data to sign. Algorithm selects rsa-sha2-256, rsa-sha2-512 (the
+// default) or ssh-rsa for RSA keys; other key types have a single algorithm.
+type SSHSignRequest struct {
+	Data      string `json:"data" binding:"required"`
+	Algorithm string `json:"algorithm"`
+}
+
+// PasskeyAssertionRequest is what a passkey provider receives from the browser: the RP ID and the SHA-256 hash of
+// the client data JSON, base64url encoded.
+type PasskeyAssertionRequest struct {
+	RPID           string `json:"rp_id" binding:"required"`
+	ClientDataHash string `json:"client_data_hash" binding:"required"`
+}
+
+type RequestMetadata struct {
+	RequestID string
+	IPAddress string
+	UserAgent string
 }
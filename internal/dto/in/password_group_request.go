@@ -1,7 +1,14 @@
 package in
 
+// PasswordGroupRequest creates a group under ParentGroupID, or at the root when it is omitted
 type PasswordGroupRequest struct {
-	Name string `json:"name,omitempty"`
+	Name          string `json:"name,omitempty"`
+	ParentGroupID *uint  `json:"parent_group_id,omitempty"`
+}
+
+// MovePasswordGroupRequest moves a group and its subtree under ParentGroupID, or to the root when it is null
+type MovePasswordGroupRequest struct {
+	ParentGroupID *uint `json:"parent_group_id"`
 }
 
 type PasswordGroupEntryRequest struct {
@@ -0,0 +1,14 @@
+package in
+
+type PasswordPolicyRequest struct {
+	Name              string `json:"name" binding:"required"`
+	UserID            *uint  `json:"user_id"`
+	GroupID           *uint  `json:"group_id"`
+	MinLength         int    `json:"min_length" binding:"min=0"`
+	RequireUppercase  bool   `json:"require_uppercase"`
+	RequireLowercase  bool   `json:"require_lowercase"`
+	RequireDigits     bool   `json:"require_digits"`
+	RequireSymbols    bool   `json:"require_symbols"`
+	MaxAgeDays        int    `json:"max_age_days" binding:"min=0"`
+	ForbidCompromised bool   `json:"forbid_compromised"`
+}
@@ -0,0 +1,14 @@
+package in
+
+// PasswordTagRequest creates or updates a tag. Color is a #RRGGBB hex color and Icon the name of an icon known to
+// the clients; an empty string clears either.
+type PasswordTagRequest struct {
+	Name  string  `json:"name" binding:"required"`
+	Color *string `json:"color"`
+	Icon  *string `json:"icon"`
+}
+
+// MergePasswordTagRequest moves every entry of a tag to TargetTagID and deletes the merged tag
+type MergePasswordTagRequest struct {
+	TargetTagID uint `json:"target_tag_id" binding:"required"`
+}
@@ -0,0 +1,23 @@
+package in
+
+type ServiceAccountRequest struct {
+	Name          string  `json:"name" binding:"required"`
+	Description   *string `json:"description"`
+	GroupIDs      []uint  `json:"group_ids"`
+	TagIDs        []uint  `json:"tag_ids"`
+	TokenName     string  `json:"token_name"`
+	ExpiresInDays int     `json:"expires_in_days"`
+}
+
+type ServiceAccountTokenRequest struct {
+	Name          string `json:"name" binding:"required"`
+	ExpiresInDays int    `json:"expires_in_days"`
+}
+
+type RenderSecretsRequest struct {
+	Group      string `form:"group" binding:"required"`
+	Format     string `form:"format"`
+	KeyField   string `form:"key"`
+	ValueField string `form:"value"`
+	Prefix     string `form:"prefix"`
+}
@@ -0,0 +1,21 @@
+package in
+
+// AuthenticatorRegistrationRequest carries the credential returned by navigator.credentials.create(). Binary
+// values are base64url encoded.
+type AuthenticatorRegistrationRequest struct {
+	Name              string   `json:"name" binding:"max=100"`
+	ID                string   `json:"id" binding:"required"`
+	ClientDataJSON    string   `json:"client_data_json" binding:"required"`
+	AttestationObject string   `json:"attestation_object" binding:"required"`
+	Transports        []string `json:"transports" binding:"max=10"`
+}
+
+// AuthenticatorAssertionRequest carries the credential returned by navigator.credentials.get(). Binary values are
+// base64url encoded.
+type AuthenticatorAssertionRequest struct {
+	ID                string `json:"id" binding:"required"`
+	ClientDataJSON    string `json:"client_data_json" binding:"required"`
+	AuthenticatorData string `json:"authenticator_data" binding:"required"`
+	Signature         string `json:"signature" binding:"required"`
+	UserHandle        string `json:"user_handle"`
+}
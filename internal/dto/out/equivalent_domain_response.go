@@ -0,0 +1,12 @@
+package out
+
+import "github.com/lib/pq"
+
+// EquivalentDomainGroupResponse is a group of domains sharing the same accounts. Global groups apply to every user
+// who did not exclude them.
+type EquivalentDomainGroupResponse struct {
+	GroupID  uint           `json:"group_id"`
+	Domains  pq.StringArray `gorm:"type:text[]" json:"domains"`
+	Global   bool           `json:"global"`
+	Excluded bool           `json:"excluded"`
+}
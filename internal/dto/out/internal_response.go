@@ -0,0 +1,35 @@
+package out
+
+type UserKeyBootstrapResponse struct {
+	UserID    uint   `json:"user_id"`
+	PublicKey string `json:"public_key"`
+	Created   bool   `json:"created"`
+}
+
+type UserDataDeletionResponse struct {
+	UserID           uint  `json:"user_id"`
+	Entries          int64 `json:"entries"`
+	Attachments      int64 `json:"attachments"`
+	Groups           int64 `json:"groups"`
+	Tags             int64 `json:"tags"`
+	Policies         int64 `json:"policies"`
+	DomainGroups     int64 `json:"domain_groups"`
+	DomainExclusions int64 `json:"domain_exclusions"`
+	Shares           int64 `json:"shares"`
+	ServiceAccounts  int64 `json:"service_accounts"`
+	Devices          int64 `json:"devices"`
+	Authenticators   int64 `json:"authenticators"`
+	Keys             int64 `json:"keys"`
+}
+
+type VaultStatisticsResponse struct {
+	UserID          *uint `json:"user_id,omitempty"`
+	Users           int64 `json:"users"`
+	Entries         int64 `json:"entries"`
+	Groups          int64 `json:"groups"`
+	Tags            int64 `json:"tags"`
+	Shares          int64 `json:"shares"`
+	ServiceAccounts int64 `json:"service_accounts"`
+	ActiveDevices   int64 `json:"active_devices"`
+	ExpiredEntries  int64 `json:"expired_entries"`
+}
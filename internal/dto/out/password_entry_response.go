@@ -1,11 +1,128 @@
 package out
 
-import "github.com/lib/pq"
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"password-management-service/internal/utils/customfield"
+	"password-management-service/internal/utils/policy"
+	"password-management-service/internal/utils/urlmatch"
+	"time"
+)
 
 type PasswordEntryListResponse struct {
 	EntryID   uint            `json:"entry_id"`
 	Title     string          `json:"title"`
-	GroupName *string         `json:"group_name,omitempty"`
-	URL       *string         `json:"url,omitempty"`
-	Tags      *pq.StringArray `gorm:"type:text[]" json:"tags,omitempty"`
+	EntryType string          `json:"entry_type"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	// GroupName is the breadcrumb path of the entry's group, e.g. /work/aws/prod
+	GroupName   *string         `json:"group_name,omitempty"`
+	URL         *string         `json:"url,omitempty"`
+	Tags        *pq.StringArray `gorm:"type:text[]" json:"tags,omitempty"`
+	Compromised bool            `json:"compromised"`
+	Favorite    bool            `json:"favorite"`
+	Pinned      bool            `json:"pinned"`
+	// LastAccessedAt is only set in the recently used and matching lists
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
+
+type PasswordEntryDetailResponse struct {
+	EntryID         uint            `json:"entry_id"`
+	Title           string          `json:"title"`
+	EntryType       string          `json:"entry_type"`
+	Metadata        json.RawMessage `json:"metadata,omitempty"`
+	URL             *string         `json:"url,omitempty"`
+	URLs            []urlmatch.Rule `gorm:"-" json:"urls,omitempty"`
+	GroupID         *uint           `json:"group_id,omitempty"`
+	GroupName       *string         `json:"group_name,omitempty"`
+	GroupIDs        []uint          `gorm:"-" json:"group_ids,omitempty"`
+	Tags            *pq.StringArray `gorm:"type:text[]" json:"tags,omitempty"`
+	MaskedUsername  string          `gorm:"-" json:"masked_username,omitempty"`
+	Username        string          `json:"-"`
+	HasNotes        bool            `json:"has_notes"`
+	HasCustomFields bool            `json:"has_custom_fields"`
+	Compromised     bool            `json:"compromised"`
+	Favorite        bool            `json:"favorite"`
+	PinnedAt        *time.Time      `json:"pinned_at,omitempty"`
+	BreachCount     int             `json:"breach_count,omitempty"`
+	BreachCheckedAt *time.Time      `json:"breach_checked_at,omitempty"`
+	ExpiresAt       *time.Time      `json:"expires_at,omitempty"`
+	LastAccessedAt  *time.Time      `json:"last_accessed_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// PasswordEntryMatchResponse is an autofill candidate with the URL that matched it
+type PasswordEntryMatchResponse struct {
+	PasswordEntryListResponse
+	MatchedURL string `json:"matched_url"`
+	Match      string `json:"match"`
+	Score      int    `json:"score"`
+}
+
+type RevealPasswordEntryResponse struct {
+	EntryID      uint                `json:"entry_id"`
+	Fields       map[string]string   `json:"fields"`
+	Data         map[string]string   `json:"data,omitempty"`
+	CustomFields []customfield.Field `json:"custom_fields,omitempty"`
+}
+
+// SSHSignResponse holds the signature in SSH wire format, as an SSH agent returns it
+type SSHSignResponse struct {
+	EntryID     uint   `json:"entry_id"`
+	Format      string `json:"format"`
+	Signature   string `json:"signature"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// PasskeyAttestationResponse is the registration response of a passkey, with a "none" attestation object. Binary
+// values are base64url encoded.
+type PasskeyAttestationResponse struct {
+	EntryID           uint   `json:"entry_id"`
+	CredentialID      string `json:"credential_id"`
+	AttestationObject string `json:"attestation_object"`
+	AuthenticatorData string `json:"authenticator_data"`
+	PublicKey         string `json:"public_key"`
+	Algorithm         int    `json:"algorithm"`
+	UserHandle        string `json:"user_handle"`
+}
+
+// PasskeyAssertionResponse is the authentication response of a passkey. Binary values are base64url encoded.
+type PasskeyAssertionResponse struct {
+	EntryID           uint   `json:"entry_id"`
+	CredentialID      string `json:"credential_id"`
+	AuthenticatorData string `json:"authenticator_data"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"user_handle"`
+	SignCount         uint32 `json:"sign_count"`
+}
+
+type BulkPasswordEntryResponse struct {
+	Action    string                    `json:"action"`
+	Succeeded int                       `json:"succeeded"`
+	Failed    int                       `json:"failed"`
+	Results   []BulkPasswordEntryResult `json:"results"`
+}
+
+type BulkPasswordEntryResult struct {
+	EntryID    uint               `json:"entry_id"`
+	Success    bool               `json:"success"`
+	Error      string             `json:"error,omitempty"`
+	Violations []policy.Violation `json:"violations,omitempty"`
+}
+
+type BreachedEntryResponse struct {
+	EntryID         uint       `json:"entry_id"`
+	Title           string     `json:"title"`
+	URL             *string    `json:"url,omitempty"`
+	GroupName       *string    `json:"group_name,omitempty"`
+	BreachCount     int        `json:"breach_count"`
+	BreachCheckedAt *time.Time `json:"breach_checked_at,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type PolicyViolationResponse struct {
+	EntryID    uint               `json:"entry_id"`
+	Title      string             `json:"title"`
+	GroupID    *uint              `json:"group_id,omitempty"`
+	Violations []policy.Violation `json:"violations"`
 }
@@ -0,0 +1,19 @@
+package out
+
+import "time"
+
+type PasswordTagResponse struct {
+	TagID      uint      `json:"tag_id"`
+	Name       string    `json:"name"`
+	Color      *string   `json:"color,omitempty"`
+	Icon       *string   `json:"icon,omitempty"`
+	EntryCount int64     `json:"entry_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type MergePasswordTagResponse struct {
+	TagID        uint  `json:"tag_id"`
+	MergedTagID  uint  `json:"merged_tag_id"`
+	EntriesMoved int64 `json:"entries_moved"`
+}
@@ -0,0 +1,34 @@
+package out
+
+import "time"
+
+type ServiceAccountResponse struct {
+	ServiceAccountID uint                          `json:"service_account_id"`
+	Name             string                        `json:"name"`
+	Description      *string                       `json:"description,omitempty"`
+	Permission       string                        `json:"permission"`
+	GroupIDs         []uint                        `json:"group_ids,omitempty"`
+	TagIDs           []uint                        `json:"tag_ids,omitempty"`
+	EntryCount       int64                         `json:"entry_count"`
+	Tokens           []ServiceAccountTokenResponse `json:"tokens,omitempty"`
+	CreatedAt        time.Time                     `json:"created_at"`
+}
+
+type ServiceAccountTokenResponse struct {
+	TokenID     uint       `json:"token_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Token       string     `json:"token,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ServiceSecretResponse struct {
+	EntryID  uint    `json:"entry_id"`
+	Title    string  `json:"title"`
+	URL      *string `json:"url,omitempty"`
+	Username string  `json:"username"`
+	Password string  `json:"password"`
+	Notes    string  `json:"notes,omitempty"`
+}
@@ -1,7 +1,31 @@
 package out
 
 type VerifyPinCodeResponse struct {
-	ClientID  string `json:"client_id"`
-	RequestID string `json:"request_id"`
-	Valid     bool   `json:"valid"`
+	ClientID   string `json:"client_id"`
+	RequestID  string `json:"request_id"`
+	Valid      bool   `json:"valid"`
+	VerifiedAt int64  `json:"verified_at,omitempty"`
+	// Method is how the user was verified; records written by the PIN flow leave it empty
+	Method string `json:"method,omitempty"`
+}
+
+type StepUpRequiredResponse struct {
+	StepUpRequired bool   `json:"step_up_required"`
+	Action         string `json:"action"`
+	Method         string `json:"method"`
+	Header         string `json:"header"`
+	Reason         string `json:"reason"`
+}
+
+type VaultSessionResponse struct {
+	SessionToken string `json:"session_token"`
+	Header       string `json:"header"`
+	IdleTimeout  int64  `json:"idle_timeout"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
+type VaultLockedResponse struct {
+	VaultLocked bool   `json:"vault_locked"`
+	Header      string `json:"header"`
+	Reason      string `json:"reason"`
 }
@@ -0,0 +1,82 @@
+package out
+
+import "time"
+
+// The ceremony options follow the JSON form of the WebAuthn options, so browsers can pass them to
+// PublicKeyCredential.parseCreationOptionsFromJSON and parseRequestOptionsFromJSON as they are.
+
+type CredentialCreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+type CredentialRequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type AuthenticatorResponse struct {
+	AuthenticatorID   uint       `json:"authenticator_id"`
+	Name              string     `json:"name"`
+	CredentialID      string     `json:"credential_id"`
+	AAGUID            string     `json:"aaguid"`
+	Algorithm         int        `json:"algorithm"`
+	AttestationFormat string     `json:"attestation_format"`
+	AttestationType   string     `json:"attestation_type"`
+	Transports        []string   `json:"transports,omitempty"`
+	BackupEligible    bool       `json:"backup_eligible"`
+	BackupState       bool       `json:"backup_state"`
+	SignCount         uint32     `json:"sign_count"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastUsedAt        *time.Time `json:"last_used_at,omitempty"`
+}
+
+// StepUpVerificationResponse tells the client to send the request ID of the verified ceremony in the header of
+// the request that needs step-up
+type StepUpVerificationResponse struct {
+	RequestID string `json:"request_id"`
+	Header    string `json:"header"`
+	Method    string `json:"method"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type AuthenticatorResetResponse struct {
+	UserID         uint  `json:"user_id"`
+	Authenticators int64 `json:"authenticators"`
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/package/response"
+	"strings"
+)
+
+type InternalMiddleware interface {
+	HandlerInternal() gin.HandlerFunc
+}
+
+type internalMiddleware struct {
+	JWTService      jwt.Service
+	AllowedServices map[string]bool
+}
+
+func NewInternalMiddleware(jwtService jwt.Service, allowedServices []string) InternalMiddleware {
+	allowed := make(map[string]bool)
+	for _, service := range allowedServices {
+		service = strings.ToLower(strings.TrimSpace(service))
+		if service != "" {
+			allowed[service] = true
+		}
+	}
+
+	return internalMiddleware{
+		JWTService:      jwtService,
+		AllowedServices: allowed,
+	}
+}
+
+// HandlerInternal accepts only internal service tokens issued to one of the allowed services
+func (i internalMiddleware) HandlerInternal() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader("Authorization")
+		if token == "" {
+			response.SendResponse(c, http.StatusUnauthorized, "Missing token", nil, "Authorization header is required")
+			c.Abort()
+			return
+		}
+		if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+			token = strings.TrimSpace(token[7:])
+		}
+
+		claims, err := i.JWTService.ValidateInternalToken(token)
+		if err != nil {
+			response.SendResponse(c, http.StatusUnauthorized, "Invalid token", nil, err.Error())
+			c.Abort()
+			return
+		}
+
+		if !i.AllowedServices[strings.ToLower(claims.Service)] {
+			response.SendResponse(c, http.StatusForbidden, "Forbidden", nil, "Service is not allowed to access this resource")
+			c.Abort()
+			return
+		}
+
+		jwt.SetInternalClaims(c, claims)
+		c.Next()
+	}
+}
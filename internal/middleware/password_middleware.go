@@ -1,10 +1,18 @@
 package middleware
 
 import (
+	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 	"net/http"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
 	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/text"
 	"password-management-service/package/response"
+	"time"
 )
 
 type PasswordMiddleware interface {
@@ -12,12 +20,18 @@ type PasswordMiddleware interface {
 }
 
 type passwordMiddleware struct {
-	JWTService jwt.Service
+	JWTService           jwt.Service
+	Redis                redis.RedisService
+	UserDeviceRepository repository.UserDeviceRepository
+	SeenInterval         time.Duration
 }
 
-func NewPasswordMiddleware(jwtService jwt.Service) PasswordMiddleware {
+func NewPasswordMiddleware(jwtService jwt.Service, redisService redis.RedisService, userDeviceRepository repository.UserDeviceRepository, seenInterval time.Duration) PasswordMiddleware {
 	return passwordMiddleware{
-		JWTService: jwtService,
+		JWTService:           jwtService,
+		Redis:                redisService,
+		UserDeviceRepository: userDeviceRepository,
+		SeenInterval:         seenInterval,
 	}
 }
 
@@ -62,7 +76,88 @@ func (a passwordMiddleware) HandlerPassword() gin.HandlerFunc {
 			return
 		}
 
+		if !a.isSessionActive(tokenClaims, token) {
+			response.SendResponse(c, http.StatusUnauthorized, "Unauthorized", nil, "Session has been revoked")
+			c.Abort()
+			return
+		}
+
+		active, err := a.recordDevice(c, tokenClaims)
+		if err != nil {
+			log.Error().Str("clientID", tokenClaims.ClientID).Err(err).Msg("Failed to check device")
+			response.SendResponse(c, http.StatusServiceUnavailable, "Error", nil, "device check unavailable, try again later")
+			c.Abort()
+			return
+		}
+		if !active {
+			response.SendResponse(c, http.StatusUnauthorized, "Unauthorized", nil, "Device has been revoked")
+			c.Abort()
+			return
+		}
+
 		c.Set("token", tokenClaims)
 		c.Next()
 	}
 }
+
+// isSessionActive checks the token against the session the auth service keeps in Redis under token:<clientID>
+func (a passwordMiddleware) isSessionActive(tokenClaims *jwt.TokenClaims, rawToken string) bool {
+	stored, err := a.Redis.GetToken(tokenClaims.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", tokenClaims.ClientID).Err(err).Msg("Failed to retrieve session from Redis")
+		return false
+	}
+	if stored == "" {
+		return false
+	}
+
+	var tokenDetails user.TokenDetails
+	if err := json.Unmarshal([]byte(stored), &tokenDetails); err == nil && tokenDetails.AccessUUID != "" {
+		return tokenDetails.AccessUUID == tokenClaims.AccessUUID
+	}
+	return stored == tokenClaims.AccessUUID || stored == rawToken
+}
+
+// recordDevice tracks the device of the current session and reports false when the device was revoked. The device
+// row is written at most once per SeenInterval and session; in between, a marker in Redis vouches for the device and
+// revoking the device deletes it. The check fails closed: when the device state cannot be read the error is
+// returned and the request refused, so an outage never lets a revoked device through.
+func (a passwordMiddleware) recordDevice(c *gin.Context, tokenClaims *jwt.TokenClaims) (bool, error) {
+	data, err := redis.GetUserRedis(a.Redis, utils.User, tokenClaims.ClientID)
+	if err != nil {
+		return false, err
+	}
+	if data.DeviceID == nil || *data.DeviceID == "" {
+		return true, nil
+	}
+
+	marker := tokenClaims.ClientID + ":" + *data.DeviceID
+	if a.SeenInterval > 0 {
+		var seenAccessUUID string
+		if err := a.Redis.GetData(utils.DeviceSeen, marker, &seenAccessUUID); err == nil && seenAccessUUID == tokenClaims.AccessUUID {
+			return true, nil
+		}
+	}
+
+	device := user.UserDevice{
+		UserID:           data.UserID,
+		ClientID:         tokenClaims.ClientID,
+		DeviceIdentifier: *data.DeviceID,
+		AccessUUID:       text.NilIfEmpty(tokenClaims.AccessUUID),
+		IPAddress:        text.NilIfEmpty(c.ClientIP()),
+		UserAgent:        text.NilIfEmpty(c.Request.UserAgent()),
+	}
+	if err := a.UserDeviceRepository.UpsertDevice(&device); err != nil {
+		return false, err
+	}
+	if device.RevokedAt != nil {
+		return false, nil
+	}
+
+	if a.SeenInterval > 0 {
+		if err := a.Redis.SaveDataWithTTL(utils.DeviceSeen, marker, tokenClaims.AccessUUID, a.SeenInterval); err != nil {
+			log.Warn().Str("clientID", tokenClaims.ClientID).Err(err).Msg("Failed to mark device as seen")
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"math"
+	"net/http"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/apitoken"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/package/response"
+	"strconv"
+	"time"
+)
+
+type RateLimitMiddleware interface {
+	HandlerRateLimit(bucket string) gin.HandlerFunc
+}
+
+type rateLimitMiddleware struct {
+	Redis   redis.RedisService
+	Enabled bool
+	Window  time.Duration
+	Limits  map[string]int
+}
+
+func NewRateLimitMiddleware(redisService redis.RedisService, enabled bool, window time.Duration, limits map[string]int) RateLimitMiddleware {
+	return rateLimitMiddleware{
+		Redis:   redisService,
+		Enabled: enabled,
+		Window:  window,
+		Limits:  limits,
+	}
+}
+
+// HandlerRateLimit throttles requests per bucket using a sliding window keyed by client ID and IP
+func (r rateLimitMiddleware) HandlerRateLimit(bucket string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, ok := r.Limits[bucket]
+		if !r.Enabled || !ok || limit <= 0 {
+			c.Next()
+			return
+		}
+
+		clientID := "anonymous"
+		if token, exist := jwt.ExtractTokenClaims(c); exist {
+			clientID = token.ClientID
+		} else if claims, exist := apitoken.ExtractServiceTokenClaims(c); exist {
+			clientID = "service-account-" + strconv.FormatUint(uint64(claims.ServiceAccountID), 10)
+		}
+		identity := bucket + ":" + clientID + ":" + c.ClientIP()
+
+		allowed, remaining, retryAfter, err := r.Redis.SlidingWindow(utils.RateLimit, identity, limit, r.Window)
+		// Requests are refused while the window cannot be evaluated, so an outage does not lift the limits
+		if err != nil {
+			log.Error().Str("clientID", clientID).Str("bucket", bucket).Err(err).Msg("Failed to evaluate rate limit")
+			if !allowed && retryAfter > 0 {
+				tooManyRequests(c, retryAfter, "rate limit exceeded, try again later")
+				return
+			}
+			response.SendResponse(c, http.StatusServiceUnavailable, "Error", nil, "rate limit unavailable, try again later")
+			c.Abort()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+		if !allowed {
+			log.Warn().Str("clientID", clientID).Str("bucket", bucket).Msg("Rate limit exceeded")
+			tooManyRequests(c, retryAfter, "rate limit exceeded, try again later")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func tooManyRequests(c *gin.Context, retryAfter time.Duration, reason string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	response.SendResponse(c, http.StatusTooManyRequests, "Too many requests", nil, reason)
+	c.Abort()
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"password-management-service/internal/services"
+	"password-management-service/internal/utils/apitoken"
+	"password-management-service/package/response"
+)
+
+type ServiceTokenMiddleware interface {
+	HandlerServiceToken() gin.HandlerFunc
+}
+
+type serviceTokenMiddleware struct {
+	ServiceAccountService services.ServiceAccountService
+}
+
+func NewServiceTokenMiddleware(serviceAccountService services.ServiceAccountService) ServiceTokenMiddleware {
+	return serviceTokenMiddleware{
+		ServiceAccountService: serviceAccountService,
+	}
+}
+
+func (s serviceTokenMiddleware) HandlerServiceToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			response.SendResponse(c, http.StatusUnauthorized, "Missing token", nil, "Authorization header is required")
+			c.Abort()
+			return
+		}
+
+		token, err := apitoken.Parse(header)
+		if err != nil {
+			response.SendResponse(c, http.StatusUnauthorized, "Invalid token", nil, err.Error())
+			c.Abort()
+			return
+		}
+
+		claims, err := s.ServiceAccountService.AuthenticateToken(token)
+		if err != nil {
+			response.SendResponse(c, http.StatusUnauthorized, "Invalid token", nil, err.Error())
+			c.Abort()
+			return
+		}
+
+		apitoken.SetServiceTokenClaims(c, claims)
+		c.Next()
+	}
+}
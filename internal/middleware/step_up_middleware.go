@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/package/response"
+	"strings"
+	"time"
+)
+
+type StepUpMiddleware interface {
+	HandlerStepUp(action string) gin.HandlerFunc
+	HandlerStepUpEnforced(action string) gin.HandlerFunc
+	HandlerStepUpWhen(action string, required func(c *gin.Context) bool) gin.HandlerFunc
+}
+
+type stepUpMiddleware struct {
+	Redis                       redis.RedisService
+	UserAuthenticatorRepository repository.UserAuthenticatorRepository
+	Actions                     map[string]bool
+	MaxAge                      time.Duration
+	MaxAttempts                 int
+	LockoutTimeout              time.Duration
+}
+
+func NewStepUpMiddleware(redisService redis.RedisService, userAuthenticatorRepository repository.UserAuthenticatorRepository, actions []string, maxAge time.Duration, maxAttempts int, lockoutTimeout time.Duration) StepUpMiddleware {
+	policy := make(map[string]bool)
+	for _, action := range actions {
+		action = strings.ToLower(strings.TrimSpace(action))
+		if action != "" {
+			policy[action] = true
+		}
+	}
+
+	return stepUpMiddleware{
+		Redis:                       redisService,
+		UserAuthenticatorRepository: userAuthenticatorRepository,
+		Actions:                     policy,
+		MaxAge:                      maxAge,
+		MaxAttempts:                 maxAttempts,
+		LockoutTimeout:              lockoutTimeout,
+	}
+}
+
+// HandlerStepUp requires a fresh PIN verification for the given action when the policy enables it, or a WebAuthn
+// assertion once the user has registered an authenticator. The verification record is consumed on use, so every
+// sensitive request needs its own check.
+func (s stepUpMiddleware) HandlerStepUp(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.Actions[action] {
+			c.Next()
+			return
+		}
+
+		s.verify(c, action)
+	}
+}
+
+// HandlerStepUpEnforced always requires a fresh PIN verification, regardless of the configured policy
+func (s stepUpMiddleware) HandlerStepUpEnforced(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s.verify(c, action)
+	}
+}
+
+// HandlerStepUpWhen behaves like HandlerStepUp for the requests where required reports true, for endpoints whose
+// body selects the sensitive action
+func (s stepUpMiddleware) HandlerStepUpWhen(action string, required func(c *gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.Actions[action] || !required(c) {
+			c.Next()
+			return
+		}
+
+		s.verify(c, action)
+	}
+}
+
+func (s stepUpMiddleware) verify(c *gin.Context, action string) {
+	token, exist := jwt.ExtractTokenClaims(c)
+	if !exist {
+		response.SendResponse(c, http.StatusUnauthorized, "Unauthorized", nil, "Token not found")
+		c.Abort()
+		return
+	}
+
+	locked, err := s.Redis.Exists(utils.PinLockout, token.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", token.ClientID).Err(err).Msg("Failed to check PIN lockout")
+		response.SendResponse(c, http.StatusServiceUnavailable, "Error", nil, "step-up unavailable, try again later")
+		c.Abort()
+		return
+	}
+	if locked {
+		retryAfter, _ := s.Redis.GetTTL(utils.PinLockout, token.ClientID)
+		tooManyRequests(c, retryAfter, "too many invalid verification codes, try again later")
+		return
+	}
+
+	method := utils.StepUpMethodPin
+	hasAuthenticators, err := s.UserAuthenticatorRepository.HasAuthenticators(token.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", token.ClientID).Err(err).Msg("Failed to retrieve authenticators")
+		response.SendResponse(c, http.StatusInternalServerError, "Error", nil, "failed to check the step-up method")
+		c.Abort()
+		return
+	}
+	if hasAuthenticators {
+		method = utils.StepUpMethodWebAuthn
+	}
+
+	requestID := c.GetHeader(utils.XRequestID)
+	if requestID == "" {
+		stepUpRequired(c, action, method, "verification code is required")
+		return
+	}
+
+	// The record is only consumed by the request it was issued for, so a request with another ID cannot burn it
+	var verify out.VerifyPinCodeResponse
+	matched, err := s.Redis.ConsumeDataIf(utils.PinVerify, token.ClientID, &verify, func() bool {
+		return verify.Valid && verify.RequestID == requestID
+	})
+	if err != nil {
+		log.Error().Str("clientID", token.ClientID).Err(err).Msg("No active PIN verification")
+		stepUpRequired(c, action, method, "no active PIN verification")
+		return
+	}
+
+	if !matched {
+		log.Error().Str("clientID", token.ClientID).Msg("Invalid verification code")
+		if s.registerFailedAttempt(token.ClientID) {
+			tooManyRequests(c, s.LockoutTimeout, "too many invalid verification codes, try again later")
+			return
+		}
+		stepUpRequired(c, action, method, "invalid verification code")
+		return
+	}
+	_ = s.Redis.DeleteData(utils.PinAttempts, token.ClientID)
+
+	// A record without a verification time cannot prove it is fresh
+	if verify.VerifiedAt <= 0 || (s.MaxAge > 0 && time.Since(time.Unix(verify.VerifiedAt, 0)) > s.MaxAge) {
+		log.Error().Str("clientID", token.ClientID).Msg("PIN verification expired")
+		stepUpRequired(c, action, method, "PIN verification expired")
+		return
+	}
+
+	if method == utils.StepUpMethodWebAuthn && verify.Method != utils.StepUpMethodWebAuthn {
+		log.Error().Str("clientID", token.ClientID).Msg("PIN verification used where an authenticator is registered")
+		stepUpRequired(c, action, method, "authenticator assertion is required")
+		return
+	}
+
+	c.Next()
+}
+
+// registerFailedAttempt counts invalid codes and reports whether the client is now locked out
+func (s stepUpMiddleware) registerFailedAttempt(clientID string) bool {
+	if s.MaxAttempts <= 0 {
+		return false
+	}
+
+	attempts, err := s.Redis.Increment(utils.PinAttempts, clientID, s.LockoutTimeout)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to record invalid verification attempt")
+		return false
+	}
+	if attempts < int64(s.MaxAttempts) {
+		return false
+	}
+
+	if err := s.Redis.SaveDataWithTTL(utils.PinLockout, clientID, attempts, s.LockoutTimeout); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to lock out client")
+	}
+	_ = s.Redis.DeleteData(utils.PinAttempts, clientID)
+	log.Warn().Str("clientID", clientID).Int64("attempts", attempts).Msg("Client locked out after invalid verification codes")
+	return true
+}
+
+func stepUpRequired(c *gin.Context, action, method, reason string) {
+	response.SendResponse(c, http.StatusForbidden, "Step-up required", out.StepUpRequiredResponse{
+		StepUpRequired: true,
+		Action:         action,
+		Method:         method,
+		Header:         utils.XRequestID,
+		Reason:         reason,
+	}, reason)
+	c.Abort()
+}
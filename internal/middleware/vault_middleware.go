@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/jwt"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/vault"
+	"password-management-service/package/response"
+	"time"
+)
+
+type VaultMiddleware interface {
+	HandlerVault() gin.HandlerFunc
+	HandlerVaultOptional() gin.HandlerFunc
+}
+
+type vaultMiddleware struct {
+	Redis       redis.RedisService
+	IdleTimeout time.Duration
+	MaxAge      time.Duration
+}
+
+func NewVaultMiddleware(redisService redis.RedisService, idleTimeout, maxAge time.Duration) VaultMiddleware {
+	return vaultMiddleware{
+		Redis:       redisService,
+		IdleTimeout: idleTimeout,
+		MaxAge:      maxAge,
+	}
+}
+
+// HandlerVault requires an unlocked vault session and exposes its derived key to the handler.
+// Every successful use pushes the idle timeout forward, up to the absolute session lifetime.
+func (v vaultMiddleware) HandlerVault() gin.HandlerFunc {
+	return v.handler(true)
+}
+
+// HandlerVaultOptional opens the vault session when one is presented and lets the request through without a
+// key otherwise, for handlers that only need the vault in some cases. An invalid session is still rejected.
+func (v vaultMiddleware) HandlerVaultOptional() gin.HandlerFunc {
+	return v.handler(false)
+}
+
+func (v vaultMiddleware) handler(required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, exist := jwt.ExtractTokenClaims(c)
+		if !exist {
+			response.SendResponse(c, http.StatusUnauthorized, "Unauthorized", nil, "Token not found")
+			c.Abort()
+			return
+		}
+
+		sessionToken := c.GetHeader(utils.XVaultSession)
+		if sessionToken == "" {
+			if !required {
+				c.Next()
+				return
+			}
+			vaultLocked(c, "vault session is required")
+			return
+		}
+
+		var session vault.Session
+		if err := v.Redis.GetData(utils.VaultSession, token.ClientID, &session); err != nil {
+			vaultLocked(c, "vault is locked")
+			return
+		}
+
+		if !session.Matches(sessionToken) {
+			log.Error().Str("clientID", token.ClientID).Msg("Invalid vault session token")
+			vaultLocked(c, "invalid vault session")
+			return
+		}
+
+		now := time.Now()
+		expiresAt := time.Unix(session.CreatedAt, 0).Add(v.MaxAge)
+		if v.MaxAge > 0 && !now.Before(expiresAt) {
+			_ = v.Redis.DeleteData(utils.VaultSession, token.ClientID)
+			vaultLocked(c, "vault session expired")
+			return
+		}
+
+		derivedKey, err := vault.Open(session.EncryptedKey, sessionToken)
+		if err != nil {
+			log.Error().Str("clientID", token.ClientID).Err(err).Msg("Failed to open vault session")
+			vaultLocked(c, "invalid vault session")
+			return
+		}
+
+		ttl := v.IdleTimeout
+		if v.MaxAge > 0 && expiresAt.Sub(now) < ttl {
+			ttl = expiresAt.Sub(now)
+		}
+		// Only slide the session this request opened: a lock or re-unlock that landed in between wins
+		var current vault.Session
+		_, err = v.Redis.RefreshDataIf(utils.VaultSession, token.ClientID, &current, func() bool {
+			if current.TokenHash != session.TokenHash {
+				return false
+			}
+			current.LastUsedAt = now.Unix()
+			return true
+		}, ttl)
+		if err != nil {
+			log.Error().Str("clientID", token.ClientID).Err(err).Msg("Failed to refresh vault session")
+		}
+
+		vault.SetVaultKey(c, derivedKey)
+		c.Next()
+	}
+}
+
+func vaultLocked(c *gin.Context, reason string) {
+	response.SendResponse(c, http.StatusLocked, "Vault locked", out.VaultLockedResponse{
+		VaultLocked: true,
+		Header:      utils.XVaultSession,
+		Reason:      reason,
+	}, reason)
+	c.Abort()
+}
@@ -0,0 +1,34 @@
+package password
+
+import (
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"time"
+)
+
+// AppDomainEquivalence maps a known mobile app, by its app URI, to a web domain its credentials belong to
+type AppDomainEquivalence struct {
+	AppID  string `gorm:"primaryKey;column:app_id" json:"app_id"`
+	Domain string `gorm:"primaryKey;column:domain" json:"domain"`
+}
+
+// EquivalentDomainGroup lists base domains that share the same accounts. It applies to every user when UserID is
+// not set.
+type EquivalentDomainGroup struct {
+	GroupID   uint           `gorm:"primaryKey;column:group_id" json:"group_id"`
+	UserID    *uint          `gorm:"column:user_id" json:"user_id,omitempty"`
+	Domains   pq.StringArray `gorm:"column:domains;type:text[]" json:"domains"`
+	CreatedAt time.Time      `gorm:"column:created_at" json:"created_at,omitempty"`
+	CreatedBy *string        `gorm:"column:created_by" json:"created_by,omitempty"`
+	UpdatedAt time.Time      `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	UpdatedBy *string        `gorm:"column:updated_by" json:"updated_by,omitempty"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at" json:"deleted_at,omitempty"`
+	DeletedBy *string        `gorm:"column:deleted_by" json:"deleted_by,omitempty"`
+}
+
+// EquivalentDomainExclusion turns a global equivalent domain group off for a user
+type EquivalentDomainExclusion struct {
+	UserID    uint      `gorm:"primaryKey;column:user_id" json:"user_id"`
+	GroupID   uint      `gorm:"primaryKey;column:group_id" json:"group_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+}
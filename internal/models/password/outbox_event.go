@@ -0,0 +1,20 @@
+package password
+
+import (
+	"time"
+)
+
+type OutboxEvent struct {
+	OutboxID      uint       `gorm:"primaryKey;column:outbox_id" json:"outbox_id,omitempty"`
+	EventID       string     `gorm:"column:event_id;not null" json:"event_id,omitempty"`
+	EventType     string     `gorm:"column:event_type;not null" json:"event_type,omitempty"`
+	EventVersion  int        `gorm:"column:event_version;not null" json:"event_version,omitempty"`
+	AggregateID   *uint      `gorm:"column:aggregate_id" json:"aggregate_id,omitempty"`
+	UserID        *uint      `gorm:"column:user_id" json:"user_id,omitempty"`
+	Payload       string     `gorm:"column:payload;type:jsonb;not null" json:"payload,omitempty"`
+	Attempts      int        `gorm:"column:attempts" json:"attempts,omitempty"`
+	LastError     *string    `gorm:"column:last_error" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at" json:"next_attempt_at,omitempty"`
+	PublishedAt   *time.Time `gorm:"column:published_at" json:"published_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"column:created_at" json:"created_at,omitempty"`
+}
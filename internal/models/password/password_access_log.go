@@ -0,0 +1,19 @@
+package password
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+type PasswordAccessLog struct {
+	LogID     uint           `gorm:"primaryKey;column:log_id" json:"log_id,omitempty"`
+	EntryID   uint           `gorm:"column:entry_id;not null" json:"entry_id,omitempty"`
+	UserID    uint           `gorm:"column:user_id;not null" json:"user_id,omitempty"`
+	Action    string         `gorm:"column:action;not null" json:"action,omitempty"`
+	Fields    pq.StringArray `gorm:"column:fields;type:text[]" json:"fields,omitempty"`
+	RequestID *string        `gorm:"column:request_id" json:"request_id,omitempty"`
+	IPAddress *string        `gorm:"column:ip_address" json:"ip_address,omitempty"`
+	UserAgent *string        `gorm:"column:user_agent" json:"user_agent,omitempty"`
+	CreatedAt time.Time      `gorm:"column:created_at" json:"created_at,omitempty"`
+	CreatedBy *string        `gorm:"column:created_by" json:"created_by,omitempty"`
+}
@@ -0,0 +1,19 @@
+package password
+
+import "time"
+
+// PasswordAttachment is the metadata of an encrypted file; the ciphertext lives in the blob store under StorageKey
+type PasswordAttachment struct {
+	AttachmentID   uint      `gorm:"primaryKey;column:attachment_id" json:"attachment_id,omitempty"`
+	EntryID        uint      `gorm:"column:entry_id;not null" json:"entry_id,omitempty"`
+	UserID         uint      `gorm:"column:user_id;not null" json:"user_id,omitempty"`
+	FileName       string    `gorm:"column:file_name;not null" json:"file_name,omitempty"`
+	ContentType    string    `gorm:"column:content_type;not null" json:"content_type,omitempty"`
+	Size           int64     `gorm:"column:size;not null" json:"size"`
+	Checksum       string    `gorm:"column:checksum;not null" json:"checksum,omitempty"`
+	StorageBackend string    `gorm:"column:storage_backend;not null" json:"-"`
+	StorageKey     string    `gorm:"column:storage_key;not null" json:"-"`
+	WrappedKey     string    `gorm:"column:wrapped_key;not null" json:"-"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	CreatedBy      *string   `gorm:"column:created_by" json:"created_by,omitempty"`
+}
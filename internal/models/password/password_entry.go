@@ -10,13 +10,26 @@ type PasswordEntry struct {
 	UserID            uint           `gorm:"column:user_id;not null" json:"user_id,omitempty"`
 	GroupID           *uint          `gorm:"column:group_id" json:"group_id,omitempty"`
 	Title             string         `gorm:"column:title;not null" json:"title,omitempty"`
+	EntryType         string         `gorm:"column:entry_type;not null;default:login" json:"entry_type,omitempty"`
 	Username          string         `gorm:"column:username;not null" json:"username,omitempty"`
 	EncryptedPassword string         `gorm:"column:encrypted_password;not null" json:"encrypted_password,omitempty"`
 	EncryptedNotes    *string        `gorm:"column:encrypted_notes" json:"encrypted_notes,omitempty"`
+	EncryptedCustom   *string        `gorm:"column:encrypted_custom_fields" json:"encrypted_custom_fields,omitempty"`
+	EncryptedData     *string        `gorm:"column:encrypted_data" json:"encrypted_data,omitempty"`
+	Metadata          *string        `gorm:"column:metadata;type:jsonb" json:"metadata,omitempty"`
 	URL               *string        `gorm:"column:url" json:"url,omitempty"`
 	Tags              []*PasswordTag `gorm:"many2many:password_entry_tags, joinForeignKey:entry_id,joinReferences:tag_id;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"tags,omitempty"`
 	ExpiresAt         *time.Time     `gorm:"column:expires_at" json:"expires_at,omitempty"`
 	LastAccessedAt    *time.Time     `gorm:"column:last_accessed_at" json:"last_accessed_at,omitempty"`
+	Favorite          bool           `gorm:"column:favorite" json:"favorite"`
+	PinnedAt          *time.Time     `gorm:"column:pinned_at" json:"pinned_at,omitempty"`
+	ExpiryNotifiedAt  *time.Time     `gorm:"column:expiry_notified_at" json:"-"`
+	Compromised       bool           `gorm:"column:compromised" json:"compromised"`
+	BreachCount       int            `gorm:"column:breach_count" json:"breach_count,omitempty"`
+	BreachCheckedAt   *time.Time     `gorm:"column:breach_checked_at" json:"breach_checked_at,omitempty"`
+	PasswordLength    *int           `gorm:"column:password_length" json:"-"`
+	PasswordClasses   *int           `gorm:"column:password_classes" json:"-"`
+	PasswordChangedAt *time.Time     `gorm:"column:password_changed_at" json:"-"`
 	CreatedAt         time.Time      `gorm:"column:created_at" json:"created_at,omitempty"`
 	CreatedBy         *string        `gorm:"column:created_by" json:"created_by,omitempty"`
 	UpdatedAt         time.Time      `gorm:"column:updated_at" json:"updated_at,omitempty"`
@@ -0,0 +1,11 @@
+package password
+
+import "time"
+
+// PasswordEntryGroup places an entry in a group; an entry can belong to any number of groups
+type PasswordEntryGroup struct {
+	EntryID   uint      `gorm:"primaryKey;column:entry_id" json:"entry_id"`
+	GroupID   uint      `gorm:"primaryKey;column:group_id" json:"group_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	CreatedBy *string   `gorm:"column:created_by" json:"created_by,omitempty"`
+}
@@ -0,0 +1,15 @@
+package password
+
+import "time"
+
+// PasswordEntryURL is one of the URLs an entry is offered for, with the strategy used to match it
+type PasswordEntryURL struct {
+	URLID      uint      `gorm:"primaryKey;column:url_id" json:"url_id"`
+	EntryID    uint      `gorm:"column:entry_id;not null" json:"entry_id"`
+	URL        string    `gorm:"column:url;not null" json:"url"`
+	MatchType  string    `gorm:"column:match_type;not null" json:"match"`
+	Host       *string   `gorm:"column:host" json:"-"`
+	BaseDomain *string   `gorm:"column:base_domain" json:"-"`
+	Position   int       `gorm:"column:position" json:"-"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+}
@@ -0,0 +1,27 @@
+package password
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// PasswordPolicy applies globally when neither UserID nor GroupID is set, otherwise to that user or group
+type PasswordPolicy struct {
+	PolicyID          uint           `gorm:"primaryKey;column:policy_id" json:"policy_id,omitempty"`
+	Name              string         `gorm:"column:name;not null" json:"name,omitempty"`
+	UserID            *uint          `gorm:"column:user_id" json:"user_id,omitempty"`
+	GroupID           *uint          `gorm:"column:group_id" json:"group_id,omitempty"`
+	MinLength         int            `gorm:"column:min_length" json:"min_length"`
+	RequireUppercase  bool           `gorm:"column:require_uppercase" json:"require_uppercase"`
+	RequireLowercase  bool           `gorm:"column:require_lowercase" json:"require_lowercase"`
+	RequireDigits     bool           `gorm:"column:require_digits" json:"require_digits"`
+	RequireSymbols    bool           `gorm:"column:require_symbols" json:"require_symbols"`
+	MaxAgeDays        int            `gorm:"column:max_age_days" json:"max_age_days"`
+	ForbidCompromised bool           `gorm:"column:forbid_compromised" json:"forbid_compromised"`
+	CreatedAt         time.Time      `gorm:"column:created_at" json:"created_at,omitempty"`
+	CreatedBy         *string        `gorm:"column:created_by" json:"created_by,omitempty"`
+	UpdatedAt         time.Time      `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	UpdatedBy         *string        `gorm:"column:updated_by" json:"updated_by,omitempty"`
+	DeletedAt         gorm.DeletedAt `gorm:"column:deleted_at" json:"deleted_at,omitempty"`
+	DeletedBy         *string        `gorm:"column:deleted_by" json:"deleted_by,omitempty"`
+}
@@ -8,7 +8,9 @@ import (
 type PasswordTag struct {
 	TagID     uint           `gorm:"primaryKey;column:tag_id" json:"tag_id"`
 	UserID    uint           `gorm:"column:user_id" json:"user_id"`
-	Name      string         `gorm:"unique;not null" json:"name"`
+	Name      string         `gorm:"column:name;not null" json:"name"`
+	Color     *string        `gorm:"column:color" json:"color,omitempty"`
+	Icon      *string        `gorm:"column:icon" json:"icon,omitempty"`
 	CreatedAt time.Time      `gorm:"column:created_at" json:"created_at,omitempty"`
 	CreatedBy *string        `gorm:"column:created_by" json:"created_by,omitempty"`
 	UpdatedAt time.Time      `gorm:"column:updated_at" json:"updated_at,omitempty"`
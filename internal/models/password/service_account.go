@@ -0,0 +1,51 @@
+package password
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type ServiceAccount struct {
+	ServiceAccountID    uint           `gorm:"primaryKey;column:service_account_id" json:"service_account_id,omitempty"`
+	UserID              uint           `gorm:"column:user_id;not null" json:"user_id,omitempty"`
+	Name                string         `gorm:"column:name;not null" json:"name,omitempty"`
+	Description         *string        `gorm:"column:description" json:"description,omitempty"`
+	Permission          string         `gorm:"column:permission;not null" json:"permission,omitempty"`
+	PublicKey           string         `gorm:"column:public_key;not null" json:"-"`
+	EncryptedPrivateKey string         `gorm:"column:encrypted_private_key;not null" json:"-"`
+	WrappedKey          string         `gorm:"column:wrapped_key;not null" json:"-"`
+	CreatedAt           time.Time      `gorm:"column:created_at" json:"created_at,omitempty"`
+	CreatedBy           *string        `gorm:"column:created_by" json:"created_by,omitempty"`
+	UpdatedAt           time.Time      `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	UpdatedBy           *string        `gorm:"column:updated_by" json:"updated_by,omitempty"`
+	DeletedAt           gorm.DeletedAt `gorm:"column:deleted_at" json:"deleted_at,omitempty"`
+	DeletedBy           *string        `gorm:"column:deleted_by" json:"deleted_by,omitempty"`
+}
+
+type ServiceAccountScope struct {
+	ScopeID          uint  `gorm:"primaryKey;column:scope_id" json:"scope_id,omitempty"`
+	ServiceAccountID uint  `gorm:"column:service_account_id;not null" json:"service_account_id,omitempty"`
+	GroupID          *uint `gorm:"column:group_id" json:"group_id,omitempty"`
+	TagID            *uint `gorm:"column:tag_id" json:"tag_id,omitempty"`
+}
+
+type ServiceAccountToken struct {
+	TokenID             uint       `gorm:"primaryKey;column:token_id" json:"token_id,omitempty"`
+	ServiceAccountID    uint       `gorm:"column:service_account_id;not null" json:"service_account_id,omitempty"`
+	Name                string     `gorm:"column:name;not null" json:"name,omitempty"`
+	TokenPrefix         string     `gorm:"column:token_prefix;not null" json:"token_prefix,omitempty"`
+	TokenHash           string     `gorm:"column:token_hash;not null" json:"-"`
+	EncryptedPrivateKey string     `gorm:"column:encrypted_private_key;not null" json:"-"`
+	Salt                string     `gorm:"column:salt;not null" json:"-"`
+	ExpiresAt           *time.Time `gorm:"column:expires_at" json:"expires_at,omitempty"`
+	LastUsedAt          *time.Time `gorm:"column:last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt           time.Time  `gorm:"column:created_at" json:"created_at,omitempty"`
+	CreatedBy           *string    `gorm:"column:created_by" json:"created_by,omitempty"`
+}
+
+type ServiceAccountEntryKey struct {
+	ServiceAccountID      uint      `gorm:"primaryKey;column:service_account_id" json:"service_account_id,omitempty"`
+	EntryID               uint      `gorm:"primaryKey;column:entry_id" json:"entry_id,omitempty"`
+	EncryptedSymmetricKey string    `gorm:"column:encrypted_symmetric_key" json:"-"`
+	CreatedAt             time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+}
@@ -0,0 +1,25 @@
+package user
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+type UserAuthenticator struct {
+	AuthenticatorID   uint           `gorm:"primaryKey;column:authenticator_id" json:"authenticator_id,omitempty"`
+	UserID            uint           `gorm:"column:user_id;not null" json:"user_id,omitempty"`
+	Name              string         `gorm:"column:name;not null" json:"name,omitempty"`
+	CredentialID      []byte         `gorm:"column:credential_id;not null" json:"-"`
+	PublicKey         []byte         `gorm:"column:public_key;not null" json:"-"`
+	Algorithm         int            `gorm:"column:algorithm;not null" json:"algorithm,omitempty"`
+	SignCount         uint32         `gorm:"column:sign_count;not null" json:"sign_count"`
+	AAGUID            string         `gorm:"column:aaguid;not null" json:"aaguid,omitempty"`
+	AttestationFormat string         `gorm:"column:attestation_format;not null" json:"attestation_format,omitempty"`
+	AttestationType   string         `gorm:"column:attestation_type;not null" json:"attestation_type,omitempty"`
+	Transports        pq.StringArray `gorm:"column:transports;type:text[]" json:"transports,omitempty"`
+	BackupEligible    bool           `gorm:"column:backup_eligible" json:"backup_eligible"`
+	BackupState       bool           `gorm:"column:backup_state" json:"backup_state"`
+	CreatedAt         time.Time      `gorm:"column:created_at" json:"created_at,omitempty"`
+	CreatedBy         *string        `gorm:"column:created_by" json:"-"`
+	LastUsedAt        *time.Time     `gorm:"column:last_used_at" json:"last_used_at,omitempty"`
+}
@@ -0,0 +1,17 @@
+package user
+
+import "time"
+
+type UserDevice struct {
+	DeviceID         uint       `gorm:"primaryKey;column:device_id" json:"device_id,omitempty"`
+	UserID           uint       `gorm:"column:user_id;not null" json:"user_id,omitempty"`
+	ClientID         string     `gorm:"column:client_id;not null" json:"-"`
+	DeviceIdentifier string     `gorm:"column:device_identifier;not null" json:"device_identifier,omitempty"`
+	AccessUUID       *string    `gorm:"column:access_uuid" json:"-"`
+	IPAddress        *string    `gorm:"column:ip_address" json:"ip_address,omitempty"`
+	UserAgent        *string    `gorm:"column:user_agent" json:"user_agent,omitempty"`
+	FirstSeenAt      time.Time  `gorm:"column:first_seen_at" json:"first_seen_at,omitempty"`
+	LastSeenAt       time.Time  `gorm:"column:last_seen_at" json:"last_seen_at,omitempty"`
+	RevokedAt        *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	RevokedBy        *string    `gorm:"column:revoked_by" json:"revoked_by,omitempty"`
+}
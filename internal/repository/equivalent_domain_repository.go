@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+	"time"
+)
+
+type EquivalentDomainRepository interface {
+	GetDomainsByAppID(appID string) ([]string, error)
+	GetAppIDsByDomains(domains []string) ([]string, error)
+	GetEquivalentDomainGroups(userID uint) ([]out.EquivalentDomainGroupResponse, error)
+	GetEquivalentDomainGroupByID(groupID uint) (*password.EquivalentDomainGroup, error)
+	AddEquivalentDomainGroup(group *password.EquivalentDomainGroup) error
+	UpdateEquivalentDomainGroup(group *password.EquivalentDomainGroup) error
+	DeleteEquivalentDomainGroup(group *password.EquivalentDomainGroup) error
+	SetEquivalentDomainGroupExcluded(userID, groupID uint, excluded bool) error
+}
+
+type equivalentDomainRepository struct {
+	db gorm.DB
+}
+
+func NewEquivalentDomainRepository(db gorm.DB) EquivalentDomainRepository {
+	return &equivalentDomainRepository{
+		db: db,
+	}
+}
+
+func (r *equivalentDomainRepository) GetDomainsByAppID(appID string) ([]string, error) {
+	var domains []string
+	if err := r.db.Table(utils.TableAppDomainEquivalenceName).
+		Where("app_id = ?", appID).
+		Pluck("domain", &domains).Error; err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+func (r *equivalentDomainRepository) GetAppIDsByDomains(domains []string) ([]string, error) {
+	var appIDs []string
+	if len(domains) == 0 {
+		return appIDs, nil
+	}
+	if err := r.db.Table(utils.TableAppDomainEquivalenceName).
+		Distinct("app_id").
+		Where("domain IN ?", domains).
+		Pluck("app_id", &appIDs).Error; err != nil {
+		return nil, err
+	}
+	return appIDs, nil
+}
+
+// GetEquivalentDomainGroups lists the global groups, flagged when the user excluded them, and the user's own groups
+func (r *equivalentDomainRepository) GetEquivalentDomainGroups(userID uint) ([]out.EquivalentDomainGroupResponse, error) {
+	var groups []out.EquivalentDomainGroupResponse
+	err := r.db.Raw(`
+		SELECT
+			edg.group_id,
+			edg.domains,
+			(edg.user_id IS NULL) AS global,
+			(ede.group_id IS NOT NULL) AS excluded
+		FROM equivalent_domain_groups edg
+		LEFT JOIN equivalent_domain_exclusions ede ON ede.group_id = edg.group_id AND ede.user_id = ?
+		WHERE edg.deleted_at IS NULL AND (edg.user_id IS NULL OR edg.user_id = ?)
+		ORDER BY (edg.user_id IS NULL) DESC, edg.group_id ASC
+	`, userID, userID).Scan(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *equivalentDomainRepository) GetEquivalentDomainGroupByID(groupID uint) (*password.EquivalentDomainGroup, error) {
+	var group password.EquivalentDomainGroup
+	if err := r.db.Table(utils.TableEquivalentDomainGroupName).
+		Where("group_id = ? AND deleted_at IS NULL", groupID).
+		First(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *equivalentDomainRepository) AddEquivalentDomainGroup(group *password.EquivalentDomainGroup) error {
+	return r.db.Table(utils.TableEquivalentDomainGroupName).Create(group).Error
+}
+
+func (r *equivalentDomainRepository) UpdateEquivalentDomainGroup(group *password.EquivalentDomainGroup) error {
+	return r.db.Table(utils.TableEquivalentDomainGroupName).
+		Where("group_id = ? AND deleted_at IS NULL", group.GroupID).
+		Updates(map[string]interface{}{
+			"domains":    group.Domains,
+			"updated_by": group.UpdatedBy,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func (r *equivalentDomainRepository) DeleteEquivalentDomainGroup(group *password.EquivalentDomainGroup) error {
+	return r.db.Table(utils.TableEquivalentDomainGroupName).
+		Where("group_id = ? AND deleted_at IS NULL", group.GroupID).
+		Updates(map[string]interface{}{
+			"deleted_by": group.DeletedBy,
+			"deleted_at": time.Now(),
+		}).Error
+}
+
+func (r *equivalentDomainRepository) SetEquivalentDomainGroupExcluded(userID, groupID uint, excluded bool) error {
+	if !excluded {
+		return r.db.Table(utils.TableDomainGroupExclusionName).
+			Where("user_id = ? AND group_id = ?", userID, groupID).
+			Delete(&password.EquivalentDomainExclusion{}).Error
+	}
+	return r.db.Exec(`
+		INSERT INTO equivalent_domain_exclusions (user_id, group_id) VALUES (?, ?)
+		ON CONFLICT DO NOTHING
+	`, userID, groupID).Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/outbox"
+	"time"
+)
+
+type OutboxRepository interface {
+	ProcessPendingEvents(limit int, publish func(event *password.OutboxEvent) error) (int, error)
+	DeletePublishedEvents(olderThan time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db gorm.DB
+}
+
+func NewOutboxRepository(db gorm.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+// addOutboxEvent records an event in the caller's transaction so it is stored if and only if the change commits
+func addOutboxEvent(tx *gorm.DB, eventType string, userID, entryID uint, actor *string, data map[string]interface{}) error {
+	event, err := outbox.NewEvent(eventType, userID, entryID, actor, data)
+	if err != nil {
+		return err
+	}
+	return tx.Table(utils.TableOutboxEventName).Create(event).Error
+}
+
+// ProcessPendingEvents locks a batch of due events, publishes them in order and records the outcome.
+// SKIP LOCKED lets several instances relay concurrently without publishing the same row twice; the
+// batch stops at the first failure so a broker outage does not burn through retry attempts.
+func (r *outboxRepository) ProcessPendingEvents(limit int, publish func(event *password.OutboxEvent) error) (int, error) {
+	published := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var events []password.OutboxEvent
+		if err := tx.Raw(`
+			SELECT * FROM outbox_events
+			WHERE published_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY outbox_id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		`, limit).Scan(&events).Error; err != nil {
+			return err
+		}
+
+		for i := range events {
+			event := &events[i]
+			if err := publish(event); err != nil {
+				return tx.Table(utils.TableOutboxEventName).
+					Where("outbox_id = ?", event.OutboxID).
+					Updates(map[string]interface{}{
+						"attempts":        event.Attempts + 1,
+						"last_error":      err.Error(),
+						"next_attempt_at": time.Now().Add(outbox.RetryDelay(event.Attempts)),
+					}).Error
+			}
+
+			if err := tx.Table(utils.TableOutboxEventName).
+				Where("outbox_id = ?", event.OutboxID).
+				Updates(map[string]interface{}{
+					"attempts":     event.Attempts + 1,
+					"last_error":   nil,
+					"published_at": time.Now(),
+				}).Error; err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	return published, err
+}
+
+func (r *outboxRepository) DeletePublishedEvents(olderThan time.Time) (int64, error) {
+	result := r.db.Table(utils.TableOutboxEventName).
+		Where("published_at IS NOT NULL AND published_at < ?", olderThan).
+		Delete(&password.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
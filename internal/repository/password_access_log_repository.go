@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+)
+
+type PasswordAccessLogRepository interface {
+	AddAccessLog(accessLog *password.PasswordAccessLog) error
+	AddAccessLogs(accessLogs []password.PasswordAccessLog) error
+	GetAccessLogsByEntryID(entryID uint) ([]password.PasswordAccessLog, error)
+}
+
+type passwordAccessLogRepository struct {
+	db gorm.DB
+}
+
+func NewPasswordAccessLogRepository(db gorm.DB) PasswordAccessLogRepository {
+	return &passwordAccessLogRepository{
+		db: db,
+	}
+}
+
+// AddAccessLog records the access, touches the entry's last_accessed_at and emits the matching
+// vault event in one transaction
+func (r *passwordAccessLogRepository) AddAccessLog(accessLog *password.PasswordAccessLog) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return addAccessLog(tx, accessLog)
+	})
+}
+
+// AddAccessLogs records several accesses in one transaction, so either all of them are recorded or none
+func (r *passwordAccessLogRepository) AddAccessLogs(accessLogs []password.PasswordAccessLog) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range accessLogs {
+			if err := addAccessLog(tx, &accessLogs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func addAccessLog(tx *gorm.DB, accessLog *password.PasswordAccessLog) error {
+	if err := tx.Table(utils.TablePasswordAccessLogName).Create(accessLog).Error; err != nil {
+		return err
+	}
+	if err := tx.Table(utils.TablePasswordEntryName).
+		Where("entry_id = ?", accessLog.EntryID).
+		UpdateColumn("last_accessed_at", gorm.Expr("CURRENT_TIMESTAMP")).Error; err != nil {
+		return err
+	}
+
+	eventType := utils.EventEntryRevealed
+	switch accessLog.Action {
+	case utils.AccessActionExport:
+		eventType = utils.EventEntryExported
+	case utils.AccessActionSSHSign:
+		eventType = utils.EventEntrySSHSigned
+	case utils.AccessActionPasskey:
+		eventType = utils.EventEntryPasskeyUsed
+	}
+	return addOutboxEvent(tx, eventType, accessLog.UserID, accessLog.EntryID, accessLog.CreatedBy, map[string]interface{}{
+		"action":     accessLog.Action,
+		"fields":     accessLog.Fields,
+		"request_id": accessLog.RequestID,
+		"ip_address": accessLog.IPAddress,
+	})
+}
+
+func (r *passwordAccessLogRepository) GetAccessLogsByEntryID(entryID uint) ([]password.PasswordAccessLog, error) {
+	var accessLogs []password.PasswordAccessLog
+	if err := r.db.Table(utils.TablePasswordAccessLogName).
+		Where("entry_id = ?", entryID).
+		Order("created_at DESC").
+		Find(&accessLogs).Error; err != nil {
+		return nil, err
+	}
+	return accessLogs, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+)
+
+// ErrEntryKeyChanged is returned when the entry key was rotated while an attachment was being encrypted
+var ErrEntryKeyChanged = errors.New("password entry key changed, retry the upload")
+
+type PasswordAttachmentRepository interface {
+	AddAttachment(attachment *password.PasswordAttachment, wrappedEntryKey string) error
+	GetAttachmentsByEntryID(entryID uint) ([]password.PasswordAttachment, error)
+	GetAttachmentByID(entryID, attachmentID uint) (*password.PasswordAttachment, error)
+	DeleteAttachment(attachment *password.PasswordAttachment, clientID string) error
+}
+
+type passwordAttachmentRepository struct {
+	db gorm.DB
+}
+
+func NewPasswordAttachmentRepository(db gorm.DB) PasswordAttachmentRepository {
+	return &passwordAttachmentRepository{
+		db: db,
+	}
+}
+
+// AddAttachment locks the entry and checks that the key the attachment key was wrapped with is still the
+// entry key, so an update rotating the key cannot miss an attachment saved concurrently
+func (r *passwordAttachmentRepository) AddAttachment(attachment *password.PasswordAttachment, wrappedEntryKey string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCompromisedState(tx, attachment.EntryID); err != nil {
+			return err
+		}
+
+		var currentKey string
+		if err := tx.Table(utils.TablePasswordEntryKeyName).Select("encrypted_symmetric_key").
+			Where("entry_id = ?", attachment.EntryID).Scan(&currentKey).Error; err != nil {
+			return err
+		}
+		if currentKey != wrappedEntryKey {
+			return ErrEntryKeyChanged
+		}
+
+		if err := tx.Table(utils.TablePasswordAttachmentName).Create(attachment).Error; err != nil {
+			return err
+		}
+		return addOutboxEvent(tx, utils.EventAttachmentAdded, attachment.UserID, attachment.EntryID, attachment.CreatedBy, map[string]interface{}{
+			"attachment_id": attachment.AttachmentID,
+			"file_name":     attachment.FileName,
+			"size":          attachment.Size,
+		})
+	})
+}
+
+func (r *passwordAttachmentRepository) GetAttachmentsByEntryID(entryID uint) ([]password.PasswordAttachment, error) {
+	var attachments []password.PasswordAttachment
+	if err := r.db.Table(utils.TablePasswordAttachmentName).
+		Where("entry_id = ?", entryID).
+		Order("attachment_id ASC").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (r *passwordAttachmentRepository) GetAttachmentByID(entryID, attachmentID uint) (*password.PasswordAttachment, error) {
+	var attachment password.PasswordAttachment
+	if err := r.db.Table(utils.TablePasswordAttachmentName).
+		Where("attachment_id = ? AND entry_id = ?", attachmentID, entryID).
+		First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *passwordAttachmentRepository) DeleteAttachment(attachment *password.PasswordAttachment, clientID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(utils.TablePasswordAttachmentName).
+			Where("attachment_id = ?", attachment.AttachmentID).
+			Delete(&password.PasswordAttachment{}).Error; err != nil {
+			return err
+		}
+		return addOutboxEvent(tx, utils.EventAttachmentDeleted, attachment.UserID, attachment.EntryID, &clientID, map[string]interface{}{
+			"attachment_id": attachment.AttachmentID,
+			"file_name":     attachment.FileName,
+		})
+	})
+}
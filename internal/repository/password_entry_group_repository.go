@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+	"time"
+)
+
+type PasswordEntryGroupRepository interface {
+	GetGroupIDsByEntryIDs(entryIDs []uint) (map[uint][]uint, error)
+	GetEntryGroupsByUserID(userID uint) ([]password.PasswordEntryGroup, error)
+	MoveEntriesToGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys ServiceAccountKeys) error
+	AddEntriesToGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys ServiceAccountKeys) error
+	RemoveEntriesFromGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys ServiceAccountKeys) error
+}
+
+type passwordEntryGroupRepository struct {
+	db gorm.DB
+}
+
+func NewPasswordEntryGroupRepository(db gorm.DB) PasswordEntryGroupRepository {
+	return &passwordEntryGroupRepository{
+		db: db,
+	}
+}
+
+func (r *passwordEntryGroupRepository) GetGroupIDsByEntryIDs(entryIDs []uint) (map[uint][]uint, error) {
+	groupIDs := make(map[uint][]uint, len(entryIDs))
+	if len(entryIDs) == 0 {
+		return groupIDs, nil
+	}
+
+	var memberships []password.PasswordEntryGroup
+	if err := r.db.Table(utils.TablePasswordEntryGroupName).
+		Where("entry_id IN ?", entryIDs).
+		Order("group_id ASC").
+		Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		groupIDs[membership.EntryID] = append(groupIDs[membership.EntryID], membership.GroupID)
+	}
+	return groupIDs, nil
+}
+
+func (r *passwordEntryGroupRepository) GetEntryGroupsByUserID(userID uint) ([]password.PasswordEntryGroup, error) {
+	var memberships []password.PasswordEntryGroup
+	if err := r.db.Table(utils.TablePasswordEntryGroupName+" peg").
+		Select("peg.*").
+		Joins("JOIN password_entries pe ON pe.entry_id = peg.entry_id").
+		Where("pe.user_id = ? AND pe.deleted_at IS NULL", userID).
+		Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// MoveEntriesToGroup makes the group the only group of every entry
+func (r *passwordEntryGroupRepository) MoveEntriesToGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys ServiceAccountKeys) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockEntries(tx, entries); err != nil {
+			return err
+		}
+		for i := range entries {
+			if err := tx.Table(utils.TablePasswordEntryGroupName).
+				Where("entry_id = ? AND group_id <> ?", entries[i].EntryID, groupID).
+				Delete(&password.PasswordEntryGroup{}).Error; err != nil {
+				return err
+			}
+			if err := addEntryGroup(tx, entries[i].EntryID, groupID, clientID); err != nil {
+				return err
+			}
+			entries[i].GroupID, _ = primaryGroupAfter(utils.BulkActionMove, entries[i].GroupID, groupID, nil)
+			if err := updateEntryGroups(tx, &entries[i], utils.BulkActionMove, groupID, clientID); err != nil {
+				return err
+			}
+		}
+		return syncServiceAccountKeys(tx, entryIDsOf(entries), serviceAccountKeys, false, &clientID)
+	})
+}
+
+// AddEntriesToGroup adds the group to the groups of every entry; it becomes the primary group of ungrouped entries
+func (r *passwordEntryGroupRepository) AddEntriesToGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys ServiceAccountKeys) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockEntries(tx, entries); err != nil {
+			return err
+		}
+		for i := range entries {
+			if err := addEntryGroup(tx, entries[i].EntryID, groupID, clientID); err != nil {
+				return err
+			}
+			entries[i].GroupID, _ = primaryGroupAfter(utils.BulkActionCopy, entries[i].GroupID, groupID, nil)
+			if err := updateEntryGroups(tx, &entries[i], utils.BulkActionCopy, groupID, clientID); err != nil {
+				return err
+			}
+		}
+		return syncServiceAccountKeys(tx, entryIDsOf(entries), serviceAccountKeys, false, &clientID)
+	})
+}
+
+// RemoveEntriesFromGroup takes the group out of the groups of every entry. Entries whose primary group it was fall
+// back to their oldest remaining group, or become ungrouped.
+func (r *passwordEntryGroupRepository) RemoveEntriesFromGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys ServiceAccountKeys) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockEntries(tx, entries); err != nil {
+			return err
+		}
+		for i := range entries {
+			if err := tx.Table(utils.TablePasswordEntryGroupName).
+				Where("entry_id = ? AND group_id = ?", entries[i].EntryID, groupID).
+				Delete(&password.PasswordEntryGroup{}).Error; err != nil {
+				return err
+			}
+			primaryGroupID, err := primaryGroupAfter(utils.BulkActionRemove, entries[i].GroupID, groupID, func() ([]uint, error) {
+				var remaining []uint
+				err := tx.Table(utils.TablePasswordEntryGroupName).
+					Where("entry_id = ?", entries[i].EntryID).
+					Order("created_at ASC, group_id ASC").
+					Limit(1).
+					Pluck("group_id", &remaining).Error
+				return remaining, err
+			})
+			if err != nil {
+				return err
+			}
+			entries[i].GroupID = primaryGroupID
+			if err := updateEntryGroups(tx, &entries[i], utils.BulkActionRemove, groupID, clientID); err != nil {
+				return err
+			}
+		}
+		return syncServiceAccountKeys(tx, entryIDsOf(entries), serviceAccountKeys, false, &clientID)
+	})
+}
+
+// primaryGroupAfter returns the primary group of an entry after a bulk action on groupID. A move makes the group
+// primary and an add only does for ungrouped entries. A removal of the primary group falls back to the first of
+// the remaining groups, oldest first, which are only looked up in that case.
+func primaryGroupAfter(action string, current *uint, groupID uint, remaining func() ([]uint, error)) (*uint, error) {
+	switch action {
+	case utils.BulkActionMove:
+		return &groupID, nil
+	case utils.BulkActionCopy:
+		if current == nil {
+			return &groupID, nil
+		}
+	case utils.BulkActionRemove:
+		if current == nil || *current != groupID {
+			return current, nil
+		}
+		groupIDs, err := remaining()
+		if err != nil {
+			return nil, err
+		}
+		if len(groupIDs) == 0 {
+			return nil, nil
+		}
+		return &groupIDs[0], nil
+	}
+	return current, nil
+}
+
+// lockEntries serializes membership changes so the primary group always stays one of the entry's groups
+func lockEntries(tx *gorm.DB, entries []password.PasswordEntry) error {
+	var locked []uint
+	return tx.Raw(`SELECT entry_id FROM password_entries WHERE entry_id IN ? ORDER BY entry_id FOR UPDATE`, entryIDsOf(entries)).Scan(&locked).Error
+}
+
+func entryIDsOf(entries []password.PasswordEntry) []uint {
+	entryIDs := make([]uint, len(entries))
+	for i, entry := range entries {
+		entryIDs[i] = entry.EntryID
+	}
+	return entryIDs
+}
+
+func addEntryGroup(tx *gorm.DB, entryID, groupID uint, clientID string) error {
+	return tx.Exec(`
+		INSERT INTO password_entry_groups (entry_id, group_id, created_by) VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING
+	`, entryID, groupID, clientID).Error
+}
+
+// updateEntryGroups stores the primary group, and the expiry when group policies tightened it
+func updateEntryGroups(tx *gorm.DB, entry *password.PasswordEntry, action string, groupID uint, clientID string) error {
+	var current password.PasswordEntry
+	if err := tx.Table(utils.TablePasswordEntryName).
+		Select("expires_at").
+		Where("entry_id = ?", entry.EntryID).
+		Take(&current).Error; err != nil {
+		return err
+	}
+
+	columns := map[string]interface{}{
+		"group_id":   entry.GroupID,
+		"updated_by": clientID,
+		"updated_at": time.Now(),
+	}
+	expiryChanged := entry.ExpiresAt != nil && (current.ExpiresAt == nil || !current.ExpiresAt.Equal(*entry.ExpiresAt))
+	if expiryChanged {
+		columns["expires_at"] = entry.ExpiresAt
+	}
+	if err := tx.Table(utils.TablePasswordEntryName).
+		Where("entry_id = ?", entry.EntryID).
+		UpdateColumns(columns).Error; err != nil {
+		return err
+	}
+	if expiryChanged {
+		if err := resetExpiryNotification(tx, entry); err != nil {
+			return err
+		}
+	}
+	return addOutboxEvent(tx, utils.EventEntryUpdated, entry.UserID, entry.EntryID, &clientID, map[string]interface{}{
+		"action":   action,
+		"group_id": groupID,
+	})
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+	"password-management-service/internal/utils"
+	"testing"
+)
+
+func TestPrimaryGroupAfter(t *testing.T) {
+	group := func(id uint) *uint { return &id }
+	remaining := func(groupIDs ...uint) func() ([]uint, error) {
+		return func() ([]uint, error) { return groupIDs, nil }
+	}
+	unused := func() ([]uint, error) { return nil, errors.New("remaining groups looked up") }
+
+	tests := []struct {
+		name      string
+		action    string
+		current   *uint
+		remaining func() ([]uint, error)
+		want      *uint
+	}{
+		{name: "move an ungrouped entry", action: utils.BulkActionMove, want: group(7)},
+		{name: "move a grouped entry", action: utils.BulkActionMove, current: group(3), want: group(7)},
+		{name: "add an ungrouped entry", action: utils.BulkActionCopy, want: group(7)},
+		{name: "add keeps the primary group", action: utils.BulkActionCopy, current: group(3), want: group(3)},
+		{name: "remove another group", action: utils.BulkActionRemove, current: group(3), remaining: unused, want: group(3)},
+		{name: "remove from an ungrouped entry", action: utils.BulkActionRemove, remaining: unused},
+		{name: "remove the primary group", action: utils.BulkActionRemove, current: group(7), remaining: remaining(4, 9), want: group(4)},
+		{name: "remove the only group", action: utils.BulkActionRemove, current: group(7), remaining: remaining()},
+		{name: "tags leave groups alone", action: utils.BulkActionTag, current: group(3), want: group(3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := primaryGroupAfter(tt.action, tt.current, 7, tt.remaining)
+			if err != nil {
+				t.Fatalf("primaryGroupAfter: %v", err)
+			}
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("primary group = %d, want none", *got)
+			case tt.want != nil && (got == nil || *got != *tt.want):
+				t.Errorf("primary group = %v, want %d", got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestPrimaryGroupAfterLookupError(t *testing.T) {
+	current := uint(7)
+	lookup := errors.New("connection reset")
+	if _, err := primaryGroupAfter(utils.BulkActionRemove, &current, 7, func() ([]uint, error) { return nil, lookup }); !errors.Is(err, lookup) {
+		t.Errorf("primaryGroupAfter err = %v, want %v", err, lookup)
+	}
+}
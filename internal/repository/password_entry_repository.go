@@ -8,25 +8,57 @@ import (
 	"password-management-service/internal/models/password"
 	"password-management-service/internal/utils"
 	"strings"
+	"time"
 )
 
+// frecencyScores weighs each access of the user in the last 90 days by its age, the way browsers rank history
+const frecencyScores = `
+	SELECT entry_id, SUM(
+		CASE
+			WHEN created_at > CURRENT_TIMESTAMP - INTERVAL '4 days' THEN 100
+			WHEN created_at > CURRENT_TIMESTAMP - INTERVAL '14 days' THEN 70
+			WHEN created_at > CURRENT_TIMESTAMP - INTERVAL '31 days' THEN 50
+			ELSE 30
+		END
+	) AS score
+	FROM password_access_logs
+	WHERE user_id = ? AND created_at > CURRENT_TIMESTAMP - INTERVAL '90 days'
+	GROUP BY entry_id
+`
+
 type PasswordEntryRepository interface {
-	AddPasswordEntry(passwordEntry *password.PasswordEntry, passwordEntryKey *password.PasswordEntryKey, tags pq.StringArray, userID uint) error
+	AddPasswordEntry(passwordEntry *password.PasswordEntry, passwordEntryKey *password.PasswordEntryKey, tags pq.StringArray, urls []password.PasswordEntryURL, userID uint, serviceAccountKeys map[uint]string) error
 	UpdatePasswordEntry(passwordEntry *password.PasswordEntry) error
-	UpdatePasswordEntryAndEntryKey(passwordEntry password.PasswordEntry, passwordEntryKey password.PasswordEntryKey) error
-	DeletePasswordEntry(entryID uint) error
-	GetListPasswordEntryResponse(userID uint, tags string, index int, size int) ([]out.PasswordEntryListResponse, error)
+	UpdatePasswordEntryAndEntryKey(passwordEntry password.PasswordEntry, passwordEntryKey password.PasswordEntryKey, attachments []password.PasswordAttachment, urls []password.PasswordEntryURL, serviceAccountKeys map[uint]string) error
+	DeletePasswordEntry(entryID, userID uint) error
+	DeletePasswordEntries(entries []password.PasswordEntry, clientID string) error
+	GetListPasswordEntryResponse(userID uint, tags string, entryType string, favorite bool, sort string, index int, size int) ([]out.PasswordEntryListResponse, error)
+	GetRecentPasswordEntries(userID uint, limit int) ([]out.PasswordEntryListResponse, error)
+	GetListPasswordEntryResponseByEntryIDs(userID uint, entryIDs []uint) ([]out.PasswordEntryListResponse, error)
 	GetListPasswordEntryResponseByTags(userID uint, tags []string, index int, size int) ([]out.PasswordEntryListResponse, error)
 	GetPasswordEntryByEntryIDAndUserID(entryID, userID uint) (*password.PasswordEntry, error)
+	GetPasswordEntriesByEntryIDsAndUserID(entryIDs []uint, userID uint) ([]password.PasswordEntry, error)
+	GetPasswordEntryDetailByEntryIDAndUserID(entryID, userID uint) (*out.PasswordEntryDetailResponse, error)
 	GetPasswordEntryByUserID(userID string) ([]password.PasswordEntry, error)
 	GetPasswordEntryByGroupID(groupID uint) ([]password.PasswordEntry, error)
 	GetPasswordEntryByGroupIDAndUserID(groupID uint, userID string) ([]password.PasswordEntry, error)
 	GetPasswordEntryByGroupIDAndEntryID(groupID uint, entryID uint) (*password.PasswordEntry, error)
 	GetPasswordEntryByGroupIDAndUserIDAndEntryID(groupID uint, userID string, entryID uint) (*password.PasswordEntry, error)
-	GetCountPasswordEntriesByUserID(userID uint) (int64, error)
+	GetCountPasswordEntriesByUserID(userID uint, entryType string, favorite bool) (int64, error)
+	SetFavorite(entryID, userID uint, favorite bool) error
+	SetPinned(entryID, userID uint, pinned bool) error
+	UpdateEncryptedData(entryID uint, current, updated string) error
 	GetCountPasswordEntriesByTags(id uint, tags []string) (int64, error)
+	MarkExpiredEntries(limit int) (int, error)
+	UpdateBreachStatus(passwordEntry *password.PasswordEntry) error
+	GetEntriesForBreachCheck(userID uint, checkedBefore time.Time, limit int) ([]password.PasswordEntry, error)
+	GetBreachedEntriesByUserID(userID uint) ([]out.BreachedEntryResponse, error)
+	GetEntriesForPolicyCheck(userID uint) ([]password.PasswordEntry, error)
 }
 
+// ErrEntryDataChanged is returned when the data document was changed by another request since it was read
+var ErrEntryDataChanged = errors.New("password entry data changed, retry")
+
 type passwordEntryRepository struct {
 	db gorm.DB
 }
@@ -37,7 +69,9 @@ func NewPasswordEntryRepository(db gorm.DB) PasswordEntryRepository {
 	}
 }
 
-func (r *passwordEntryRepository) AddPasswordEntry(passwordEntry *password.PasswordEntry, passwordEntryKey *password.PasswordEntryKey, tags pq.StringArray, userID uint) error {
+// AddPasswordEntry stores the entry with its tags. serviceAccountKeys is the entry key wrapped for the user's
+// service accounts, by service account ID; the accounts whose scope covers a tag of the entry get theirs.
+func (r *passwordEntryRepository) AddPasswordEntry(passwordEntry *password.PasswordEntry, passwordEntryKey *password.PasswordEntryKey, tags pq.StringArray, urls []password.PasswordEntryURL, userID uint, serviceAccountKeys map[uint]string) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Table(utils.TablePasswordEntryName).Create(passwordEntry).Error; err != nil {
 			return err
@@ -47,14 +81,30 @@ func (r *passwordEntryRepository) AddPasswordEntry(passwordEntry *password.Passw
 		if err := tx.Table(utils.TablePasswordEntryKeyName).Create(passwordEntryKey).Error; err != nil {
 			return err
 		}
+		if err := replaceEntryURLs(tx, passwordEntry.EntryID, urls); err != nil {
+			return err
+		}
+
+		if err := addOutboxEvent(tx, utils.EventEntryCreated, passwordEntry.UserID, passwordEntry.EntryID, passwordEntry.CreatedBy, map[string]interface{}{
+			"group_id": passwordEntry.GroupID,
+		}); err != nil {
+			return err
+		}
+		if passwordEntry.Compromised {
+			if err := addCompromisedEvent(tx, passwordEntry); err != nil {
+				return err
+			}
+		}
 
 		for _, tagName := range tags {
 			var tag password.PasswordTag
-			if err := r.db.Where("name = ? AND user_id = ?", tagName, userID).First(&tag).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			err := tx.Where("name = ? AND user_id = ?", tagName, userID).First(&tag).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
 				tag = password.PasswordTag{UserID: userID, Name: tagName, CreatedBy: passwordEntry.CreatedBy, UpdatedBy: passwordEntry.CreatedBy}
-				if err := r.db.Create(&tag).Error; err != nil {
-					return err
-				}
+				err = tx.Create(&tag).Error
+			}
+			if err != nil {
+				return err
 			}
 
 			if err := tx.Table(utils.TablePasswordEntryTagName).Create(&password.PasswordEntryTag{
@@ -65,7 +115,10 @@ func (r *passwordEntryRepository) AddPasswordEntry(passwordEntry *password.Passw
 			}
 		}
 
-		return nil
+		return syncServiceAccountKeys(tx, []uint{passwordEntry.EntryID}, ServiceAccountKeys{
+			Wrapped:   map[uint]map[uint]string{passwordEntry.EntryID: serviceAccountKeys},
+			EntryKeys: map[uint]string{passwordEntry.EntryID: passwordEntryKey.EncryptedSymmetricKey},
+		}, false, passwordEntry.CreatedBy)
 	})
 }
 
@@ -74,57 +127,320 @@ func (r *passwordEntryRepository) UpdatePasswordEntry(passwordEntry *password.Pa
 		if err := tx.Table(utils.TablePasswordEntryName).Where("entry_id = ?", passwordEntry.EntryID).Updates(&passwordEntry).Error; err != nil {
 			return err
 		}
-		return nil
+		if err := resetExpiryNotification(tx, passwordEntry); err != nil {
+			return err
+		}
+		return addOutboxEvent(tx, utils.EventEntryUpdated, passwordEntry.UserID, passwordEntry.EntryID, passwordEntry.UpdatedBy, nil)
 	})
 }
 
-func (r *passwordEntryRepository) UpdatePasswordEntryAndEntryKey(passwordEntry password.PasswordEntry, passwordEntryKey password.PasswordEntryKey) error {
+// UpdatePasswordEntryAndEntryKey stores a re-encrypted entry; the breach status always travels with the new password.
+// The service accounts with access get the new key from serviceAccountKeys in the same transaction.
+func (r *passwordEntryRepository) UpdatePasswordEntryAndEntryKey(passwordEntry password.PasswordEntry, passwordEntryKey password.PasswordEntryKey, attachments []password.PasswordAttachment, urls []password.PasswordEntryURL, serviceAccountKeys map[uint]string) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
+		wasCompromised, err := lockCompromisedState(tx, passwordEntry.EntryID)
+		if err != nil {
+			return err
+		}
 		if err := tx.Table(utils.TablePasswordEntryName).Where("entry_id = ?", passwordEntry.EntryID).Updates(&passwordEntry).Error; err != nil {
 			return err
 		}
 		if err := tx.Table(utils.TablePasswordEntryKeyName).Where("entry_id = ?", passwordEntry.EntryID).Updates(&passwordEntryKey).Error; err != nil {
 			return err
 		}
+		if err := rewrapAttachments(tx, passwordEntry.EntryID, attachments); err != nil {
+			return err
+		}
+		if err := syncServiceAccountKeys(tx, []uint{passwordEntry.EntryID}, ServiceAccountKeys{
+			Wrapped:   map[uint]map[uint]string{passwordEntry.EntryID: serviceAccountKeys},
+			EntryKeys: map[uint]string{passwordEntry.EntryID: passwordEntryKey.EncryptedSymmetricKey},
+		}, true, passwordEntry.UpdatedBy); err != nil {
+			return err
+		}
+		// Written explicitly so optional documents encrypted under the old key, and the URL, are cleared when omitted
+		if err := tx.Table(utils.TablePasswordEntryName).Where("entry_id = ?", passwordEntry.EntryID).
+			UpdateColumns(map[string]interface{}{
+				"encrypted_custom_fields": passwordEntry.EncryptedCustom,
+				"encrypted_data":          passwordEntry.EncryptedData,
+				"metadata":                passwordEntry.Metadata,
+				"url":                     passwordEntry.URL,
+			}).Error; err != nil {
+			return err
+		}
+		if err := replaceEntryURLs(tx, passwordEntry.EntryID, urls); err != nil {
+			return err
+		}
+		if err := resetExpiryNotification(tx, &passwordEntry); err != nil {
+			return err
+		}
+		if err := updateBreachStatus(tx, &passwordEntry, wasCompromised); err != nil {
+			return err
+		}
+		return addOutboxEvent(tx, utils.EventEntryUpdated, passwordEntry.UserID, passwordEntry.EntryID, passwordEntry.UpdatedBy, map[string]interface{}{
+			"key_rotated": true,
+		})
+	})
+}
+
+// rewrapAttachments stores the attachment keys rewrapped for the new entry key. The entry row is locked, so a
+// count mismatch means an attachment was added after the keys were rewrapped.
+func rewrapAttachments(tx *gorm.DB, entryID uint, attachments []password.PasswordAttachment) error {
+	var count int64
+	if err := tx.Table(utils.TablePasswordAttachmentName).Where("entry_id = ?", entryID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count != int64(len(attachments)) {
+		return errors.New("attachments changed during the update, retry")
+	}
+
+	for _, attachment := range attachments {
+		if err := tx.Table(utils.TablePasswordAttachmentName).
+			Where("attachment_id = ? AND entry_id = ?", attachment.AttachmentID, entryID).
+			UpdateColumn("wrapped_key", attachment.WrappedKey).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeletePasswordEntry deletes an entry of userID and returns gorm.ErrRecordNotFound when the user has no such entry
+func (r *passwordEntryRepository) DeletePasswordEntry(entryID, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Table(utils.TablePasswordEntryName).
+			Where("entry_id = ? AND user_id = ?", entryID, userID).
+			Delete(&password.PasswordEntry{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return addOutboxEvent(tx, utils.EventEntryDeleted, userID, entryID, nil, nil)
+	})
+}
+
+// DeletePasswordEntries deletes the entries of a bulk operation in one transaction
+func (r *passwordEntryRepository) DeletePasswordEntries(entries []password.PasswordEntry, clientID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			if err := tx.Unscoped().Table(utils.TablePasswordEntryName).Delete(&password.PasswordEntry{}, entry.EntryID).Error; err != nil {
+				return err
+			}
+			if err := addOutboxEvent(tx, utils.EventEntryDeleted, entry.UserID, entry.EntryID, &clientID, map[string]interface{}{
+				"action": utils.BulkActionDelete,
+			}); err != nil {
+				return err
+			}
+		}
 		return nil
+	})
+}
 
+// MarkExpiredEntries flags entries whose expiry has passed and emits one entry.expired event for each
+func (r *passwordEntryRepository) MarkExpiredEntries(limit int) (int, error) {
+	marked := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var entries []password.PasswordEntry
+		if err := tx.Raw(`
+			SELECT entry_id, user_id, expires_at FROM password_entries
+			WHERE expires_at IS NOT NULL AND expires_at <= CURRENT_TIMESTAMP
+				AND expiry_notified_at IS NULL AND deleted_at IS NULL
+			ORDER BY expires_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		`, limit).Scan(&entries).Error; err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := tx.Table(utils.TablePasswordEntryName).
+				Where("entry_id = ?", entry.EntryID).
+				UpdateColumn("expiry_notified_at", gorm.Expr("CURRENT_TIMESTAMP")).Error; err != nil {
+				return err
+			}
+			if err := addOutboxEvent(tx, utils.EventEntryExpired, entry.UserID, entry.EntryID, nil, map[string]interface{}{
+				"expires_at": entry.ExpiresAt,
+			}); err != nil {
+				return err
+			}
+			marked++
+		}
+		return nil
 	})
+	return marked, err
+}
+
+// resetExpiryNotification re-arms the expiry event when an entry gets a new expiry date
+func resetExpiryNotification(tx *gorm.DB, passwordEntry *password.PasswordEntry) error {
+	if passwordEntry.ExpiresAt == nil {
+		return nil
+	}
+	return tx.Table(utils.TablePasswordEntryName).
+		Where("entry_id = ?", passwordEntry.EntryID).
+		UpdateColumn("expiry_notified_at", nil).Error
+}
+
+// UpdateBreachStatus records the result of a breach check and emits entry.compromised when the entry turns
+// compromised. The row is only touched while it still holds the checked password, so a concurrent password change wins.
+func (r *passwordEntryRepository) UpdateBreachStatus(passwordEntry *password.PasswordEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		wasCompromised, err := lockCompromisedState(tx, passwordEntry.EntryID)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Table(utils.TablePasswordEntryName).
+			Where("entry_id = ? AND encrypted_password = ?", passwordEntry.EntryID, passwordEntry.EncryptedPassword).
+			UpdateColumns(map[string]interface{}{
+				"compromised":       passwordEntry.Compromised,
+				"breach_count":      passwordEntry.BreachCount,
+				"breach_checked_at": passwordEntry.BreachCheckedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 || !passwordEntry.Compromised || wasCompromised {
+			return nil
+		}
+		return addCompromisedEvent(tx, passwordEntry)
+	})
+}
+
+// GetEntriesForBreachCheck returns the user's entries that were never checked or not since checkedBefore
+func (r *passwordEntryRepository) GetEntriesForBreachCheck(userID uint, checkedBefore time.Time, limit int) ([]password.PasswordEntry, error) {
+	var entries []password.PasswordEntry
+	err := r.db.Raw(`
+		SELECT entry_id, user_id, encrypted_password, compromised, breach_count, breach_checked_at, updated_by
+		FROM password_entries
+		WHERE user_id = ? AND deleted_at IS NULL
+			AND (breach_checked_at IS NULL OR breach_checked_at < ?)
+		ORDER BY breach_checked_at NULLS FIRST, entry_id
+		LIMIT ?
+	`, userID, checkedBefore, limit).Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *passwordEntryRepository) GetBreachedEntriesByUserID(userID uint) ([]out.BreachedEntryResponse, error) {
+	var entries []out.BreachedEntryResponse
+	err := r.db.Raw(`
+		SELECT
+			pe.entry_id,
+			pe.title,
+			pe.url,
+			pg.path AS group_name,
+			pe.breach_count,
+			pe.breach_checked_at,
+			pe.updated_at
+		FROM password_entries pe
+		LEFT JOIN password_groups pg ON pg.group_id = pe.group_id
+		WHERE pe.user_id = ? AND pe.compromised = TRUE AND pe.deleted_at IS NULL
+		ORDER BY pe.breach_count DESC, pe.entry_id ASC
+	`, userID).Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *passwordEntryRepository) GetEntriesForPolicyCheck(userID uint) ([]password.PasswordEntry, error) {
+	var entries []password.PasswordEntry
+	err := r.db.Raw(`
+		SELECT entry_id, user_id, group_id, title, compromised, password_length, password_classes, password_changed_at, created_at, updated_at
+		FROM password_entries
+		WHERE user_id = ? AND deleted_at IS NULL
+		ORDER BY entry_id ASC
+	`, userID).Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func lockCompromisedState(tx *gorm.DB, entryID uint) (bool, error) {
+	var compromised bool
+	err := tx.Raw(`SELECT compromised FROM password_entries WHERE entry_id = ? FOR UPDATE`, entryID).Scan(&compromised).Error
+	return compromised, err
 }
 
-func (r *passwordEntryRepository) DeletePasswordEntry(entryID uint) error {
-	if err := r.db.Unscoped().Table(utils.TablePasswordEntryName).Delete(&password.PasswordEntry{}, entryID).Error; err != nil {
+// updateBreachStatus writes every breach column explicitly because Updates skips zero values such as compromised = false
+func updateBreachStatus(tx *gorm.DB, passwordEntry *password.PasswordEntry, wasCompromised bool) error {
+	if err := tx.Table(utils.TablePasswordEntryName).
+		Where("entry_id = ?", passwordEntry.EntryID).
+		UpdateColumns(map[string]interface{}{
+			"compromised":       passwordEntry.Compromised,
+			"breach_count":      passwordEntry.BreachCount,
+			"breach_checked_at": passwordEntry.BreachCheckedAt,
+		}).Error; err != nil {
 		return err
 	}
-	return nil
+	if !passwordEntry.Compromised || wasCompromised {
+		return nil
+	}
+	return addCompromisedEvent(tx, passwordEntry)
 }
 
-func (r *passwordEntryRepository) GetListPasswordEntryResponse(userID uint, tagParams string, index int, size int) ([]out.PasswordEntryListResponse, error) {
+func addCompromisedEvent(tx *gorm.DB, passwordEntry *password.PasswordEntry) error {
+	return addOutboxEvent(tx, utils.EventEntryCompromised, passwordEntry.UserID, passwordEntry.EntryID, passwordEntry.UpdatedBy, map[string]interface{}{
+		"breach_count": passwordEntry.BreachCount,
+	})
+}
+
+// GetListPasswordEntryResponse lists the user's entries, pinned entries first. With sort=frecency the others are
+// ordered by how often and how recently they were accessed.
+func (r *passwordEntryRepository) GetListPasswordEntryResponse(userID uint, tagParams string, entryType string, favorite bool, sort string, index int, size int) ([]out.PasswordEntryListResponse, error) {
 	var passwordEntries []out.PasswordEntryListResponse
 
 	// Build base query
 	query := `
-		SELECT DISTINCT
+		SELECT
 			pe.entry_id,
 			pe.title,
+			pe.entry_type,
+			pe.metadata,
 			pe.url,
-			pg.name AS group_name
+			pe.compromised,
+			pe.favorite,
+			(pe.pinned_at IS NOT NULL) AS pinned,
+			pg.path AS group_name
 		FROM password_entries pe
 		LEFT JOIN password_groups pg ON pg.group_id = pe.group_id
-		LEFT JOIN password_entry_tags pet ON pet.entry_id = pe.entry_id
-		LEFT JOIN password_tags pt ON pt.tag_id = pet.tag_id
-		WHERE pe.user_id = ? AND pe.deleted_at IS NULL
 	`
 	var args []interface{}
+	if sort == utils.SortFrecency {
+		query += ` LEFT JOIN (` + frecencyScores + `) f ON f.entry_id = pe.entry_id`
+		args = append(args, userID)
+	}
+	query += ` WHERE pe.user_id = ? AND pe.deleted_at IS NULL`
 	args = append(args, userID)
 
 	// Filter by tags if provided
 	if tagParams != "" {
 		tags := strings.Split(tagParams, ",")
-		query += ` AND pt.name = ANY(?)`
+		query += ` AND EXISTS (
+			SELECT 1 FROM password_entry_tags pet
+			JOIN password_tags pt ON pt.tag_id = pet.tag_id
+			WHERE pet.entry_id = pe.entry_id AND pt.name = ANY(?)
+		)`
 		args = append(args, pq.Array(tags))
 	}
 
-	query += ` ORDER BY pe.entry_id ASC LIMIT ? OFFSET ?`
+	if entryType != "" {
+		query += ` AND pe.entry_type = ?`
+		args = append(args, entryType)
+	}
+
+	if favorite {
+		query += ` AND pe.favorite = TRUE`
+	}
+
+	query += ` ORDER BY pe.pinned_at ASC NULLS LAST`
+	if sort == utils.SortFrecency {
+		query += `, COALESCE(f.score, 0) DESC, pe.last_accessed_at DESC NULLS LAST`
+	}
+	query += `, pe.entry_id ASC LIMIT ? OFFSET ?`
 	args = append(args, size, (index-1)*size)
 
 	// Execute main query
@@ -132,7 +448,78 @@ func (r *passwordEntryRepository) GetListPasswordEntryResponse(userID uint, tagP
 		return nil, err
 	}
 
-	// Fetch tags for all entries in a single query for efficiency
+	if err := r.attachTags(passwordEntries); err != nil {
+		return nil, err
+	}
+	return passwordEntries, nil
+}
+
+// GetRecentPasswordEntries lists the user's most recently accessed entries
+func (r *passwordEntryRepository) GetRecentPasswordEntries(userID uint, limit int) ([]out.PasswordEntryListResponse, error) {
+	var passwordEntries []out.PasswordEntryListResponse
+	err := r.db.Raw(`
+		SELECT
+			pe.entry_id,
+			pe.title,
+			pe.entry_type,
+			pe.metadata,
+			pe.url,
+			pe.compromised,
+			pe.favorite,
+			(pe.pinned_at IS NOT NULL) AS pinned,
+			pe.last_accessed_at,
+			pg.path AS group_name
+		FROM password_entries pe
+		LEFT JOIN password_groups pg ON pg.group_id = pe.group_id
+		WHERE pe.user_id = ? AND pe.deleted_at IS NULL AND pe.last_accessed_at IS NOT NULL
+		ORDER BY pe.last_accessed_at DESC, pe.entry_id ASC
+		LIMIT ?
+	`, userID, limit).Scan(&passwordEntries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.attachTags(passwordEntries); err != nil {
+		return nil, err
+	}
+	return passwordEntries, nil
+}
+
+// GetListPasswordEntryResponseByEntryIDs lists the given entries of the user, in no particular order
+func (r *passwordEntryRepository) GetListPasswordEntryResponseByEntryIDs(userID uint, entryIDs []uint) ([]out.PasswordEntryListResponse, error) {
+	var passwordEntries []out.PasswordEntryListResponse
+	if len(entryIDs) == 0 {
+		return passwordEntries, nil
+	}
+
+	err := r.db.Raw(`
+		SELECT
+			pe.entry_id,
+			pe.title,
+			pe.entry_type,
+			pe.metadata,
+			pe.url,
+			pe.compromised,
+			pe.favorite,
+			(pe.pinned_at IS NOT NULL) AS pinned,
+			pe.last_accessed_at,
+			pg.path AS group_name
+		FROM password_entries pe
+		LEFT JOIN password_groups pg ON pg.group_id = pe.group_id
+		WHERE pe.user_id = ? AND pe.entry_id IN ? AND pe.deleted_at IS NULL
+	`, userID, entryIDs).Scan(&passwordEntries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.attachTags(passwordEntries); err != nil {
+		return nil, err
+	}
+	return passwordEntries, nil
+}
+
+// attachTags fetches the tags of all listed entries in a single query
+func (r *passwordEntryRepository) attachTags(passwordEntries []out.PasswordEntryListResponse) error {
 	entryIDs := make([]uint, 0, len(passwordEntries))
 	entryIndex := make(map[uint]int)
 	for i, entry := range passwordEntries {
@@ -151,7 +538,7 @@ func (r *passwordEntryRepository) GetListPasswordEntryResponse(userID uint, tagP
 			Joins("JOIN password_tags pt ON pt.tag_id = password_entry_tags.tag_id").
 			Where("password_entry_tags.entry_id IN ?", entryIDs).
 			Scan(&tagResults).Error; err != nil {
-			return nil, err
+			return err
 		}
 
 		// Map tags to entries
@@ -165,7 +552,7 @@ func (r *passwordEntryRepository) GetListPasswordEntryResponse(userID uint, tagP
 		}
 	}
 
-	return passwordEntries, nil
+	return nil
 }
 
 func (r *passwordEntryRepository) GetListPasswordEntryResponseByTags(userID uint, tags []string, index int, size int) ([]out.PasswordEntryListResponse, error) {
@@ -178,7 +565,10 @@ func (r *passwordEntryRepository) GetListPasswordEntryResponseByTags(userID uint
 			pe.username, 
 			pe.url, 
 			pe.tags, 
-			pg.name AS group_name
+			pe.entry_type,
+			pe.metadata,
+			pe.compromised,
+			pg.path AS group_name
 		FROM password_entries pe
 		LEFT JOIN password_groups pg ON pg.group_id = pe.group_id
 		WHERE pe.user_id = ? AND pe.deleted_at IS NULL AND pe.tags && ?::text[]
@@ -200,6 +590,80 @@ func (r *passwordEntryRepository) GetPasswordEntryByEntryIDAndUserID(entryID, us
 	return &passwordEntry, nil
 }
 
+func (r *passwordEntryRepository) GetPasswordEntriesByEntryIDsAndUserID(entryIDs []uint, userID uint) ([]password.PasswordEntry, error) {
+	var passwordEntries []password.PasswordEntry
+	if err := r.db.Where("entry_id IN ? AND user_id = ?", entryIDs, userID).Order("entry_id ASC").Find(&passwordEntries).Error; err != nil {
+		return nil, err
+	}
+	return passwordEntries, nil
+}
+
+func (r *passwordEntryRepository) GetPasswordEntryDetailByEntryIDAndUserID(entryID, userID uint) (*out.PasswordEntryDetailResponse, error) {
+	var detail out.PasswordEntryDetailResponse
+
+	err := r.db.Raw(`
+		SELECT
+			pe.entry_id,
+			pe.title,
+			pe.entry_type,
+			pe.metadata,
+			pe.url,
+			pe.group_id,
+			pg.path AS group_name,
+			pe.username,
+			(pe.encrypted_notes IS NOT NULL AND pe.encrypted_notes <> '') AS has_notes,
+			(pe.encrypted_custom_fields IS NOT NULL) AS has_custom_fields,
+			pe.compromised,
+			pe.favorite,
+			pe.pinned_at,
+			pe.breach_count,
+			pe.breach_checked_at,
+			pe.expires_at,
+			pe.last_accessed_at,
+			pe.created_at,
+			pe.updated_at
+		FROM password_entries pe
+		LEFT JOIN password_groups pg ON pg.group_id = pe.group_id
+		WHERE pe.entry_id = ? AND pe.user_id = ? AND pe.deleted_at IS NULL
+	`, entryID, userID).Scan(&detail).Error
+	if err != nil {
+		return nil, err
+	}
+	if detail.EntryID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var tags []string
+	if err := r.db.Table(utils.TablePasswordEntryTagName).
+		Select("pt.name").
+		Joins("JOIN password_tags pt ON pt.tag_id = password_entry_tags.tag_id").
+		Where("password_entry_tags.entry_id = ?", entryID).
+		Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		strArray := pq.StringArray(tags)
+		detail.Tags = &strArray
+	}
+
+	if err := r.db.Table(utils.TablePasswordEntryGroupName).
+		Where("entry_id = ?", entryID).
+		Order("group_id ASC").
+		Pluck("group_id", &detail.GroupIDs).Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Table(utils.TablePasswordEntryURLName).
+		Select("url, match_type AS match").
+		Where("entry_id = ?", entryID).
+		Order("position ASC").
+		Scan(&detail.URLs).Error; err != nil {
+		return nil, err
+	}
+
+	return &detail, nil
+}
+
 func (r *passwordEntryRepository) GetPasswordEntryByUserID(userID string) ([]password.PasswordEntry, error) {
 	var passwordEntry []password.PasswordEntry
 	if err := r.db.Where("user_id = ?", userID).Find(&passwordEntry).Error; err != nil {
@@ -208,9 +672,11 @@ func (r *passwordEntryRepository) GetPasswordEntryByUserID(userID string) ([]pas
 	return passwordEntry, nil
 }
 
+// GetPasswordEntryByGroupID returns the entries that belong to the group, whether or not it is their primary group
 func (r *passwordEntryRepository) GetPasswordEntryByGroupID(groupID uint) ([]password.PasswordEntry, error) {
 	var passwordEntry []password.PasswordEntry
-	if err := r.db.Where("group_id = ?", groupID).Find(&passwordEntry).Error; err != nil {
+	if err := r.db.Where("entry_id IN (?)", r.db.Table(utils.TablePasswordEntryGroupName).Select("entry_id").Where("group_id = ?", groupID)).
+		Find(&passwordEntry).Error; err != nil {
 		return nil, err
 	}
 	return passwordEntry, nil
@@ -240,9 +706,16 @@ func (r *passwordEntryRepository) GetPasswordEntryByGroupIDAndUserIDAndEntryID(g
 	return &passwordEntry, nil
 }
 
-func (r *passwordEntryRepository) GetCountPasswordEntriesByUserID(userID uint) (int64, error) {
+func (r *passwordEntryRepository) GetCountPasswordEntriesByUserID(userID uint, entryType string, favorite bool) (int64, error) {
 	var count int64
-	if err := r.db.Model(&password.PasswordEntry{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+	query := r.db.Model(&password.PasswordEntry{}).Where("user_id = ?", userID)
+	if entryType != "" {
+		query = query.Where("entry_type = ?", entryType)
+	}
+	if favorite {
+		query = query.Where("favorite = TRUE")
+	}
+	if err := query.Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
@@ -257,3 +730,46 @@ func (r *passwordEntryRepository) GetCountPasswordEntriesByTags(id uint, tags []
 	}
 	return count, nil
 }
+
+// SetFavorite marks or unmarks a favorite. Favorites and pins are the owner's view of the vault, so they neither
+// touch updated_at nor emit events.
+func (r *passwordEntryRepository) SetFavorite(entryID, userID uint, favorite bool) error {
+	return r.setEntryMark(entryID, userID, "favorite", favorite)
+}
+
+func (r *passwordEntryRepository) SetPinned(entryID, userID uint, pinned bool) error {
+	var pinnedAt interface{}
+	if pinned {
+		// Pinning a pinned entry keeps its place
+		pinnedAt = gorm.Expr("COALESCE(pinned_at, CURRENT_TIMESTAMP)")
+	}
+	return r.setEntryMark(entryID, userID, "pinned_at", pinnedAt)
+}
+
+// UpdateEncryptedData swaps the data document only if it is still the one that was read, so concurrent passkey
+// assertions cannot reuse a sign count. It is authenticator state rather than an edit: no event is emitted.
+func (r *passwordEntryRepository) UpdateEncryptedData(entryID uint, current, updated string) error {
+	result := r.db.Table(utils.TablePasswordEntryName).
+		Where("entry_id = ? AND encrypted_data = ? AND deleted_at IS NULL", entryID, current).
+		UpdateColumn("encrypted_data", updated)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrEntryDataChanged
+	}
+	return nil
+}
+
+func (r *passwordEntryRepository) setEntryMark(entryID, userID uint, column string, value interface{}) error {
+	result := r.db.Table(utils.TablePasswordEntryName).
+		Where("entry_id = ? AND user_id = ? AND deleted_at IS NULL", entryID, userID).
+		UpdateColumn(column, value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+)
+
+type PasswordEntryURLRepository interface {
+	GetMatchCandidates(userID uint, keys []string) ([]password.PasswordEntryURL, error)
+}
+
+type passwordEntryURLRepository struct {
+	db gorm.DB
+}
+
+func NewPasswordEntryURLRepository(db gorm.DB) PasswordEntryURLRepository {
+	return &passwordEntryURLRepository{
+		db: db,
+	}
+}
+
+// GetMatchCandidates returns the user's URLs that can match a URL with one of the base domains or app URIs: those
+// with one of the keys, regex patterns and URLs not normalized yet
+func (r *passwordEntryURLRepository) GetMatchCandidates(userID uint, keys []string) ([]password.PasswordEntryURL, error) {
+	var urls []password.PasswordEntryURL
+	if err := r.db.Table(utils.TablePasswordEntryURLName+" peu").
+		Select("peu.*").
+		Joins("JOIN password_entries pe ON pe.entry_id = peu.entry_id").
+		Where("pe.user_id = ? AND pe.deleted_at IS NULL", userID).
+		Where("peu.base_domain IN ? OR peu.base_domain IS NULL", keys).
+		Order("peu.entry_id ASC, peu.position ASC").
+		Find(&urls).Error; err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// replaceEntryURLs stores the URLs of an entry, replacing the ones it had
+func replaceEntryURLs(tx *gorm.DB, entryID uint, urls []password.PasswordEntryURL) error {
+	if err := tx.Table(utils.TablePasswordEntryURLName).
+		Where("entry_id = ?", entryID).
+		Delete(&password.PasswordEntryURL{}).Error; err != nil {
+		return err
+	}
+	for i := range urls {
+		urls[i].URLID = 0
+		urls[i].EntryID = entryID
+		urls[i].Position = i
+		if err := tx.Table(utils.TablePasswordEntryURLName).Create(&urls[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
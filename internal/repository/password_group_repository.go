@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"gorm.io/gorm"
 	"password-management-service/internal/dto/out"
 	"password-management-service/internal/models/password"
@@ -8,13 +9,30 @@ import (
 	"time"
 )
 
+// descendantGroupIDs is a CTE yielding every group below the group bound to its parameter
+const descendantGroupIDs = `
+	WITH RECURSIVE descendants AS (
+		SELECT group_id FROM password_groups WHERE group_id = ?
+		UNION ALL
+		SELECT pg.group_id FROM password_groups pg JOIN descendants d ON pg.parent_group_id = d.group_id
+	)`
+
+var (
+	ErrGroupExists = errors.New("a group with this name already exists in the parent group")
+	ErrGroupCycle  = errors.New("a group cannot be moved into itself or one of its subgroups")
+)
+
 type PasswordGroupRepository interface {
 	AddPasswordGroup(group *password.PasswordGroup) error
 	UpdatePasswordGroup(group *password.PasswordGroup) error
 	GetPasswordGroupByID(groupID uint) (*password.PasswordGroup, error)
 	GetPasswordGroupByUserID(userID uint) ([]password.PasswordGroup, error)
 	GetPasswordGroupByUserIDAndGroupID(userID, groupID uint) (*password.PasswordGroup, error)
-	GetItemListPasswordGroup(groupID uint, userID uint) (interface{}, error)
+	GetPasswordGroupByPath(userID uint, path string) (*password.PasswordGroup, error)
+	GetAncestorGroupIDs(groupID uint) ([]uint, error)
+	GetCountChildGroups(groupID uint) (int64, error)
+	GetItemListPasswordGroup(group *password.PasswordGroup, recursive bool) (interface{}, error)
+	MovePasswordGroup(userID, groupID uint, parentGroupID *uint, name string, clientID string) (*password.PasswordGroup, error)
 	DeletePasswordGroupByID(groupID uint, clientID string) error
 }
 
@@ -28,11 +46,19 @@ func NewPasswordGroupRepository(db gorm.DB) PasswordGroupRepository {
 	}
 }
 
+// AddPasswordGroup serializes group changes of the user so two siblings with the same name cannot be created concurrently
 func (r *passwordGroupRepository) AddPasswordGroup(group *password.PasswordGroup) error {
-	if err := r.db.Table(utils.TablePasswordGroupName).Create(&group).Error; err != nil {
-		return err
-	}
-	return nil
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockUserGroups(tx, group.UserID); err != nil {
+			return err
+		}
+		if exists, err := groupPathExists(tx, group.UserID, group.Path, 0); err != nil {
+			return err
+		} else if exists {
+			return ErrGroupExists
+		}
+		return tx.Table(utils.TablePasswordGroupName).Create(&group).Error
+	})
 }
 
 func (r *passwordGroupRepository) UpdatePasswordGroup(group *password.PasswordGroup) error {
@@ -52,7 +78,7 @@ func (r *passwordGroupRepository) GetPasswordGroupByID(groupID uint) (*password.
 
 func (r *passwordGroupRepository) GetPasswordGroupByUserID(userID uint) ([]password.PasswordGroup, error) {
 	var passwordGroups []password.PasswordGroup
-	if err := r.db.Table(utils.TablePasswordGroupName).Where("user_id = ?", userID).Find(&passwordGroups).Error; err != nil {
+	if err := r.db.Table(utils.TablePasswordGroupName).Where("user_id = ?", userID).Order("path ASC").Find(&passwordGroups).Error; err != nil {
 		return passwordGroups, err
 	}
 	return passwordGroups, nil
@@ -66,9 +92,43 @@ func (r *passwordGroupRepository) GetPasswordGroupByUserIDAndGroupID(userID, gro
 	return &passwordGroup, nil
 }
 
-func (r *passwordGroupRepository) GetItemListPasswordGroup(groupID uint, userID uint) (interface{}, error) {
+// GetPasswordGroupByPath resolves a breadcrumb path; the oldest group wins for duplicates created before paths were unique
+func (r *passwordGroupRepository) GetPasswordGroupByPath(userID uint, path string) (*password.PasswordGroup, error) {
+	var passwordGroup password.PasswordGroup
+	if err := r.db.Table(utils.TablePasswordGroupName).
+		Where("user_id = ? AND path = ?", userID, path).
+		Order("group_id ASC").
+		First(&passwordGroup).Error; err != nil {
+		return nil, err
+	}
+	return &passwordGroup, nil
+}
+
+// GetAncestorGroupIDs returns the group and every group above it, nearest first
+func (r *passwordGroupRepository) GetAncestorGroupIDs(groupID uint) ([]uint, error) {
+	return ancestorGroupIDs(&r.db, groupID)
+}
+
+func (r *passwordGroupRepository) GetCountChildGroups(groupID uint) (int64, error) {
+	var count int64
+	if err := r.db.Table(utils.TablePasswordGroupName).
+		Where("parent_group_id = ? AND deleted_at IS NULL", groupID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetItemListPasswordGroup lists the entries of a group and, when recursive, of all its subgroups. An entry belongs
+// to every group it was added to; group_name stays the path of its primary group.
+func (r *passwordGroupRepository) GetItemListPasswordGroup(group *password.PasswordGroup, recursive bool) (interface{}, error) {
 	var passwordEntry []out.PasswordEntryListResponse
 
+	filter := `peg.group_id = ?`
+	if recursive {
+		filter = `peg.group_id IN (` + descendantGroupIDs + ` SELECT group_id FROM descendants)`
+	}
+
 	err := r.db.Raw(`
 		SELECT 
 			pe.entry_id, 
@@ -76,12 +136,17 @@ func (r *passwordGroupRepository) GetItemListPasswordGroup(groupID uint, userID
 			pe.username, 
 			pe.url, 
 			pe.tags, 
-			pg.name AS group_name
+			pe.entry_type,
+			pe.metadata,
+			pe.compromised,
+			pg.path AS group_name
 		FROM password_entries pe
 		LEFT JOIN password_groups pg ON pg.group_id = pe.group_id
-		WHERE pe.user_id = ? AND pe.group_id = ? AND pe.deleted_at IS NULL
-		ORDER BY pe.entry_id ASC
-	`, userID, groupID).Scan(&passwordEntry).Error
+		WHERE pe.user_id = ? AND pe.deleted_at IS NULL AND pe.entry_id IN (
+			SELECT peg.entry_id FROM password_entry_groups peg WHERE `+filter+`
+		)
+		ORDER BY pg.path ASC, pe.entry_id ASC
+	`, group.UserID, group.GroupID).Scan(&passwordEntry).Error
 
 	if err != nil {
 		return nil, err
@@ -90,6 +155,73 @@ func (r *passwordGroupRepository) GetItemListPasswordGroup(groupID uint, userID
 	return passwordEntry, nil
 }
 
+// MovePasswordGroup renames and/or reparents a group and rewrites the paths of its whole subtree. The user's
+// groups are locked for the duration, so the cycle and name checks cannot race with another move.
+func (r *passwordGroupRepository) MovePasswordGroup(userID, groupID uint, parentGroupID *uint, name string, clientID string) (*password.PasswordGroup, error) {
+	var group password.PasswordGroup
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockUserGroups(tx, userID); err != nil {
+			return err
+		}
+		if err := tx.Table(utils.TablePasswordGroupName).Where("group_id = ? AND user_id = ?", groupID, userID).First(&group).Error; err != nil {
+			return err
+		}
+
+		parentPath := ""
+		if parentGroupID != nil {
+			var parent password.PasswordGroup
+			if err := tx.Table(utils.TablePasswordGroupName).Where("group_id = ? AND user_id = ?", *parentGroupID, userID).First(&parent).Error; err != nil {
+				return err
+			}
+			ancestors, err := ancestorGroupIDs(tx, parent.GroupID)
+			if err != nil {
+				return err
+			}
+			if err := checkGroupParent(group.GroupID, ancestors); err != nil {
+				return err
+			}
+			parentPath = parent.Path
+		}
+
+		oldPath := group.Path
+		newPath := parentPath + "/" + name
+		if newPath != oldPath {
+			if exists, err := groupPathExists(tx, userID, newPath, group.GroupID); err != nil {
+				return err
+			} else if exists {
+				return ErrGroupExists
+			}
+		}
+
+		// The subtree includes the group itself, whose path becomes exactly newPath
+		if err := tx.Exec(`
+			UPDATE password_groups
+			SET path = ? || substr(path, length(?) + 1)
+			WHERE group_id IN (`+descendantGroupIDs+` SELECT group_id FROM descendants)
+		`, newPath, oldPath, group.GroupID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table(utils.TablePasswordGroupName).Where("group_id = ?", group.GroupID).
+			UpdateColumns(map[string]interface{}{
+				"parent_group_id": parentGroupID,
+				"name":            name,
+				"updated_by":      clientID,
+				"updated_at":      time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		group.ParentGroupID = parentGroupID
+		group.Name = name
+		group.Path = newPath
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
 func (r *passwordGroupRepository) DeletePasswordGroupByID(groupID uint, clientID string) error {
 	if err := r.db.Table(utils.TablePasswordGroupName).Where("group_id = ?", groupID).
 		Updates(map[string]interface{}{
@@ -100,3 +232,49 @@ func (r *passwordGroupRepository) DeletePasswordGroupByID(groupID uint, clientID
 	}
 	return nil
 }
+
+func lockUserGroups(tx *gorm.DB, userID uint) error {
+	var groupIDs []uint
+	return tx.Raw(`SELECT group_id FROM password_groups WHERE user_id = ? FOR UPDATE`, userID).Scan(&groupIDs).Error
+}
+
+func groupPathExists(tx *gorm.DB, userID uint, path string, excludeGroupID uint) (bool, error) {
+	var count int64
+	if err := tx.Table(utils.TablePasswordGroupName).
+		Where("user_id = ? AND path = ? AND group_id <> ? AND deleted_at IS NULL", userID, path, excludeGroupID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// checkGroupParent refuses a parent whose ancestors, itself included, contain the group being moved
+func checkGroupParent(groupID uint, parentAncestorIDs []uint) error {
+	for _, ancestorID := range parentAncestorIDs {
+		if ancestorID == groupID {
+			return ErrGroupCycle
+		}
+	}
+	return nil
+}
+
+// ancestorGroupIDs returns the group and every group above it, nearest first
+func ancestorGroupIDs(tx *gorm.DB, groupID uint) ([]uint, error) {
+	var groupIDs []uint
+	err := tx.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT group_id, parent_group_id, 0 AS depth
+			FROM password_groups
+			WHERE group_id = ?
+			UNION ALL
+			SELECT pg.group_id, pg.parent_group_id, a.depth + 1
+			FROM password_groups pg
+			JOIN ancestors a ON pg.group_id = a.parent_group_id
+		)
+		SELECT group_id FROM ancestors ORDER BY depth ASC
+	`, groupID).Scan(&groupIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return groupIDs, nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+)
+
+// The groups form /work (1) > /work/aws (2) > /work/aws/prod (3), next to /home (4)
+func TestCheckGroupParent(t *testing.T) {
+	tests := []struct {
+		name              string
+		groupID           uint
+		parentAncestorIDs []uint
+		err               error
+	}{
+		{name: "into itself", groupID: 2, parentAncestorIDs: []uint{2, 1}, err: ErrGroupCycle},
+		{name: "into its child", groupID: 2, parentAncestorIDs: []uint{3, 2, 1}, err: ErrGroupCycle},
+		{name: "root into a descendant", groupID: 1, parentAncestorIDs: []uint{3, 2, 1}, err: ErrGroupCycle},
+		{name: "into a sibling tree", groupID: 2, parentAncestorIDs: []uint{4}},
+		{name: "into its own parent", groupID: 3, parentAncestorIDs: []uint{2, 1}},
+		{name: "child above its parent", groupID: 3, parentAncestorIDs: []uint{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkGroupParent(tt.groupID, tt.parentAncestorIDs); !errors.Is(err, tt.err) {
+				t.Errorf("checkGroupParent err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
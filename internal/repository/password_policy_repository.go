@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+	"time"
+)
+
+type PasswordPolicyRepository interface {
+	AddPolicy(policy *password.PasswordPolicy) error
+	UpdatePolicy(policy *password.PasswordPolicy) error
+	GetPolicyByID(policyID uint) (*password.PasswordPolicy, error)
+	GetPolicies() ([]password.PasswordPolicy, error)
+	GetEffectivePolicies(userID uint, groupIDs []uint) ([]password.PasswordPolicy, error)
+	GetPoliciesByUserID(userID uint) ([]password.PasswordPolicy, error)
+	DeletePolicy(policyID uint, clientID string) error
+}
+
+type passwordPolicyRepository struct {
+	db gorm.DB
+}
+
+func NewPasswordPolicyRepository(db gorm.DB) PasswordPolicyRepository {
+	return &passwordPolicyRepository{
+		db: db,
+	}
+}
+
+func (r *passwordPolicyRepository) AddPolicy(policy *password.PasswordPolicy) error {
+	return r.db.Table(utils.TablePasswordPolicyName).Create(policy).Error
+}
+
+// UpdatePolicy writes every rule column so rules can be switched off again
+func (r *passwordPolicyRepository) UpdatePolicy(policy *password.PasswordPolicy) error {
+	return r.db.Table(utils.TablePasswordPolicyName).
+		Where("policy_id = ? AND deleted_at IS NULL", policy.PolicyID).
+		Updates(map[string]interface{}{
+			"name":               policy.Name,
+			"user_id":            policy.UserID,
+			"group_id":           policy.GroupID,
+			"min_length":         policy.MinLength,
+			"require_uppercase":  policy.RequireUppercase,
+			"require_lowercase":  policy.RequireLowercase,
+			"require_digits":     policy.RequireDigits,
+			"require_symbols":    policy.RequireSymbols,
+			"max_age_days":       policy.MaxAgeDays,
+			"forbid_compromised": policy.ForbidCompromised,
+			"updated_by":         policy.UpdatedBy,
+			"updated_at":         time.Now(),
+		}).Error
+}
+
+func (r *passwordPolicyRepository) GetPolicyByID(policyID uint) (*password.PasswordPolicy, error) {
+	var policy password.PasswordPolicy
+	if err := r.db.Table(utils.TablePasswordPolicyName).
+		Where("policy_id = ? AND deleted_at IS NULL", policyID).
+		First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *passwordPolicyRepository) GetPolicies() ([]password.PasswordPolicy, error) {
+	var policies []password.PasswordPolicy
+	if err := r.db.Table(utils.TablePasswordPolicyName).
+		Where("deleted_at IS NULL").
+		Order("policy_id ASC").
+		Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetEffectivePolicies returns the global policies, the user's policies and the policies of the entry's groups and
+// every group above them
+func (r *passwordPolicyRepository) GetEffectivePolicies(userID uint, groupIDs []uint) ([]password.PasswordPolicy, error) {
+	var policies []password.PasswordPolicy
+	query := r.db.Table(utils.TablePasswordPolicyName).Where("deleted_at IS NULL")
+	if len(groupIDs) > 0 {
+		var scopeIDs []uint
+		for _, groupID := range groupIDs {
+			ancestors, err := ancestorGroupIDs(&r.db, groupID)
+			if err != nil {
+				return nil, err
+			}
+			scopeIDs = append(scopeIDs, ancestors...)
+		}
+		query = query.Where("(user_id IS NULL AND group_id IS NULL) OR user_id = ? OR group_id IN ?", userID, scopeIDs)
+	} else {
+		query = query.Where("(user_id IS NULL AND group_id IS NULL) OR user_id = ?", userID)
+	}
+	if err := query.Order("policy_id ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetPoliciesByUserID returns every policy that can apply to the user's entries, including those of the user's groups
+func (r *passwordPolicyRepository) GetPoliciesByUserID(userID uint) ([]password.PasswordPolicy, error) {
+	var policies []password.PasswordPolicy
+	err := r.db.Raw(`
+		SELECT pp.*
+		FROM password_policies pp
+		LEFT JOIN password_groups pg ON pg.group_id = pp.group_id
+		WHERE pp.deleted_at IS NULL AND (
+			(pp.user_id IS NULL AND pp.group_id IS NULL)
+			OR pp.user_id = ?
+			OR pg.user_id = ?
+		)
+		ORDER BY pp.policy_id ASC
+	`, userID, userID).Scan(&policies).Error
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (r *passwordPolicyRepository) DeletePolicy(policyID uint, clientID string) error {
+	result := r.db.Table(utils.TablePasswordPolicyName).
+		Where("policy_id = ? AND deleted_at IS NULL", policyID).
+		Updates(map[string]interface{}{
+			"deleted_by": clientID,
+			"deleted_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
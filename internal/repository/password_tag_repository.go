@@ -3,18 +3,27 @@ package repository
 import (
 	"errors"
 	"gorm.io/gorm"
+	"password-management-service/internal/dto/out"
 	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+	"time"
 )
 
+var ErrTagExists = errors.New("a tag with this name already exists, merge the tags instead")
+
 type PasswordTagRepository interface {
 	AddPasswordTag(tag *password.PasswordTag) error
 	UpdatePasswordTag(tag *password.PasswordTag) error
 	GetPasswordTagByIDAndUserID(id uint, userID uint) (*password.PasswordTag, error)
-	GetListPasswordTag(userID uint, index int, size int) (*[]password.PasswordTag, error)
+	GetListPasswordTag(userID uint, index int, size int) ([]out.PasswordTagResponse, error)
 	GetPasswordTagsByEntryID(entryID uint) ([]*password.PasswordTag, error)
 	DeletePasswordTag(tag *password.PasswordTag) error
-	FindOrCreate(name string, createdBy string) (*password.PasswordTag, error)
+	GetEntryIDsByTagIDs(tagIDs []uint) ([]uint, error)
+	MergePasswordTags(source, target *password.PasswordTag, clientID string, serviceAccountKeys ServiceAccountKeys) (int64, error)
+	FindOrCreate(userID uint, name string, createdBy string) (*password.PasswordTag, error)
 	LinkTagToEntry(entryID uint, tagID uint) error
+	AddTagsToEntries(entries []password.PasswordEntry, userID uint, names []string, clientID string, serviceAccountKeys ServiceAccountKeys) error
+	RemoveTagsFromEntries(entries []password.PasswordEntry, userID uint, names []string, clientID string, serviceAccountKeys ServiceAccountKeys) error
 	GetCountPasswordTag(userID uint) (int64, error)
 }
 
@@ -42,7 +51,12 @@ func (r *passwordTagRepository) AddPasswordTag(tag *password.PasswordTag) error
 		return errors.New("updated by cannot be empty")
 	}
 
-	return r.db.Create(tag).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkTagNameFree(tx, tag); err != nil {
+			return err
+		}
+		return tx.Create(tag).Error
+	})
 }
 
 func (r *passwordTagRepository) UpdatePasswordTag(tag *password.PasswordTag) error {
@@ -59,7 +73,27 @@ func (r *passwordTagRepository) UpdatePasswordTag(tag *password.PasswordTag) err
 		return errors.New("updated by cannot be empty")
 	}
 
-	return r.db.Save(tag).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkTagNameFree(tx, tag); err != nil {
+			return err
+		}
+		return tx.Save(tag).Error
+	})
+}
+
+// checkTagNameFree reports ErrTagExists when another live tag of the user has the name. The unique index still
+// catches a concurrent insert; this gives the common case a clear error.
+func checkTagNameFree(tx *gorm.DB, tag *password.PasswordTag) error {
+	var count int64
+	if err := tx.Model(&password.PasswordTag{}).
+		Where("user_id = ? AND name = ? AND tag_id <> ?", tag.UserID, tag.Name, tag.TagID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTagExists
+	}
+	return nil
 }
 
 func (r *passwordTagRepository) GetPasswordTagByIDAndUserID(tagID uint, userID uint) (*password.PasswordTag, error) {
@@ -71,26 +105,38 @@ func (r *passwordTagRepository) GetPasswordTagByIDAndUserID(tagID uint, userID u
 	return &tag, nil
 }
 
-func (r *passwordTagRepository) GetListPasswordTag(userID uint, index int, size int) (*[]password.PasswordTag, error) {
-	var tags []password.PasswordTag
-	err := r.db.
-		Where("deleted_at IS NULL AND user_id = ?", userID).
-		Order("tag_id ASC").
-		Limit(size).
-		Offset((index - 1) * size).
-		Find(&tags).Error
+// GetListPasswordTag lists the user's tags with the number of live entries carrying each tag
+func (r *passwordTagRepository) GetListPasswordTag(userID uint, index int, size int) ([]out.PasswordTagResponse, error) {
+	var tags []out.PasswordTagResponse
+	err := r.db.Raw(`
+		SELECT
+			pt.tag_id,
+			pt.name,
+			pt.color,
+			pt.icon,
+			COUNT(pe.entry_id) AS entry_count,
+			pt.created_at,
+			pt.updated_at
+		FROM password_tags pt
+		LEFT JOIN password_entry_tags pet ON pet.tag_id = pt.tag_id
+		LEFT JOIN password_entries pe ON pe.entry_id = pet.entry_id AND pe.deleted_at IS NULL
+		WHERE pt.user_id = ? AND pt.deleted_at IS NULL
+		GROUP BY pt.tag_id
+		ORDER BY pt.tag_id ASC
+		LIMIT ? OFFSET ?
+	`, userID, size, (index-1)*size).Scan(&tags).Error
 	if err != nil {
 		return nil, err
 	}
-	return &tags, nil
+	return tags, nil
 }
 
 func (r *passwordTagRepository) GetPasswordTagsByEntryID(entryID uint) ([]*password.PasswordTag, error) {
 	var tags []*password.PasswordTag
 	err := r.db.Table("password_entry_tags").
 		Select("password_tags.*").
-		Joins("JOIN password_tags ON password_entry_tags.tag_id = password_tags.id").
-		Where("password_entry_tags.entry_id = ?", entryID).
+		Joins("JOIN password_tags ON password_entry_tags.tag_id = password_tags.tag_id").
+		Where("password_entry_tags.entry_id = ? AND password_tags.deleted_at IS NULL", entryID).
 		Find(&tags).Error
 	if err != nil {
 		return nil, err
@@ -120,11 +166,89 @@ func (r *passwordTagRepository) DeletePasswordTag(tag *password.PasswordTag) err
 		Delete(tag).Error
 }
 
-func (r *passwordTagRepository) FindOrCreate(name string, createdBy string) (*password.PasswordTag, error) {
+// MergePasswordTags re-points the entries and service account scopes of source to target and deletes source. It
+// returns the number of entries that did not carry target yet. The entries of both tags may change service accounts,
+// so their keys are synced from serviceAccountKeys.
+func (r *passwordTagRepository) MergePasswordTags(source, target *password.PasswordTag, clientID string, serviceAccountKeys ServiceAccountKeys) (int64, error) {
+	var moved int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var affected []uint
+		if err := tx.Table(utils.TablePasswordEntryTagName).
+			Where("tag_id IN ?", []uint{source.TagID, target.TagID}).
+			Distinct().
+			Pluck("entry_id", &affected).Error; err != nil {
+			return err
+		}
+
+		var entries []password.PasswordEntry
+		if err := tx.Raw(`
+			SELECT pe.entry_id, pe.user_id
+			FROM password_entry_tags pet
+			JOIN password_entries pe ON pe.entry_id = pet.entry_id
+			WHERE pet.tag_id = ? AND NOT EXISTS (
+				SELECT 1 FROM password_entry_tags existing WHERE existing.entry_id = pet.entry_id AND existing.tag_id = ?
+			)
+		`, source.TagID, target.TagID).Scan(&entries).Error; err != nil {
+			return err
+		}
+		moved = int64(len(entries))
+
+		if err := tx.Exec(`
+			INSERT INTO password_entry_tags (entry_id, tag_id)
+			SELECT entry_id, ? FROM password_entry_tags WHERE tag_id = ?
+			ON CONFLICT DO NOTHING
+		`, target.TagID, source.TagID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM password_entry_tags WHERE tag_id = ?`, source.TagID).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(utils.TableServiceAccountScopeName).
+			Where("tag_id = ?", source.TagID).
+			UpdateColumn("tag_id", target.TagID).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(utils.TablePasswordTagName).
+			Where("tag_id = ?", source.TagID).
+			UpdateColumns(map[string]interface{}{
+				"deleted_by": clientID,
+				"deleted_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := addOutboxEvent(tx, utils.EventEntryUpdated, entry.UserID, entry.EntryID, &clientID, map[string]interface{}{
+				"action":        "merge_tags",
+				"tag_id":        target.TagID,
+				"merged_tag_id": source.TagID,
+			}); err != nil {
+				return err
+			}
+		}
+		return syncServiceAccountKeys(tx, affected, serviceAccountKeys, false, &clientID)
+	})
+	return moved, err
+}
+
+// GetEntryIDsByTagIDs lists the entries carrying any of the tags
+func (r *passwordTagRepository) GetEntryIDsByTagIDs(tagIDs []uint) ([]uint, error) {
+	var entryIDs []uint
+	if len(tagIDs) == 0 {
+		return entryIDs, nil
+	}
+	err := r.db.Table(utils.TablePasswordEntryTagName).
+		Where("tag_id IN ?", tagIDs).
+		Distinct().
+		Pluck("entry_id", &entryIDs).Error
+	return entryIDs, err
+}
+
+func (r *passwordTagRepository) FindOrCreate(userID uint, name string, createdBy string) (*password.PasswordTag, error) {
 	var tag password.PasswordTag
-	err := r.db.Where("name = ?", name).First(&tag).Error
+	err := r.db.Where("user_id = ? AND name = ?", userID, name).First(&tag).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		tag = password.PasswordTag{Name: name, CreatedBy: &createdBy, UpdatedBy: &createdBy}
+		tag = password.PasswordTag{UserID: userID, Name: name, CreatedBy: &createdBy, UpdatedBy: &createdBy}
 		if err := r.db.Create(&tag).Error; err != nil {
 			return nil, err
 		}
@@ -137,6 +261,62 @@ func (r *passwordTagRepository) LinkTagToEntry(entryID uint, tagID uint) error {
 	return r.db.Exec(`INSERT INTO password_entry_tags (entry_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING`, entryID, tagID).Error
 }
 
+// AddTagsToEntries links the user's tags to every entry, creating the tags that do not exist yet
+func (r *passwordTagRepository) AddTagsToEntries(entries []password.PasswordEntry, userID uint, names []string, clientID string, serviceAccountKeys ServiceAccountKeys) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range names {
+			var tag password.PasswordTag
+			err := tx.Where("name = ? AND user_id = ?", name, userID).First(&tag).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				tag = password.PasswordTag{UserID: userID, Name: name, CreatedBy: &clientID, UpdatedBy: &clientID}
+				err = tx.Create(&tag).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range entries {
+				if err := tx.Exec(`INSERT INTO password_entry_tags (entry_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING`, entry.EntryID, tag.TagID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := addTagEvents(tx, entries, utils.BulkActionTag, names, clientID); err != nil {
+			return err
+		}
+		return syncServiceAccountKeys(tx, entryIDsOf(entries), serviceAccountKeys, false, &clientID)
+	})
+}
+
+// RemoveTagsFromEntries unlinks the user's tags from every entry; the tags themselves are kept
+func (r *passwordTagRepository) RemoveTagsFromEntries(entries []password.PasswordEntry, userID uint, names []string, clientID string, serviceAccountKeys ServiceAccountKeys) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		entryIDs := entryIDsOf(entries)
+		if err := tx.Exec(`
+			DELETE FROM password_entry_tags
+			WHERE entry_id IN ? AND tag_id IN (SELECT tag_id FROM password_tags WHERE user_id = ? AND name IN ?)
+		`, entryIDs, userID, names).Error; err != nil {
+			return err
+		}
+		if err := addTagEvents(tx, entries, utils.BulkActionUntag, names, clientID); err != nil {
+			return err
+		}
+		return syncServiceAccountKeys(tx, entryIDs, serviceAccountKeys, false, &clientID)
+	})
+}
+
+func addTagEvents(tx *gorm.DB, entries []password.PasswordEntry, action string, names []string, clientID string) error {
+	for _, entry := range entries {
+		if err := addOutboxEvent(tx, utils.EventEntryUpdated, entry.UserID, entry.EntryID, &clientID, map[string]interface{}{
+			"action": action,
+			"tags":   names,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *passwordTagRepository) GetCountPasswordTag(userID uint) (int64, error) {
 	var count int64
 	err := r.db.Model(&password.PasswordTag{}).
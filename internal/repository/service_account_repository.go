@@ -0,0 +1,543 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+	"time"
+)
+
+// ServiceAccountKeys are entry keys wrapped for the service accounts that may gain access to the entries
+type ServiceAccountKeys struct {
+	// Wrapped holds the wrapped keys by entry ID and then service account ID
+	Wrapped map[uint]map[uint]string
+	// EntryKeys are the owner-wrapped keys Wrapped was made from, by entry ID
+	EntryKeys map[uint]string
+}
+
+var (
+	// ErrServiceAccountKeyRequired is returned when a change brings an entry into the scope of a service account and
+	// its key could not be wrapped for the account, because the vault is locked or the account is new
+	ErrServiceAccountKeyRequired = errors.New("an entry is shared with a service account, unlock the vault and retry")
+	// ErrScopeChanged is returned when the entries in a service account scope or their keys changed while the keys
+	// were wrapped
+	ErrScopeChanged = errors.New("entries in the service account scope changed, retry")
+)
+
+type ServiceAccountRepository interface {
+	AddServiceAccount(serviceAccount *password.ServiceAccount, scopes []password.ServiceAccountScope, keys []password.ServiceAccountEntryKey, entryKeys map[uint]string, token *password.ServiceAccountToken) error
+	GetServiceAccountByIDAndUserID(serviceAccountID, userID uint) (*password.ServiceAccount, error)
+	GetServiceAccountByID(serviceAccountID uint) (*password.ServiceAccount, error)
+	GetServiceAccountsByUserID(userID uint) ([]password.ServiceAccount, error)
+	GetServiceAccountsByScope(userID uint, groupIDs []uint, tagNames []string) ([]password.ServiceAccount, error)
+	GetScopesByServiceAccountID(serviceAccountID uint) ([]password.ServiceAccountScope, error)
+	DeleteServiceAccount(serviceAccount *password.ServiceAccount, clientID string) error
+	AddToken(token *password.ServiceAccountToken) error
+	GetTokensByServiceAccountID(serviceAccountID uint) ([]password.ServiceAccountToken, error)
+	GetTokenByHash(tokenHash string) (*password.ServiceAccountToken, error)
+	DeleteToken(serviceAccountID, tokenID uint) error
+	TouchToken(tokenID uint) error
+	GetEntriesInScope(serviceAccountID, userID uint) ([]password.PasswordEntry, error)
+	GetEntriesInGroupsAndTags(userID uint, groupIDs, tagIDs []uint) ([]password.PasswordEntry, error)
+	ReplaceEntryKeys(serviceAccountID uint, keys []password.ServiceAccountEntryKey, entryKeys map[uint]string) error
+	GetEntryKey(serviceAccountID, entryID uint) (*password.ServiceAccountEntryKey, error)
+	GetCountEntryKeys(serviceAccountID uint) (int64, error)
+	GetAccessibleEntries(serviceAccountID uint) ([]out.PasswordEntryListResponse, error)
+	GetAccessibleEntriesByGroupID(serviceAccountID, groupID uint) ([]password.PasswordEntry, error)
+}
+
+type serviceAccountRepository struct {
+	db gorm.DB
+}
+
+func NewServiceAccountRepository(db gorm.DB) ServiceAccountRepository {
+	return &serviceAccountRepository{
+		db: db,
+	}
+}
+
+// AddServiceAccount stores the account with its scopes, the entry keys of its initial sync and its first token in one
+// transaction, so a failed sync leaves no account behind. entryKeys are the owner-wrapped keys the entry keys were
+// rewrapped from.
+func (r *serviceAccountRepository) AddServiceAccount(serviceAccount *password.ServiceAccount, scopes []password.ServiceAccountScope, keys []password.ServiceAccountEntryKey, entryKeys map[uint]string, token *password.ServiceAccountToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(utils.TableServiceAccountName).Create(serviceAccount).Error; err != nil {
+			return err
+		}
+
+		for i := range scopes {
+			scopes[i].ServiceAccountID = serviceAccount.ServiceAccountID
+			if err := tx.Table(utils.TableServiceAccountScopeName).Create(&scopes[i]).Error; err != nil {
+				return err
+			}
+		}
+		if err := storeEntryKeys(tx, serviceAccount, keys, entryKeys); err != nil {
+			return err
+		}
+
+		token.ServiceAccountID = serviceAccount.ServiceAccountID
+		return tx.Table(utils.TableServiceAccountTokenName).Create(token).Error
+	})
+}
+
+func (r *serviceAccountRepository) GetServiceAccountByIDAndUserID(serviceAccountID, userID uint) (*password.ServiceAccount, error) {
+	var serviceAccount password.ServiceAccount
+	if err := r.db.Table(utils.TableServiceAccountName).
+		Where("service_account_id = ? AND user_id = ? AND deleted_at IS NULL", serviceAccountID, userID).
+		First(&serviceAccount).Error; err != nil {
+		return nil, err
+	}
+	return &serviceAccount, nil
+}
+
+func (r *serviceAccountRepository) GetServiceAccountByID(serviceAccountID uint) (*password.ServiceAccount, error) {
+	var serviceAccount password.ServiceAccount
+	if err := r.db.Table(utils.TableServiceAccountName).
+		Where("service_account_id = ? AND deleted_at IS NULL", serviceAccountID).
+		First(&serviceAccount).Error; err != nil {
+		return nil, err
+	}
+	return &serviceAccount, nil
+}
+
+func (r *serviceAccountRepository) GetServiceAccountsByUserID(userID uint) ([]password.ServiceAccount, error) {
+	var serviceAccounts []password.ServiceAccount
+	if err := r.db.Table(utils.TableServiceAccountName).
+		Where("user_id = ? AND deleted_at IS NULL", userID).
+		Order("service_account_id ASC").
+		Find(&serviceAccounts).Error; err != nil {
+		return nil, err
+	}
+	return serviceAccounts, nil
+}
+
+// GetServiceAccountsByScope returns the user's service accounts whose scope includes any of the groups or tags
+func (r *serviceAccountRepository) GetServiceAccountsByScope(userID uint, groupIDs []uint, tagNames []string) ([]password.ServiceAccount, error) {
+	var serviceAccounts []password.ServiceAccount
+	if len(groupIDs) == 0 && len(tagNames) == 0 {
+		return serviceAccounts, nil
+	}
+	err := r.db.Raw(`
+		SELECT DISTINCT sa.*
+		FROM service_accounts sa
+		JOIN service_account_scopes sas ON sas.service_account_id = sa.service_account_id
+		LEFT JOIN password_tags pt ON pt.tag_id = sas.tag_id
+		WHERE sa.user_id = ? AND sa.deleted_at IS NULL AND (sas.group_id IN ? OR (pt.user_id = ? AND pt.name IN ?))
+		ORDER BY sa.service_account_id ASC
+	`, userID, groupIDs, userID, tagNames).Scan(&serviceAccounts).Error
+	if err != nil {
+		return nil, err
+	}
+	return serviceAccounts, nil
+}
+
+func (r *serviceAccountRepository) GetScopesByServiceAccountID(serviceAccountID uint) ([]password.ServiceAccountScope, error) {
+	var scopes []password.ServiceAccountScope
+	if err := r.db.Table(utils.TableServiceAccountScopeName).
+		Where("service_account_id = ?", serviceAccountID).
+		Find(&scopes).Error; err != nil {
+		return nil, err
+	}
+	return scopes, nil
+}
+
+// DeleteServiceAccount revokes all access by dropping the wrapped entry keys and tokens before soft-deleting the account
+func (r *serviceAccountRepository) DeleteServiceAccount(serviceAccount *password.ServiceAccount, clientID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(utils.TableServiceAccountEntryKeyName).
+			Where("service_account_id = ?", serviceAccount.ServiceAccountID).
+			Delete(&password.ServiceAccountEntryKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(utils.TableServiceAccountTokenName).
+			Where("service_account_id = ?", serviceAccount.ServiceAccountID).
+			Delete(&password.ServiceAccountToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(utils.TableServiceAccountName).
+			Where("service_account_id = ?", serviceAccount.ServiceAccountID).
+			Updates(map[string]interface{}{
+				"deleted_by": clientID,
+				"deleted_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *serviceAccountRepository) AddToken(token *password.ServiceAccountToken) error {
+	return r.db.Table(utils.TableServiceAccountTokenName).Create(token).Error
+}
+
+func (r *serviceAccountRepository) GetTokensByServiceAccountID(serviceAccountID uint) ([]password.ServiceAccountToken, error) {
+	var tokens []password.ServiceAccountToken
+	if err := r.db.Table(utils.TableServiceAccountTokenName).
+		Where("service_account_id = ?", serviceAccountID).
+		Order("token_id ASC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *serviceAccountRepository) GetTokenByHash(tokenHash string) (*password.ServiceAccountToken, error) {
+	var token password.ServiceAccountToken
+	if err := r.db.Table(utils.TableServiceAccountTokenName+" sat").
+		Select("sat.*").
+		Joins("JOIN service_accounts sa ON sa.service_account_id = sat.service_account_id").
+		Where("sat.token_hash = ? AND sa.deleted_at IS NULL", tokenHash).
+		First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *serviceAccountRepository) DeleteToken(serviceAccountID, tokenID uint) error {
+	result := r.db.Table(utils.TableServiceAccountTokenName).
+		Where("service_account_id = ? AND token_id = ?", serviceAccountID, tokenID).
+		Delete(&password.ServiceAccountToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) TouchToken(tokenID uint) error {
+	return r.db.Table(utils.TableServiceAccountTokenName).
+		Where("token_id = ?", tokenID).
+		UpdateColumn("last_used_at", time.Now()).Error
+}
+
+// GetEntriesInScope returns the owner's entries that belong to any group or tag in the service account scope
+func (r *serviceAccountRepository) GetEntriesInScope(serviceAccountID, userID uint) ([]password.PasswordEntry, error) {
+	return entriesInScope(&r.db, serviceAccountID, userID)
+}
+
+// GetEntriesInGroupsAndTags returns the owner's entries that belong to any of the groups or tags, for a scope that
+// is not stored yet
+func (r *serviceAccountRepository) GetEntriesInGroupsAndTags(userID uint, groupIDs, tagIDs []uint) ([]password.PasswordEntry, error) {
+	var entries []password.PasswordEntry
+	err := r.db.Raw(`
+		SELECT DISTINCT pe.*
+		FROM password_entries pe
+		WHERE pe.user_id = ? AND pe.deleted_at IS NULL AND (
+			pe.entry_id IN (SELECT entry_id FROM password_entry_groups WHERE group_id IN ?)
+			OR pe.entry_id IN (SELECT entry_id FROM password_entry_tags WHERE tag_id IN ?)
+		)
+		ORDER BY pe.entry_id ASC
+	`, userID, groupIDs, tagIDs).Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func entriesInScope(db *gorm.DB, serviceAccountID, userID uint) ([]password.PasswordEntry, error) {
+	var entries []password.PasswordEntry
+	err := db.Raw(`
+		SELECT DISTINCT pe.*
+		FROM password_entries pe
+		WHERE pe.user_id = ? AND pe.deleted_at IS NULL AND (
+			pe.entry_id IN (
+				SELECT peg.entry_id FROM password_entry_groups peg
+				JOIN service_account_scopes sas ON sas.group_id = peg.group_id
+				WHERE sas.service_account_id = ?
+			)
+			OR pe.entry_id IN (
+				SELECT pet.entry_id FROM password_entry_tags pet
+				JOIN service_account_scopes sas ON sas.tag_id = pet.tag_id
+				WHERE sas.service_account_id = ?
+			)
+		)
+		ORDER BY pe.entry_id ASC
+	`, userID, serviceAccountID, serviceAccountID).Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ReplaceEntryKeys swaps the full set of wrapped keys so entries leaving the scope lose access in the same transaction
+// and emits entry.shared for entries the account did not have before
+func (r *serviceAccountRepository) ReplaceEntryKeys(serviceAccountID uint, keys []password.ServiceAccountEntryKey, entryKeys map[uint]string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var serviceAccount password.ServiceAccount
+		if err := tx.Table(utils.TableServiceAccountName).
+			Where("service_account_id = ?", serviceAccountID).
+			First(&serviceAccount).Error; err != nil {
+			return err
+		}
+		return storeEntryKeys(tx, &serviceAccount, keys, entryKeys)
+	})
+}
+
+// storeEntryKeys replaces the entry keys of a service account. The owner keys the keys were rewrapped from are
+// locked and compared, and the scope is evaluated again, so an update rotating a key or an entry joining the scope
+// while the keys were wrapped makes the sync fail instead of leaving a stale or missing key.
+func storeEntryKeys(tx *gorm.DB, serviceAccount *password.ServiceAccount, keys []password.ServiceAccountEntryKey, entryKeys map[uint]string) error {
+	entryIDs := make([]uint, 0, len(keys))
+	for _, key := range keys {
+		entryIDs = append(entryIDs, key.EntryID)
+	}
+	if len(entryIDs) > 0 {
+		var current []password.PasswordEntryKey
+		if err := tx.Raw(`
+			SELECT entry_id, encrypted_symmetric_key FROM password_entry_keys WHERE entry_id IN ? FOR SHARE
+		`, entryIDs).Scan(&current).Error; err != nil {
+			return err
+		}
+		if len(current) != len(entryIDs) {
+			return ErrScopeChanged
+		}
+		for _, key := range current {
+			if entryKeys[key.EntryID] != key.EncryptedSymmetricKey {
+				return ErrScopeChanged
+			}
+		}
+	}
+
+	inScope, err := entriesInScope(tx, serviceAccount.ServiceAccountID, serviceAccount.UserID)
+	if err != nil {
+		return err
+	}
+	if len(inScope) != len(keys) {
+		return ErrScopeChanged
+	}
+	wrapped := make(map[uint]bool, len(keys))
+	for _, key := range keys {
+		wrapped[key.EntryID] = true
+	}
+	for _, entry := range inScope {
+		if !wrapped[entry.EntryID] {
+			return ErrScopeChanged
+		}
+	}
+
+	var existingEntryIDs []uint
+	if err := tx.Table(utils.TableServiceAccountEntryKeyName).
+		Where("service_account_id = ?", serviceAccount.ServiceAccountID).
+		Pluck("entry_id", &existingEntryIDs).Error; err != nil {
+		return err
+	}
+	existing := make(map[uint]bool, len(existingEntryIDs))
+	for _, entryID := range existingEntryIDs {
+		existing[entryID] = true
+	}
+
+	if err := tx.Table(utils.TableServiceAccountEntryKeyName).
+		Where("service_account_id = ?", serviceAccount.ServiceAccountID).
+		Delete(&password.ServiceAccountEntryKey{}).Error; err != nil {
+		return err
+	}
+	for i := range keys {
+		keys[i].ServiceAccountID = serviceAccount.ServiceAccountID
+		if err := tx.Table(utils.TableServiceAccountEntryKeyName).Create(&keys[i]).Error; err != nil {
+			return err
+		}
+		if existing[keys[i].EntryID] {
+			continue
+		}
+		if err := addSharedEvent(tx, serviceAccount.UserID, keys[i].EntryID, serviceAccount.ServiceAccountID, serviceAccount.UpdatedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryAccess is a service account holding, or entitled to, the key of an entry. Shared marks a granted key the
+// account did not hold before.
+type entryAccess struct {
+	EntryID          uint
+	ServiceAccountID uint
+	UserID           uint
+	Shared           bool `gorm:"-"`
+}
+
+// syncServiceAccountKeys is run by every change of the groups, tags or key of entries. Service accounts whose scope
+// now covers an entry get its key from keys and accounts that no longer cover it lose theirs. When the entry key
+// was rotated, every account that keeps access gets the key from keys.
+func syncServiceAccountKeys(tx *gorm.DB, entryIDs []uint, keys ServiceAccountKeys, rotated bool, actor *string) error {
+	if len(entryIDs) == 0 {
+		return nil
+	}
+
+	var covered []entryAccess
+	if err := tx.Raw(`
+		SELECT peg.entry_id, sas.service_account_id, sa.user_id
+		FROM password_entry_groups peg
+		JOIN service_account_scopes sas ON sas.group_id = peg.group_id
+		JOIN service_accounts sa ON sa.service_account_id = sas.service_account_id
+		WHERE peg.entry_id IN ? AND sa.deleted_at IS NULL
+		UNION
+		SELECT pet.entry_id, sas.service_account_id, sa.user_id
+		FROM password_entry_tags pet
+		JOIN service_account_scopes sas ON sas.tag_id = pet.tag_id
+		JOIN service_accounts sa ON sa.service_account_id = sas.service_account_id
+		WHERE pet.entry_id IN ? AND sa.deleted_at IS NULL
+	`, entryIDs, entryIDs).Scan(&covered).Error; err != nil {
+		return err
+	}
+	var existing []entryAccess
+	if err := tx.Table(utils.TableServiceAccountEntryKeyName).
+		Select("entry_id, service_account_id").
+		Where("entry_id IN ?", entryIDs).
+		Scan(&existing).Error; err != nil {
+		return err
+	}
+
+	revoked, granted, err := planServiceAccountKeys(covered, existing, keys.Wrapped, rotated)
+	if err != nil {
+		return err
+	}
+	if err := checkEntryKeys(tx, granted, keys.EntryKeys); err != nil {
+		return err
+	}
+	for _, access := range revoked {
+		if err := tx.Table(utils.TableServiceAccountEntryKeyName).
+			Where("service_account_id = ? AND entry_id = ?", access.ServiceAccountID, access.EntryID).
+			Delete(&password.ServiceAccountEntryKey{}).Error; err != nil {
+			return err
+		}
+	}
+	for _, access := range granted {
+		if err := tx.Table(utils.TableServiceAccountEntryKeyName).Create(&password.ServiceAccountEntryKey{
+			ServiceAccountID:      access.ServiceAccountID,
+			EntryID:               access.EntryID,
+			EncryptedSymmetricKey: keys.Wrapped[access.EntryID][access.ServiceAccountID],
+		}).Error; err != nil {
+			return err
+		}
+		if !access.Shared {
+			continue
+		}
+		if err := addSharedEvent(tx, access.UserID, access.EntryID, access.ServiceAccountID, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkEntryKeys locks the owner keys of the granted entries and makes sure the wrapped keys were made from them, so
+// an update rotating a key while a membership change wrapped it fails the change instead of granting a stale key
+func checkEntryKeys(tx *gorm.DB, granted []entryAccess, entryKeys map[uint]string) error {
+	if len(granted) == 0 {
+		return nil
+	}
+	entryIDs := make([]uint, 0, len(granted))
+	for _, access := range granted {
+		entryIDs = append(entryIDs, access.EntryID)
+	}
+	var current []password.PasswordEntryKey
+	if err := tx.Raw(`
+		SELECT entry_id, encrypted_symmetric_key FROM password_entry_keys WHERE entry_id IN ? FOR SHARE
+	`, entryIDs).Scan(&current).Error; err != nil {
+		return err
+	}
+	for _, key := range current {
+		if entryKeys[key.EntryID] != key.EncryptedSymmetricKey {
+			return ErrScopeChanged
+		}
+	}
+	return nil
+}
+
+// planServiceAccountKeys compares the accounts covering the entries with the keys they hold. It returns the keys to
+// delete and the keys to insert; with rotated every held key is replaced.
+func planServiceAccountKeys(covered, existing []entryAccess, keys map[uint]map[uint]string, rotated bool) ([]entryAccess, []entryAccess, error) {
+	type pair struct{ entryID, serviceAccountID uint }
+	coveredPairs := make(map[pair]bool, len(covered))
+	for _, access := range covered {
+		coveredPairs[pair{access.EntryID, access.ServiceAccountID}] = true
+	}
+	held := make(map[pair]bool, len(existing))
+
+	var revoked, granted []entryAccess
+	for _, access := range existing {
+		held[pair{access.EntryID, access.ServiceAccountID}] = true
+		if rotated || !coveredPairs[pair{access.EntryID, access.ServiceAccountID}] {
+			revoked = append(revoked, access)
+		}
+	}
+	for _, access := range covered {
+		if held[pair{access.EntryID, access.ServiceAccountID}] && !rotated {
+			continue
+		}
+		if _, ok := keys[access.EntryID][access.ServiceAccountID]; !ok {
+			return nil, nil, ErrServiceAccountKeyRequired
+		}
+		access.Shared = !held[pair{access.EntryID, access.ServiceAccountID}]
+		granted = append(granted, access)
+	}
+	return revoked, granted, nil
+}
+
+func addSharedEvent(tx *gorm.DB, userID, entryID, serviceAccountID uint, actor *string) error {
+	return addOutboxEvent(tx, utils.EventEntryShared, userID, entryID, actor, map[string]interface{}{
+		"target_type":        "service_account",
+		"service_account_id": serviceAccountID,
+	})
+}
+
+func (r *serviceAccountRepository) GetEntryKey(serviceAccountID, entryID uint) (*password.ServiceAccountEntryKey, error) {
+	var key password.ServiceAccountEntryKey
+	if err := r.db.Table(utils.TableServiceAccountEntryKeyName).
+		Where("service_account_id = ? AND entry_id = ?", serviceAccountID, entryID).
+		First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *serviceAccountRepository) GetCountEntryKeys(serviceAccountID uint) (int64, error) {
+	var count int64
+	if err := r.db.Table(utils.TableServiceAccountEntryKeyName).
+		Where("service_account_id = ?", serviceAccountID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *serviceAccountRepository) GetAccessibleEntries(serviceAccountID uint) ([]out.PasswordEntryListResponse, error) {
+	var entries []out.PasswordEntryListResponse
+	err := r.db.Raw(`
+		SELECT
+			pe.entry_id,
+			pe.title,
+			pe.entry_type,
+			pe.url,
+			pe.compromised,
+			pg.path AS group_name
+		FROM service_account_entry_keys saek
+		JOIN password_entries pe ON pe.entry_id = saek.entry_id
+		LEFT JOIN password_groups pg ON pg.group_id = pe.group_id
+		WHERE saek.service_account_id = ? AND pe.deleted_at IS NULL
+		ORDER BY pe.entry_id ASC
+	`, serviceAccountID).Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *serviceAccountRepository) GetAccessibleEntriesByGroupID(serviceAccountID, groupID uint) ([]password.PasswordEntry, error) {
+	var entries []password.PasswordEntry
+	err := r.db.Raw(`
+		SELECT pe.*
+		FROM service_account_entry_keys saek
+		JOIN password_entries pe ON pe.entry_id = saek.entry_id
+		JOIN password_entry_groups peg ON peg.entry_id = pe.entry_id
+		WHERE saek.service_account_id = ? AND peg.group_id = ? AND pe.deleted_at IS NULL
+		ORDER BY pe.entry_id ASC
+	`, serviceAccountID, groupID).Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlanServiceAccountKeys(t *testing.T) {
+	keys := map[uint]map[uint]string{
+		1: {10: "key-1-10", 20: "key-1-20"},
+		2: {10: "key-2-10"},
+	}
+
+	tests := []struct {
+		name     string
+		covered  []entryAccess
+		existing []entryAccess
+		keys     map[uint]map[uint]string
+		rotated  bool
+		revoked  []entryAccess
+		granted  []entryAccess
+		err      error
+	}{
+		{
+			name:    "entry joins a scope",
+			covered: []entryAccess{{EntryID: 1, ServiceAccountID: 10, UserID: 7}},
+			keys:    keys,
+			granted: []entryAccess{{EntryID: 1, ServiceAccountID: 10, UserID: 7, Shared: true}},
+		},
+		{
+			name:     "entry stays in scope",
+			covered:  []entryAccess{{EntryID: 1, ServiceAccountID: 10, UserID: 7}},
+			existing: []entryAccess{{EntryID: 1, ServiceAccountID: 10}},
+		},
+		{
+			name:     "entry leaves a scope",
+			existing: []entryAccess{{EntryID: 1, ServiceAccountID: 10}},
+			revoked:  []entryAccess{{EntryID: 1, ServiceAccountID: 10}},
+		},
+		{
+			name:     "rotated key replaces every held key",
+			covered:  []entryAccess{{EntryID: 1, ServiceAccountID: 10, UserID: 7}, {EntryID: 1, ServiceAccountID: 20, UserID: 7}},
+			existing: []entryAccess{{EntryID: 1, ServiceAccountID: 10}},
+			keys:     keys,
+			rotated:  true,
+			revoked:  []entryAccess{{EntryID: 1, ServiceAccountID: 10}},
+			granted: []entryAccess{
+				{EntryID: 1, ServiceAccountID: 10, UserID: 7},
+				{EntryID: 1, ServiceAccountID: 20, UserID: 7, Shared: true},
+			},
+		},
+		{
+			name:     "rotated key of an entry leaving the scope",
+			existing: []entryAccess{{EntryID: 2, ServiceAccountID: 10}},
+			rotated:  true,
+			revoked:  []entryAccess{{EntryID: 2, ServiceAccountID: 10}},
+		},
+		{
+			name:    "missing key for a new grant",
+			covered: []entryAccess{{EntryID: 2, ServiceAccountID: 20, UserID: 7}},
+			keys:    keys,
+			err:     ErrServiceAccountKeyRequired,
+		},
+		{
+			name:     "missing key for a rotated grant",
+			covered:  []entryAccess{{EntryID: 1, ServiceAccountID: 10, UserID: 7}},
+			existing: []entryAccess{{EntryID: 1, ServiceAccountID: 10}},
+			rotated:  true,
+			err:      ErrServiceAccountKeyRequired,
+		},
+		{
+			name:     "locked vault still revokes",
+			covered:  []entryAccess{{EntryID: 1, ServiceAccountID: 10, UserID: 7}},
+			existing: []entryAccess{{EntryID: 1, ServiceAccountID: 10}, {EntryID: 1, ServiceAccountID: 20}},
+			revoked:  []entryAccess{{EntryID: 1, ServiceAccountID: 20}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, granted, err := planServiceAccountKeys(tt.covered, tt.existing, tt.keys, tt.rotated)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(revoked, tt.revoked) {
+				t.Errorf("revoked = %+v, want %+v", revoked, tt.revoked)
+			}
+			if !reflect.DeepEqual(granted, tt.granted) {
+				t.Errorf("granted = %+v, want %+v", granted, tt.granted)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/utils"
+	"time"
+)
+
+var (
+	ErrAuthenticatorExists = errors.New("this authenticator is already registered")
+	// ErrSignCountChanged is returned when another assertion of the authenticator was accepted concurrently
+	ErrSignCountChanged = errors.New("authenticator sign count changed, retry")
+)
+
+type UserAuthenticatorRepository interface {
+	AddAuthenticator(authenticator *user.UserAuthenticator) error
+	GetAuthenticatorsByUserID(userID uint) ([]user.UserAuthenticator, error)
+	GetAuthenticatorByCredentialIDAndUserID(credentialID []byte, userID uint) (*user.UserAuthenticator, error)
+	HasAuthenticators(clientID string) (bool, error)
+	UpdateSignCount(authenticator *user.UserAuthenticator, signCount uint32, backupState bool) error
+	DeleteAuthenticator(authenticatorID, userID uint) error
+	ResetAuthenticators(userID uint, actor string) (int64, error)
+}
+
+type userAuthenticatorRepository struct {
+	db gorm.DB
+}
+
+func NewUserAuthenticatorRepository(db gorm.DB) UserAuthenticatorRepository {
+	return &userAuthenticatorRepository{
+		db: db,
+	}
+}
+
+// AddAuthenticator reports ErrAuthenticatorExists for a credential registered by any user. The unique index still
+// catches a concurrent insert; this gives the common case a clear error.
+func (r *userAuthenticatorRepository) AddAuthenticator(authenticator *user.UserAuthenticator) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(utils.TableUserAuthenticatorName).
+			Where("credential_id = ?", authenticator.CredentialID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAuthenticatorExists
+		}
+		return tx.Table(utils.TableUserAuthenticatorName).Create(authenticator).Error
+	})
+}
+
+func (r *userAuthenticatorRepository) GetAuthenticatorsByUserID(userID uint) ([]user.UserAuthenticator, error) {
+	var authenticators []user.UserAuthenticator
+	if err := r.db.Table(utils.TableUserAuthenticatorName).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&authenticators).Error; err != nil {
+		return nil, err
+	}
+	return authenticators, nil
+}
+
+func (r *userAuthenticatorRepository) GetAuthenticatorByCredentialIDAndUserID(credentialID []byte, userID uint) (*user.UserAuthenticator, error) {
+	var authenticator user.UserAuthenticator
+	if err := r.db.Table(utils.TableUserAuthenticatorName).
+		Where("credential_id = ? AND user_id = ?", credentialID, userID).
+		First(&authenticator).Error; err != nil {
+		return nil, err
+	}
+	return &authenticator, nil
+}
+
+// HasAuthenticators reports whether the user of the client has registered an authenticator
+func (r *userAuthenticatorRepository) HasAuthenticators(clientID string) (bool, error) {
+	var count int64
+	if err := r.db.Table(utils.TableUserAuthenticatorName).
+		Joins("JOIN users ON users.user_id = user_authenticators.user_id").
+		Where("users.client_id = ?", clientID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UpdateSignCount stores the sign count of an accepted assertion. It only applies over the count the assertion
+// was checked against, so two assertions cannot both be accepted with the same count.
+func (r *userAuthenticatorRepository) UpdateSignCount(authenticator *user.UserAuthenticator, signCount uint32, backupState bool) error {
+	result := r.db.Table(utils.TableUserAuthenticatorName).
+		Where("authenticator_id = ? AND sign_count = ?", authenticator.AuthenticatorID, authenticator.SignCount).
+		UpdateColumns(map[string]interface{}{
+			"sign_count":   signCount,
+			"backup_state": backupState,
+			"last_used_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSignCountChanged
+	}
+	return nil
+}
+
+func (r *userAuthenticatorRepository) DeleteAuthenticator(authenticatorID, userID uint) error {
+	result := r.db.Table(utils.TableUserAuthenticatorName).
+		Where("authenticator_id = ? AND user_id = ?", authenticatorID, userID).
+		Delete(&user.UserAuthenticator{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ResetAuthenticators removes every authenticator of a user, so one who lost their only security key can step up
+// with the PIN again and register a new one
+func (r *userAuthenticatorRepository) ResetAuthenticators(userID uint, actor string) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(utils.TableUserAuthenticatorName).Where("user_id = ?", userID).Delete(&user.UserAuthenticator{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		if deleted == 0 {
+			return nil
+		}
+		return addOutboxEvent(tx, utils.EventAuthenticatorsReset, userID, 0, &actor, map[string]interface{}{
+			"authenticators": deleted,
+		})
+	})
+	return deleted, err
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/utils"
+	"time"
+)
+
+type UserDeviceRepository interface {
+	UpsertDevice(device *user.UserDevice) error
+	GetDevicesByUserID(userID uint) ([]user.UserDevice, error)
+	GetDeviceByIDAndUserID(deviceID, userID uint) (*user.UserDevice, error)
+	RevokeDevice(deviceID uint, clientID string) error
+	ApproveDevice(deviceID uint) error
+}
+
+type userDeviceRepository struct {
+	db gorm.DB
+}
+
+func NewUserDeviceRepository(db gorm.DB) UserDeviceRepository {
+	return &userDeviceRepository{
+		db: db,
+	}
+}
+
+// UpsertDevice records the device for the current session and loads its stored state, including revocation
+func (r *userDeviceRepository) UpsertDevice(device *user.UserDevice) error {
+	return r.db.Raw(`
+		INSERT INTO user_devices (user_id, client_id, device_identifier, access_uuid, ip_address, user_agent, first_seen_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, device_identifier) DO UPDATE SET
+			client_id    = CASE WHEN user_devices.revoked_at IS NULL THEN EXCLUDED.client_id ELSE user_devices.client_id END,
+			access_uuid  = CASE WHEN user_devices.revoked_at IS NULL THEN EXCLUDED.access_uuid ELSE user_devices.access_uuid END,
+			ip_address   = EXCLUDED.ip_address,
+			user_agent   = EXCLUDED.user_agent,
+			last_seen_at = CURRENT_TIMESTAMP
+		RETURNING *
+	`, device.UserID, device.ClientID, device.DeviceIdentifier, device.AccessUUID, device.IPAddress, device.UserAgent).Scan(device).Error
+}
+
+func (r *userDeviceRepository) GetDevicesByUserID(userID uint) ([]user.UserDevice, error) {
+	var devices []user.UserDevice
+	if err := r.db.Table(utils.TableUserDeviceName).
+		Where("user_id = ?", userID).
+		Order("last_seen_at DESC").
+		Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (r *userDeviceRepository) GetDeviceByIDAndUserID(deviceID, userID uint) (*user.UserDevice, error) {
+	var device user.UserDevice
+	if err := r.db.Table(utils.TableUserDeviceName).
+		Where("device_id = ? AND user_id = ?", deviceID, userID).
+		First(&device).Error; err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *userDeviceRepository) RevokeDevice(deviceID uint, clientID string) error {
+	return r.db.Table(utils.TableUserDeviceName).
+		Where("device_id = ?", deviceID).
+		Updates(map[string]interface{}{
+			"revoked_at": time.Now(),
+			"revoked_by": clientID,
+		}).Error
+}
+
+// ApproveDevice lifts the revocation so the device can be used again
+func (r *userDeviceRepository) ApproveDevice(deviceID uint) error {
+	return r.db.Table(utils.TableUserDeviceName).
+		Where("device_id = ?", deviceID).
+		Updates(map[string]interface{}{
+			"revoked_at": nil,
+			"revoked_by": nil,
+		}).Error
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
+	"errors"
 	"golang.org/x/crypto/argon2"
 	"gorm.io/gorm"
 	"password-management-service/internal/models/user"
@@ -17,6 +18,8 @@ type UserKeysRepository interface {
 	GetUserKeys(userID uint) (*user.UserKey, error)
 	GetPublicKeyByUserID(userID uint) (*rsa.PublicKey, error)
 	GetPrivateKeyByUserID(userID uint, clientID string) (*rsa.PrivateKey, error)
+	GetDerivedKeyByUserID(userID uint, clientID string) ([]byte, error)
+	GetPrivateKeyWithDerivedKey(userID uint, derivedKey []byte) (*rsa.PrivateKey, error)
 }
 
 type userKeysRepository struct {
@@ -61,14 +64,21 @@ func (r *userKeysRepository) GetPublicKeyByUserID(userID uint) (*rsa.PublicKey,
 }
 
 func (r *userKeysRepository) GetPrivateKeyByUserID(userID uint, clientID string) (*rsa.PrivateKey, error) {
+	derivedKey, err := r.GetDerivedKeyByUserID(userID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetPrivateKeyWithDerivedKey(userID, derivedKey)
+}
+
+// GetDerivedKeyByUserID derives the key protecting the user's private key and checks that it opens it
+func (r *userKeysRepository) GetDerivedKeyByUserID(userID uint, clientID string) ([]byte, error) {
 	var userKey user.UserKey
 	if err := r.db.Table(utils.TableUserKeyName).Where("user_id = ?", userID).First(&userKey).Error; err != nil {
 		return nil, err
 	}
 
 	salt, _ := base64.StdEncoding.DecodeString(userKey.Salt)
-	encPrivateKey, _ := base64.StdEncoding.DecodeString(userKey.EncryptedPrivateKey)
-
 	aesKey := argon2.IDKey(
 		[]byte(clientID),
 		salt,
@@ -77,8 +87,38 @@ func (r *userKeysRepository) GetPrivateKeyByUserID(userID uint, clientID string)
 		8,
 		32,
 	)
-	block, _ := aes.NewCipher(aesKey)
-	gcm, _ := cipher.NewGCM(block)
+	if _, err := openPrivateKey(userKey.EncryptedPrivateKey, aesKey); err != nil {
+		return nil, err
+	}
+	return aesKey, nil
+}
+
+// GetPrivateKeyWithDerivedKey opens the user's private key with a key obtained from GetDerivedKeyByUserID
+func (r *userKeysRepository) GetPrivateKeyWithDerivedKey(userID uint, derivedKey []byte) (*rsa.PrivateKey, error) {
+	var userKey user.UserKey
+	if err := r.db.Table(utils.TableUserKeyName).Where("user_id = ?", userID).First(&userKey).Error; err != nil {
+		return nil, err
+	}
+	return openPrivateKey(userKey.EncryptedPrivateKey, derivedKey)
+}
+
+func openPrivateKey(encryptedPrivateKey string, aesKey []byte) (*rsa.PrivateKey, error) {
+	encPrivateKey, err := base64.StdEncoding.DecodeString(encryptedPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(encPrivateKey) < gcm.NonceSize() {
+		return nil, errors.New("encrypted private key is too short")
+	}
 	nonce := encPrivateKey[:gcm.NonceSize()]
 	cipherText := encPrivateKey[gcm.NonceSize():]
 	der, err := gcm.Open(nil, nonce, cipherText, nil)
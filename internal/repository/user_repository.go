@@ -7,6 +7,7 @@ import (
 
 type UserRepository interface {
 	GetUserByID(id uint) (*user.Users, error)
+	GetUserByIDWithDeleted(id uint) (*user.Users, error)
 	GetUserByPhoneNumber(number string) (*user.Users, error)
 	GetUserByClientID(clientID string) (*user.Users, error)
 	GetUserByClientAndRole(clientID, roleID uint) (*[]user.Users, error)
@@ -28,6 +29,15 @@ func (r userRepository) GetUserByID(id uint) (*user.Users, error) {
 	return &users, nil
 }
 
+// GetUserByIDWithDeleted also finds users the auth service has already soft-deleted
+func (r userRepository) GetUserByIDWithDeleted(id uint) (*user.Users, error) {
+	var users user.Users
+	if err := r.db.Unscoped().Where("user_id = ?", id).First(&users).Error; err != nil {
+		return nil, err
+	}
+	return &users, nil
+}
+
 func (r userRepository) GetUserByPhoneNumber(number string) (*user.Users, error) {
 	var users user.Users
 	if err := r.db.Where("phone_number = ?", number).Find(&users).Error; err != nil {
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/utils"
+)
+
+type VaultRepository interface {
+	DeleteUserData(userID uint) (*out.UserDataDeletionResponse, []password.PasswordAttachment, error)
+	GetStatistics(userID *uint) (*out.VaultStatisticsResponse, error)
+}
+
+type vaultRepository struct {
+	db gorm.DB
+}
+
+func NewVaultRepository(db gorm.DB) VaultRepository {
+	return &vaultRepository{
+		db: db,
+	}
+}
+
+// DeleteUserData hard-deletes everything the vault holds for a user in one transaction. Entry
+// children (keys, tags, history, access logs, service account keys) go with the entries through
+// ON DELETE CASCADE, as do group policies with their groups; shares are removed in both directions.
+// Exclusions of global equivalent domain groups are removed before the user's own groups.
+// The deleted attachments are returned so their blobs can be purged once the transaction has committed.
+func (r *vaultRepository) DeleteUserData(userID uint) (*out.UserDataDeletionResponse, []password.PasswordAttachment, error) {
+	result := out.UserDataDeletionResponse{UserID: userID}
+	var attachments []password.PasswordAttachment
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(`
+			SELECT pa.attachment_id, pa.entry_id, pa.storage_backend, pa.storage_key
+			FROM password_attachments pa
+			JOIN password_entries pe ON pe.entry_id = pa.entry_id
+			WHERE pe.user_id = ?
+			FOR UPDATE OF pa
+		`, userID).Scan(&attachments).Error; err != nil {
+			return err
+		}
+
+		deletes := []struct {
+			table string
+			where string
+			args  []interface{}
+			count *int64
+		}{
+			{utils.TableSharedPasswordName, "from_user_id = ? OR to_user_id = ?", []interface{}{userID, userID}, &result.Shares},
+			{utils.TableServiceAccountName, "user_id = ?", []interface{}{userID}, &result.ServiceAccounts},
+			{utils.TablePasswordEntryName, "user_id = ?", []interface{}{userID}, &result.Entries},
+			{utils.TablePasswordPolicyName, "user_id = ?", []interface{}{userID}, &result.Policies},
+			{utils.TablePasswordGroupName, "user_id = ?", []interface{}{userID}, &result.Groups},
+			{utils.TablePasswordTagName, "user_id = ?", []interface{}{userID}, &result.Tags},
+			{utils.TableDomainGroupExclusionName, "user_id = ?", []interface{}{userID}, &result.DomainExclusions},
+			{utils.TableEquivalentDomainGroupName, "user_id = ?", []interface{}{userID}, &result.DomainGroups},
+			{utils.TableUserDeviceName, "user_id = ?", []interface{}{userID}, &result.Devices},
+			{utils.TableUserAuthenticatorName, "user_id = ?", []interface{}{userID}, &result.Authenticators},
+			{utils.TableUserKeyName, "user_id = ?", []interface{}{userID}, &result.Keys},
+		}
+
+		for _, d := range deletes {
+			res := tx.Exec("DELETE FROM "+d.table+" WHERE "+d.where, d.args...)
+			if res.Error != nil {
+				return res.Error
+			}
+			*d.count = res.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	result.Attachments = int64(len(attachments))
+	return &result, attachments, nil
+}
+
+// GetStatistics counts live vault data, for a single user when userID is set
+func (r *vaultRepository) GetStatistics(userID *uint) (*out.VaultStatisticsResponse, error) {
+	result := out.VaultStatisticsResponse{UserID: userID}
+
+	scope := func(table string) *gorm.DB {
+		query := r.db.Table(table)
+		if userID != nil {
+			query = query.Where("user_id = ?", *userID)
+		}
+		return query
+	}
+
+	if err := scope(utils.TableUserKeyName).Where("deleted_at IS NULL").Count(&result.Users).Error; err != nil {
+		return nil, err
+	}
+	if err := scope(utils.TablePasswordEntryName).Where("deleted_at IS NULL").Count(&result.Entries).Error; err != nil {
+		return nil, err
+	}
+	if err := scope(utils.TablePasswordEntryName).Where("deleted_at IS NULL AND expires_at IS NOT NULL AND expires_at < CURRENT_TIMESTAMP").Count(&result.ExpiredEntries).Error; err != nil {
+		return nil, err
+	}
+	if err := scope(utils.TablePasswordGroupName).Where("deleted_at IS NULL").Count(&result.Groups).Error; err != nil {
+		return nil, err
+	}
+	if err := scope(utils.TablePasswordTagName).Where("deleted_at IS NULL").Count(&result.Tags).Error; err != nil {
+		return nil, err
+	}
+	if err := scope(utils.TableServiceAccountName).Where("deleted_at IS NULL").Count(&result.ServiceAccounts).Error; err != nil {
+		return nil, err
+	}
+	if err := scope(utils.TableUserDeviceName).Where("revoked_at IS NULL").Count(&result.ActiveDevices).Error; err != nil {
+		return nil, err
+	}
+
+	shares := r.db.Table(utils.TableSharedPasswordName)
+	if userID != nil {
+		shares = shares.Where("from_user_id = ? OR to_user_id = ?", *userID, *userID)
+	}
+	if err := shares.Count(&result.Shares).Error; err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
+)
+
+func AttachmentRoutes(r *gin.Engine, middleware config.Middleware, controller controller.AttachmentController) {
+	rateLimit := middleware.RateLimitMiddleware.HandlerRateLimit
+	stepUp := middleware.StepUpMiddleware.HandlerStepUp
+	vault := middleware.VaultMiddleware.HandlerVault()
+
+	routerGroup := r.Group("/v1/entry/:id/attachments")
+	routerGroup.Use(middleware.PasswordMiddleware.HandlerPassword())
+	{
+		routerGroup.POST("", vault, controller.AddAttachment)
+		routerGroup.GET("", rateLimit(utils.RateLimitBucketRead), controller.GetListAttachments)
+		routerGroup.GET("/:attachmentId", rateLimit(utils.RateLimitBucketReveal), rateLimit(utils.RateLimitBucketPinVerify), vault, stepUp(utils.StepUpActionReveal), controller.DownloadAttachment)
+		routerGroup.DELETE("/:attachmentId", rateLimit(utils.RateLimitBucketPinVerify), stepUp(utils.StepUpActionDelete), controller.DeleteAttachment)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
+)
+
+func EquivalentDomainRoutes(r *gin.Engine, middleware config.Middleware, controller controller.EquivalentDomainController) {
+	routerDomain := r.Group("/v1/domain/equivalent")
+	routerDomain.Use(middleware.PasswordMiddleware.HandlerPassword())
+	{
+		routerDomain.GET("/", middleware.RateLimitMiddleware.HandlerRateLimit(utils.RateLimitBucketRead), controller.GetListEquivalentDomainGroups)
+		routerDomain.POST("/", controller.AddEquivalentDomainGroup)
+		routerDomain.PUT("/:id", controller.UpdateEquivalentDomainGroup)
+		routerDomain.DELETE("/:id", controller.DeleteEquivalentDomainGroup)
+		routerDomain.POST("/:id/exclude", controller.ExcludeEquivalentDomainGroup)
+		routerDomain.DELETE("/:id/exclude", controller.IncludeEquivalentDomainGroup)
+	}
+}
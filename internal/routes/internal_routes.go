@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+)
+
+func InternalRoutes(r *gin.Engine, middleware config.Middleware, controller controller.InternalController) {
+	routerInternal := r.Group("/internal/v1")
+	routerInternal.Use(middleware.InternalMiddleware.HandlerInternal())
+	{
+		routerInternal.POST("/users/:id/keys", controller.BootstrapUserKey)
+		routerInternal.DELETE("/users/:id", controller.DeleteUser)
+		routerInternal.GET("/statistics", controller.GetStatistics)
+	}
+}
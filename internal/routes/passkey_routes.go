@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
+)
+
+func PasskeyRoutes(r *gin.Engine, middleware config.Middleware, controller controller.PasskeyController) {
+	rateLimit := middleware.RateLimitMiddleware.HandlerRateLimit
+	stepUp := middleware.StepUpMiddleware.HandlerStepUp
+	vault := middleware.VaultMiddleware.HandlerVault()
+
+	routerGroup := r.Group("/v1/entry/:id/passkey")
+	routerGroup.Use(middleware.PasswordMiddleware.HandlerPassword())
+	{
+		routerGroup.GET("/attestation", rateLimit(utils.RateLimitBucketRead), vault, controller.GetAttestation)
+		routerGroup.POST("/assert", rateLimit(utils.RateLimitBucketReveal), vault, stepUp(utils.StepUpActionPasskey), controller.Assert)
+	}
+}
@@ -4,17 +4,32 @@ import (
 	"github.com/gin-gonic/gin"
 	"password-management-service/config"
 	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
 )
 
 func PasswordEntryRoutes(r *gin.Engine, middleware config.Middleware, controller controller.PasswordEntryController) {
+	rateLimit := middleware.RateLimitMiddleware.HandlerRateLimit
+	stepUp := middleware.StepUpMiddleware.HandlerStepUp
+	vault := middleware.VaultMiddleware.HandlerVault()
+	vaultOptional := middleware.VaultMiddleware.HandlerVaultOptional()
+	bulkDeleteStepUp := middleware.StepUpMiddleware.HandlerStepUpWhen(utils.StepUpActionDelete, controller.IsBulkDelete)
+
 	routerGroup := r.Group("/v1/entry")
 	routerGroup.Use(middleware.PasswordMiddleware.HandlerPassword())
 	{
-		routerGroup.POST("/", controller.AddPasswordEntry)
-		routerGroup.PUT("/:id", controller.UpdatePasswordEntry)
-		routerGroup.POST("/group/:id", controller.AddGroupPasswordEntry)
-		routerGroup.GET("/", controller.GetListPasswordEntries)
-		routerGroup.GET("/:id", controller.GetPasswordEntryByID)
-		routerGroup.DELETE("/:id", controller.DeletePasswordEntry)
+		routerGroup.POST("/", rateLimit(utils.RateLimitBucketPinVerify), middleware.StepUpMiddleware.HandlerStepUpEnforced(utils.StepUpActionCreate), controller.AddPasswordEntry)
+		routerGroup.PUT("/:id", rateLimit(utils.RateLimitBucketPinVerify), stepUp(utils.StepUpActionRotateKey), vaultOptional, controller.UpdatePasswordEntry)
+		routerGroup.POST("/group/:id", vaultOptional, controller.AddGroupPasswordEntry)
+		routerGroup.POST("/bulk", rateLimit(utils.RateLimitBucketPinVerify), bulkDeleteStepUp, vaultOptional, controller.BulkPasswordEntries)
+		routerGroup.GET("/", rateLimit(utils.RateLimitBucketRead), controller.GetListPasswordEntries)
+		routerGroup.GET("/recent", rateLimit(utils.RateLimitBucketRead), controller.GetRecentPasswordEntries)
+		routerGroup.GET("/match", rateLimit(utils.RateLimitBucketRead), controller.GetMatchingPasswordEntries)
+		routerGroup.GET("/:id", rateLimit(utils.RateLimitBucketRead), vault, controller.GetPasswordEntryByID)
+		routerGroup.POST("/:id/reveal", rateLimit(utils.RateLimitBucketReveal), rateLimit(utils.RateLimitBucketPinVerify), vault, stepUp(utils.StepUpActionReveal), controller.RevealPasswordEntry)
+		routerGroup.POST("/:id/favorite", controller.FavoritePasswordEntry)
+		routerGroup.DELETE("/:id/favorite", controller.UnfavoritePasswordEntry)
+		routerGroup.POST("/:id/pin", controller.PinPasswordEntry)
+		routerGroup.DELETE("/:id/pin", controller.UnpinPasswordEntry)
+		routerGroup.DELETE("/:id", rateLimit(utils.RateLimitBucketPinVerify), stepUp(utils.StepUpActionDelete), controller.DeletePasswordEntry)
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"password-management-service/config"
 	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
 )
 
 func PasswordGroupRoutes(r *gin.Engine, middleware config.Middleware, controller controller.PasswordGroupController) {
@@ -12,8 +13,10 @@ func PasswordGroupRoutes(r *gin.Engine, middleware config.Middleware, controller
 	{
 		routerGroup.POST("/", controller.AddPasswordGroup)
 		routerGroup.PUT("/:id", controller.UpdatePasswordGroup)
-		routerGroup.GET("/", controller.GetListPasswordGroup)
-		routerGroup.GET("/item/:id", controller.GetItemListPasswordGroup)
+		routerGroup.PUT("/:id/move", controller.MovePasswordGroup)
+		routerGroup.GET("/", middleware.RateLimitMiddleware.HandlerRateLimit(utils.RateLimitBucketRead), controller.GetListPasswordGroup)
+		routerGroup.GET("/path", middleware.RateLimitMiddleware.HandlerRateLimit(utils.RateLimitBucketRead), controller.GetPasswordGroupByPath)
+		routerGroup.GET("/item/:id", middleware.RateLimitMiddleware.HandlerRateLimit(utils.RateLimitBucketRead), controller.GetItemListPasswordGroup)
 		routerGroup.DELETE("/:id", controller.DeletePasswordGroup)
 	}
 }
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+)
+
+func PasswordPolicyRoutes(r *gin.Engine, middleware config.Middleware, controller controller.PasswordPolicyController) {
+	routerPolicy := r.Group("/v1/admin/policies")
+	routerPolicy.Use(middleware.AdminMiddleware.HandlerAsset())
+	{
+		routerPolicy.POST("/", controller.AddPolicy)
+		routerPolicy.GET("/", controller.GetListPolicies)
+		routerPolicy.GET("/:id", controller.GetPolicyByID)
+		routerPolicy.PUT("/:id", controller.UpdatePolicy)
+		routerPolicy.DELETE("/:id", controller.DeletePolicy)
+	}
+}
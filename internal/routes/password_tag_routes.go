@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"password-management-service/config"
 	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
 )
 
 func PasswordTagRoutes(r *gin.Engine, middleware config.Middleware, controller controller.PasswordTagController) {
@@ -12,7 +13,8 @@ func PasswordTagRoutes(r *gin.Engine, middleware config.Middleware, controller c
 	{
 		routerTag.POST("/", controller.AddPasswordTag)
 		routerTag.PUT("/:id", controller.UpdatePasswordTag)
-		routerTag.GET("/", controller.GetListPasswordTag)
+		routerTag.POST("/:id/merge", middleware.VaultMiddleware.HandlerVaultOptional(), controller.MergePasswordTag)
+		routerTag.GET("/", middleware.RateLimitMiddleware.HandlerRateLimit(utils.RateLimitBucketRead), controller.GetListPasswordTag)
 		routerTag.DELETE("/:id", controller.DeletePasswordTag)
 	}
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
+)
+
+func ReportRoutes(r *gin.Engine, middleware config.Middleware, controller controller.ReportController) {
+	routerReport := r.Group("/v1/report")
+	routerReport.Use(middleware.PasswordMiddleware.HandlerPassword())
+	{
+		routerReport.GET("/breached", middleware.RateLimitMiddleware.HandlerRateLimit(utils.RateLimitBucketRead), controller.GetBreachedEntries)
+		routerReport.GET("/policy", middleware.RateLimitMiddleware.HandlerRateLimit(utils.RateLimitBucketRead), controller.GetPolicyViolations)
+	}
+}
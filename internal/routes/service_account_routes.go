@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
+)
+
+func ServiceAccountRoutes(r *gin.Engine, middleware config.Middleware, controller controller.ServiceAccountController) {
+	rateLimit := middleware.RateLimitMiddleware.HandlerRateLimit
+	stepUp := middleware.StepUpMiddleware.HandlerStepUp
+	vault := middleware.VaultMiddleware.HandlerVault()
+
+	routerGroup := r.Group("/v1/service-account")
+	routerGroup.Use(middleware.PasswordMiddleware.HandlerPassword())
+	{
+		routerGroup.POST("/", rateLimit(utils.RateLimitBucketPinVerify), vault, stepUp(utils.StepUpActionShare), controller.AddServiceAccount)
+		routerGroup.GET("/", rateLimit(utils.RateLimitBucketRead), controller.GetListServiceAccount)
+		routerGroup.DELETE("/:id", controller.DeleteServiceAccount)
+		routerGroup.POST("/:id/token", rateLimit(utils.RateLimitBucketPinVerify), vault, stepUp(utils.StepUpActionShare), controller.AddServiceAccountToken)
+		routerGroup.DELETE("/:id/token/:token_id", controller.DeleteServiceAccountToken)
+		routerGroup.POST("/:id/sync", rateLimit(utils.RateLimitBucketPinVerify), vault, stepUp(utils.StepUpActionShare), controller.SyncServiceAccount)
+	}
+
+	serviceGroup := r.Group("/v1/service")
+	serviceGroup.Use(middleware.ServiceTokenMiddleware.HandlerServiceToken())
+	{
+		serviceGroup.GET("/entry", rateLimit(utils.RateLimitBucketRead), controller.GetListServiceEntries)
+		serviceGroup.GET("/entry/:id", rateLimit(utils.RateLimitBucketReveal), controller.GetServiceSecret)
+	}
+
+	secretsGroup := r.Group("/v1/secrets")
+	secretsGroup.Use(middleware.ServiceTokenMiddleware.HandlerServiceToken())
+	{
+		secretsGroup.GET("/render", rateLimit(utils.RateLimitBucketExport), controller.RenderSecrets)
+	}
+}
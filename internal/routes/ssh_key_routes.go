@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
+)
+
+func SSHKeyRoutes(r *gin.Engine, middleware config.Middleware, controller controller.SSHKeyController) {
+	rateLimit := middleware.RateLimitMiddleware.HandlerRateLimit
+	stepUp := middleware.StepUpMiddleware.HandlerStepUp
+	vault := middleware.VaultMiddleware.HandlerVault()
+
+	routerGroup := r.Group("/v1/entry/:id/ssh")
+	routerGroup.Use(middleware.PasswordMiddleware.HandlerPassword())
+	{
+		routerGroup.POST("/sign", rateLimit(utils.RateLimitBucketReveal), vault, stepUp(utils.StepUpActionSSHSign), controller.Sign)
+		routerGroup.GET("/authorized_keys", rateLimit(utils.RateLimitBucketRead), controller.GetAuthorizedKey)
+	}
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
+)
+
+func UserDeviceRoutes(r *gin.Engine, middleware config.Middleware, controller controller.UserDeviceController) {
+	routerDevice := r.Group("/v1/devices")
+	routerDevice.Use(middleware.PasswordMiddleware.HandlerPassword())
+	{
+		routerDevice.GET("/", middleware.RateLimitMiddleware.HandlerRateLimit(utils.RateLimitBucketRead), controller.GetListDevices)
+		routerDevice.DELETE("/:id", controller.RevokeDevice)
+		routerDevice.POST("/:id/approve", middleware.StepUpMiddleware.HandlerStepUpEnforced(utils.StepUpActionApprove), controller.ApproveDevice)
+	}
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
+)
+
+func VaultRoutes(r *gin.Engine, middleware config.Middleware, controller controller.VaultController) {
+	rateLimit := middleware.RateLimitMiddleware.HandlerRateLimit
+
+	routerVault := r.Group("/v1/vault")
+	routerVault.Use(middleware.PasswordMiddleware.HandlerPassword())
+	{
+		routerVault.POST("/unlock", rateLimit(utils.RateLimitBucketPinVerify), middleware.StepUpMiddleware.HandlerStepUpEnforced(utils.StepUpActionUnlock), controller.UnlockVault)
+		routerVault.POST("/lock", controller.LockVault)
+	}
+}
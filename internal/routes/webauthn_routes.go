@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"password-management-service/config"
+	"password-management-service/internal/controller"
+	"password-management-service/internal/utils"
+)
+
+func WebAuthnRoutes(r *gin.Engine, middleware config.Middleware, controller controller.WebAuthnController) {
+	rateLimit := middleware.RateLimitMiddleware.HandlerRateLimit
+	stepUp := middleware.StepUpMiddleware.HandlerStepUpEnforced
+
+	routerGroup := r.Group("/v1/webauthn")
+	routerGroup.Use(middleware.PasswordMiddleware.HandlerPassword())
+	{
+		routerGroup.GET("/authenticators", rateLimit(utils.RateLimitBucketRead), controller.GetListAuthenticators)
+		routerGroup.DELETE("/authenticators/:id", rateLimit(utils.RateLimitBucketPinVerify), stepUp(utils.StepUpActionDelete), controller.DeleteAuthenticator)
+		routerGroup.POST("/register/begin", rateLimit(utils.RateLimitBucketPinVerify), stepUp(utils.StepUpActionEnroll), controller.BeginRegistration)
+		routerGroup.POST("/register/finish", rateLimit(utils.RateLimitBucketPinVerify), controller.FinishRegistration)
+		routerGroup.POST("/authenticate/begin", rateLimit(utils.RateLimitBucketPinVerify), controller.BeginAuthentication)
+		routerGroup.POST("/authenticate/finish", rateLimit(utils.RateLimitBucketPinVerify), controller.FinishAuthentication)
+	}
+
+	routerAdmin := r.Group("/v1/admin/users")
+	routerAdmin.Use(middleware.AdminMiddleware.HandlerAsset())
+	{
+		routerAdmin.DELETE("/:id/authenticators", controller.ResetAuthenticators)
+	}
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"mime"
+	"net/http"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/blob"
+	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/text"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const maxFileNameLen = 255
+
+type AttachmentService interface {
+	AddAttachment(entryID uint, fileName, contentType string, data []byte, clientID string, vaultKey []byte) (interface{}, error)
+	GetListAttachments(entryID uint, clientID string) (interface{}, error)
+	DownloadAttachment(entryID, attachmentID uint, clientID string, vaultKey []byte, metadata in.RequestMetadata) (*password.PasswordAttachment, []byte, error)
+	DeleteAttachment(entryID, attachmentID uint, clientID string) error
+	GetEntryAttachments(entryID uint) ([]password.PasswordAttachment, error)
+	PurgeBlobs(attachments []password.PasswordAttachment)
+	MaxSize() int64
+}
+
+type attachmentService struct {
+	UserRepository               repository.UserRepository
+	UserKeyRepository            repository.UserKeysRepository
+	PasswordEntryRepository      repository.PasswordEntryRepository
+	PasswordEntryKeyRepository   repository.PasswordEntryKeysRepository
+	PasswordAttachmentRepository repository.PasswordAttachmentRepository
+	PasswordAccessLogRepository  repository.PasswordAccessLogRepository
+	EncryptionService            encryption.Encryption
+	BlobStore                    blob.Store
+	Redis                        redis.RedisService
+	MaxAttachmentSize            int64
+}
+
+func NewAttachmentService(
+	userRepository repository.UserRepository,
+	userKeyRepository repository.UserKeysRepository,
+	passwordEntryRepository repository.PasswordEntryRepository,
+	passwordEntryKeysRepository repository.PasswordEntryKeysRepository,
+	passwordAttachmentRepository repository.PasswordAttachmentRepository,
+	passwordAccessLogRepository repository.PasswordAccessLogRepository,
+	encryptionService encryption.Encryption,
+	blobStore blob.Store,
+	redis redis.RedisService,
+	maxSize int64) AttachmentService {
+	return &attachmentService{
+		UserRepository:               userRepository,
+		UserKeyRepository:            userKeyRepository,
+		PasswordEntryRepository:      passwordEntryRepository,
+		PasswordEntryKeyRepository:   passwordEntryKeysRepository,
+		PasswordAttachmentRepository: passwordAttachmentRepository,
+		PasswordAccessLogRepository:  passwordAccessLogRepository,
+		EncryptionService:            encryptionService,
+		BlobStore:                    blobStore,
+		Redis:                        redis,
+		MaxAttachmentSize:            maxSize,
+	}
+}
+
+func (s *attachmentService) MaxSize() int64 {
+	return s.MaxAttachmentSize
+}
+
+// AddAttachment encrypts the file under its own key, writes the ciphertext to the blob store and then records
+// the metadata. The blob is removed again when the metadata cannot be saved.
+func (s *attachmentService) AddAttachment(entryID uint, fileName, contentType string, data []byte, clientID string, vaultKey []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, errors.New("attachment is empty")
+	}
+	if int64(len(data)) > s.MaxAttachmentSize {
+		return nil, fmt.Errorf("attachment exceeds the maximum size of %d bytes", s.MaxAttachmentSize)
+	}
+	fileName, err := sanitizeFileName(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, entry, err := s.getOwnedEntry(entryID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, passwordEntryKey, err := s.getDecryptionKeys(entry.EntryID, owner, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, wrappedKey, err := s.EncryptionService.EncryptAttachment(data, passwordEntryKey.EncryptedSymmetricKey, privateKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to encrypt attachment")
+		return nil, err
+	}
+
+	storageKey, err := blob.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.BlobStore.Put(storageKey, ciphertext); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to store attachment")
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(data)
+	attachment := password.PasswordAttachment{
+		EntryID:        entry.EntryID,
+		UserID:         owner.UserID,
+		FileName:       fileName,
+		ContentType:    normalizeContentType(contentType, data),
+		Size:           int64(len(data)),
+		Checksum:       hex.EncodeToString(checksum[:]),
+		StorageBackend: s.BlobStore.Backend(),
+		StorageKey:     storageKey,
+		WrappedKey:     wrappedKey,
+		CreatedBy:      &clientID,
+	}
+	if err := s.PasswordAttachmentRepository.AddAttachment(&attachment, passwordEntryKey.EncryptedSymmetricKey); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to add attachment")
+		if err := s.BlobStore.Delete(storageKey); err != nil {
+			log.Error().Str("clientID", clientID).Str("storageKey", storageKey).Err(err).Msg("Failed to remove orphaned attachment blob")
+		}
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *attachmentService) GetListAttachments(entryID uint, clientID string) (interface{}, error) {
+	_, entry, err := s.getOwnedEntry(entryID, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.PasswordAttachmentRepository.GetAttachmentsByEntryID(entry.EntryID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve attachments")
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// DownloadAttachment decrypts the file, verifies it against the checksum recorded on upload and logs the access
+func (s *attachmentService) DownloadAttachment(entryID, attachmentID uint, clientID string, vaultKey []byte, metadata in.RequestMetadata) (*password.PasswordAttachment, []byte, error) {
+	owner, entry, err := s.getOwnedEntry(entryID, clientID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attachment, err := s.PasswordAttachmentRepository.GetAttachmentByID(entry.EntryID, attachmentID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve attachment")
+		return nil, nil, err
+	}
+	if attachment.StorageBackend != s.BlobStore.Backend() {
+		log.Error().Str("clientID", clientID).Str("backend", attachment.StorageBackend).Msg("Attachment is stored in another backend")
+		return nil, nil, fmt.Errorf("attachment is stored in the %s backend", attachment.StorageBackend)
+	}
+
+	privateKey, passwordEntryKey, err := s.getDecryptionKeys(entry.EntryID, owner, vaultKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext, err := s.BlobStore.Get(attachment.StorageKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to read attachment")
+		return nil, nil, err
+	}
+	data, err := s.EncryptionService.DecryptAttachment(ciphertext, attachment.WrappedKey, passwordEntryKey.EncryptedSymmetricKey, privateKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decrypt attachment")
+		return nil, nil, err
+	}
+	checksum := sha256.Sum256(data)
+	if hex.EncodeToString(checksum[:]) != attachment.Checksum {
+		log.Error().Str("clientID", clientID).Uint("attachmentID", attachment.AttachmentID).Msg("Attachment checksum mismatch")
+		return nil, nil, errors.New("attachment checksum mismatch")
+	}
+
+	accessLog := password.PasswordAccessLog{
+		EntryID:   entry.EntryID,
+		UserID:    owner.UserID,
+		Action:    utils.AccessActionDownload,
+		Fields:    pq.StringArray{fmt.Sprintf("attachments.%d", attachment.AttachmentID)},
+		RequestID: text.NilIfEmpty(metadata.RequestID),
+		IPAddress: text.NilIfEmpty(metadata.IPAddress),
+		UserAgent: text.NilIfEmpty(metadata.UserAgent),
+		CreatedBy: &clientID,
+	}
+	if err := s.PasswordAccessLogRepository.AddAccessLog(&accessLog); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to record attachment download")
+		return nil, nil, err
+	}
+
+	return attachment, data, nil
+}
+
+func (s *attachmentService) DeleteAttachment(entryID, attachmentID uint, clientID string) error {
+	_, entry, err := s.getOwnedEntry(entryID, clientID)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := s.PasswordAttachmentRepository.GetAttachmentByID(entry.EntryID, attachmentID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve attachment")
+		return err
+	}
+	if err := s.PasswordAttachmentRepository.DeleteAttachment(attachment, clientID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to delete attachment")
+		return err
+	}
+
+	s.PurgeBlobs([]password.PasswordAttachment{*attachment})
+	return nil
+}
+
+func (s *attachmentService) GetEntryAttachments(entryID uint) ([]password.PasswordAttachment, error) {
+	return s.PasswordAttachmentRepository.GetAttachmentsByEntryID(entryID)
+}
+
+// PurgeBlobs removes the ciphertext of attachments whose metadata is already gone. Failures only leave
+// unreadable ciphertext behind, so they are logged rather than returned.
+func (s *attachmentService) PurgeBlobs(attachments []password.PasswordAttachment) {
+	for _, attachment := range attachments {
+		if attachment.StorageBackend != s.BlobStore.Backend() {
+			log.Warn().Str("storageKey", attachment.StorageKey).Str("backend", attachment.StorageBackend).Msg("Attachment blob left in another backend")
+			continue
+		}
+		if err := s.BlobStore.Delete(attachment.StorageKey); err != nil {
+			log.Error().Str("storageKey", attachment.StorageKey).Err(err).Msg("Failed to delete attachment blob")
+		}
+	}
+}
+
+func (s *attachmentService) getOwnedEntry(entryID uint, clientID string) (*user.Users, *password.PasswordEntry, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, nil, err
+	}
+	owner, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, nil, err
+	}
+	if owner == nil {
+		log.Error().Str("clientID", clientID).Msg("User not found")
+		return nil, nil, errors.New("user not found")
+	}
+
+	entry, err := s.PasswordEntryRepository.GetPasswordEntryByEntryIDAndUserID(entryID, owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry")
+		return nil, nil, err
+	}
+	if entry == nil {
+		log.Error().Str("clientID", clientID).Msg("Password entry not found")
+		return nil, nil, errors.New("password entry not found")
+	}
+	return owner, entry, nil
+}
+
+// getDecryptionKeys opens the owner's private key with the key held by the unlocked vault session
+func (s *attachmentService) getDecryptionKeys(entryID uint, owner *user.Users, vaultKey []byte) (*rsa.PrivateKey, *password.PasswordEntryKey, error) {
+	if len(vaultKey) == 0 {
+		log.Error().Str("clientID", owner.ClientID).Msg("Vault is locked")
+		return nil, nil, errors.New("vault is locked")
+	}
+
+	privateKey, err := s.UserKeyRepository.GetPrivateKeyWithDerivedKey(owner.UserID, vaultKey)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to retrieve user keys")
+		return nil, nil, err
+	}
+	if privateKey == nil {
+		log.Error().Str("clientID", owner.ClientID).Msg("User private key not found")
+		return nil, nil, errors.New("user private key not found")
+	}
+
+	passwordEntryKey, err := s.PasswordEntryKeyRepository.GetPasswordEntryKeyByEntryID(entryID)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to retrieve password entry key")
+		return nil, nil, err
+	}
+	if passwordEntryKey == nil {
+		log.Error().Str("clientID", owner.ClientID).Msg("Password entry key not found")
+		return nil, nil, errors.New("password entry key not found")
+	}
+	return privateKey, passwordEntryKey, nil
+}
+
+// sanitizeFileName keeps only the base name so a client cannot smuggle paths or control characters into headers
+func sanitizeFileName(fileName string) (string, error) {
+	fileName = filepath.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	fileName = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, fileName))
+	if fileName == "" || fileName == "." || fileName == "/" {
+		return "", errors.New("attachment file name is required")
+	}
+	if len(fileName) > maxFileNameLen {
+		return "", fmt.Errorf("attachment file name must be at most %d bytes", maxFileNameLen)
+	}
+	return fileName, nil
+}
+
+// normalizeContentType trusts the declared type when it parses and falls back to sniffing the content
+func normalizeContentType(contentType string, data []byte) string {
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil {
+		return mime.FormatMediaType(mediaType, params)
+	}
+	return http.DetectContentType(data)
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils/breach"
+	"password-management-service/internal/utils/encryption"
+	"sync"
+	"time"
+)
+
+type BreachService interface {
+	ResetStatus(passwordEntry *password.PasswordEntry)
+	CheckPassword(passwordEntry password.PasswordEntry, plaintext string)
+	RecheckEntries(owner *user.Users, vaultKey []byte)
+	RecheckDueEntries(owner *user.Users)
+	Stop()
+}
+
+type breachService struct {
+	PasswordEntryRepository    repository.PasswordEntryRepository
+	PasswordEntryKeyRepository repository.PasswordEntryKeysRepository
+	UserKeyRepository          repository.UserKeysRepository
+	EncryptionService          encryption.Encryption
+	Checker                    breach.Checker
+	RecheckInterval            time.Duration
+	BatchSize                  int
+	jobs                       chan func()
+	workers                    sync.WaitGroup
+	mu                         sync.RWMutex
+	stopped                    bool
+}
+
+// NewBreachService checks passwords against the HIBP range data on a pool of background workers, so lookups never
+// hold up a request. A nil checker disables detection. No password hash is stored: entries are rechecked by
+// decrypting them while their owner's vault is unlocked, on unlock and on the recheck schedule.
+func NewBreachService(
+	passwordEntryRepository repository.PasswordEntryRepository,
+	passwordEntryKeyRepository repository.PasswordEntryKeysRepository,
+	userKeyRepository repository.UserKeysRepository,
+	encryptionService encryption.Encryption,
+	checker breach.Checker,
+	recheckInterval time.Duration,
+	batchSize int,
+	workers int,
+	queueSize int) BreachService {
+	s := &breachService{
+		PasswordEntryRepository:    passwordEntryRepository,
+		PasswordEntryKeyRepository: passwordEntryKeyRepository,
+		UserKeyRepository:          userKeyRepository,
+		EncryptionService:          encryptionService,
+		Checker:                    checker,
+		RecheckInterval:            recheckInterval,
+		BatchSize:                  batchSize,
+		jobs:                       make(chan func(), max(queueSize, 1)),
+	}
+	if checker != nil {
+		for i := 0; i < max(workers, 1); i++ {
+			s.workers.Add(1)
+			go s.work()
+		}
+	}
+	return s
+}
+
+func (s *breachService) work() {
+	defer s.workers.Done()
+	for job := range s.jobs {
+		job()
+	}
+}
+
+// enqueue drops the job when the queue is full; the entry then stays unchecked until the next recheck
+func (s *breachService) enqueue(job func()) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.Checker == nil || s.stopped {
+		return false
+	}
+	select {
+	case s.jobs <- job:
+		return true
+	default:
+		log.Warn().Msg("Breach check queue is full, skipping check")
+		return false
+	}
+}
+
+// Stop finishes the queued checks and stops the workers
+func (s *breachService) Stop() {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.jobs)
+	}
+	s.mu.Unlock()
+	s.workers.Wait()
+}
+
+// ResetStatus clears the breach fields of an entry whose password is being saved, until CheckPassword reports back
+func (s *breachService) ResetStatus(passwordEntry *password.PasswordEntry) {
+	passwordEntry.Compromised = false
+	passwordEntry.BreachCount = 0
+	passwordEntry.BreachCheckedAt = nil
+}
+
+// CheckPassword looks up the password of a saved entry in the background. A failed lookup leaves the entry
+// unchecked so the next recheck picks it up.
+func (s *breachService) CheckPassword(passwordEntry password.PasswordEntry, plaintext string) {
+	if plaintext == "" {
+		return
+	}
+	s.enqueue(func() {
+		prefix, suffix := breach.Hash(plaintext)
+		count, err := breach.Count(s.Checker, prefix, suffix)
+		if err != nil {
+			log.Warn().Uint("entryID", passwordEntry.EntryID).Err(err).Msg("Failed to check password against breach data")
+			return
+		}
+		s.updateStatus(&passwordEntry, count)
+	})
+}
+
+// RecheckEntries re-evaluates the owner's entries not checked within the recheck interval, using the key of the
+// vault session that was just unlocked. Each prefix is queried once per run.
+func (s *breachService) RecheckEntries(owner *user.Users, vaultKey []byte) {
+	if len(vaultKey) == 0 {
+		return
+	}
+	vaultKey = append([]byte(nil), vaultKey...)
+	s.enqueue(func() {
+		s.recheck(owner.UserID, owner.ClientID, func() ([]byte, error) { return vaultKey, nil })
+	})
+}
+
+// RecheckDueEntries is the scheduled counterpart of RecheckEntries for an owner whose vault is still unlocked. The
+// vault key is only derived on the worker, and only when the owner has entries due.
+func (s *breachService) RecheckDueEntries(owner *user.Users) {
+	userID, clientID := owner.UserID, owner.ClientID
+	s.enqueue(func() {
+		s.recheck(userID, clientID, func() ([]byte, error) {
+			return s.UserKeyRepository.GetDerivedKeyByUserID(userID, clientID)
+		})
+	})
+}
+
+func (s *breachService) recheck(userID uint, clientID string, vaultKey func() ([]byte, error)) {
+	entries, err := s.PasswordEntryRepository.GetEntriesForBreachCheck(userID, time.Now().Add(-s.RecheckInterval), s.BatchSize)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entries for breach check")
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+	derivedKey, err := vaultKey()
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to derive vault key")
+		return
+	}
+	privateKey, err := s.UserKeyRepository.GetPrivateKeyWithDerivedKey(userID, derivedKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user keys")
+		return
+	}
+
+	ranges := make(map[string]map[string]int)
+	checked := 0
+	for i := range entries {
+		entry := &entries[i]
+		passwordEntryKey, err := s.PasswordEntryKeyRepository.GetPasswordEntryKeyByEntryID(entry.EntryID)
+		if err != nil {
+			log.Error().Uint("entryID", entry.EntryID).Err(err).Msg("Failed to retrieve password entry key")
+			continue
+		}
+		plaintext, err := s.EncryptionService.DecryptPasswordEntryField(entry.EncryptedPassword, passwordEntryKey.EncryptedSymmetricKey, privateKey)
+		if err != nil {
+			log.Error().Uint("entryID", entry.EntryID).Err(err).Msg("Failed to decrypt password for breach check")
+			continue
+		}
+
+		count := 0
+		if plaintext != "" {
+			prefix, suffix := breach.Hash(plaintext)
+			hashes, found := ranges[prefix]
+			if !found {
+				if hashes, err = s.Checker.Range(prefix); err != nil {
+					log.Error().Str("clientID", clientID).Err(err).Msg("Failed to load breach range")
+					return
+				}
+				ranges[prefix] = hashes
+			}
+			count = hashes[suffix]
+		}
+		if s.updateStatus(entry, count) {
+			checked++
+		}
+	}
+
+	if checked > 0 {
+		log.Info().Str("clientID", clientID).Int("entries", checked).Msg("Password entries rechecked against breach data")
+	}
+}
+
+func (s *breachService) updateStatus(passwordEntry *password.PasswordEntry, count int) bool {
+	now := time.Now()
+	passwordEntry.BreachCount = count
+	passwordEntry.Compromised = count > 0
+	passwordEntry.BreachCheckedAt = &now
+	if err := s.PasswordEntryRepository.UpdateBreachStatus(passwordEntry); err != nil {
+		log.Error().Uint("entryID", passwordEntry.EntryID).Err(err).Msg("Failed to update breach status")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/urlmatch"
+)
+
+const maxEquivalentDomains = 50
+
+type EquivalentDomainService interface {
+	GetListEquivalentDomainGroups(clientID string) (interface{}, error)
+	AddEquivalentDomainGroup(req *in.EquivalentDomainGroupRequest, clientID string) (interface{}, error)
+	UpdateEquivalentDomainGroup(groupID uint, req *in.EquivalentDomainGroupRequest, clientID string) (interface{}, error)
+	DeleteEquivalentDomainGroup(groupID uint, clientID string) error
+	SetEquivalentDomainGroupExcluded(groupID uint, clientID string, excluded bool) error
+	MatchKeys(userID uint, target *urlmatch.URI) (map[string]bool, error)
+}
+
+type equivalentDomainService struct {
+	UserRepository             repository.UserRepository
+	EquivalentDomainRepository repository.EquivalentDomainRepository
+	Redis                      redis.RedisService
+}
+
+func NewEquivalentDomainService(
+	userRepository repository.UserRepository,
+	equivalentDomainRepository repository.EquivalentDomainRepository,
+	redis redis.RedisService) EquivalentDomainService {
+	return &equivalentDomainService{
+		UserRepository:             userRepository,
+		EquivalentDomainRepository: equivalentDomainRepository,
+		Redis:                      redis,
+	}
+}
+
+func (s *equivalentDomainService) GetListEquivalentDomainGroups(clientID string) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	groups, err := s.EquivalentDomainRepository.GetEquivalentDomainGroups(user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve equivalent domain groups")
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (s *equivalentDomainService) AddEquivalentDomainGroup(req *in.EquivalentDomainGroupRequest, clientID string) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	domains, err := normalizeEquivalentDomains(req.Domains)
+	if err != nil {
+		return nil, err
+	}
+	group := &password.EquivalentDomainGroup{
+		UserID:    &user.UserID,
+		Domains:   domains,
+		CreatedBy: &user.ClientID,
+		UpdatedBy: &user.ClientID,
+	}
+	if err := s.EquivalentDomainRepository.AddEquivalentDomainGroup(group); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to add equivalent domain group")
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *equivalentDomainService) UpdateEquivalentDomainGroup(groupID uint, req *in.EquivalentDomainGroupRequest, clientID string) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	group, err := s.getOwnGroup(groupID, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	domains, err := normalizeEquivalentDomains(req.Domains)
+	if err != nil {
+		return nil, err
+	}
+	group.Domains = domains
+	group.UpdatedBy = &user.ClientID
+	if err := s.EquivalentDomainRepository.UpdateEquivalentDomainGroup(group); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to update equivalent domain group")
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *equivalentDomainService) DeleteEquivalentDomainGroup(groupID uint, clientID string) error {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return err
+	}
+
+	group, err := s.getOwnGroup(groupID, user.UserID)
+	if err != nil {
+		return err
+	}
+	group.DeletedBy = &user.ClientID
+	if err := s.EquivalentDomainRepository.DeleteEquivalentDomainGroup(group); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to delete equivalent domain group")
+		return err
+	}
+	return nil
+}
+
+// SetEquivalentDomainGroupExcluded turns a global group off or back on for the user
+func (s *equivalentDomainService) SetEquivalentDomainGroupExcluded(groupID uint, clientID string, excluded bool) error {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return err
+	}
+
+	group, err := s.EquivalentDomainRepository.GetEquivalentDomainGroupByID(groupID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve equivalent domain group")
+		return err
+	}
+	if group.UserID != nil {
+		return errors.New("only global equivalent domain groups can be excluded")
+	}
+	if err := s.EquivalentDomainRepository.SetEquivalentDomainGroupExcluded(user.UserID, groupID, excluded); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to update equivalent domain group exclusion")
+		return err
+	}
+	return nil
+}
+
+// MatchKeys returns the base domains and app URIs equivalent to the target for the user: the domains of a known
+// app, the other domains of the groups they belong to, and the known apps of all those domains
+func (s *equivalentDomainService) MatchKeys(userID uint, target *urlmatch.URI) (map[string]bool, error) {
+	keys := map[string]bool{target.BaseDomain: true}
+	if target.IsApp() {
+		domains, err := s.EquivalentDomainRepository.GetDomainsByAppID(target.BaseDomain)
+		if err != nil {
+			return nil, err
+		}
+		for _, domain := range domains {
+			keys[domain] = true
+		}
+	}
+
+	groups, err := s.EquivalentDomainRepository.GetEquivalentDomainGroups(userID)
+	if err != nil {
+		return nil, err
+	}
+	var related []string
+	for _, group := range groups {
+		if group.Excluded || !containsAny(group.Domains, keys) {
+			continue
+		}
+		related = append(related, group.Domains...)
+	}
+	for _, domain := range related {
+		keys[domain] = true
+	}
+
+	domains := make([]string, 0, len(keys))
+	for key := range keys {
+		domains = append(domains, key)
+	}
+	appIDs, err := s.EquivalentDomainRepository.GetAppIDsByDomains(domains)
+	if err != nil {
+		return nil, err
+	}
+	for _, appID := range appIDs {
+		keys[appID] = true
+	}
+	return keys, nil
+}
+
+func (s *equivalentDomainService) getOwnGroup(groupID, userID uint) (*password.EquivalentDomainGroup, error) {
+	group, err := s.EquivalentDomainRepository.GetEquivalentDomainGroupByID(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group.UserID == nil {
+		return nil, errors.New("global equivalent domain groups can only be excluded")
+	}
+	if *group.UserID != userID {
+		return nil, errors.New("equivalent domain group not found")
+	}
+	return group, nil
+}
+
+func normalizeEquivalentDomains(domains []string) ([]string, error) {
+	if len(domains) > maxEquivalentDomains {
+		return nil, fmt.Errorf("at most %d domains are allowed", maxEquivalentDomains)
+	}
+	seen := make(map[string]bool, len(domains))
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		baseDomain, err := urlmatch.NormalizeDomain(domain)
+		if err != nil {
+			return nil, err
+		}
+		if seen[baseDomain] {
+			continue
+		}
+		seen[baseDomain] = true
+		normalized = append(normalized, baseDomain)
+	}
+	if len(normalized) < 2 {
+		return nil, errors.New("an equivalent domain group needs at least two different domains")
+	}
+	return normalized, nil
+}
+
+func containsAny(values []string, set map[string]bool) bool {
+	for _, value := range values {
+		if set[value] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils/urlmatch"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// fakeEquivalentDomainRepository knows the YouTube app and the Google domain groups
+type fakeEquivalentDomainRepository struct {
+	repository.EquivalentDomainRepository
+	groups []out.EquivalentDomainGroupResponse
+}
+
+var knownApps = map[string][]string{
+	"androidapp://com.google.android.youtube": {"youtube.com"},
+	"androidapp://com.google.android.gm":      {"gmail.com", "google.com"},
+}
+
+func (f fakeEquivalentDomainRepository) GetDomainsByAppID(appID string) ([]string, error) {
+	return knownApps[appID], nil
+}
+
+func (f fakeEquivalentDomainRepository) GetAppIDsByDomains(domains []string) ([]string, error) {
+	var appIDs []string
+	for appID, appDomains := range knownApps {
+		for _, domain := range domains {
+			if slices.Contains(appDomains, domain) {
+				appIDs = append(appIDs, appID)
+				break
+			}
+		}
+	}
+	return appIDs, nil
+}
+
+func (f fakeEquivalentDomainRepository) GetEquivalentDomainGroups(userID uint) ([]out.EquivalentDomainGroupResponse, error) {
+	return f.groups, nil
+}
+
+func TestMatchKeys(t *testing.T) {
+	google := out.EquivalentDomainGroupResponse{GroupID: 1, Domains: []string{"google.com", "youtube.com", "google.co.uk"}, Global: true}
+	excluded := out.EquivalentDomainGroupResponse{GroupID: 2, Domains: []string{"example.com", "example.net"}, Global: true, Excluded: true}
+	own := out.EquivalentDomainGroupResponse{GroupID: 3, Domains: []string{"example.com", "example.org"}}
+
+	tests := []struct {
+		name   string
+		target string
+		groups []out.EquivalentDomainGroupResponse
+		want   []string
+	}{
+		{name: "no groups", target: "https://login.example.com", want: []string{"example.com"}},
+		{
+			name:   "subdomain joins its base domain group and the apps of every domain in it",
+			target: "https://accounts.google.co.uk/signin",
+			groups: []out.EquivalentDomainGroupResponse{google, own},
+			want:   []string{"google.co.uk", "google.com", "youtube.com", "androidapp://com.google.android.gm", "androidapp://com.google.android.youtube"},
+		},
+		{
+			name:   "app expands to its domains and their groups",
+			target: "androidapp://com.google.android.youtube",
+			groups: []out.EquivalentDomainGroupResponse{google},
+			want:   []string{"androidapp://com.google.android.youtube", "youtube.com", "google.com", "google.co.uk", "androidapp://com.google.android.gm"},
+		},
+		{
+			name:   "excluded global group is skipped",
+			target: "https://example.com",
+			groups: []out.EquivalentDomainGroupResponse{excluded, own},
+			want:   []string{"example.com", "example.org"},
+		},
+		{
+			name:   "public suffix keeps unrelated registrable domains apart",
+			target: "https://notgoogle.co.uk",
+			groups: []out.EquivalentDomainGroupResponse{google},
+			want:   []string{"notgoogle.co.uk"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := urlmatch.Parse(tt.target)
+			if err != nil {
+				t.Fatalf("Parse(%s): %v", tt.target, err)
+			}
+			service := &equivalentDomainService{EquivalentDomainRepository: fakeEquivalentDomainRepository{groups: tt.groups}}
+
+			keys, err := service.MatchKeys(1, target)
+			if err != nil {
+				t.Fatalf("MatchKeys: %v", err)
+			}
+			want := make(map[string]bool, len(tt.want))
+			for _, key := range tt.want {
+				want[key] = true
+			}
+			if !reflect.DeepEqual(keys, want) {
+				t.Errorf("MatchKeys = %v, want %v", keys, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRequest matches the errors caused by the client's input rather than by the service, which controllers
+// answer with 400
+var ErrInvalidRequest = errors.New("invalid request")
+
+var ErrEntryNotFound = errors.New("password entry not found")
+
+type requestError struct {
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func (e *requestError) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+func invalidRequest(format string, args ...interface{}) error {
+	return &requestError{message: fmt.Sprintf(format, args...)}
+}
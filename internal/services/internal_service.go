@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/redis"
+)
+
+type InternalService interface {
+	BootstrapUserKey(userID uint, service string) (interface{}, error)
+	DeleteUser(userID uint, service string) (interface{}, error)
+	GetStatistics(userID *uint) (interface{}, error)
+}
+
+type internalService struct {
+	UserRepository    repository.UserRepository
+	UserKeyRepository repository.UserKeysRepository
+	VaultRepository   repository.VaultRepository
+	AttachmentService AttachmentService
+	EncryptionService encryption.Encryption
+	Redis             redis.RedisService
+}
+
+func NewInternalService(
+	userRepository repository.UserRepository,
+	userKeyRepository repository.UserKeysRepository,
+	vaultRepository repository.VaultRepository,
+	attachmentService AttachmentService,
+	encryptionService encryption.Encryption,
+	redis redis.RedisService) InternalService {
+	return &internalService{
+		UserRepository:    userRepository,
+		UserKeyRepository: userKeyRepository,
+		VaultRepository:   vaultRepository,
+		AttachmentService: attachmentService,
+		EncryptionService: encryptionService,
+		Redis:             redis,
+	}
+}
+
+// BootstrapUserKey creates the user's key pair if it does not exist yet. It is safe to call repeatedly.
+func (s *internalService) BootstrapUserKey(userID uint, service string) (interface{}, error) {
+	user, err := s.UserRepository.GetUserByID(userID)
+	if err != nil {
+		log.Error().Str("service", service).Uint("userID", userID).Err(err).Msg("Failed to retrieve user")
+		return nil, err
+	}
+
+	key, err := s.UserKeyRepository.GetUserKeys(user.UserID)
+	if err == nil {
+		return out.UserKeyBootstrapResponse{
+			UserID:    user.UserID,
+			PublicKey: key.PublicKey,
+			Created:   false,
+		}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error().Str("service", service).Uint("userID", userID).Err(err).Msg("Failed to retrieve user keys")
+		return nil, err
+	}
+
+	userKey, err := s.EncryptionService.GenerateUserKey(user)
+	if err != nil {
+		log.Error().Str("service", service).Uint("userID", userID).Err(err).Msg("Failed to generate user key pair")
+		return nil, err
+	}
+	userKey.CreatedBy = &service
+	userKey.UpdatedBy = &service
+	if err := s.UserKeyRepository.AddUserKey(userKey); err != nil {
+		log.Error().Str("service", service).Uint("userID", userID).Err(err).Msg("Failed to add user key")
+		return nil, err
+	}
+
+	log.Info().Str("service", service).Uint("userID", userID).Msg("User key bootstrapped")
+	return out.UserKeyBootstrapResponse{
+		UserID:    user.UserID,
+		PublicKey: userKey.PublicKey,
+		Created:   true,
+	}, nil
+}
+
+// DeleteUser wipes all vault data of a user, including attachment blobs, and drops the user's vault state in Redis
+func (s *internalService) DeleteUser(userID uint, service string) (interface{}, error) {
+	result, attachments, err := s.VaultRepository.DeleteUserData(userID)
+	if err != nil {
+		log.Error().Str("service", service).Uint("userID", userID).Err(err).Msg("Failed to delete user data")
+		return nil, err
+	}
+	s.AttachmentService.PurgeBlobs(attachments)
+
+	if user, err := s.UserRepository.GetUserByIDWithDeleted(userID); err == nil {
+		for _, key := range []string{utils.VaultSession, utils.PinVerify, utils.PinAttempts, utils.PinLockout, utils.WebAuthnChallenge} {
+			if err := s.Redis.DeleteData(key, user.ClientID); err != nil {
+				log.Error().Str("service", service).Uint("userID", userID).Err(err).Msg("Failed to delete user state from Redis")
+			}
+		}
+	}
+
+	log.Info().Str("service", service).Uint("userID", userID).Int64("entries", result.Entries).Msg("User data deleted")
+	return result, nil
+}
+
+func (s *internalService) GetStatistics(userID *uint) (interface{}, error) {
+	statistics, err := s.VaultRepository.GetStatistics(userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retrieve vault statistics")
+		return nil, err
+	}
+	return statistics, nil
+}
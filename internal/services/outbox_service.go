@@ -0,0 +1,81 @@
+package services
+
+import (
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils/event"
+	"time"
+)
+
+type OutboxService interface {
+	RelayEvents() (int, error)
+	MarkExpiredEntries() (int, error)
+	DeletePublishedEvents() (int64, error)
+}
+
+type outboxService struct {
+	OutboxRepository        repository.OutboxRepository
+	PasswordEntryRepository repository.PasswordEntryRepository
+	Publisher               event.Publisher
+	SubjectPrefix           string
+	BatchSize               int
+	Retention               time.Duration
+}
+
+func NewOutboxService(
+	outboxRepository repository.OutboxRepository,
+	passwordEntryRepository repository.PasswordEntryRepository,
+	publisher event.Publisher,
+	subjectPrefix string,
+	batchSize int,
+	retention time.Duration) OutboxService {
+	return &outboxService{
+		OutboxRepository:        outboxRepository,
+		PasswordEntryRepository: passwordEntryRepository,
+		Publisher:               publisher,
+		SubjectPrefix:           subjectPrefix,
+		BatchSize:               batchSize,
+		Retention:               retention,
+	}
+}
+
+// RelayEvents publishes committed outbox rows to NATS on <prefix><event type>. A row is only marked
+// published after the broker accepted it, so events are delivered at least once.
+func (s *outboxService) RelayEvents() (int, error) {
+	total := 0
+	for {
+		published, err := s.OutboxRepository.ProcessPendingEvents(s.BatchSize, func(outboxEvent *password.OutboxEvent) error {
+			return s.Publisher.Publish(s.SubjectPrefix+outboxEvent.EventType, outboxEvent.EventID, []byte(outboxEvent.Payload))
+		})
+		total += published
+		if err != nil {
+			log.Error().Err(err).Int("published", total).Msg("Failed to relay outbox events")
+			return total, err
+		}
+		if published < s.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (s *outboxService) MarkExpiredEntries() (int, error) {
+	marked, err := s.PasswordEntryRepository.MarkExpiredEntries(s.BatchSize)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to mark expired password entries")
+		return 0, err
+	}
+	if marked > 0 {
+		log.Info().Int("entries", marked).Msg("Expired password entries recorded")
+	}
+	return marked, nil
+}
+
+func (s *outboxService) DeletePublishedEvents() (int64, error) {
+	deleted, err := s.OutboxRepository.DeletePublishedEvents(time.Now().Add(-s.Retention))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete published outbox events")
+		return 0, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/entrytype"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/text"
+	"password-management-service/internal/utils/webauthn"
+	"strconv"
+	"strings"
+)
+
+// passkeyFlags are set on every ceremony: an unlocked vault stands in for user presence and verification, and the
+// credential is synced, so it is backup eligible and backed up
+const passkeyFlags = webauthn.FlagUserPresent | webauthn.FlagUserVerified | webauthn.FlagBackupEligible | webauthn.FlagBackupState
+
+type PasskeyService interface {
+	GetAttestation(entryID uint, clientID string, vaultKey []byte) (interface{}, error)
+	Assert(entryID uint, req *in.PasskeyAssertionRequest, clientID string, vaultKey []byte, metadata in.RequestMetadata) (interface{}, error)
+}
+
+type passkeyService struct {
+	UserRepository              repository.UserRepository
+	UserKeyRepository           repository.UserKeysRepository
+	PasswordEntryRepository     repository.PasswordEntryRepository
+	PasswordEntryKeyRepository  repository.PasswordEntryKeysRepository
+	PasswordAccessLogRepository repository.PasswordAccessLogRepository
+	EncryptionService           encryption.Encryption
+	Redis                       redis.RedisService
+}
+
+func NewPasskeyService(
+	userRepository repository.UserRepository,
+	userKeyRepository repository.UserKeysRepository,
+	passwordEntryRepository repository.PasswordEntryRepository,
+	passwordEntryKeysRepository repository.PasswordEntryKeysRepository,
+	passwordAccessLogRepository repository.PasswordAccessLogRepository,
+	encryptionService encryption.Encryption,
+	redis redis.RedisService) PasskeyService {
+	return &passkeyService{
+		UserRepository:              userRepository,
+		UserKeyRepository:           userKeyRepository,
+		PasswordEntryRepository:     passwordEntryRepository,
+		PasswordEntryKeyRepository:  passwordEntryKeysRepository,
+		PasswordAccessLogRepository: passwordAccessLogRepository,
+		EncryptionService:           encryptionService,
+		Redis:                       redis,
+	}
+}
+
+// passkeyDocument is a decrypted passkey with what is needed to write it back under the same entry key
+type passkeyDocument struct {
+	owner      *user.Users
+	entry      *password.PasswordEntry
+	data       map[string]string
+	privateKey *ecdsa.PrivateKey
+	signCount  uint32
+	entryKey   string
+	userKey    *rsa.PrivateKey
+}
+
+// GetAttestation returns the registration response of the passkey, for clients that save a new passkey to the
+// vault while the relying party is waiting for it. The credential is attested with the "none" format.
+func (s *passkeyService) GetAttestation(entryID uint, clientID string, vaultKey []byte) (interface{}, error) {
+	passkey, err := s.openPasskey(entryID, clientID, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID, _ := webauthn.DecodeBase64URL(passkey.data["credential_id"])
+	publicKey, err := webauthn.EncodeCOSEKey(&passkey.privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	attested := webauthn.AttestedCredentialData([16]byte{}, credentialID, publicKey)
+	authData := webauthn.AuthenticatorData(passkey.data["rp_id"], passkeyFlags, passkey.signCount, attested)
+	attestationObject, err := webauthn.NoneAttestationObject(authData)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to encode attestation object")
+		return nil, err
+	}
+
+	return out.PasskeyAttestationResponse{
+		EntryID:           passkey.entry.EntryID,
+		CredentialID:      passkey.data["credential_id"],
+		AttestationObject: webauthn.EncodeBase64URL(attestationObject),
+		AuthenticatorData: webauthn.EncodeBase64URL(authData),
+		PublicKey:         webauthn.EncodeBase64URL(publicKey),
+		Algorithm:         webauthn.AlgES256,
+		UserHandle:        passkey.data["user_handle"],
+	}, nil
+}
+
+// Assert signs an authentication ceremony with the passkey. The incremented sign count is stored before the
+// signature is returned, so a signature is never handed out with a count that was already used.
+func (s *passkeyService) Assert(entryID uint, req *in.PasskeyAssertionRequest, clientID string, vaultKey []byte, metadata in.RequestMetadata) (interface{}, error) {
+	clientDataHash, err := webauthn.DecodeBase64URL(req.ClientDataHash)
+	if err != nil || len(clientDataHash) != 32 {
+		return nil, errors.New("client_data_hash must be a base64url encoded SHA-256 hash")
+	}
+
+	passkey, err := s.openPasskey(entryID, clientID, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(strings.TrimSpace(req.RPID)) != passkey.data["rp_id"] {
+		log.Warn().Str("clientID", clientID).Uint("entryID", passkey.entry.EntryID).Str("rpID", req.RPID).Msg("Passkey requested for another relying party")
+		return nil, errors.New("passkey does not belong to this relying party")
+	}
+
+	signCount := passkey.signCount + 1
+	if signCount == 0 {
+		return nil, errors.New("passkey sign count is exhausted")
+	}
+	authData := webauthn.AuthenticatorData(passkey.data["rp_id"], passkeyFlags, signCount, nil)
+	signature, err := webauthn.SignAssertion(passkey.privateKey, authData, clientDataHash)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to sign passkey assertion")
+		return nil, err
+	}
+
+	passkey.data["sign_count"] = strconv.FormatUint(uint64(signCount), 10)
+	document, err := json.Marshal(passkey.data)
+	if err != nil {
+		return nil, err
+	}
+	encryptedData, err := s.EncryptionService.EncryptPasswordEntryField(string(document), passkey.entryKey, passkey.userKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to encrypt passkey")
+		return nil, err
+	}
+	if err := s.PasswordEntryRepository.UpdateEncryptedData(passkey.entry.EntryID, text.DerefString(passkey.entry.EncryptedData), encryptedData); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to update passkey sign count")
+		return nil, err
+	}
+
+	accessLog := password.PasswordAccessLog{
+		EntryID:   passkey.entry.EntryID,
+		UserID:    passkey.owner.UserID,
+		Action:    utils.AccessActionPasskey,
+		Fields:    pq.StringArray{"private_key"},
+		RequestID: text.NilIfEmpty(metadata.RequestID),
+		IPAddress: text.NilIfEmpty(metadata.IPAddress),
+		UserAgent: text.NilIfEmpty(metadata.UserAgent),
+		CreatedBy: &clientID,
+	}
+	if err := s.PasswordAccessLogRepository.AddAccessLog(&accessLog); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to record passkey assertion")
+		return nil, err
+	}
+
+	log.Info().Str("clientID", clientID).Uint("entryID", passkey.entry.EntryID).Str("rpID", passkey.data["rp_id"]).
+		Uint32("signCount", signCount).Msg("Passkey assertion signed")
+
+	return out.PasskeyAssertionResponse{
+		EntryID:           passkey.entry.EntryID,
+		CredentialID:      passkey.data["credential_id"],
+		AuthenticatorData: webauthn.EncodeBase64URL(authData),
+		Signature:         webauthn.EncodeBase64URL(signature),
+		UserHandle:        passkey.data["user_handle"],
+		SignCount:         signCount,
+	}, nil
+}
+
+func (s *passkeyService) openPasskey(entryID uint, clientID string, vaultKey []byte) (*passkeyDocument, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	owner, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+	if owner == nil {
+		log.Error().Str("clientID", clientID).Msg("User not found")
+		return nil, errors.New("user not found")
+	}
+
+	entry, err := s.PasswordEntryRepository.GetPasswordEntryByEntryIDAndUserID(entryID, owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry")
+		return nil, err
+	}
+	if entry == nil {
+		log.Error().Str("clientID", clientID).Msg("Password entry not found")
+		return nil, errors.New("password entry not found")
+	}
+	if entry.EntryType != entrytype.Passkey {
+		return nil, errors.New("password entry is not a passkey")
+	}
+
+	if len(vaultKey) == 0 {
+		log.Error().Str("clientID", clientID).Msg("Vault is locked")
+		return nil, errors.New("vault is locked")
+	}
+	privateKey, err := s.UserKeyRepository.GetPrivateKeyWithDerivedKey(owner.UserID, vaultKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user keys")
+		return nil, err
+	}
+	if privateKey == nil {
+		log.Error().Str("clientID", clientID).Msg("User private key not found")
+		return nil, errors.New("user private key not found")
+	}
+	passwordEntryKey, err := s.PasswordEntryKeyRepository.GetPasswordEntryKeyByEntryID(entry.EntryID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry key")
+		return nil, err
+	}
+	if passwordEntryKey == nil {
+		log.Error().Str("clientID", clientID).Msg("Password entry key not found")
+		return nil, errors.New("password entry key not found")
+	}
+
+	document, err := s.EncryptionService.DecryptPasswordEntryField(text.DerefString(entry.EncryptedData), passwordEntryKey.EncryptedSymmetricKey, privateKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decrypt passkey")
+		return nil, err
+	}
+	var keyData map[string]string
+	if err := json.Unmarshal([]byte(document), &keyData); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decode passkey")
+		return nil, err
+	}
+	passkeyKey, err := entrytype.PasskeyKey(keyData)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to open passkey")
+		return nil, err
+	}
+	signCount, err := strconv.ParseUint(keyData["sign_count"], 10, 32)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Invalid passkey sign count")
+		return nil, err
+	}
+
+	return &passkeyDocument{
+		owner:      owner,
+		entry:      entry,
+		data:       keyData,
+		privateKey: passkeyKey,
+		signCount:  uint32(signCount),
+		entryKey:   passwordEntryKey.EncryptedSymmetricKey,
+		userKey:    privateKey,
+	}, nil
+}
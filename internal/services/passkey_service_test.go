@@ -0,0 +1,313 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/entrytype"
+	"password-management-service/internal/utils/webauthn"
+	"testing"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// fakePasskeyRepository holds one passkey entry. stored is the row; served is what reads return, which a test can
+// point at an older ciphertext to act as a request that read the entry before another assertion committed.
+type fakePasskeyRepository struct {
+	repository.PasswordEntryRepository
+	stored string
+	served string
+}
+
+func (f *fakePasskeyRepository) GetPasswordEntryByEntryIDAndUserID(entryID, userID uint) (*password.PasswordEntry, error) {
+	served := f.served
+	return &password.PasswordEntry{EntryID: entryID, UserID: userID, EntryType: entrytype.Passkey, EncryptedData: &served}, nil
+}
+
+func (f *fakePasskeyRepository) UpdateEncryptedData(entryID uint, current, updated string) error {
+	if current != f.stored {
+		return repository.ErrEntryDataChanged
+	}
+	f.stored = updated
+	f.served = updated
+	return nil
+}
+
+type fakeAccessLogRepository struct {
+	repository.PasswordAccessLogRepository
+	logs []password.PasswordAccessLog
+}
+
+func (f *fakeAccessLogRepository) AddAccessLog(accessLog *password.PasswordAccessLog) error {
+	f.logs = append(f.logs, *accessLog)
+	return nil
+}
+
+type passkeyFixture struct {
+	service      PasskeyService
+	entries      *fakePasskeyRepository
+	accessLogs   *fakeAccessLogRepository
+	keys         keyFixture
+	entryKey     string
+	credentialID string
+}
+
+// newPasskeyFixture saves a passkey the way an entry request does: the entry type validation generates its key
+func newPasskeyFixture(t *testing.T) passkeyFixture {
+	t.Helper()
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+	result, err := entrytype.Validate(entrytype.Passkey, map[string]string{
+		"rp_id":         testRPID,
+		"credential_id": webauthn.EncodeBase64URL(credentialID),
+		"user_handle":   webauthn.EncodeBase64URL([]byte("user-1")),
+	})
+	if err != nil {
+		t.Fatalf("validate passkey: %v", err)
+	}
+	document, err := json.Marshal(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := newKeyFixture(t)
+	enc := encryption.NewEncryption()
+	aesKey := make([]byte, 32)
+	if _, err := rand.Read(aesKey); err != nil {
+		t.Fatal(err)
+	}
+	entryKey, err := enc.WrapEntryKey(aesKey, &keys.owner.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedData, err := enc.EncryptPasswordEntryField(string(document), entryKey, keys.owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := &fakePasskeyRepository{stored: encryptedData, served: encryptedData}
+	accessLogs := &fakeAccessLogRepository{}
+	return passkeyFixture{
+		service: NewPasskeyService(
+			fakeUserRepository{},
+			fakeUserKeyRepository{privateKey: keys.owner},
+			entries,
+			fakeEntryKeyRepository{keys: map[uint]string{1: entryKey}},
+			accessLogs,
+			enc,
+			fakeRedis{}),
+		entries:      entries,
+		accessLogs:   accessLogs,
+		keys:         keys,
+		entryKey:     entryKey,
+		credentialID: result.Data["credential_id"],
+	}
+}
+
+// storedSignCount decrypts the passkey as it is stored
+func (f passkeyFixture) storedSignCount(t *testing.T) string {
+	t.Helper()
+	document, err := encryption.NewEncryption().DecryptPasswordEntryField(f.entries.stored, f.entryKey, f.keys.owner)
+	if err != nil {
+		t.Fatalf("decrypt passkey: %v", err)
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(document), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data["sign_count"]
+}
+
+// register verifies the attestation of the passkey as a relying party would and returns its COSE public key
+func (f passkeyFixture) register(t *testing.T) []byte {
+	t.Helper()
+
+	response, err := f.service.GetAttestation(1, testClientID, []byte("vault-key"))
+	if err != nil {
+		t.Fatalf("GetAttestation: %v", err)
+	}
+	attestationResponse := response.(out.PasskeyAttestationResponse)
+
+	attestationObject, err := webauthn.DecodeBase64URL(attestationResponse.AttestationObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attestation, err := webauthn.VerifyAttestation(attestationObject, make([]byte, 32))
+	if err != nil {
+		t.Fatalf("VerifyAttestation: %v", err)
+	}
+	if attestation.Format != webauthn.FormatNone || attestation.Type != webauthn.AttestationNone {
+		t.Errorf("attestation = %s/%s, want none/none", attestation.Format, attestation.Type)
+	}
+	if err := attestation.AuthData.VerifyRPID(testRPID); err != nil {
+		t.Errorf("VerifyRPID: %v", err)
+	}
+	if !attestation.AuthData.Has(webauthn.FlagUserPresent | webauthn.FlagUserVerified) {
+		t.Errorf("flags = %#x, want user present and verified", attestation.AuthData.Flags)
+	}
+	if got := webauthn.EncodeBase64URL(attestation.AuthData.CredentialID); got != f.credentialID {
+		t.Errorf("credential ID = %s, want %s", got, f.credentialID)
+	}
+	if got := webauthn.EncodeBase64URL(attestation.AuthData.PublicKey); got != attestationResponse.PublicKey {
+		t.Errorf("attested public key differs from the returned one")
+	}
+	if algorithm, err := webauthn.Algorithm(attestation.AuthData.PublicKey); err != nil || algorithm != webauthn.AlgES256 {
+		t.Errorf("algorithm = %d, %v, want %d", algorithm, err, webauthn.AlgES256)
+	}
+	return attestation.AuthData.PublicKey
+}
+
+// assert runs an authentication ceremony and returns the client data JSON with the response
+func (f passkeyFixture) assert(t *testing.T, rpID string) ([]byte, out.PasskeyAssertionResponse, error) {
+	t.Helper()
+
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		t.Fatal(err)
+	}
+	clientDataJSON, err := json.Marshal(webauthn.ClientData{
+		Type:      webauthn.CeremonyGet,
+		Challenge: webauthn.EncodeBase64URL(challenge),
+		Origin:    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+
+	response, err := f.service.Assert(1, &in.PasskeyAssertionRequest{
+		RPID:           rpID,
+		ClientDataHash: webauthn.EncodeBase64URL(clientDataHash[:]),
+	}, testClientID, []byte("vault-key"), in.RequestMetadata{RequestID: "request-1"})
+	if err != nil {
+		return clientDataJSON, out.PasskeyAssertionResponse{}, err
+	}
+	return clientDataJSON, response.(out.PasskeyAssertionResponse), nil
+}
+
+// verifyAssertion checks an assertion as a relying party would and returns the sign count it carries
+func verifyAssertion(t *testing.T, publicKey, clientDataJSON []byte, response out.PasskeyAssertionResponse) uint32 {
+	t.Helper()
+
+	authData, err := webauthn.DecodeBase64URL(response.AuthenticatorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := webauthn.DecodeBase64URL(response.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := webauthn.VerifyAssertion(publicKey, authData, clientDataJSON, signature); err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+
+	parsed, err := webauthn.ParseAuthenticatorData(authData)
+	if err != nil {
+		t.Fatalf("ParseAuthenticatorData: %v", err)
+	}
+	if err := parsed.VerifyRPID(testRPID); err != nil {
+		t.Errorf("VerifyRPID: %v", err)
+	}
+	if parsed.Has(webauthn.FlagAttestedCredentialData) {
+		t.Error("assertion carries attested credential data")
+	}
+	if parsed.SignCount != response.SignCount {
+		t.Errorf("authenticator data sign count = %d, response says %d", parsed.SignCount, response.SignCount)
+	}
+	return parsed.SignCount
+}
+
+func TestPasskeyRegisterAndAssert(t *testing.T) {
+	fixture := newPasskeyFixture(t)
+	publicKey := fixture.register(t)
+
+	var last uint32
+	for i := 0; i < 2; i++ {
+		clientDataJSON, response, err := fixture.assert(t, testRPID)
+		if err != nil {
+			t.Fatalf("Assert: %v", err)
+		}
+		if response.CredentialID != fixture.credentialID {
+			t.Errorf("credential ID = %s, want %s", response.CredentialID, fixture.credentialID)
+		}
+		signCount := verifyAssertion(t, publicKey, clientDataJSON, response)
+		if signCount <= last {
+			t.Errorf("sign count %d did not increase past %d", signCount, last)
+		}
+		last = signCount
+	}
+
+	if got := fixture.storedSignCount(t); got != "2" {
+		t.Errorf("stored sign count = %s, want 2", got)
+	}
+	if len(fixture.accessLogs.logs) != 2 {
+		t.Errorf("recorded %d assertions, want 2", len(fixture.accessLogs.logs))
+	}
+}
+
+// A request that read the passkey before another assertion was stored must not sign with a reused count
+func TestPasskeyAssertRejectsStaleSignCount(t *testing.T) {
+	fixture := newPasskeyFixture(t)
+	fixture.register(t)
+
+	stale := fixture.entries.stored
+	if _, _, err := fixture.assert(t, testRPID); err != nil {
+		t.Fatalf("Assert: %v", err)
+	}
+
+	fixture.entries.served = stale
+	_, response, err := fixture.assert(t, testRPID)
+	if !errors.Is(err, repository.ErrEntryDataChanged) {
+		t.Fatalf("Assert on a stale read err = %v, want ErrEntryDataChanged", err)
+	}
+	if response.Signature != "" {
+		t.Error("a signature was returned for a reused sign count")
+	}
+	if got := fixture.storedSignCount(t); got != "1" {
+		t.Errorf("stored sign count = %s, want 1", got)
+	}
+	if len(fixture.accessLogs.logs) != 1 {
+		t.Errorf("recorded %d assertions, want 1", len(fixture.accessLogs.logs))
+	}
+}
+
+func TestPasskeyAssertRejectsOtherRelyingParty(t *testing.T) {
+	fixture := newPasskeyFixture(t)
+	before := fixture.entries.stored
+
+	if _, _, err := fixture.assert(t, "evil.example"); err == nil {
+		t.Fatal("Assert signed for another relying party")
+	}
+	if fixture.entries.stored != before {
+		t.Error("the passkey was updated for a rejected assertion")
+	}
+}
+
+func TestPasskeyAssertRejectsLockedVault(t *testing.T) {
+	fixture := newPasskeyFixture(t)
+	before := fixture.entries.stored
+	hash := sha256.Sum256([]byte("{}"))
+
+	_, err := fixture.service.Assert(1, &in.PasskeyAssertionRequest{
+		RPID:           testRPID,
+		ClientDataHash: webauthn.EncodeBase64URL(hash[:]),
+	}, testClientID, nil, in.RequestMetadata{})
+	if err == nil {
+		t.Fatal("Assert signed with a locked vault")
+	}
+	if fixture.entries.stored != before {
+		t.Error("the passkey was updated with a locked vault")
+	}
+}
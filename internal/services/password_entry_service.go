@@ -1,39 +1,72 @@
 package services
 
 import (
+	"crypto/rsa"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
 	"password-management-service/internal/dto/in"
 	"password-management-service/internal/dto/out"
 	"password-management-service/internal/models/password"
+	"password-management-service/internal/models/user"
 	"password-management-service/internal/repository"
 	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/customfield"
 	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/entrytype"
+	"password-management-service/internal/utils/policy"
 	"password-management-service/internal/utils/redis"
 	"password-management-service/internal/utils/text"
+	"password-management-service/internal/utils/urlmatch"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxBulkEntries   = 500
+	maxRecentEntries = 100
+	maxMatchEntries  = 50
 )
 
 type PasswordEntryService interface {
-	AddPasswordEntry(passwordEntryRequest *in.PasswordEntryRequest, clientID string, requestID string) error
-	UpdatePasswordEntry(passwordEntryID uint, passwordEntryRequest *in.PasswordEntryRequest, clientID string) error
+	AddPasswordEntry(passwordEntryRequest *in.PasswordEntryRequest, clientID string) error
+	UpdatePasswordEntry(passwordEntryID uint, passwordEntryRequest *in.PasswordEntryRequest, clientID string, vaultKey []byte) error
 	AddGroupPasswordEntry(req struct {
 		GroupID uint `json:"group_id"`
 		EntryID uint `json:"entry_id"`
-	}, clientID string) error
-	GetPasswordEntryByID(passwordEntryID uint, clientID string) (interface{}, error)
-	GetListPasswordEntries(clientID string, tags string, index int, size int) (interface{}, int64, error)
+	}, clientID string, vaultKey []byte) error
+	BulkPasswordEntries(req *in.BulkPasswordEntryRequest, clientID string, vaultKey []byte) (*out.BulkPasswordEntryResponse, error)
+	GetPasswordEntryByID(passwordEntryID uint, clientID string, vaultKey []byte) (interface{}, error)
+	RevealPasswordEntry(passwordEntryID uint, req *in.RevealPasswordEntryRequest, clientID string, vaultKey []byte, metadata in.RequestMetadata) (interface{}, error)
+	GetListPasswordEntries(clientID string, tags string, entryType string, favorite bool, sort string, index int, size int) (interface{}, int64, error)
+	GetRecentPasswordEntries(clientID string, limit int) (interface{}, error)
+	GetMatchingPasswordEntries(clientID string, rawURL string) (interface{}, error)
+	SetFavorite(passwordEntryID uint, clientID string, favorite bool) error
+	SetPinned(passwordEntryID uint, clientID string, pinned bool) error
 	DeletePasswordEntry(passwordEntryID uint, clientID string) error
 }
 
 type passwordEntryService struct {
-	UserRepository             repository.UserRepository
-	UserKeyRepository          repository.UserKeysRepository
-	PasswordEntryRepository    repository.PasswordEntryRepository
-	PasswordEntryKeyRepository repository.PasswordEntryKeysRepository
-	PasswordTagRepository      repository.PasswordTagRepository
-	PasswordGroupRepository    repository.PasswordGroupRepository
-	EncryptionService          encryption.Encryption
-	Redis                      redis.RedisService
+	UserRepository               repository.UserRepository
+	UserKeyRepository            repository.UserKeysRepository
+	PasswordEntryRepository      repository.PasswordEntryRepository
+	PasswordEntryKeyRepository   repository.PasswordEntryKeysRepository
+	PasswordTagRepository        repository.PasswordTagRepository
+	PasswordGroupRepository      repository.PasswordGroupRepository
+	PasswordEntryGroupRepository repository.PasswordEntryGroupRepository
+	PasswordEntryURLRepository   repository.PasswordEntryURLRepository
+	PasswordAccessLogRepository  repository.PasswordAccessLogRepository
+	BreachService                BreachService
+	PasswordPolicyService        PasswordPolicyService
+	AttachmentService            AttachmentService
+	EquivalentDomainService      EquivalentDomainService
+	ServiceAccountKeyService     ServiceAccountKeyService
+	EncryptionService            encryption.Encryption
+	Redis                        redis.RedisService
 }
 
 func NewPasswordEntryService(
@@ -43,39 +76,44 @@ func NewPasswordEntryService(
 	passwordEntryKeysRepository repository.PasswordEntryKeysRepository,
 	PasswordTagRepository repository.PasswordTagRepository,
 	PasswordGroupRepository repository.PasswordGroupRepository,
+	passwordEntryGroupRepository repository.PasswordEntryGroupRepository,
+	passwordEntryURLRepository repository.PasswordEntryURLRepository,
+	passwordAccessLogRepository repository.PasswordAccessLogRepository,
+	breachService BreachService,
+	passwordPolicyService PasswordPolicyService,
+	attachmentService AttachmentService,
+	equivalentDomainService EquivalentDomainService,
+	serviceAccountKeyService ServiceAccountKeyService,
 	encryptionService encryption.Encryption,
 	redis redis.RedisService) PasswordEntryService {
 	return &passwordEntryService{
-		UserRepository:             userRepository,
-		UserKeyRepository:          userKeyRepository,
-		PasswordEntryRepository:    passwordEntryRepository,
-		PasswordEntryKeyRepository: passwordEntryKeysRepository,
-		PasswordTagRepository:      PasswordTagRepository,
-		PasswordGroupRepository:    PasswordGroupRepository,
-		EncryptionService:          encryptionService,
-		Redis:                      redis,
+		UserRepository:               userRepository,
+		UserKeyRepository:            userKeyRepository,
+		PasswordEntryRepository:      passwordEntryRepository,
+		PasswordEntryKeyRepository:   passwordEntryKeysRepository,
+		PasswordTagRepository:        PasswordTagRepository,
+		PasswordGroupRepository:      PasswordGroupRepository,
+		PasswordEntryGroupRepository: passwordEntryGroupRepository,
+		PasswordEntryURLRepository:   passwordEntryURLRepository,
+		PasswordAccessLogRepository:  passwordAccessLogRepository,
+		BreachService:                breachService,
+		PasswordPolicyService:        passwordPolicyService,
+		AttachmentService:            attachmentService,
+		EquivalentDomainService:      equivalentDomainService,
+		ServiceAccountKeyService:     serviceAccountKeyService,
+		EncryptionService:            encryptionService,
+		Redis:                        redis,
 	}
 }
 
-func (s *passwordEntryService) AddPasswordEntry(passwordEntryRequest *in.PasswordEntryRequest, clientID string, verifyCode string) error {
+// AddPasswordEntry stores a new entry; the route requires step-up, so the PIN or authenticator was checked before
+func (s *passwordEntryService) AddPasswordEntry(passwordEntryRequest *in.PasswordEntryRequest, clientID string) error {
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
 		return err
 	}
 
-	// Verify the code
-	var verify *out.VerifyPinCodeResponse
-	if err := s.Redis.GetData(utils.PinVerify, data.ClientID, &verify); err != nil {
-		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to verify code")
-		return err
-	}
-
-	if verify.RequestID != verifyCode {
-		log.Error().Str("clientID", clientID).Msg("Invalid verification code")
-		return errors.New("invalid verification code")
-	}
-
 	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
 	if err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
@@ -101,34 +139,79 @@ func (s *passwordEntryService) AddPasswordEntry(passwordEntryRequest *in.Passwor
 		return err
 	}
 
-	encryptedUsername, encryptPassword, notes, wrappedKey, err := s.EncryptionService.EncryptPasswordEntry(passwordEntryRequest.Username, passwordEntryRequest.Password, text.DerefString(passwordEntryRequest.Notes), publicKey)
+	document, err := encodeEntryDocument(passwordEntryRequest)
+	if err != nil {
+		return err
+	}
+	primaryURL, urls, err := entryURLs(passwordEntryRequest)
+	if err != nil {
+		return err
+	}
+	customFields, err := encodeCustomFields(passwordEntryRequest.CustomFields)
+	if err != nil {
+		return err
+	}
+
+	// The fresh key is wrapped for every service account up front; the repository keeps the keys of the accounts
+	// whose scope covers the entry
+	recipients, err := s.ServiceAccountKeyService.GetRecipients(user.UserID)
+	if err != nil {
+		return err
+	}
+	encrypted, wrappedKey, serviceAccountKeys, err := s.EncryptionService.EncryptPasswordEntryFor(encryption.EntryFields{
+		Username:     passwordEntryRequest.Username,
+		Password:     passwordEntryRequest.Password,
+		Notes:        text.DerefString(passwordEntryRequest.Notes),
+		CustomFields: customFields,
+		Data:         document.Data,
+	}, publicKey, recipients)
 	if err != nil {
 		return err
 	}
 
 	passwordEntry := password.PasswordEntry{
 		Title:             passwordEntryRequest.Title,
+		EntryType:         document.EntryType,
 		UserID:            user.UserID,
-		Username:          encryptedUsername,
-		EncryptedPassword: encryptPassword,
-		EncryptedNotes:    &notes,
-		URL:               passwordEntryRequest.URL,
+		Username:          encrypted.Username,
+		EncryptedPassword: encrypted.Password,
+		EncryptedNotes:    &encrypted.Notes,
+		EncryptedCustom:   text.NilIfEmpty(encrypted.CustomFields),
+		EncryptedData:     text.NilIfEmpty(encrypted.Data),
+		Metadata:          document.Metadata,
+		ExpiresAt:         document.ExpiresAt,
+		URL:               primaryURL,
 		CreatedBy:         &clientID,
 		UpdatedBy:         &clientID,
 	}
+	s.BreachService.ResetStatus(&passwordEntry)
+	if document.EntryType == entrytype.Login {
+		if err := s.PasswordPolicyService.ApplyPolicies(&passwordEntry, nil, passwordEntryRequest.Password); err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Password entry rejected by policy")
+			return err
+		}
+	}
 
 	passwordEntryKey := password.PasswordEntryKey{
 		EncryptedSymmetricKey: wrappedKey,
 	}
 
-	if err := s.PasswordEntryRepository.AddPasswordEntry(&passwordEntry, &passwordEntryKey, *passwordEntryRequest.Tags, user.UserID); err != nil {
+	var tags pq.StringArray
+	if passwordEntryRequest.Tags != nil {
+		tags = normalizeTags(*passwordEntryRequest.Tags)
+	}
+
+	if err := s.PasswordEntryRepository.AddPasswordEntry(&passwordEntry, &passwordEntryKey, tags, urls, user.UserID, serviceAccountKeys); err != nil {
 		return err
 	}
+	s.BreachService.CheckPassword(passwordEntry, passwordEntryRequest.Password)
 
 	return nil
 }
 
-func (s *passwordEntryService) UpdatePasswordEntry(passwordEntryID uint, passwordEntryRequest *in.PasswordEntryRequest, clientID string) error {
+// UpdatePasswordEntry re-encrypts the entry under a new key. Attachment keys are wrapped by the entry key, so
+// entries with attachments can only be updated with an unlocked vault.
+func (s *passwordEntryService) UpdatePasswordEntry(passwordEntryID uint, passwordEntryRequest *in.PasswordEntryRequest, clientID string, vaultKey []byte) error {
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
@@ -166,7 +249,32 @@ func (s *passwordEntryService) UpdatePasswordEntry(passwordEntryID uint, passwor
 		return err
 	}
 
-	encryptedUsername, encryptPassword, notes, wrappedKey, err := s.EncryptionService.EncryptPasswordEntry(passwordEntryRequest.Username, passwordEntryRequest.Password, text.DerefString(passwordEntryRequest.Notes), publicKey)
+	document, err := encodeEntryDocument(passwordEntryRequest)
+	if err != nil {
+		return err
+	}
+	primaryURL, urls, err := entryURLs(passwordEntryRequest)
+	if err != nil {
+		return err
+	}
+	customFields, err := encodeCustomFields(passwordEntryRequest.CustomFields)
+	if err != nil {
+		return err
+	}
+
+	// The fresh key is wrapped for every service account up front; the repository keeps the keys of the accounts
+	// whose scope covers the entry
+	recipients, err := s.ServiceAccountKeyService.GetRecipients(user.UserID)
+	if err != nil {
+		return err
+	}
+	encrypted, wrappedKey, serviceAccountKeys, err := s.EncryptionService.EncryptPasswordEntryFor(encryption.EntryFields{
+		Username:     passwordEntryRequest.Username,
+		Password:     passwordEntryRequest.Password,
+		Notes:        text.DerefString(passwordEntryRequest.Notes),
+		CustomFields: customFields,
+		Data:         document.Data,
+	}, publicKey, recipients)
 	if err != nil {
 		return err
 	}
@@ -179,73 +287,233 @@ func (s *passwordEntryService) UpdatePasswordEntry(passwordEntryID uint, passwor
 	passwordEntry := password.PasswordEntry{
 		EntryID:           entry.EntryID,
 		Title:             passwordEntryRequest.Title,
+		EntryType:         document.EntryType,
 		UserID:            user.UserID,
-		Username:          encryptedUsername,
-		EncryptedPassword: encryptPassword,
-		EncryptedNotes:    &notes,
-		URL:               passwordEntryRequest.URL,
+		Username:          encrypted.Username,
+		EncryptedPassword: encrypted.Password,
+		EncryptedNotes:    &encrypted.Notes,
+		EncryptedCustom:   text.NilIfEmpty(encrypted.CustomFields),
+		EncryptedData:     text.NilIfEmpty(encrypted.Data),
+		Metadata:          document.Metadata,
+		ExpiresAt:         document.ExpiresAt,
+		URL:               primaryURL,
 		Tags:              passwordTags,
 		UpdatedBy:         &clientID,
 	}
+	s.BreachService.ResetStatus(&passwordEntry)
+	if document.EntryType == entrytype.Login {
+		groupIDs, err := s.PasswordEntryGroupRepository.GetGroupIDsByEntryIDs([]uint{entry.EntryID})
+		if err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry groups")
+			return err
+		}
+		if err := s.PasswordPolicyService.ApplyPolicies(&passwordEntry, groupIDs[entry.EntryID], passwordEntryRequest.Password); err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Password entry rejected by policy")
+			return err
+		}
+	}
 
 	passwordEntryKey := password.PasswordEntryKey{
 		EntryID:               entry.EntryID,
 		EncryptedSymmetricKey: wrappedKey,
 	}
 
-	if err := s.PasswordEntryRepository.UpdatePasswordEntryAndEntryKey(passwordEntry, passwordEntryKey); err != nil {
+	attachments, err := s.rewrapAttachments(entry.EntryID, user, wrappedKey, vaultKey)
+	if err != nil {
+		return err
+	}
+
+	if err := s.PasswordEntryRepository.UpdatePasswordEntryAndEntryKey(passwordEntry, passwordEntryKey, attachments, urls, serviceAccountKeys); err != nil {
 		return err
 	}
+	s.BreachService.CheckPassword(passwordEntry, passwordEntryRequest.Password)
 	return nil
 }
 
+// AddGroupPasswordEntry adds a single entry to a group; it is a bulk copy of one entry
 func (s *passwordEntryService) AddGroupPasswordEntry(req struct {
 	GroupID uint `json:"group_id"`
 	EntryID uint `json:"entry_id"`
-}, clientID string) error {
-	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+}, clientID string, vaultKey []byte) error {
+	result, err := s.BulkPasswordEntries(&in.BulkPasswordEntryRequest{
+		Action:   utils.BulkActionCopy,
+		EntryIDs: []uint{req.EntryID},
+		GroupID:  &req.GroupID,
+	}, clientID, vaultKey)
 	if err != nil {
-		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
 		return err
 	}
 
+	item := result.Results[0]
+	if len(item.Violations) > 0 {
+		return &policy.ViolationError{Violations: item.Violations}
+	}
+	if !item.Success {
+		return errors.New(item.Error)
+	}
+	return nil
+}
+
+// BulkPasswordEntries applies one action to many entries. Entries that are not found or violate a group policy
+// are reported and skipped; the others are changed together in one transaction. Moving entries into the scope of a
+// service account needs the unlocked vault to wrap their keys for it.
+func (s *passwordEntryService) BulkPasswordEntries(req *in.BulkPasswordEntryRequest, clientID string, vaultKey []byte) (*out.BulkPasswordEntryResponse, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
 	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
 	if err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
-		return err
+		return nil, err
+	}
+
+	entryIDs := uniqueEntryIDs(req.EntryIDs)
+	if len(entryIDs) == 0 {
+		return nil, errors.New("at least one entry is required")
+	}
+	if len(entryIDs) > maxBulkEntries {
+		return nil, fmt.Errorf("a bulk operation is limited to %d entries", maxBulkEntries)
+	}
+
+	var group *password.PasswordGroup
+	var tags []string
+	switch req.Action {
+	case utils.BulkActionMove, utils.BulkActionCopy, utils.BulkActionRemove:
+		if req.GroupID == nil {
+			return nil, errors.New("group_id is required")
+		}
+		group, err = s.PasswordGroupRepository.GetPasswordGroupByUserIDAndGroupID(user.UserID, *req.GroupID)
+		if err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password group")
+			return nil, errors.New("password group not found")
+		}
+	case utils.BulkActionTag, utils.BulkActionUntag:
+		tags = normalizeTags(req.Tags)
+		if len(tags) == 0 {
+			return nil, errors.New("at least one tag is required")
+		}
+	case utils.BulkActionDelete:
+	default:
+		return nil, fmt.Errorf("unsupported bulk action %q", req.Action)
 	}
 
-	entry, err := s.PasswordEntryRepository.GetPasswordEntryByEntryIDAndUserID(req.EntryID, user.UserID)
+	entries, err := s.PasswordEntryRepository.GetPasswordEntriesByEntryIDsAndUserID(entryIDs, user.UserID)
 	if err != nil {
-		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry")
-		return err
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entries")
+		return nil, err
+	}
+	found := make(map[uint]password.PasswordEntry, len(entries))
+	for _, entry := range entries {
+		found[entry.EntryID] = entry
+	}
+
+	var memberships map[uint][]uint
+	changesGroups := req.Action == utils.BulkActionMove || req.Action == utils.BulkActionCopy
+	if changesGroups {
+		memberships, err = s.PasswordEntryGroupRepository.GetGroupIDsByEntryIDs(entryIDs)
+		if err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry groups")
+			return nil, err
+		}
+	}
+
+	results := make([]out.BulkPasswordEntryResult, len(entryIDs))
+	applicable := make([]password.PasswordEntry, 0, len(entries))
+	for i, entryID := range entryIDs {
+		results[i].EntryID = entryID
+		entry, ok := found[entryID]
+		if !ok {
+			results[i].Error = "password entry not found"
+			continue
+		}
+		if changesGroups {
+			groupIDs := []uint{group.GroupID}
+			if req.Action == utils.BulkActionCopy {
+				groupIDs = append(memberships[entryID], group.GroupID)
+			}
+			if err := s.PasswordPolicyService.CheckGroupMove(&entry, groupIDs); err != nil {
+				var violation *policy.ViolationError
+				if errors.As(err, &violation) {
+					results[i].Error = violation.Error()
+					results[i].Violations = violation.Violations
+					continue
+				}
+				return nil, err
+			}
+		}
+		applicable = append(applicable, entry)
+	}
+
+	if len(applicable) > 0 {
+		if err := s.applyBulkAction(req.Action, applicable, user, group, tags, vaultKey); err != nil {
+			log.Error().Str("clientID", clientID).Str("action", req.Action).Err(err).Msg("Failed to apply bulk action")
+			return nil, err
+		}
+	}
+
+	response := &out.BulkPasswordEntryResponse{Action: req.Action, Results: results}
+	for i := range results {
+		if results[i].Error == "" {
+			results[i].Success = true
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
 	}
-	if entry == nil {
-		log.Error().Str("clientID", clientID).Msg("Password entry not found")
-		return errors.New("password entry not found")
+	return response, nil
+}
+
+func (s *passwordEntryService) applyBulkAction(action string, entries []password.PasswordEntry, owner *user.Users, group *password.PasswordGroup, tags []string, vaultKey []byte) error {
+	clientID := owner.ClientID
+	entryIDs := make([]uint, len(entries))
+	for i, entry := range entries {
+		entryIDs[i] = entry.EntryID
 	}
 
-	group, err := s.PasswordGroupRepository.GetPasswordGroupByUserIDAndGroupID(user.UserID, req.GroupID)
+	// Only moves, copies and tags can bring entries into a service account scope; removals just revoke keys
+	var serviceAccountKeys repository.ServiceAccountKeys
+	var err error
+	switch action {
+	case utils.BulkActionMove, utils.BulkActionCopy:
+		serviceAccountKeys, err = s.ServiceAccountKeyService.WrapEntryKeys(owner, entryIDs, []uint{group.GroupID}, nil, vaultKey)
+	case utils.BulkActionTag:
+		serviceAccountKeys, err = s.ServiceAccountKeyService.WrapEntryKeys(owner, entryIDs, nil, tags, vaultKey)
+	}
 	if err != nil {
-		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password group")
 		return err
 	}
 
-	if group == nil {
-		log.Error().Str("clientID", clientID).Msg("Password group not found")
-		return errors.New("password group not found")
+	switch action {
+	case utils.BulkActionMove:
+		return s.PasswordEntryGroupRepository.MoveEntriesToGroup(entries, group.GroupID, clientID, serviceAccountKeys)
+	case utils.BulkActionCopy:
+		return s.PasswordEntryGroupRepository.AddEntriesToGroup(entries, group.GroupID, clientID, serviceAccountKeys)
+	case utils.BulkActionRemove:
+		return s.PasswordEntryGroupRepository.RemoveEntriesFromGroup(entries, group.GroupID, clientID, serviceAccountKeys)
+	case utils.BulkActionTag:
+		return s.PasswordTagRepository.AddTagsToEntries(entries, owner.UserID, tags, clientID, serviceAccountKeys)
+	case utils.BulkActionUntag:
+		return s.PasswordTagRepository.RemoveTagsFromEntries(entries, owner.UserID, tags, clientID, serviceAccountKeys)
 	}
 
-	entry.GroupID = &group.GroupID
-	if err := s.PasswordEntryRepository.UpdatePasswordEntry(entry); err != nil {
-		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to update password entry")
+	var attachments []password.PasswordAttachment
+	for _, entry := range entries {
+		entryAttachments, err := s.AttachmentService.GetEntryAttachments(entry.EntryID)
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, entryAttachments...)
+	}
+	if err := s.PasswordEntryRepository.DeletePasswordEntries(entries, clientID); err != nil {
 		return err
 	}
-
+	s.AttachmentService.PurgeBlobs(attachments)
 	return nil
 }
 
-func (s *passwordEntryService) GetPasswordEntryByID(passwordEntryID uint, clientID string) (interface{}, error) {
+func (s *passwordEntryService) GetPasswordEntryByID(passwordEntryID uint, clientID string, vaultKey []byte) (interface{}, error) {
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
@@ -261,43 +529,150 @@ func (s *passwordEntryService) GetPasswordEntryByID(passwordEntryID uint, client
 		return nil, errors.New("user not found")
 	}
 
-	passwordEntry, err := s.PasswordEntryRepository.GetPasswordEntryByEntryIDAndUserID(passwordEntryID, user.UserID)
+	detail, err := s.PasswordEntryRepository.GetPasswordEntryDetailByEntryIDAndUserID(passwordEntryID, user.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEntryNotFound
+	}
 	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry")
 		return nil, err
 	}
-	if passwordEntry == nil {
-		log.Error().Str("clientID", clientID).Msg("Password entry not found")
-		return nil, errors.New("password entry not found")
+
+	privateKey, passwordEntryKey, err := s.getEntryDecryptionKeys(detail.EntryID, user, vaultKey)
+	if err != nil {
+		return nil, err
 	}
 
-	privateKey, err := s.UserKeyRepository.GetPrivateKeyByUserID(user.UserID, user.ClientID)
+	entryKey, err := s.EncryptionService.UnwrapEntryKey(passwordEntryKey.EncryptedSymmetricKey, privateKey)
 	if err != nil {
-		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user keys")
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to open password entry key")
 		return nil, err
 	}
-	if privateKey == nil {
-		log.Error().Str("clientID", clientID).Msg("User public key not found")
-		return nil, errors.New("user public key not found")
+	username, err := s.EncryptionService.DecryptEntryField(detail.Username, entryKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decrypt password entry username")
+		return nil, err
 	}
+	detail.MaskedUsername = text.MaskString(username)
 
-	passwordEntryKey, err := s.PasswordEntryKeyRepository.GetPasswordEntryKeyByEntryID(passwordEntry.EntryID)
+	return detail, nil
+}
+
+func (s *passwordEntryService) RevealPasswordEntry(passwordEntryID uint, req *in.RevealPasswordEntryRequest, clientID string, vaultKey []byte, metadata in.RequestMetadata) (interface{}, error) {
+	fields, err := normalizeRevealFields(req.Fields)
 	if err != nil {
-		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry key")
 		return nil, err
 	}
-	if passwordEntryKey == nil {
-		log.Error().Str("clientID", clientID).Msg("Password entry key not found")
-		return nil, errors.New("password entry key not found")
+
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+	if user == nil {
+		log.Error().Str("clientID", clientID).Msg("User not found")
+		return nil, errors.New("user not found")
+	}
+
+	passwordEntry, err := s.PasswordEntryRepository.GetPasswordEntryByEntryIDAndUserID(passwordEntryID, user.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry")
+		return nil, err
+	}
+
+	privateKey, passwordEntryKey, err := s.getEntryDecryptionKeys(passwordEntry.EntryID, user, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+	// The entry key is opened once; every requested field is decrypted with it
+	entryKey, err := s.EncryptionService.UnwrapEntryKey(passwordEntryKey.EncryptedSymmetricKey, privateKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to open password entry key")
+		return nil, err
+	}
+
+	revealed := make(map[string]string, len(fields))
+	var revealedData map[string]string
+	var customFields []customfield.Field
+	for _, field := range fields {
+		if field == utils.RevealFieldData {
+			revealedData, err = s.revealData(passwordEntry, entryKey)
+			if err != nil {
+				log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decrypt password entry data")
+				return nil, err
+			}
+			continue
+		}
+		if field == utils.RevealFieldCustom {
+			customFields, err = s.revealCustomFields(passwordEntry, entryKey, fields)
+			if err != nil {
+				log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decrypt password entry custom fields")
+				return nil, err
+			}
+			continue
+		}
+
+		var encValue string
+		switch field {
+		case utils.RevealFieldUsername:
+			encValue = passwordEntry.Username
+		case utils.RevealFieldPassword:
+			encValue = passwordEntry.EncryptedPassword
+		case utils.RevealFieldNotes:
+			encValue = text.DerefString(passwordEntry.EncryptedNotes)
+		default:
+			continue
+		}
+
+		value, err := s.EncryptionService.DecryptEntryField(encValue, entryKey)
+		if err != nil {
+			log.Error().Str("clientID", clientID).Str("field", field).Err(err).Msg("Failed to decrypt password entry field")
+			return nil, err
+		}
+		revealed[field] = value
 	}
 
-	encUsername, encPass, encNotes, err := s.EncryptionService.DecryptPasswordEntry(passwordEntry.Username, passwordEntry.EncryptedPassword, *passwordEntry.EncryptedNotes, passwordEntryKey.EncryptedSymmetricKey, privateKey)
-	passwordEntry.Username = encUsername
-	passwordEntry.EncryptedPassword = encPass
-	passwordEntry.EncryptedNotes = &encNotes
-	return passwordEntry, nil
+	accessLog := password.PasswordAccessLog{
+		EntryID:   passwordEntry.EntryID,
+		UserID:    user.UserID,
+		Action:    utils.AccessActionReveal,
+		Fields:    fields,
+		RequestID: text.NilIfEmpty(metadata.RequestID),
+		IPAddress: text.NilIfEmpty(metadata.IPAddress),
+		UserAgent: text.NilIfEmpty(metadata.UserAgent),
+		CreatedBy: &clientID,
+	}
+	if err := s.PasswordAccessLogRepository.AddAccessLog(&accessLog); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to record password reveal")
+		return nil, err
+	}
+
+	log.Info().Str("clientID", clientID).Uint("entryID", passwordEntry.EntryID).Strs("fields", fields).Msg("Password entry revealed")
+
+	return out.RevealPasswordEntryResponse{
+		EntryID:      passwordEntry.EntryID,
+		Fields:       revealed,
+		Data:         revealedData,
+		CustomFields: customFields,
+	}, nil
 }
 
-func (s *passwordEntryService) GetListPasswordEntries(clientID string, tags string, index int, size int) (interface{}, int64, error) {
+func (s *passwordEntryService) GetListPasswordEntries(clientID string, tags string, entryType string, favorite bool, sort string, index int, size int) (interface{}, int64, error) {
+	if entryType != "" && !entrytype.IsValid(entryType) {
+		return nil, 0, fmt.Errorf("unsupported entry type: %s", entryType)
+	}
+	if sort != "" && sort != utils.SortFrecency {
+		return nil, 0, fmt.Errorf("unsupported sort: %s", sort)
+	}
+
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
@@ -313,9 +688,9 @@ func (s *passwordEntryService) GetListPasswordEntries(clientID string, tags stri
 		return nil, 0, errors.New("user not found")
 	}
 
-	totalPasswordEntries, err := s.PasswordEntryRepository.GetCountPasswordEntriesByUserID(user.UserID)
+	totalPasswordEntries, err := s.PasswordEntryRepository.GetCountPasswordEntriesByUserID(user.UserID, entryType, favorite)
 
-	passwordEntries, err := s.PasswordEntryRepository.GetListPasswordEntryResponse(user.UserID, tags, index, size)
+	passwordEntries, err := s.PasswordEntryRepository.GetListPasswordEntryResponse(user.UserID, tags, entryType, favorite, sort, index, size)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -323,6 +698,169 @@ func (s *passwordEntryService) GetListPasswordEntries(clientID string, tags stri
 	return passwordEntries, totalPasswordEntries, nil
 }
 
+func (s *passwordEntryService) GetRecentPasswordEntries(clientID string, limit int) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	if limit <= 0 || limit > maxRecentEntries {
+		limit = maxRecentEntries
+	}
+	passwordEntries, err := s.PasswordEntryRepository.GetRecentPasswordEntries(user.UserID, limit)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve recent password entries")
+		return nil, err
+	}
+	return passwordEntries, nil
+}
+
+// GetMatchingPasswordEntries returns the user's entries with a URL matching the given one, the most specific match
+// first. Entries for an equivalent domain or a known app of the domain match with a lower score.
+func (s *passwordEntryService) GetMatchingPasswordEntries(clientID string, rawURL string) (interface{}, error) {
+	target, err := urlmatch.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	keys, err := s.EquivalentDomainService.MatchKeys(user.UserID, target)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve equivalent domains")
+		return nil, err
+	}
+	keyList := make([]string, 0, len(keys))
+	for key := range keys {
+		keyList = append(keyList, key)
+	}
+
+	candidates, err := s.PasswordEntryURLRepository.GetMatchCandidates(user.UserID, keyList)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve URL match candidates")
+		return nil, err
+	}
+
+	best := make(map[uint]out.PasswordEntryMatchResponse)
+	for _, candidate := range candidates {
+		rule := urlmatch.Rule{URL: candidate.URL, Match: candidate.MatchType}
+		match := rule.Match
+		score, matched := urlmatch.Score(rule, target)
+		if !matched {
+			match = urlmatch.MatchEquivalent
+			score, matched = urlmatch.ScoreEquivalent(rule, keys)
+		}
+		if !matched || score <= best[candidate.EntryID].Score {
+			continue
+		}
+		best[candidate.EntryID] = out.PasswordEntryMatchResponse{
+			MatchedURL: candidate.URL,
+			Match:      match,
+			Score:      score,
+		}
+	}
+	entryIDs := make([]uint, 0, len(best))
+	for entryID := range best {
+		entryIDs = append(entryIDs, entryID)
+	}
+
+	passwordEntries, err := s.PasswordEntryRepository.GetListPasswordEntryResponseByEntryIDs(user.UserID, entryIDs)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve matching password entries")
+		return nil, err
+	}
+
+	matches := make([]out.PasswordEntryMatchResponse, 0, len(passwordEntries))
+	for _, entry := range passwordEntries {
+		match := best[entry.EntryID]
+		match.PasswordEntryListResponse = entry
+		matches = append(matches, match)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return rankMatch(matches[i], matches[j])
+	})
+	if len(matches) > maxMatchEntries {
+		matches = matches[:maxMatchEntries]
+	}
+	return matches, nil
+}
+
+// rankMatch orders matches by specificity, then pinned, favorite and recently used entries first
+func rankMatch(a, b out.PasswordEntryMatchResponse) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Pinned != b.Pinned {
+		return a.Pinned
+	}
+	if a.Favorite != b.Favorite {
+		return a.Favorite
+	}
+	if (a.LastAccessedAt == nil) != (b.LastAccessedAt == nil) {
+		return a.LastAccessedAt != nil
+	}
+	if a.LastAccessedAt != nil && !a.LastAccessedAt.Equal(*b.LastAccessedAt) {
+		return a.LastAccessedAt.After(*b.LastAccessedAt)
+	}
+	if a.Title != b.Title {
+		return a.Title < b.Title
+	}
+	return a.EntryID < b.EntryID
+}
+
+func (s *passwordEntryService) SetFavorite(passwordEntryID uint, clientID string, favorite bool) error {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return err
+	}
+
+	if err := s.PasswordEntryRepository.SetFavorite(passwordEntryID, user.UserID, favorite); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to update favorite")
+		return err
+	}
+	return nil
+}
+
+func (s *passwordEntryService) SetPinned(passwordEntryID uint, clientID string, pinned bool) error {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return err
+	}
+
+	if err := s.PasswordEntryRepository.SetPinned(passwordEntryID, user.UserID, pinned); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to update pin")
+		return err
+	}
+	return nil
+}
+
 func (s *passwordEntryService) DeletePasswordEntry(passwordEntryID uint, clientID string) error {
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
@@ -338,9 +876,261 @@ func (s *passwordEntryService) DeletePasswordEntry(passwordEntryID uint, clientI
 		log.Error().Str("clientID", clientID).Msg("User not found")
 		return errors.New("user not found")
 	}
-	if err := s.PasswordEntryRepository.DeletePasswordEntry(passwordEntryID); err != nil {
+	// Attachments are only looked up once the entry is known to belong to the caller
+	if _, err := s.PasswordEntryRepository.GetPasswordEntryByEntryIDAndUserID(passwordEntryID, user.UserID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrEntryNotFound
+	} else if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry")
+		return err
+	}
+	attachments, err := s.AttachmentService.GetEntryAttachments(passwordEntryID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve attachments")
+		return err
+	}
+	if err := s.PasswordEntryRepository.DeletePasswordEntry(passwordEntryID, user.UserID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrEntryNotFound
+	} else if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to delete password entry")
 		return err
 	}
+	s.AttachmentService.PurgeBlobs(attachments)
 
 	return nil
 }
+
+// rewrapAttachments wraps the keys of the entry's attachments with the new entry key
+func (s *passwordEntryService) rewrapAttachments(entryID uint, owner *user.Users, newWrappedKey string, vaultKey []byte) ([]password.PasswordAttachment, error) {
+	attachments, err := s.AttachmentService.GetEntryAttachments(entryID)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to retrieve attachments")
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+
+	privateKey, passwordEntryKey, err := s.getEntryDecryptionKeys(entryID, owner, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedKeys := make([]string, len(attachments))
+	for i, attachment := range attachments {
+		wrappedKeys[i] = attachment.WrappedKey
+	}
+	rewrapped, err := s.EncryptionService.RewrapAttachmentKeys(wrappedKeys, passwordEntryKey.EncryptedSymmetricKey, newWrappedKey, privateKey)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to rewrap attachment keys")
+		return nil, err
+	}
+	for i := range attachments {
+		attachments[i].WrappedKey = rewrapped[i]
+	}
+	return attachments, nil
+}
+
+// getEntryDecryptionKeys opens the owner's private key with the key held by the unlocked vault session
+func (s *passwordEntryService) getEntryDecryptionKeys(entryID uint, owner *user.Users, vaultKey []byte) (*rsa.PrivateKey, *password.PasswordEntryKey, error) {
+	if len(vaultKey) == 0 {
+		log.Error().Str("clientID", owner.ClientID).Msg("Vault is locked")
+		return nil, nil, errors.New("vault is locked")
+	}
+
+	privateKey, err := s.UserKeyRepository.GetPrivateKeyWithDerivedKey(owner.UserID, vaultKey)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to retrieve user keys")
+		return nil, nil, err
+	}
+	if privateKey == nil {
+		log.Error().Str("clientID", owner.ClientID).Msg("User private key not found")
+		return nil, nil, errors.New("user private key not found")
+	}
+
+	passwordEntryKey, err := s.PasswordEntryKeyRepository.GetPasswordEntryKeyByEntryID(entryID)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to retrieve password entry key")
+		return nil, nil, err
+	}
+	if passwordEntryKey == nil {
+		log.Error().Str("clientID", owner.ClientID).Msg("Password entry key not found")
+		return nil, nil, errors.New("password entry key not found")
+	}
+
+	return privateKey, passwordEntryKey, nil
+}
+
+// normalizeRevealFields accepts "custom_fields.<name>" to unmask one secret custom field; it implies "custom_fields"
+func normalizeRevealFields(fields []string) (pq.StringArray, error) {
+	seen := make(map[string]bool)
+	var normalized pq.StringArray
+	add := func(field string) {
+		if !seen[field] {
+			seen[field] = true
+			normalized = append(normalized, field)
+		}
+	}
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if name, found := strings.CutPrefix(field, utils.RevealFieldCustom+"."); found {
+			if strings.TrimSpace(name) == "" {
+				return nil, invalidRequest("custom field name is required")
+			}
+			add(utils.RevealFieldCustom)
+			add(utils.RevealFieldCustom + "." + strings.TrimSpace(name))
+			continue
+		}
+
+		field = strings.ToLower(field)
+		switch field {
+		case utils.RevealFieldUsername, utils.RevealFieldPassword, utils.RevealFieldNotes, utils.RevealFieldData, utils.RevealFieldCustom:
+		default:
+			return nil, invalidRequest("unsupported reveal field: %s", field)
+		}
+		add(field)
+	}
+	if len(normalized) == 0 {
+		return nil, invalidRequest("at least one field must be requested")
+	}
+	return normalized, nil
+}
+
+// revealCustomFields decrypts the custom field document and masks secret values that were not requested by name
+func (s *passwordEntryService) revealCustomFields(passwordEntry *password.PasswordEntry, entryKey []byte, fields []string) ([]customfield.Field, error) {
+	document, err := s.EncryptionService.DecryptEntryField(text.DerefString(passwordEntry.EncryptedCustom), entryKey)
+	if err != nil {
+		return nil, err
+	}
+	customFields, err := customfield.Unmarshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	reveal := make(map[string]bool)
+	for _, field := range fields {
+		if name, found := strings.CutPrefix(field, utils.RevealFieldCustom+"."); found {
+			reveal[name] = true
+		}
+	}
+	return customfield.Mask(customFields, reveal), nil
+}
+
+func (s *passwordEntryService) revealData(passwordEntry *password.PasswordEntry, entryKey []byte) (map[string]string, error) {
+	document, err := s.EncryptionService.DecryptEntryField(text.DerefString(passwordEntry.EncryptedData), entryKey)
+	if err != nil || document == "" {
+		return nil, err
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(document), &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+type entryDocument struct {
+	EntryType string
+	Data      string
+	Metadata  *string
+	ExpiresAt *time.Time
+}
+
+// encodeEntryDocument validates the type-specific data of a request and prepares its encrypted document
+// and the clear-text metadata, such as a card's last digits or an SSH key fingerprint
+func encodeEntryDocument(req *in.PasswordEntryRequest) (*entryDocument, error) {
+	entryType, err := entrytype.Normalize(req.EntryType)
+	if err != nil {
+		return nil, err
+	}
+	if entryType == entrytype.SecureNote && strings.TrimSpace(text.DerefString(req.Notes)) == "" {
+		return nil, errors.New("secure notes require notes")
+	}
+
+	result, err := entrytype.Validate(entryType, req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &entryDocument{EntryType: entryType, ExpiresAt: result.ExpiresAt}
+	if result.Data != nil {
+		data, err := json.Marshal(result.Data)
+		if err != nil {
+			return nil, err
+		}
+		document.Data = string(data)
+	}
+	if result.Metadata != nil {
+		metadata, err := json.Marshal(result.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		document.Metadata = text.NilIfEmpty(string(metadata))
+	}
+	return document, nil
+}
+
+// entryURLs normalizes the URLs of the request. The primary URL, kept on the entry, is the first one that is not a
+// pattern.
+func entryURLs(req *in.PasswordEntryRequest) (*string, []password.PasswordEntryURL, error) {
+	rules := req.URLs
+	if rules == nil && req.URL != nil && strings.TrimSpace(*req.URL) != "" {
+		rules = []urlmatch.Rule{{URL: *req.URL}}
+	}
+	normalized, err := urlmatch.NormalizeRules(rules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var primaryURL *string
+	urls := make([]password.PasswordEntryURL, 0, len(normalized))
+	for _, rule := range normalized {
+		entryURL := password.PasswordEntryURL{URL: rule.URL, MatchType: rule.Match}
+		if rule.Match != urlmatch.MatchRegex {
+			uri, err := urlmatch.Parse(rule.URL)
+			if err != nil {
+				return nil, nil, err
+			}
+			entryURL.Host = &uri.Host
+			entryURL.BaseDomain = &uri.BaseDomain
+			if primaryURL == nil {
+				primaryURL = &entryURL.URL
+			}
+		}
+		urls = append(urls, entryURL)
+	}
+	return primaryURL, urls, nil
+}
+
+func encodeCustomFields(fields []customfield.Field) (string, error) {
+	normalized, err := customfield.Normalize(fields)
+	if err != nil {
+		return "", err
+	}
+	return customfield.Marshal(normalized)
+}
+
+func uniqueEntryIDs(entryIDs []uint) []uint {
+	seen := make(map[uint]bool, len(entryIDs))
+	unique := make([]uint, 0, len(entryIDs))
+	for _, entryID := range entryIDs {
+		if entryID == 0 || seen[entryID] {
+			continue
+		}
+		seen[entryID] = true
+		unique = append(unique, entryID)
+	}
+	return unique
+}
+
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"errors"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/policy"
+	"reflect"
+	"testing"
+)
+
+// bulkEntryGroupRepository records the membership change a bulk request applied
+type bulkEntryGroupRepository struct {
+	repository.PasswordEntryGroupRepository
+	memberships map[uint][]uint
+	action      string
+	groupID     uint
+	entryIDs    []uint
+}
+
+func (f *bulkEntryGroupRepository) GetGroupIDsByEntryIDs(entryIDs []uint) (map[uint][]uint, error) {
+	return f.memberships, nil
+}
+
+func (f *bulkEntryGroupRepository) record(action string, entries []password.PasswordEntry, groupID uint) error {
+	f.action, f.groupID = action, groupID
+	for _, entry := range entries {
+		f.entryIDs = append(f.entryIDs, entry.EntryID)
+	}
+	return nil
+}
+
+func (f *bulkEntryGroupRepository) MoveEntriesToGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys repository.ServiceAccountKeys) error {
+	return f.record(utils.BulkActionMove, entries, groupID)
+}
+
+func (f *bulkEntryGroupRepository) AddEntriesToGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys repository.ServiceAccountKeys) error {
+	return f.record(utils.BulkActionCopy, entries, groupID)
+}
+
+func (f *bulkEntryGroupRepository) RemoveEntriesFromGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys repository.ServiceAccountKeys) error {
+	return f.record(utils.BulkActionRemove, entries, groupID)
+}
+
+// bulkGroupRepository only knows group 7 of user 1
+type bulkGroupRepository struct {
+	repository.PasswordGroupRepository
+}
+
+func (f bulkGroupRepository) GetPasswordGroupByUserIDAndGroupID(userID, groupID uint) (*password.PasswordGroup, error) {
+	if userID != 1 || groupID != 7 {
+		return nil, errors.New("record not found")
+	}
+	return &password.PasswordGroup{GroupID: groupID, UserID: userID}, nil
+}
+
+// bulkPolicyService records the groups each entry was checked against and rejects the entry in reject
+type bulkPolicyService struct {
+	PasswordPolicyService
+	reject  uint
+	checked map[uint][]uint
+}
+
+func (f *bulkPolicyService) CheckGroupMove(passwordEntry *password.PasswordEntry, groupIDs []uint) error {
+	f.checked[passwordEntry.EntryID] = groupIDs
+	if passwordEntry.EntryID == f.reject {
+		return &policy.ViolationError{Violations: []policy.Violation{{PolicyID: 2, Rule: "max_age_days", Message: "password is older than 30 days"}}}
+	}
+	return nil
+}
+
+type noServiceAccountKeyService struct {
+	ServiceAccountKeyService
+}
+
+func (f noServiceAccountKeyService) WrapEntryKeys(owner *user.Users, entryIDs []uint, groupIDs []uint, tagNames []string, vaultKey []byte) (repository.ServiceAccountKeys, error) {
+	return repository.ServiceAccountKeys{}, nil
+}
+
+func TestBulkGroupActions(t *testing.T) {
+	groupID := func(id uint) *uint { return &id }
+
+	tests := []struct {
+		name        string
+		req         in.BulkPasswordEntryRequest
+		memberships map[uint][]uint
+		reject      uint
+		wantErr     bool
+		wantApplied []uint
+		wantChecked map[uint][]uint
+		wantFailed  map[uint]string
+	}{
+		{
+			name:        "move",
+			req:         in.BulkPasswordEntryRequest{Action: utils.BulkActionMove, EntryIDs: []uint{5, 6, 5, 0}, GroupID: groupID(7)},
+			memberships: map[uint][]uint{5: {3}},
+			wantApplied: []uint{5, 6},
+			wantChecked: map[uint][]uint{5: {7}, 6: {7}},
+		},
+		{
+			name:        "add keeps the other groups in the policy check",
+			req:         in.BulkPasswordEntryRequest{Action: utils.BulkActionCopy, EntryIDs: []uint{5, 6}, GroupID: groupID(7)},
+			memberships: map[uint][]uint{5: {3, 4}},
+			wantApplied: []uint{5, 6},
+			wantChecked: map[uint][]uint{5: {3, 4, 7}, 6: {7}},
+		},
+		{
+			name:        "remove",
+			req:         in.BulkPasswordEntryRequest{Action: utils.BulkActionRemove, EntryIDs: []uint{6}, GroupID: groupID(7)},
+			wantApplied: []uint{6},
+			wantChecked: map[uint][]uint{},
+		},
+		{
+			name:        "policy violation fails only that entry",
+			req:         in.BulkPasswordEntryRequest{Action: utils.BulkActionCopy, EntryIDs: []uint{5, 6}, GroupID: groupID(7)},
+			reject:      6,
+			wantApplied: []uint{5},
+			wantChecked: map[uint][]uint{5: {7}, 6: {7}},
+			wantFailed:  map[uint]string{6: "password policy violation: password is older than 30 days"},
+		},
+		{
+			name:        "entry of another user",
+			req:         in.BulkPasswordEntryRequest{Action: utils.BulkActionMove, EntryIDs: []uint{5, 9}, GroupID: groupID(7)},
+			wantApplied: []uint{5},
+			wantChecked: map[uint][]uint{5: {7}},
+			wantFailed:  map[uint]string{9: "password entry not found"},
+		},
+		{
+			name:    "group of another user",
+			req:     in.BulkPasswordEntryRequest{Action: utils.BulkActionMove, EntryIDs: []uint{5}, GroupID: groupID(8)},
+			wantErr: true,
+		},
+		{
+			name:    "missing group",
+			req:     in.BulkPasswordEntryRequest{Action: utils.BulkActionCopy, EntryIDs: []uint{5}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported action",
+			req:     in.BulkPasswordEntryRequest{Action: "archive", EntryIDs: []uint{5}, GroupID: groupID(7)},
+			wantErr: true,
+		},
+		{
+			name:    "no entries",
+			req:     in.BulkPasswordEntryRequest{Action: utils.BulkActionMove, EntryIDs: []uint{0}, GroupID: groupID(7)},
+			wantErr: true,
+		},
+		{
+			name:    "too many entries",
+			req:     in.BulkPasswordEntryRequest{Action: utils.BulkActionMove, EntryIDs: sequence(maxBulkEntries + 1), GroupID: groupID(7)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entryGroups := &bulkEntryGroupRepository{memberships: tt.memberships}
+			policies := &bulkPolicyService{reject: tt.reject, checked: map[uint][]uint{}}
+			service := &passwordEntryService{
+				UserRepository:               fakeUserRepository{},
+				PasswordEntryRepository:      &fakeEntryRepository{entries: []password.PasswordEntry{{EntryID: 5, UserID: 1}, {EntryID: 6, UserID: 1}}},
+				PasswordGroupRepository:      bulkGroupRepository{},
+				PasswordEntryGroupRepository: entryGroups,
+				PasswordPolicyService:        policies,
+				ServiceAccountKeyService:     noServiceAccountKeyService{},
+				Redis:                        fakeRedis{},
+			}
+
+			response, err := service.BulkPasswordEntries(&tt.req, testClientID, []byte("vault-key"))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("BulkPasswordEntries accepted the request")
+				}
+				if entryGroups.action != "" {
+					t.Errorf("a rejected request applied %s", entryGroups.action)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BulkPasswordEntries: %v", err)
+			}
+
+			if entryGroups.action != tt.req.Action || entryGroups.groupID != *tt.req.GroupID {
+				t.Errorf("applied %s to group %d, want %s to group %d", entryGroups.action, entryGroups.groupID, tt.req.Action, *tt.req.GroupID)
+			}
+			if !reflect.DeepEqual(entryGroups.entryIDs, tt.wantApplied) {
+				t.Errorf("applied to entries %v, want %v", entryGroups.entryIDs, tt.wantApplied)
+			}
+			if !reflect.DeepEqual(policies.checked, tt.wantChecked) {
+				t.Errorf("policy checked %v, want %v", policies.checked, tt.wantChecked)
+			}
+
+			if response.Failed != len(tt.wantFailed) || response.Succeeded != len(response.Results)-len(tt.wantFailed) {
+				t.Errorf("succeeded %d, failed %d, want %d failed of %d", response.Succeeded, response.Failed, len(tt.wantFailed), len(response.Results))
+			}
+			for _, result := range response.Results {
+				want, failed := tt.wantFailed[result.EntryID]
+				if result.Success == failed || result.Error != want {
+					t.Errorf("entry %d: success %t, error %q, want error %q", result.EntryID, result.Success, result.Error, want)
+				}
+				if failed && tt.reject == result.EntryID && len(result.Violations) != 1 {
+					t.Errorf("entry %d: %d violations, want 1", result.EntryID, len(result.Violations))
+				}
+			}
+		})
+	}
+}
+
+func sequence(n int) []uint {
+	ids := make([]uint, n)
+	for i := range ids {
+		ids[i] = uint(i + 1)
+	}
+	return ids
+}
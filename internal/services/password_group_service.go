@@ -8,16 +8,21 @@ import (
 	"password-management-service/internal/repository"
 	"password-management-service/internal/utils"
 	"password-management-service/internal/utils/redis"
+	"strings"
 )
 
+const maxGroupNameLength = 100
+
 type PasswordGroupService interface {
 	AddPasswordGroup(group *in.PasswordGroupRequest, clientID string) (interface{}, error)
 	UpdatePasswordGroup(groupID uint, req struct {
 		Name string `json:"name" binding:"required"`
 	}, clientID string) (interface{}, error)
 	GetListPasswordGroup(clientID string) (interface{}, error)
-	GetItemListPasswordGroup(groupID uint, clientID string) (interface{}, error)
+	MovePasswordGroup(groupID uint, req in.MovePasswordGroupRequest, clientID string) (interface{}, error)
+	GetItemListPasswordGroup(groupID uint, clientID string, recursive bool) (interface{}, error)
 	GetPasswordGroupByID(groupID uint, clientID string) (interface{}, error)
+	GetPasswordGroupByPath(path string, clientID string) (interface{}, error)
 	DeletePasswordGroupByID(groupID uint, clientID string) error
 }
 
@@ -53,11 +58,27 @@ func (s *passwordGroupService) AddPasswordGroup(group *in.PasswordGroupRequest,
 		return nil, err
 	}
 
+	name, err := normalizeGroupName(group.Name)
+	if err != nil {
+		return nil, err
+	}
+	path := "/" + name
+	if group.ParentGroupID != nil {
+		parent, err := s.PasswordGroupRepository.GetPasswordGroupByUserIDAndGroupID(user.UserID, *group.ParentGroupID)
+		if err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve parent password group")
+			return nil, errors.New("parent password group not found")
+		}
+		path = parent.Path + path
+	}
+
 	passwordGroup := password.PasswordGroup{
-		UserID:    user.UserID,
-		Name:      group.Name,
-		CreatedBy: &user.ClientID,
-		UpdatedBy: &user.ClientID,
+		UserID:        user.UserID,
+		ParentGroupID: group.ParentGroupID,
+		Name:          name,
+		Path:          path,
+		CreatedBy:     &user.ClientID,
+		UpdatedBy:     &user.ClientID,
 	}
 
 	if err := s.PasswordGroupRepository.AddPasswordGroup(&passwordGroup); err != nil {
@@ -88,12 +109,43 @@ func (s *passwordGroupService) UpdatePasswordGroup(groupID uint, req struct {
 		return err, nil
 	}
 
-	passwordGroup.Name = req.Name
-	passwordGroup.UpdatedBy = &user.ClientID
+	name, err := normalizeGroupName(req.Name)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := s.PasswordGroupRepository.UpdatePasswordGroup(passwordGroup); err != nil {
+	// Renaming rewrites the breadcrumb of every subgroup, so it goes through the same path as a move
+	passwordGroup, err = s.PasswordGroupRepository.MovePasswordGroup(user.UserID, passwordGroup.GroupID, passwordGroup.ParentGroupID, name, user.ClientID)
+	if err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to update password group")
-		return err, nil
+		return nil, err
+	}
+
+	return passwordGroup, nil
+}
+
+func (s *passwordGroupService) MovePasswordGroup(groupID uint, req in.MovePasswordGroupRequest, clientID string) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	passwordGroup, err := s.PasswordGroupRepository.GetPasswordGroupByUserIDAndGroupID(user.UserID, groupID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password group by user ID and group ID")
+		return nil, err
+	}
+
+	passwordGroup, err = s.PasswordGroupRepository.MovePasswordGroup(user.UserID, passwordGroup.GroupID, req.ParentGroupID, passwordGroup.Name, user.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to move password group")
+		return nil, err
 	}
 
 	return passwordGroup, nil
@@ -120,7 +172,8 @@ func (s *passwordGroupService) GetListPasswordGroup(clientID string) (interface{
 	return passwordGroups, nil
 }
 
-func (s *passwordGroupService) GetItemListPasswordGroup(groupID uint, clientID string) (interface{}, error) {
+// GetItemListPasswordGroup lists the entries of the group, including those of all subgroups when recursive is set
+func (s *passwordGroupService) GetItemListPasswordGroup(groupID uint, clientID string, recursive bool) (interface{}, error) {
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
@@ -132,7 +185,13 @@ func (s *passwordGroupService) GetItemListPasswordGroup(groupID uint, clientID s
 		return nil, err
 	}
 
-	passwordEntry, err := s.PasswordGroupRepository.GetItemListPasswordGroup(groupID, user.UserID)
+	passwordGroup, err := s.PasswordGroupRepository.GetPasswordGroupByUserIDAndGroupID(user.UserID, groupID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password group by user ID and group ID")
+		return nil, err
+	}
+
+	passwordEntry, err := s.PasswordGroupRepository.GetItemListPasswordGroup(passwordGroup, recursive)
 	if err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password groups by user ID")
 		return nil, err
@@ -162,6 +221,32 @@ func (s *passwordGroupService) GetPasswordGroupByID(groupID uint, clientID strin
 	return passwordGroup, nil
 }
 
+func (s *passwordGroupService) GetPasswordGroupByPath(path string, clientID string) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	path = "/" + strings.Trim(strings.TrimSpace(path), "/")
+	if path == "/" {
+		return nil, errors.New("group path is required")
+	}
+
+	passwordGroup, err := s.PasswordGroupRepository.GetPasswordGroupByPath(user.UserID, path)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password group by path")
+		return nil, err
+	}
+
+	return passwordGroup, nil
+}
+
 func (s *passwordGroupService) DeletePasswordGroupByID(groupID uint, clientID string) error {
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
@@ -183,6 +268,14 @@ func (s *passwordGroupService) DeletePasswordGroupByID(groupID uint, clientID st
 	if len(entry) > 0 {
 		return errors.New("cannot delete password group with entries")
 	}
+	childGroups, err := s.PasswordGroupRepository.GetCountChildGroups(passwordGroup.GroupID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to count child password groups")
+		return err
+	}
+	if childGroups > 0 {
+		return errors.New("cannot delete password group with subgroups")
+	}
 
 	if err := s.PasswordGroupRepository.DeletePasswordGroupByID(groupID, user.ClientID); err != nil {
 		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to delete password group by ID")
@@ -191,3 +284,18 @@ func (s *passwordGroupService) DeletePasswordGroupByID(groupID uint, clientID st
 
 	return nil
 }
+
+// normalizeGroupName trims the name and rejects names that would break the breadcrumb path
+func normalizeGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("group name is required")
+	}
+	if strings.Contains(name, "/") {
+		return "", errors.New("group name cannot contain '/'")
+	}
+	if len([]rune(name)) > maxGroupNameLength {
+		return "", errors.New("group name is too long")
+	}
+	return name, nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeGroupName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", input: "prod", want: "prod"},
+		{name: "trimmed", input: "  aws prod \t", want: "aws prod"},
+		{name: "unicode at the limit", input: strings.Repeat("é", maxGroupNameLength), want: strings.Repeat("é", maxGroupNameLength)},
+		{name: "empty", input: "", wantErr: true},
+		{name: "blank", input: "   ", wantErr: true},
+		{name: "path separator", input: "work/aws", wantErr: true},
+		{name: "too long", input: strings.Repeat("a", maxGroupNameLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeGroupName(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("normalizeGroupName(%q) = %q, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("normalizeGroupName(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
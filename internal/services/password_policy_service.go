@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils/policy"
+	"time"
+)
+
+type PasswordPolicyService interface {
+	AddPolicy(req *in.PasswordPolicyRequest, clientID string) (interface{}, error)
+	UpdatePolicy(policyID uint, req *in.PasswordPolicyRequest, clientID string) error
+	GetListPolicies() (interface{}, error)
+	GetPolicyByID(policyID uint) (interface{}, error)
+	DeletePolicy(policyID uint, clientID string) error
+	ApplyPolicies(passwordEntry *password.PasswordEntry, groupIDs []uint, plaintext string) error
+	CheckGroupMove(passwordEntry *password.PasswordEntry, groupIDs []uint) error
+}
+
+type passwordPolicyService struct {
+	PasswordPolicyRepository repository.PasswordPolicyRepository
+	PasswordGroupRepository  repository.PasswordGroupRepository
+}
+
+func NewPasswordPolicyService(
+	passwordPolicyRepository repository.PasswordPolicyRepository,
+	passwordGroupRepository repository.PasswordGroupRepository) PasswordPolicyService {
+	return &passwordPolicyService{
+		PasswordPolicyRepository: passwordPolicyRepository,
+		PasswordGroupRepository:  passwordGroupRepository,
+	}
+}
+
+func (s *passwordPolicyService) AddPolicy(req *in.PasswordPolicyRequest, clientID string) (interface{}, error) {
+	if err := s.validateScope(req); err != nil {
+		return nil, err
+	}
+
+	passwordPolicy := newPasswordPolicy(req)
+	passwordPolicy.CreatedBy = &clientID
+	passwordPolicy.UpdatedBy = &clientID
+	if err := s.PasswordPolicyRepository.AddPolicy(&passwordPolicy); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to add password policy")
+		return nil, err
+	}
+	return passwordPolicy, nil
+}
+
+func (s *passwordPolicyService) UpdatePolicy(policyID uint, req *in.PasswordPolicyRequest, clientID string) error {
+	if err := s.validateScope(req); err != nil {
+		return err
+	}
+	if _, err := s.PasswordPolicyRepository.GetPolicyByID(policyID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password policy")
+		return err
+	}
+
+	passwordPolicy := newPasswordPolicy(req)
+	passwordPolicy.PolicyID = policyID
+	passwordPolicy.UpdatedBy = &clientID
+	if err := s.PasswordPolicyRepository.UpdatePolicy(&passwordPolicy); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to update password policy")
+		return err
+	}
+	return nil
+}
+
+func (s *passwordPolicyService) GetListPolicies() (interface{}, error) {
+	policies, err := s.PasswordPolicyRepository.GetPolicies()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retrieve password policies")
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (s *passwordPolicyService) GetPolicyByID(policyID uint) (interface{}, error) {
+	passwordPolicy, err := s.PasswordPolicyRepository.GetPolicyByID(policyID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retrieve password policy")
+		return nil, err
+	}
+	return passwordPolicy, nil
+}
+
+func (s *passwordPolicyService) DeletePolicy(policyID uint, clientID string) error {
+	if err := s.PasswordPolicyRepository.DeletePolicy(policyID, clientID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to delete password policy")
+		return err
+	}
+	return nil
+}
+
+// ApplyPolicies records the characteristics of a new password, rejects it when it violates a policy in effect
+// and brings the expiry forward to the strictest rotation deadline, keeping an earlier expiry the user chose
+func (s *passwordPolicyService) ApplyPolicies(passwordEntry *password.PasswordEntry, groupIDs []uint, plaintext string) error {
+	characteristics := policy.Inspect(plaintext)
+	now := time.Now()
+	passwordEntry.PasswordLength = &characteristics.Length
+	passwordEntry.PasswordClasses = &characteristics.Classes
+	passwordEntry.PasswordChangedAt = &now
+
+	policies, err := s.PasswordPolicyRepository.GetEffectivePolicies(passwordEntry.UserID, groupIDs)
+	if err != nil {
+		log.Error().Uint("userID", passwordEntry.UserID).Err(err).Msg("Failed to retrieve password policies")
+		return err
+	}
+
+	if violations := policy.Evaluate(policies, &characteristics, now, passwordEntry.Compromised, now); len(violations) > 0 {
+		return &policy.ViolationError{Violations: violations}
+	}
+	if deadline := policy.RotationDeadline(policies, now); deadline != nil && (passwordEntry.ExpiresAt == nil || deadline.Before(*passwordEntry.ExpiresAt)) {
+		passwordEntry.ExpiresAt = deadline
+	}
+	return nil
+}
+
+// CheckGroupMove evaluates an existing entry against the policies of the groups it will belong to
+func (s *passwordPolicyService) CheckGroupMove(passwordEntry *password.PasswordEntry, groupIDs []uint) error {
+	policies, err := s.PasswordPolicyRepository.GetEffectivePolicies(passwordEntry.UserID, groupIDs)
+	if err != nil {
+		log.Error().Uint("userID", passwordEntry.UserID).Err(err).Msg("Failed to retrieve password policies")
+		return err
+	}
+
+	changedAt := passwordChangedAt(passwordEntry)
+	if violations := policy.Evaluate(policies, policy.FromEntry(passwordEntry), changedAt, passwordEntry.Compromised, time.Now()); len(violations) > 0 {
+		return &policy.ViolationError{Violations: violations}
+	}
+	if deadline := policy.RotationDeadline(policies, changedAt); deadline != nil && (passwordEntry.ExpiresAt == nil || deadline.Before(*passwordEntry.ExpiresAt)) {
+		passwordEntry.ExpiresAt = deadline
+	}
+	return nil
+}
+
+func (s *passwordPolicyService) validateScope(req *in.PasswordPolicyRequest) error {
+	if req.UserID != nil && req.GroupID != nil {
+		return errors.New("a policy applies either to a user or to a group, not both")
+	}
+	if req.GroupID != nil {
+		if _, err := s.PasswordGroupRepository.GetPasswordGroupByID(*req.GroupID); err != nil {
+			log.Error().Uint("groupID", *req.GroupID).Err(err).Msg("Failed to retrieve password group")
+			return err
+		}
+	}
+	return nil
+}
+
+func newPasswordPolicy(req *in.PasswordPolicyRequest) password.PasswordPolicy {
+	return password.PasswordPolicy{
+		Name:              req.Name,
+		UserID:            req.UserID,
+		GroupID:           req.GroupID,
+		MinLength:         req.MinLength,
+		RequireUppercase:  req.RequireUppercase,
+		RequireLowercase:  req.RequireLowercase,
+		RequireDigits:     req.RequireDigits,
+		RequireSymbols:    req.RequireSymbols,
+		MaxAgeDays:        req.MaxAgeDays,
+		ForbidCompromised: req.ForbidCompromised,
+	}
+}
+
+// passwordChangedAt falls back to the last update for entries saved before password changes were tracked
+func passwordChangedAt(passwordEntry *password.PasswordEntry) time.Time {
+	if passwordEntry.PasswordChangedAt != nil {
+		return *passwordEntry.PasswordChangedAt
+	}
+	return passwordEntry.UpdatedAt
+}
@@ -1,44 +1,54 @@
 package services
 
 import (
+	"errors"
 	"github.com/rs/zerolog/log"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/dto/out"
 	"password-management-service/internal/models/password"
 	"password-management-service/internal/repository"
 	"password-management-service/internal/utils"
 	"password-management-service/internal/utils/redis"
+	"regexp"
+	"strings"
+)
+
+const maxTagNameLength = 50
+
+var (
+	tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	tagIconPattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
 )
 
 type PasswordTagService interface {
-	AddPasswordTag(req struct {
-		Name string `json:"name" binding:"required"`
-	}, clientID string) (interface{}, error)
-	UpdatePasswordTag(tagID uint, req struct {
-		Name string `json:"name" binding:"required"`
-	}, clientID string) (interface{}, error)
+	AddPasswordTag(req *in.PasswordTagRequest, clientID string) (interface{}, error)
+	UpdatePasswordTag(tagID uint, req *in.PasswordTagRequest, clientID string) (interface{}, error)
+	MergePasswordTag(tagID uint, req *in.MergePasswordTagRequest, clientID string, vaultKey []byte) (interface{}, error)
 	GetListPasswordTag(clientID string, index, size int) (interface{}, int64, error)
 	DeletePasswordTagByID(tagID uint, clientID string) error
 }
 
 type passwordTagService struct {
-	UserRepository        repository.UserRepository
-	PasswordTagRepository repository.PasswordTagRepository
-	Redis                 redis.RedisService
+	UserRepository           repository.UserRepository
+	PasswordTagRepository    repository.PasswordTagRepository
+	ServiceAccountKeyService ServiceAccountKeyService
+	Redis                    redis.RedisService
 }
 
 func NewPasswordTagService(
 	userRepository repository.UserRepository,
 	passwordTagRepository repository.PasswordTagRepository,
+	serviceAccountKeyService ServiceAccountKeyService,
 	redis redis.RedisService) PasswordTagService {
 	return &passwordTagService{
-		UserRepository:        userRepository,
-		PasswordTagRepository: passwordTagRepository,
-		Redis:                 redis,
+		UserRepository:           userRepository,
+		PasswordTagRepository:    passwordTagRepository,
+		ServiceAccountKeyService: serviceAccountKeyService,
+		Redis:                    redis,
 	}
 }
 
-func (s *passwordTagService) AddPasswordTag(req struct {
-	Name string `json:"name" binding:"required"`
-}, clientID string) (interface{}, error) {
+func (s *passwordTagService) AddPasswordTag(req *in.PasswordTagRequest, clientID string) (interface{}, error) {
 
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
@@ -54,10 +64,12 @@ func (s *passwordTagService) AddPasswordTag(req struct {
 
 	passwordTag := &password.PasswordTag{
 		UserID:    user.UserID,
-		Name:      req.Name,
 		CreatedBy: &user.ClientID,
 		UpdatedBy: &user.ClientID,
 	}
+	if err := applyTagRequest(passwordTag, req); err != nil {
+		return nil, err
+	}
 
 	err = s.PasswordTagRepository.AddPasswordTag(passwordTag)
 	if err != nil {
@@ -67,9 +79,7 @@ func (s *passwordTagService) AddPasswordTag(req struct {
 	return passwordTag, nil
 }
 
-func (s *passwordTagService) UpdatePasswordTag(tagID uint, req struct {
-	Name string `json:"name" binding:"required"`
-}, clientID string) (interface{}, error) {
+func (s *passwordTagService) UpdatePasswordTag(tagID uint, req *in.PasswordTagRequest, clientID string) (interface{}, error) {
 
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
@@ -88,7 +98,9 @@ func (s *passwordTagService) UpdatePasswordTag(tagID uint, req struct {
 		return nil, err
 	}
 
-	passwordTag.Name = req.Name
+	if err := applyTagRequest(passwordTag, req); err != nil {
+		return nil, err
+	}
 	passwordTag.UpdatedBy = &user.ClientID
 
 	err = s.PasswordTagRepository.UpdatePasswordTag(passwordTag)
@@ -99,6 +111,58 @@ func (s *passwordTagService) UpdatePasswordTag(tagID uint, req struct {
 	return passwordTag, nil
 }
 
+// MergePasswordTag moves every entry of the tag to the target tag and deletes the merged tag. The service accounts
+// scoped to either tag end up covering the entries of both, so their keys are wrapped with the unlocked vault.
+func (s *passwordTagService) MergePasswordTag(tagID uint, req *in.MergePasswordTagRequest, clientID string, vaultKey []byte) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	if tagID == req.TargetTagID {
+		return nil, errors.New("a tag cannot be merged into itself")
+	}
+	source, err := s.PasswordTagRepository.GetPasswordTagByIDAndUserID(tagID, user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password tag by ID")
+		return nil, err
+	}
+	target, err := s.PasswordTagRepository.GetPasswordTagByIDAndUserID(req.TargetTagID, user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve target password tag by ID")
+		return nil, err
+	}
+
+	entryIDs, err := s.PasswordTagRepository.GetEntryIDsByTagIDs([]uint{source.TagID, target.TagID})
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve tagged password entries")
+		return nil, err
+	}
+	serviceAccountKeys, err := s.ServiceAccountKeyService.WrapEntryKeys(user, entryIDs, nil, []string{source.Name, target.Name}, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+
+	moved, err := s.PasswordTagRepository.MergePasswordTags(source, target, user.ClientID, serviceAccountKeys)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to merge password tags")
+		return nil, err
+	}
+
+	return out.MergePasswordTagResponse{
+		TagID:        target.TagID,
+		MergedTagID:  source.TagID,
+		EntriesMoved: moved,
+	}, nil
+}
+
 func (s *passwordTagService) GetListPasswordTag(clientID string, index, size int) (interface{}, int64, error) {
 	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
 	if err != nil {
@@ -149,3 +213,41 @@ func (s *passwordTagService) DeletePasswordTagByID(tagID uint, clientID string)
 	}
 	return nil
 }
+
+// applyTagRequest validates the request and copies it onto the tag. A nil color or icon keeps the current value and
+// an empty one clears it.
+func applyTagRequest(tag *password.PasswordTag, req *in.PasswordTagRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("tag name is required")
+	}
+	if len([]rune(name)) > maxTagNameLength {
+		return errors.New("tag name is too long")
+	}
+	tag.Name = name
+
+	if req.Color != nil {
+		color := strings.TrimSpace(*req.Color)
+		switch {
+		case color == "":
+			tag.Color = nil
+		case tagColorPattern.MatchString(color):
+			color = strings.ToLower(color)
+			tag.Color = &color
+		default:
+			return errors.New("tag color must be a #RRGGBB hex color")
+		}
+	}
+	if req.Icon != nil {
+		icon := strings.TrimSpace(*req.Icon)
+		switch {
+		case icon == "":
+			tag.Icon = nil
+		case tagIconPattern.MatchString(icon):
+			tag.Icon = &icon
+		default:
+			return errors.New("tag icon must be lowercase letters, digits, '-' or '_'")
+		}
+	}
+	return nil
+}
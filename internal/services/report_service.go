@@ -0,0 +1,142 @@
+package services
+
+import (
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/policy"
+	"password-management-service/internal/utils/redis"
+	"time"
+)
+
+type ReportService interface {
+	GetBreachedEntries(clientID string) (interface{}, error)
+	GetPolicyViolations(clientID string) (interface{}, error)
+}
+
+type reportService struct {
+	UserRepository               repository.UserRepository
+	PasswordEntryRepository      repository.PasswordEntryRepository
+	PasswordPolicyRepository     repository.PasswordPolicyRepository
+	PasswordGroupRepository      repository.PasswordGroupRepository
+	PasswordEntryGroupRepository repository.PasswordEntryGroupRepository
+	Redis                        redis.RedisService
+}
+
+func NewReportService(
+	userRepository repository.UserRepository,
+	passwordEntryRepository repository.PasswordEntryRepository,
+	passwordPolicyRepository repository.PasswordPolicyRepository,
+	passwordGroupRepository repository.PasswordGroupRepository,
+	passwordEntryGroupRepository repository.PasswordEntryGroupRepository,
+	redis redis.RedisService) ReportService {
+	return &reportService{
+		UserRepository:               userRepository,
+		PasswordEntryRepository:      passwordEntryRepository,
+		PasswordPolicyRepository:     passwordPolicyRepository,
+		PasswordGroupRepository:      passwordGroupRepository,
+		PasswordEntryGroupRepository: passwordEntryGroupRepository,
+		Redis:                        redis,
+	}
+}
+
+func (s *reportService) GetBreachedEntries(clientID string) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	entries, err := s.PasswordEntryRepository.GetBreachedEntriesByUserID(user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve breached password entries")
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetPolicyViolations evaluates every entry of the user against the policies in effect for it
+func (s *reportService) GetPolicyViolations(clientID string) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	policies, err := s.PasswordPolicyRepository.GetPoliciesByUserID(user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password policies")
+		return nil, err
+	}
+	entries, err := s.PasswordEntryRepository.GetEntriesForPolicyCheck(user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entries")
+		return nil, err
+	}
+	groups, err := s.PasswordGroupRepository.GetPasswordGroupByUserID(user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password groups")
+		return nil, err
+	}
+	parents := make(map[uint]*uint, len(groups))
+	for _, group := range groups {
+		parents[group.GroupID] = group.ParentGroupID
+	}
+	entryGroups, err := s.PasswordEntryGroupRepository.GetEntryGroupsByUserID(user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry groups")
+		return nil, err
+	}
+	memberships := make(map[uint][]uint)
+	for _, entryGroup := range entryGroups {
+		memberships[entryGroup.EntryID] = append(memberships[entryGroup.EntryID], entryGroup.GroupID)
+	}
+
+	now := time.Now()
+	report := make([]out.PolicyViolationResponse, 0)
+	for i := range entries {
+		entry := &entries[i]
+		violations := policy.Evaluate(policiesForEntry(policies, memberships[entry.EntryID], parents), policy.FromEntry(entry), passwordChangedAt(entry), entry.Compromised, now)
+		if len(violations) == 0 {
+			continue
+		}
+		report = append(report, out.PolicyViolationResponse{
+			EntryID:    entry.EntryID,
+			Title:      entry.Title,
+			GroupID:    entry.GroupID,
+			Violations: violations,
+		})
+	}
+	return report, nil
+}
+
+// policiesForEntry keeps the global and user policies plus those of the entry's groups and the groups above them
+func policiesForEntry(policies []password.PasswordPolicy, entryGroupIDs []uint, parents map[uint]*uint) []password.PasswordPolicy {
+	groupIDs := make(map[uint]bool)
+	for _, entryGroupID := range entryGroupIDs {
+		for groupID := &entryGroupID; groupID != nil && !groupIDs[*groupID]; groupID = parents[*groupID] {
+			groupIDs[*groupID] = true
+		}
+	}
+
+	var effective []password.PasswordPolicy
+	for _, p := range policies {
+		if p.GroupID != nil && !groupIDs[*p.GroupID] {
+			continue
+		}
+		effective = append(effective, p)
+	}
+	return effective
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"crypto/rsa"
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils/encryption"
+)
+
+// ServiceAccountKeyService wraps entry keys for the owner's service accounts, so every change of an entry's key,
+// groups or tags hands the accounts that cover the entry its key in the same transaction
+type ServiceAccountKeyService interface {
+	GetRecipients(userID uint) (map[uint]*rsa.PublicKey, error)
+	WrapEntryKeys(owner *user.Users, entryIDs []uint, groupIDs []uint, tagNames []string, vaultKey []byte) (repository.ServiceAccountKeys, error)
+}
+
+type serviceAccountKeyService struct {
+	ServiceAccountRepository   repository.ServiceAccountRepository
+	UserKeyRepository          repository.UserKeysRepository
+	PasswordEntryKeyRepository repository.PasswordEntryKeysRepository
+	EncryptionService          encryption.Encryption
+}
+
+func NewServiceAccountKeyService(
+	serviceAccountRepository repository.ServiceAccountRepository,
+	userKeyRepository repository.UserKeysRepository,
+	passwordEntryKeysRepository repository.PasswordEntryKeysRepository,
+	encryptionService encryption.Encryption) ServiceAccountKeyService {
+	return &serviceAccountKeyService{
+		ServiceAccountRepository:   serviceAccountRepository,
+		UserKeyRepository:          userKeyRepository,
+		PasswordEntryKeyRepository: passwordEntryKeysRepository,
+		EncryptionService:          encryptionService,
+	}
+}
+
+// GetRecipients returns the public keys of all the user's service accounts, for a fresh entry key. The repository
+// only stores the keys of the accounts whose scope covers the entry.
+func (s *serviceAccountKeyService) GetRecipients(userID uint) (map[uint]*rsa.PublicKey, error) {
+	serviceAccounts, err := s.ServiceAccountRepository.GetServiceAccountsByUserID(userID)
+	if err != nil {
+		log.Error().Uint("userID", userID).Err(err).Msg("Failed to retrieve service accounts")
+		return nil, err
+	}
+	return parseServiceAccountKeys(serviceAccounts)
+}
+
+// WrapEntryKeys wraps the keys of existing entries for the service accounts scoped to any of the groups or tags the
+// entries are joining. With a locked vault nothing is wrapped; the change then goes through unless it gives an
+// account an entry it did not have, which fails with repository.ErrServiceAccountKeyRequired.
+func (s *serviceAccountKeyService) WrapEntryKeys(owner *user.Users, entryIDs []uint, groupIDs []uint, tagNames []string, vaultKey []byte) (repository.ServiceAccountKeys, error) {
+	var keys repository.ServiceAccountKeys
+	serviceAccounts, err := s.ServiceAccountRepository.GetServiceAccountsByScope(owner.UserID, groupIDs, tagNames)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to retrieve service accounts in scope")
+		return keys, err
+	}
+	if len(serviceAccounts) == 0 || len(entryIDs) == 0 || len(vaultKey) == 0 {
+		return keys, nil
+	}
+
+	recipients, err := parseServiceAccountKeys(serviceAccounts)
+	if err != nil {
+		return keys, err
+	}
+	privateKey, err := s.UserKeyRepository.GetPrivateKeyWithDerivedKey(owner.UserID, vaultKey)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to retrieve user keys")
+		return keys, err
+	}
+
+	keys.Wrapped = make(map[uint]map[uint]string, len(entryIDs))
+	keys.EntryKeys = make(map[uint]string, len(entryIDs))
+	for _, entryID := range entryIDs {
+		passwordEntryKey, err := s.PasswordEntryKeyRepository.GetPasswordEntryKeyByEntryID(entryID)
+		if err != nil {
+			log.Error().Str("clientID", owner.ClientID).Uint("entryID", entryID).Err(err).Msg("Failed to retrieve password entry key")
+			return keys, err
+		}
+		entryKey, err := s.EncryptionService.UnwrapEntryKey(passwordEntryKey.EncryptedSymmetricKey, privateKey)
+		if err != nil {
+			log.Error().Str("clientID", owner.ClientID).Uint("entryID", entryID).Err(err).Msg("Failed to open password entry key")
+			return keys, err
+		}
+
+		wrapped := make(map[uint]string, len(recipients))
+		for serviceAccountID, publicKey := range recipients {
+			wrapped[serviceAccountID], err = s.EncryptionService.WrapEntryKey(entryKey, publicKey)
+			if err != nil {
+				log.Error().Str("clientID", owner.ClientID).Uint("entryID", entryID).Err(err).Msg("Failed to wrap entry key for service account")
+				return keys, err
+			}
+		}
+		keys.Wrapped[entryID] = wrapped
+		keys.EntryKeys[entryID] = passwordEntryKey.EncryptedSymmetricKey
+	}
+	return keys, nil
+}
+
+func parseServiceAccountKeys(serviceAccounts []password.ServiceAccount) (map[uint]*rsa.PublicKey, error) {
+	recipients := make(map[uint]*rsa.PublicKey, len(serviceAccounts))
+	for _, serviceAccount := range serviceAccounts {
+		publicKey, err := encryption.ParsePublicKey(serviceAccount.PublicKey)
+		if err != nil {
+			log.Error().Uint("serviceAccountID", serviceAccount.ServiceAccountID).Err(err).Msg("Failed to parse service account public key")
+			return nil, err
+		}
+		recipients[serviceAccount.ServiceAccountID] = publicKey
+	}
+	return recipients, nil
+}
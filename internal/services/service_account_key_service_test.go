@@ -0,0 +1,354 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/lib/pq"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/redis"
+	"testing"
+)
+
+const testClientID = "client-1"
+
+type fakeRedis struct {
+	redis.RedisService
+}
+
+func (f fakeRedis) GetData(key, clientID string, target interface{}) error {
+	data, _ := json.Marshal(user.UserRedis{UserID: 1, ClientID: clientID})
+	return json.Unmarshal(data, target)
+}
+
+type fakeUserRepository struct {
+	repository.UserRepository
+}
+
+func (f fakeUserRepository) GetUserByClientID(clientID string) (*user.Users, error) {
+	return &user.Users{UserID: 1, ClientID: clientID}, nil
+}
+
+type fakeUserKeyRepository struct {
+	repository.UserKeysRepository
+	privateKey *rsa.PrivateKey
+}
+
+func (f fakeUserKeyRepository) GetUserKeys(userID uint) (*user.UserKey, error) {
+	return &user.UserKey{}, nil
+}
+
+func (f fakeUserKeyRepository) GetPublicKeyByUserID(userID uint) (*rsa.PublicKey, error) {
+	return &f.privateKey.PublicKey, nil
+}
+
+func (f fakeUserKeyRepository) GetPrivateKeyWithDerivedKey(userID uint, derivedKey []byte) (*rsa.PrivateKey, error) {
+	return f.privateKey, nil
+}
+
+type fakeServiceAccountRepository struct {
+	repository.ServiceAccountRepository
+	serviceAccounts []password.ServiceAccount
+	// scoped are the service accounts GetServiceAccountsByScope returns
+	scoped []password.ServiceAccount
+}
+
+func (f fakeServiceAccountRepository) GetServiceAccountsByUserID(userID uint) ([]password.ServiceAccount, error) {
+	return f.serviceAccounts, nil
+}
+
+func (f fakeServiceAccountRepository) GetServiceAccountsByScope(userID uint, groupIDs []uint, tagNames []string) ([]password.ServiceAccount, error) {
+	return f.scoped, nil
+}
+
+type fakeEntryKeyRepository struct {
+	repository.PasswordEntryKeysRepository
+	keys map[uint]string
+}
+
+func (f fakeEntryKeyRepository) GetPasswordEntryKeyByEntryID(entryID uint) (*password.PasswordEntryKey, error) {
+	return &password.PasswordEntryKey{EntryID: entryID, EncryptedSymmetricKey: f.keys[entryID]}, nil
+}
+
+type fakeEntryRepository struct {
+	repository.PasswordEntryRepository
+	entries            []password.PasswordEntry
+	entryKey           string
+	serviceAccountKeys map[uint]string
+}
+
+func (f *fakeEntryRepository) AddPasswordEntry(passwordEntry *password.PasswordEntry, passwordEntryKey *password.PasswordEntryKey, tags pq.StringArray, urls []password.PasswordEntryURL, userID uint, serviceAccountKeys map[uint]string) error {
+	f.entryKey = passwordEntryKey.EncryptedSymmetricKey
+	f.serviceAccountKeys = serviceAccountKeys
+	return nil
+}
+
+func (f *fakeEntryRepository) UpdatePasswordEntryAndEntryKey(passwordEntry password.PasswordEntry, passwordEntryKey password.PasswordEntryKey, attachments []password.PasswordAttachment, urls []password.PasswordEntryURL, serviceAccountKeys map[uint]string) error {
+	f.entryKey = passwordEntryKey.EncryptedSymmetricKey
+	f.serviceAccountKeys = serviceAccountKeys
+	return nil
+}
+
+func (f *fakeEntryRepository) GetPasswordEntryByEntryIDAndUserID(entryID, userID uint) (*password.PasswordEntry, error) {
+	return &password.PasswordEntry{EntryID: entryID, UserID: userID}, nil
+}
+
+func (f *fakeEntryRepository) GetPasswordEntriesByEntryIDsAndUserID(entryIDs []uint, userID uint) ([]password.PasswordEntry, error) {
+	return f.entries, nil
+}
+
+type fakeTagRepository struct {
+	repository.PasswordTagRepository
+	serviceAccountKeys repository.ServiceAccountKeys
+}
+
+func (f *fakeTagRepository) GetPasswordTagsByEntryID(entryID uint) ([]*password.PasswordTag, error) {
+	return nil, nil
+}
+
+func (f *fakeTagRepository) AddTagsToEntries(entries []password.PasswordEntry, userID uint, names []string, clientID string, serviceAccountKeys repository.ServiceAccountKeys) error {
+	f.serviceAccountKeys = serviceAccountKeys
+	return nil
+}
+
+type fakeEntryGroupRepository struct {
+	repository.PasswordEntryGroupRepository
+	serviceAccountKeys repository.ServiceAccountKeys
+}
+
+func (f *fakeEntryGroupRepository) GetGroupIDsByEntryIDs(entryIDs []uint) (map[uint][]uint, error) {
+	return map[uint][]uint{}, nil
+}
+
+func (f *fakeEntryGroupRepository) MoveEntriesToGroup(entries []password.PasswordEntry, groupID uint, clientID string, serviceAccountKeys repository.ServiceAccountKeys) error {
+	f.serviceAccountKeys = serviceAccountKeys
+	return nil
+}
+
+type fakeGroupRepository struct {
+	repository.PasswordGroupRepository
+}
+
+func (f fakeGroupRepository) GetPasswordGroupByUserIDAndGroupID(userID, groupID uint) (*password.PasswordGroup, error) {
+	return &password.PasswordGroup{GroupID: groupID, UserID: userID}, nil
+}
+
+type fakeBreachService struct {
+	BreachService
+}
+
+func (f fakeBreachService) ResetStatus(passwordEntry *password.PasswordEntry) {}
+
+func (f fakeBreachService) CheckPassword(passwordEntry password.PasswordEntry, plaintext string) {}
+
+type fakePolicyService struct {
+	PasswordPolicyService
+}
+
+func (f fakePolicyService) ApplyPolicies(passwordEntry *password.PasswordEntry, groupIDs []uint, plaintext string) error {
+	return nil
+}
+
+func (f fakePolicyService) CheckGroupMove(passwordEntry *password.PasswordEntry, groupIDs []uint) error {
+	return nil
+}
+
+type fakeAttachmentService struct {
+	AttachmentService
+}
+
+func (f fakeAttachmentService) GetEntryAttachments(entryID uint) ([]password.PasswordAttachment, error) {
+	return nil, nil
+}
+
+type keyFixture struct {
+	owner           *rsa.PrivateKey
+	serviceAccounts map[uint]*rsa.PrivateKey
+}
+
+func newKeyFixture(t *testing.T, serviceAccountIDs ...uint) keyFixture {
+	t.Helper()
+	fixture := keyFixture{owner: generateKey(t), serviceAccounts: map[uint]*rsa.PrivateKey{}}
+	for _, id := range serviceAccountIDs {
+		fixture.serviceAccounts[id] = generateKey(t)
+	}
+	return fixture
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func (f keyFixture) accounts(ids ...uint) []password.ServiceAccount {
+	var accounts []password.ServiceAccount
+	for _, id := range ids {
+		accounts = append(accounts, password.ServiceAccount{
+			ServiceAccountID: id,
+			UserID:           1,
+			PublicKey:        base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&f.serviceAccounts[id].PublicKey)),
+		})
+	}
+	return accounts
+}
+
+// assertSameEntryKey checks that every service account key opens to the AES key the owner key opens to
+func (f keyFixture) assertSameEntryKey(t *testing.T, ownerKey string, serviceAccountKeys map[uint]string, want ...uint) {
+	t.Helper()
+	enc := encryption.NewEncryption()
+	entryKey, err := enc.UnwrapEntryKey(ownerKey, f.owner)
+	if err != nil {
+		t.Fatalf("open owner key: %v", err)
+	}
+	if len(serviceAccountKeys) != len(want) {
+		t.Fatalf("got keys for %d service accounts, want %d", len(serviceAccountKeys), len(want))
+	}
+	for _, id := range want {
+		wrapped, ok := serviceAccountKeys[id]
+		if !ok {
+			t.Fatalf("no key for service account %d", id)
+		}
+		got, err := enc.UnwrapEntryKey(wrapped, f.serviceAccounts[id])
+		if err != nil {
+			t.Fatalf("open key of service account %d: %v", id, err)
+		}
+		if !bytes.Equal(got, entryKey) {
+			t.Errorf("service account %d got a different entry key", id)
+		}
+	}
+}
+
+func newTestEntryService(fixture keyFixture, serviceAccountRepository repository.ServiceAccountRepository, entryKeys map[uint]string) (*passwordEntryService, *fakeEntryRepository, *fakeTagRepository, *fakeEntryGroupRepository) {
+	userKeyRepository := fakeUserKeyRepository{privateKey: fixture.owner}
+	entryRepository := &fakeEntryRepository{entries: []password.PasswordEntry{{EntryID: 5, UserID: 1}, {EntryID: 6, UserID: 1}}}
+	tagRepository := &fakeTagRepository{}
+	entryGroupRepository := &fakeEntryGroupRepository{}
+	enc := encryption.NewEncryption()
+	service := NewPasswordEntryService(
+		fakeUserRepository{},
+		userKeyRepository,
+		entryRepository,
+		fakeEntryKeyRepository{keys: entryKeys},
+		tagRepository,
+		fakeGroupRepository{},
+		entryGroupRepository,
+		nil,
+		nil,
+		fakeBreachService{},
+		fakePolicyService{},
+		fakeAttachmentService{},
+		nil,
+		NewServiceAccountKeyService(serviceAccountRepository, userKeyRepository, fakeEntryKeyRepository{keys: entryKeys}, enc),
+		enc,
+		fakeRedis{}).(*passwordEntryService)
+	return service, entryRepository, tagRepository, entryGroupRepository
+}
+
+func TestAddPasswordEntryWrapsKeyForServiceAccounts(t *testing.T) {
+	fixture := newKeyFixture(t, 10, 20)
+	service, entryRepository, _, _ := newTestEntryService(fixture, fakeServiceAccountRepository{serviceAccounts: fixture.accounts(10, 20)}, nil)
+
+	if err := service.AddPasswordEntry(&in.PasswordEntryRequest{Title: "db", Username: "admin", Password: "s3cret"}, testClientID); err != nil {
+		t.Fatalf("AddPasswordEntry: %v", err)
+	}
+	fixture.assertSameEntryKey(t, entryRepository.entryKey, entryRepository.serviceAccountKeys, 10, 20)
+}
+
+func TestUpdatePasswordEntryRewrapsServiceAccountKeys(t *testing.T) {
+	fixture := newKeyFixture(t, 10)
+	service, entryRepository, _, _ := newTestEntryService(fixture, fakeServiceAccountRepository{serviceAccounts: fixture.accounts(10)}, nil)
+
+	if err := service.UpdatePasswordEntry(5, &in.PasswordEntryRequest{Title: "db", Username: "admin", Password: "rotated"}, testClientID, nil); err != nil {
+		t.Fatalf("UpdatePasswordEntry: %v", err)
+	}
+	fixture.assertSameEntryKey(t, entryRepository.entryKey, entryRepository.serviceAccountKeys, 10)
+}
+
+func TestBulkPasswordEntriesWrapsKeysForScopedServiceAccounts(t *testing.T) {
+	fixture := newKeyFixture(t, 10)
+	enc := encryption.NewEncryption()
+	entryKeys := map[uint]string{}
+	for _, entryID := range []uint{5, 6} {
+		_, wrapped, err := enc.EncryptPasswordEntry(encryption.EntryFields{Password: "p"}, &fixture.owner.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entryKeys[entryID] = wrapped
+	}
+	groupID := uint(3)
+
+	tests := []struct {
+		name     string
+		req      in.BulkPasswordEntryRequest
+		vaultKey []byte
+		scoped   []password.ServiceAccount
+		wrapped  bool
+	}{
+		{
+			name:     "tag into a scope",
+			req:      in.BulkPasswordEntryRequest{Action: utils.BulkActionTag, EntryIDs: []uint{5, 6}, Tags: []string{"prod"}},
+			vaultKey: []byte("vault"),
+			scoped:   fixture.accounts(10),
+			wrapped:  true,
+		},
+		{
+			name:     "move into a scope",
+			req:      in.BulkPasswordEntryRequest{Action: utils.BulkActionMove, EntryIDs: []uint{5, 6}, GroupID: &groupID},
+			vaultKey: []byte("vault"),
+			scoped:   fixture.accounts(10),
+			wrapped:  true,
+		},
+		{
+			name:   "locked vault leaves the grant to the repository",
+			req:    in.BulkPasswordEntryRequest{Action: utils.BulkActionTag, EntryIDs: []uint{5, 6}, Tags: []string{"prod"}},
+			scoped: fixture.accounts(10),
+		},
+		{
+			name:     "no service account in scope",
+			req:      in.BulkPasswordEntryRequest{Action: utils.BulkActionMove, EntryIDs: []uint{5, 6}, GroupID: &groupID},
+			vaultKey: []byte("vault"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, tagRepository, entryGroupRepository := newTestEntryService(fixture, fakeServiceAccountRepository{scoped: tt.scoped}, entryKeys)
+			result, err := service.BulkPasswordEntries(&tt.req, testClientID, tt.vaultKey)
+			if err != nil {
+				t.Fatalf("BulkPasswordEntries: %v", err)
+			}
+			if result.Succeeded != 2 {
+				t.Fatalf("succeeded = %d, want 2", result.Succeeded)
+			}
+
+			keys := tagRepository.serviceAccountKeys
+			if tt.req.Action == utils.BulkActionMove {
+				keys = entryGroupRepository.serviceAccountKeys
+			}
+			if !tt.wrapped {
+				if len(keys.Wrapped) != 0 {
+					t.Fatalf("wrapped keys for %d entries, want none", len(keys.Wrapped))
+				}
+				return
+			}
+			for _, entryID := range []uint{5, 6} {
+				if keys.EntryKeys[entryID] != entryKeys[entryID] {
+					t.Errorf("entry %d: keys were not recorded as made from the current owner key", entryID)
+				}
+				fixture.assertSameEntryKey(t, entryKeys[entryID], keys.Wrapped[entryID], 10)
+			}
+		})
+	}
+}
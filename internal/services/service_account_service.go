@@ -0,0 +1,713 @@
+package services
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/apitoken"
+	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/render"
+	"password-management-service/internal/utils/text"
+	"strings"
+	"time"
+)
+
+type ServiceAccountService interface {
+	AddServiceAccount(req *in.ServiceAccountRequest, clientID string, vaultKey []byte) (interface{}, error)
+	GetListServiceAccount(clientID string) (interface{}, error)
+	DeleteServiceAccount(serviceAccountID uint, clientID string) error
+	AddServiceAccountToken(serviceAccountID uint, req *in.ServiceAccountTokenRequest, clientID string, vaultKey []byte) (interface{}, error)
+	DeleteServiceAccountToken(serviceAccountID, tokenID uint, clientID string) error
+	SyncServiceAccount(serviceAccountID uint, clientID string, vaultKey []byte) (interface{}, error)
+	AuthenticateToken(token string) (*apitoken.ServiceTokenClaims, error)
+	GetListServiceEntries(claims *apitoken.ServiceTokenClaims) (interface{}, error)
+	GetServiceSecret(entryID uint, claims *apitoken.ServiceTokenClaims, metadata in.RequestMetadata) (interface{}, error)
+	RenderSecrets(req *in.RenderSecretsRequest, claims *apitoken.ServiceTokenClaims, metadata in.RequestMetadata) ([]byte, string, error)
+}
+
+type serviceAccountService struct {
+	UserRepository              repository.UserRepository
+	UserKeyRepository           repository.UserKeysRepository
+	ServiceAccountRepository    repository.ServiceAccountRepository
+	PasswordEntryRepository     repository.PasswordEntryRepository
+	PasswordEntryKeyRepository  repository.PasswordEntryKeysRepository
+	PasswordGroupRepository     repository.PasswordGroupRepository
+	PasswordTagRepository       repository.PasswordTagRepository
+	PasswordAccessLogRepository repository.PasswordAccessLogRepository
+	EncryptionService           encryption.Encryption
+	Redis                       redis.RedisService
+}
+
+func NewServiceAccountService(
+	userRepository repository.UserRepository,
+	userKeyRepository repository.UserKeysRepository,
+	serviceAccountRepository repository.ServiceAccountRepository,
+	passwordEntryRepository repository.PasswordEntryRepository,
+	passwordEntryKeysRepository repository.PasswordEntryKeysRepository,
+	passwordGroupRepository repository.PasswordGroupRepository,
+	passwordTagRepository repository.PasswordTagRepository,
+	passwordAccessLogRepository repository.PasswordAccessLogRepository,
+	encryptionService encryption.Encryption,
+	redis redis.RedisService) ServiceAccountService {
+	return &serviceAccountService{
+		UserRepository:              userRepository,
+		UserKeyRepository:           userKeyRepository,
+		ServiceAccountRepository:    serviceAccountRepository,
+		PasswordEntryRepository:     passwordEntryRepository,
+		PasswordEntryKeyRepository:  passwordEntryKeysRepository,
+		PasswordGroupRepository:     passwordGroupRepository,
+		PasswordTagRepository:       passwordTagRepository,
+		PasswordAccessLogRepository: passwordAccessLogRepository,
+		EncryptionService:           encryptionService,
+		Redis:                       redis,
+	}
+}
+
+func (s *serviceAccountService) AddServiceAccount(req *in.ServiceAccountRequest, clientID string, vaultKey []byte) (interface{}, error) {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.GroupIDs) == 0 && len(req.TagIDs) == 0 {
+		return nil, errors.New("service account requires at least one group or tag scope")
+	}
+
+	var scopes []password.ServiceAccountScope
+	for _, groupID := range req.GroupIDs {
+		if _, err := s.PasswordGroupRepository.GetPasswordGroupByUserIDAndGroupID(owner.UserID, groupID); err != nil {
+			log.Error().Str("clientID", clientID).Uint("groupID", groupID).Err(err).Msg("Failed to retrieve password group")
+			return nil, fmt.Errorf("password group %d not found", groupID)
+		}
+		scopes = append(scopes, password.ServiceAccountScope{GroupID: &groupID})
+	}
+	for _, tagID := range req.TagIDs {
+		if _, err := s.PasswordTagRepository.GetPasswordTagByIDAndUserID(tagID, owner.UserID); err != nil {
+			log.Error().Str("clientID", clientID).Uint("tagID", tagID).Err(err).Msg("Failed to retrieve password tag")
+			return nil, fmt.Errorf("password tag %d not found", tagID)
+		}
+		scopes = append(scopes, password.ServiceAccountScope{TagID: &tagID})
+	}
+
+	key, err := s.UserKeyRepository.GetUserKeys(owner.UserID)
+	if key == nil && err != nil {
+		userKey, err := s.EncryptionService.GenerateUserKey(owner)
+		if err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to generate user key pair")
+			return nil, err
+		}
+		if err := s.UserKeyRepository.AddUserKey(userKey); err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to add user key")
+			return nil, err
+		}
+	}
+
+	ownerPublicKey, err := s.UserKeyRepository.GetPublicKeyByUserID(owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user keys")
+		return nil, err
+	}
+
+	ownerPrivateKey, err := s.getOwnerPrivateKey(owner, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, encryptedPrivateKey, wrappedKey, err := s.EncryptionService.GenerateServiceAccountKey(ownerPublicKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to generate service account key pair")
+		return nil, err
+	}
+
+	serviceAccountPublicKey, err := encryption.ParsePublicKey(publicKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to parse service account public key")
+		return nil, err
+	}
+	serviceAccountPrivateKey, err := s.EncryptionService.DecryptServiceAccountKey(encryptedPrivateKey, wrappedKey, ownerPrivateKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decrypt service account key")
+		return nil, err
+	}
+
+	entries, err := s.ServiceAccountRepository.GetEntriesInGroupsAndTags(owner.UserID, req.GroupIDs, req.TagIDs)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve entries in service account scope")
+		return nil, err
+	}
+	keys, entryKeys, err := s.wrapEntryKeys(entries, serviceAccountPublicKey, owner, ownerPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenName := req.TokenName
+	if tokenName == "" {
+		tokenName = "default"
+	}
+	serviceAccountToken, token, err := s.newToken(serviceAccountPrivateKey, tokenName, req.ExpiresInDays, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccount := password.ServiceAccount{
+		UserID:              owner.UserID,
+		Name:                req.Name,
+		Description:         req.Description,
+		Permission:          utils.ServiceAccountPermissionRead,
+		PublicKey:           publicKey,
+		EncryptedPrivateKey: encryptedPrivateKey,
+		WrappedKey:          wrappedKey,
+		CreatedBy:           &clientID,
+		UpdatedBy:           &clientID,
+	}
+	if err := s.ServiceAccountRepository.AddServiceAccount(&serviceAccount, scopes, keys, entryKeys, serviceAccountToken); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to add service account")
+		return nil, err
+	}
+	return out.ServiceAccountResponse{
+		ServiceAccountID: serviceAccount.ServiceAccountID,
+		Name:             serviceAccount.Name,
+		Description:      serviceAccount.Description,
+		Permission:       serviceAccount.Permission,
+		GroupIDs:         req.GroupIDs,
+		TagIDs:           req.TagIDs,
+		EntryCount:       int64(len(keys)),
+		Tokens:           []out.ServiceAccountTokenResponse{*tokenResponse(serviceAccountToken, token)},
+		CreatedAt:        serviceAccount.CreatedAt,
+	}, nil
+}
+
+func (s *serviceAccountService) GetListServiceAccount(clientID string) (interface{}, error) {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccounts, err := s.ServiceAccountRepository.GetServiceAccountsByUserID(owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve service accounts")
+		return nil, err
+	}
+
+	responses := make([]out.ServiceAccountResponse, 0, len(serviceAccounts))
+	for _, serviceAccount := range serviceAccounts {
+		scopes, err := s.ServiceAccountRepository.GetScopesByServiceAccountID(serviceAccount.ServiceAccountID)
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := s.ServiceAccountRepository.GetTokensByServiceAccountID(serviceAccount.ServiceAccountID)
+		if err != nil {
+			return nil, err
+		}
+		entryCount, err := s.ServiceAccountRepository.GetCountEntryKeys(serviceAccount.ServiceAccountID)
+		if err != nil {
+			return nil, err
+		}
+
+		response := out.ServiceAccountResponse{
+			ServiceAccountID: serviceAccount.ServiceAccountID,
+			Name:             serviceAccount.Name,
+			Description:      serviceAccount.Description,
+			Permission:       serviceAccount.Permission,
+			EntryCount:       entryCount,
+			CreatedAt:        serviceAccount.CreatedAt,
+		}
+		for _, scope := range scopes {
+			if scope.GroupID != nil {
+				response.GroupIDs = append(response.GroupIDs, *scope.GroupID)
+			}
+			if scope.TagID != nil {
+				response.TagIDs = append(response.TagIDs, *scope.TagID)
+			}
+		}
+		for _, token := range tokens {
+			response.Tokens = append(response.Tokens, out.ServiceAccountTokenResponse{
+				TokenID:     token.TokenID,
+				Name:        token.Name,
+				TokenPrefix: token.TokenPrefix,
+				ExpiresAt:   token.ExpiresAt,
+				LastUsedAt:  token.LastUsedAt,
+				CreatedAt:   token.CreatedAt,
+			})
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+func (s *serviceAccountService) DeleteServiceAccount(serviceAccountID uint, clientID string) error {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return err
+	}
+
+	serviceAccount, err := s.ServiceAccountRepository.GetServiceAccountByIDAndUserID(serviceAccountID, owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve service account")
+		return err
+	}
+
+	if err := s.ServiceAccountRepository.DeleteServiceAccount(serviceAccount, clientID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to delete service account")
+		return err
+	}
+	return nil
+}
+
+func (s *serviceAccountService) AddServiceAccountToken(serviceAccountID uint, req *in.ServiceAccountTokenRequest, clientID string, vaultKey []byte) (interface{}, error) {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccount, err := s.ServiceAccountRepository.GetServiceAccountByIDAndUserID(serviceAccountID, owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve service account")
+		return nil, err
+	}
+
+	ownerPrivateKey, err := s.getOwnerPrivateKey(owner, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccountPrivateKey, err := s.EncryptionService.DecryptServiceAccountKey(serviceAccount.EncryptedPrivateKey, serviceAccount.WrappedKey, ownerPrivateKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decrypt service account key")
+		return nil, err
+	}
+
+	return s.createToken(serviceAccount, serviceAccountPrivateKey, req.Name, req.ExpiresInDays, clientID)
+}
+
+func (s *serviceAccountService) DeleteServiceAccountToken(serviceAccountID, tokenID uint, clientID string) error {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.ServiceAccountRepository.GetServiceAccountByIDAndUserID(serviceAccountID, owner.UserID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve service account")
+		return err
+	}
+
+	if err := s.ServiceAccountRepository.DeleteToken(serviceAccountID, tokenID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to delete service account token")
+		return err
+	}
+	return nil
+}
+
+func (s *serviceAccountService) SyncServiceAccount(serviceAccountID uint, clientID string, vaultKey []byte) (interface{}, error) {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccount, err := s.ServiceAccountRepository.GetServiceAccountByIDAndUserID(serviceAccountID, owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve service account")
+		return nil, err
+	}
+
+	ownerPrivateKey, err := s.getOwnerPrivateKey(owner, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+
+	entryCount, err := s.syncEntryKeys(serviceAccount, owner, ownerPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"service_account_id": serviceAccount.ServiceAccountID,
+		"entry_count":        entryCount,
+	}, nil
+}
+
+func (s *serviceAccountService) AuthenticateToken(token string) (*apitoken.ServiceTokenClaims, error) {
+	serviceAccountToken, err := s.ServiceAccountRepository.GetTokenByHash(apitoken.Hash(token))
+	if err != nil {
+		return nil, errors.New("invalid API token")
+	}
+
+	if serviceAccountToken.ExpiresAt != nil && serviceAccountToken.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("API token has expired")
+	}
+
+	serviceAccount, err := s.ServiceAccountRepository.GetServiceAccountByID(serviceAccountToken.ServiceAccountID)
+	if err != nil {
+		return nil, errors.New("service account not found")
+	}
+
+	if err := s.ServiceAccountRepository.TouchToken(serviceAccountToken.TokenID); err != nil {
+		log.Error().Uint("tokenID", serviceAccountToken.TokenID).Err(err).Msg("Failed to update token last used time")
+	}
+
+	return &apitoken.ServiceTokenClaims{
+		ServiceAccountID: serviceAccount.ServiceAccountID,
+		TokenID:          serviceAccountToken.TokenID,
+		UserID:           serviceAccount.UserID,
+		Secret:           token,
+	}, nil
+}
+
+func (s *serviceAccountService) GetListServiceEntries(claims *apitoken.ServiceTokenClaims) (interface{}, error) {
+	entries, err := s.ServiceAccountRepository.GetAccessibleEntries(claims.ServiceAccountID)
+	if err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to retrieve accessible entries")
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *serviceAccountService) GetServiceSecret(entryID uint, claims *apitoken.ServiceTokenClaims, metadata in.RequestMetadata) (interface{}, error) {
+	entryKey, err := s.ServiceAccountRepository.GetEntryKey(claims.ServiceAccountID, entryID)
+	if err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Uint("entryID", entryID).Err(err).Msg("Entry is not accessible to service account")
+		return nil, errors.New("password entry not found")
+	}
+
+	passwordEntry, err := s.PasswordEntryRepository.GetPasswordEntryByEntryIDAndUserID(entryKey.EntryID, claims.UserID)
+	if err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to retrieve password entry")
+		return nil, err
+	}
+
+	privateKey, err := s.getServiceAccountPrivateKey(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	username, pass, notes, err := s.EncryptionService.DecryptPasswordEntry(passwordEntry.Username, passwordEntry.EncryptedPassword, text.DerefString(passwordEntry.EncryptedNotes), entryKey.EncryptedSymmetricKey, privateKey)
+	if err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to decrypt password entry")
+		return nil, err
+	}
+
+	actor := fmt.Sprintf("service-account:%d", claims.ServiceAccountID)
+	accessLog := password.PasswordAccessLog{
+		EntryID:   passwordEntry.EntryID,
+		UserID:    claims.UserID,
+		Action:    utils.AccessActionServiceRead,
+		Fields:    []string{utils.RevealFieldUsername, utils.RevealFieldPassword, utils.RevealFieldNotes},
+		RequestID: text.NilIfEmpty(metadata.RequestID),
+		IPAddress: text.NilIfEmpty(metadata.IPAddress),
+		UserAgent: text.NilIfEmpty(metadata.UserAgent),
+		CreatedBy: &actor,
+	}
+	if err := s.PasswordAccessLogRepository.AddAccessLog(&accessLog); err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to record service account access")
+		return nil, err
+	}
+
+	return out.ServiceSecretResponse{
+		EntryID:  passwordEntry.EntryID,
+		Title:    passwordEntry.Title,
+		URL:      passwordEntry.URL,
+		Username: username,
+		Password: pass,
+		Notes:    notes,
+	}, nil
+}
+
+// RenderSecrets decrypts every accessible entry of a scoped group and renders them as key/value pairs. The request
+// is validated before any entry is decrypted, and the exports are only logged, all in one transaction, once every
+// entry has rendered; a failed render leaves no export record.
+func (s *serviceAccountService) RenderSecrets(req *in.RenderSecretsRequest, claims *apitoken.ServiceTokenClaims, metadata in.RequestMetadata) ([]byte, string, error) {
+	if !render.SupportedFormat(req.Format) {
+		return nil, "", invalidRequest("unsupported format: %s", req.Format)
+	}
+	keyField := strings.ToLower(req.KeyField)
+	if keyField == "" {
+		keyField = utils.RenderFieldTitle
+	}
+	valueField := strings.ToLower(req.ValueField)
+	if valueField == "" {
+		valueField = utils.RenderFieldPassword
+	}
+	switch keyField {
+	case utils.RenderFieldTitle, utils.RenderFieldUsername, utils.RenderFieldURL:
+	default:
+		return nil, "", invalidRequest("unsupported key field: %s", keyField)
+	}
+	switch valueField {
+	case utils.RenderFieldPassword, utils.RenderFieldUsername, utils.RenderFieldNotes, utils.RenderFieldURL:
+	default:
+		return nil, "", invalidRequest("unsupported value field: %s", valueField)
+	}
+
+	group, err := s.resolveScopedGroup(req.Group, claims)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries, err := s.ServiceAccountRepository.GetAccessibleEntriesByGroupID(claims.ServiceAccountID, group.GroupID)
+	if err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to retrieve group entries")
+		return nil, "", err
+	}
+
+	privateKey, err := s.getServiceAccountPrivateKey(claims)
+	if err != nil {
+		return nil, "", err
+	}
+
+	actor := fmt.Sprintf("service-account:%d", claims.ServiceAccountID)
+	secrets := make(map[string]string, len(entries))
+	accessLogs := make([]password.PasswordAccessLog, 0, len(entries))
+	for _, entry := range entries {
+		entryKey, err := s.ServiceAccountRepository.GetEntryKey(claims.ServiceAccountID, entry.EntryID)
+		if err != nil {
+			log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Uint("entryID", entry.EntryID).Err(err).Msg("Failed to retrieve service account entry key")
+			return nil, "", err
+		}
+
+		username, pass, notes, err := s.EncryptionService.DecryptPasswordEntry(entry.Username, entry.EncryptedPassword, text.DerefString(entry.EncryptedNotes), entryKey.EncryptedSymmetricKey, privateKey)
+		if err != nil {
+			log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Uint("entryID", entry.EntryID).Err(err).Msg("Failed to decrypt password entry")
+			return nil, "", err
+		}
+
+		fields := map[string]string{
+			utils.RenderFieldTitle:    entry.Title,
+			utils.RenderFieldUsername: username,
+			utils.RenderFieldPassword: pass,
+			utils.RenderFieldNotes:    notes,
+			utils.RenderFieldURL:      text.DerefString(entry.URL),
+		}
+
+		key := render.EnvKey(req.Prefix + fields[keyField])
+		if key == "" {
+			return nil, "", fmt.Errorf("entry %d has an empty %s and cannot be used as a key", entry.EntryID, keyField)
+		}
+		if _, exists := secrets[key]; exists {
+			return nil, "", fmt.Errorf("duplicate key %s in group %s", key, group.Name)
+		}
+		secrets[key] = fields[valueField]
+
+		accessLogs = append(accessLogs, password.PasswordAccessLog{
+			EntryID:   entry.EntryID,
+			UserID:    claims.UserID,
+			Action:    utils.AccessActionExport,
+			Fields:    []string{valueField},
+			RequestID: text.NilIfEmpty(metadata.RequestID),
+			IPAddress: text.NilIfEmpty(metadata.IPAddress),
+			UserAgent: text.NilIfEmpty(metadata.UserAgent),
+			CreatedBy: &actor,
+		})
+	}
+
+	rendered, contentType, err := render.Secrets(req.Format, secrets)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.PasswordAccessLogRepository.AddAccessLogs(accessLogs); err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to record service account export")
+		return nil, "", err
+	}
+	return rendered, contentType, nil
+}
+
+// resolveScopedGroup finds the group by ID or name and checks the service account was granted it
+func (s *serviceAccountService) resolveScopedGroup(groupParam string, claims *apitoken.ServiceTokenClaims) (*password.PasswordGroup, error) {
+	var group *password.PasswordGroup
+	if groupID, err := utils.ConvertToUint(groupParam); err == nil {
+		found, err := s.PasswordGroupRepository.GetPasswordGroupByUserIDAndGroupID(claims.UserID, groupID)
+		if err == nil {
+			group = found
+		}
+	} else {
+		groups, err := s.PasswordGroupRepository.GetPasswordGroupByUserID(claims.UserID)
+		if err != nil {
+			log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to retrieve password groups")
+			return nil, err
+		}
+		for i := range groups {
+			if strings.EqualFold(groups[i].Name, groupParam) {
+				group = &groups[i]
+				break
+			}
+		}
+	}
+	if group == nil {
+		return nil, errors.New("password group not found")
+	}
+
+	scopes, err := s.ServiceAccountRepository.GetScopesByServiceAccountID(claims.ServiceAccountID)
+	if err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to retrieve service account scopes")
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if scope.GroupID != nil && *scope.GroupID == group.GroupID {
+			return group, nil
+		}
+	}
+	return nil, errors.New("password group is not in the service account scope")
+}
+
+func (s *serviceAccountService) getUser(clientID string) (*user.Users, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	owner, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+	if owner == nil || owner.UserID == 0 {
+		log.Error().Str("clientID", clientID).Msg("User not found")
+		return nil, errors.New("user not found")
+	}
+	return owner, nil
+}
+
+// getOwnerPrivateKey opens the owner's private key with the key held by the unlocked vault session
+func (s *serviceAccountService) getOwnerPrivateKey(owner *user.Users, vaultKey []byte) (*rsa.PrivateKey, error) {
+	if len(vaultKey) == 0 {
+		log.Error().Str("clientID", owner.ClientID).Msg("Vault is locked")
+		return nil, errors.New("vault is locked")
+	}
+
+	privateKey, err := s.UserKeyRepository.GetPrivateKeyWithDerivedKey(owner.UserID, vaultKey)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to retrieve user keys")
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+func (s *serviceAccountService) getServiceAccountPrivateKey(claims *apitoken.ServiceTokenClaims) (*rsa.PrivateKey, error) {
+	token, err := s.ServiceAccountRepository.GetTokenByHash(apitoken.Hash(claims.Secret))
+	if err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to retrieve service account token")
+		return nil, err
+	}
+
+	privateKey, err := s.EncryptionService.OpenPrivateKeyWithSecret(token.EncryptedPrivateKey, token.Salt, claims.Secret)
+	if err != nil {
+		log.Error().Uint("serviceAccountID", claims.ServiceAccountID).Err(err).Msg("Failed to decrypt service account key")
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// syncEntryKeys wraps every entry key in the current scope to the service account's public key
+func (s *serviceAccountService) syncEntryKeys(serviceAccount *password.ServiceAccount, owner *user.Users, ownerPrivateKey *rsa.PrivateKey) (int, error) {
+	publicKey, err := encryption.ParsePublicKey(serviceAccount.PublicKey)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to parse service account public key")
+		return 0, err
+	}
+
+	entries, err := s.ServiceAccountRepository.GetEntriesInScope(serviceAccount.ServiceAccountID, owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to retrieve entries in service account scope")
+		return 0, err
+	}
+
+	keys, entryKeys, err := s.wrapEntryKeys(entries, publicKey, owner, ownerPrivateKey)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.ServiceAccountRepository.ReplaceEntryKeys(serviceAccount.ServiceAccountID, keys, entryKeys); err != nil {
+		log.Error().Str("clientID", owner.ClientID).Err(err).Msg("Failed to store service account entry keys")
+		return 0, err
+	}
+	return len(keys), nil
+}
+
+// wrapEntryKeys rewraps the entry keys for the service account. It also returns the owner-wrapped keys they were
+// made from, which the repository compares so a key rotated in the meantime is not stored stale.
+func (s *serviceAccountService) wrapEntryKeys(entries []password.PasswordEntry, publicKey *rsa.PublicKey, owner *user.Users, ownerPrivateKey *rsa.PrivateKey) ([]password.ServiceAccountEntryKey, map[uint]string, error) {
+	keys := make([]password.ServiceAccountEntryKey, 0, len(entries))
+	entryKeys := make(map[uint]string, len(entries))
+	for _, entry := range entries {
+		passwordEntryKey, err := s.PasswordEntryKeyRepository.GetPasswordEntryKeyByEntryID(entry.EntryID)
+		if err != nil {
+			log.Error().Str("clientID", owner.ClientID).Uint("entryID", entry.EntryID).Err(err).Msg("Failed to retrieve password entry key")
+			return nil, nil, err
+		}
+
+		wrappedKey, err := s.EncryptionService.RewrapEntryKey(passwordEntryKey.EncryptedSymmetricKey, ownerPrivateKey, publicKey)
+		if err != nil {
+			log.Error().Str("clientID", owner.ClientID).Uint("entryID", entry.EntryID).Err(err).Msg("Failed to wrap entry key for service account")
+			return nil, nil, err
+		}
+
+		keys = append(keys, password.ServiceAccountEntryKey{
+			EntryID:               entry.EntryID,
+			EncryptedSymmetricKey: wrappedKey,
+		})
+		entryKeys[entry.EntryID] = passwordEntryKey.EncryptedSymmetricKey
+	}
+	return keys, entryKeys, nil
+}
+
+func (s *serviceAccountService) createToken(serviceAccount *password.ServiceAccount, privateKey *rsa.PrivateKey, name string, expiresInDays int, clientID string) (*out.ServiceAccountTokenResponse, error) {
+	serviceAccountToken, token, err := s.newToken(privateKey, name, expiresInDays, clientID)
+	if err != nil {
+		return nil, err
+	}
+	serviceAccountToken.ServiceAccountID = serviceAccount.ServiceAccountID
+
+	if err := s.ServiceAccountRepository.AddToken(serviceAccountToken); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to add service account token")
+		return nil, err
+	}
+	return tokenResponse(serviceAccountToken, token), nil
+}
+
+// newToken generates an API token and seals the service account key with it; the token row is not stored yet
+func (s *serviceAccountService) newToken(privateKey *rsa.PrivateKey, name string, expiresInDays int, clientID string) (*password.ServiceAccountToken, string, error) {
+	token, prefix, hash, err := apitoken.Generate()
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to generate API token")
+		return nil, "", err
+	}
+
+	encryptedPrivateKey, salt, err := s.EncryptionService.SealPrivateKeyWithSecret(privateKey, token)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to seal service account key")
+		return nil, "", err
+	}
+
+	serviceAccountToken := password.ServiceAccountToken{
+		Name:                name,
+		TokenPrefix:         prefix,
+		TokenHash:           hash,
+		EncryptedPrivateKey: encryptedPrivateKey,
+		Salt:                salt,
+		CreatedBy:           &clientID,
+	}
+	if expiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, expiresInDays)
+		serviceAccountToken.ExpiresAt = &expiresAt
+	}
+	return &serviceAccountToken, token, nil
+}
+
+func tokenResponse(serviceAccountToken *password.ServiceAccountToken, token string) *out.ServiceAccountTokenResponse {
+	return &out.ServiceAccountTokenResponse{
+		TokenID:     serviceAccountToken.TokenID,
+		Name:        serviceAccountToken.Name,
+		TokenPrefix: serviceAccountToken.TokenPrefix,
+		Token:       token,
+		ExpiresAt:   serviceAccountToken.ExpiresAt,
+		CreatedAt:   serviceAccountToken.CreatedAt,
+	}
+}
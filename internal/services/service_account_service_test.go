@@ -0,0 +1,126 @@
+package services
+
+import (
+	"crypto/rsa"
+	"errors"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/apitoken"
+	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/render"
+	"testing"
+)
+
+// renderServiceAccountRepository grants group 7 and serves its entries
+type renderServiceAccountRepository struct {
+	repository.ServiceAccountRepository
+	entries []password.PasswordEntry
+}
+
+func (f renderServiceAccountRepository) GetScopesByServiceAccountID(serviceAccountID uint) ([]password.ServiceAccountScope, error) {
+	groupID := uint(7)
+	return []password.ServiceAccountScope{{GroupID: &groupID}}, nil
+}
+
+func (f renderServiceAccountRepository) GetTokenByHash(tokenHash string) (*password.ServiceAccountToken, error) {
+	return &password.ServiceAccountToken{}, nil
+}
+
+func (f renderServiceAccountRepository) GetAccessibleEntriesByGroupID(serviceAccountID, groupID uint) ([]password.PasswordEntry, error) {
+	return f.entries, nil
+}
+
+func (f renderServiceAccountRepository) GetEntryKey(serviceAccountID, entryID uint) (*password.ServiceAccountEntryKey, error) {
+	return &password.ServiceAccountEntryKey{}, nil
+}
+
+// plaintextEncryption stores entries unencrypted, so a test can read what RenderSecrets decrypted
+type plaintextEncryption struct {
+	encryption.Encryption
+}
+
+func (f plaintextEncryption) OpenPrivateKeyWithSecret(encryptedPrivateKey, salt, secret string) (*rsa.PrivateKey, error) {
+	return nil, nil
+}
+
+func (f plaintextEncryption) DecryptPasswordEntry(encUsername, encPassword, encNotes, wrappedAESKey string, privateKey *rsa.PrivateKey) (string, string, string, error) {
+	return encUsername, encPassword, encNotes, nil
+}
+
+type batchAccessLogRepository struct {
+	repository.PasswordAccessLogRepository
+	batches [][]password.PasswordAccessLog
+}
+
+func (f *batchAccessLogRepository) AddAccessLog(accessLog *password.PasswordAccessLog) error {
+	return errors.New("exports must be recorded in one batch")
+}
+
+func (f *batchAccessLogRepository) AddAccessLogs(accessLogs []password.PasswordAccessLog) error {
+	f.batches = append(f.batches, accessLogs)
+	return nil
+}
+
+func TestRenderSecretsLogsOnlyRenderedExports(t *testing.T) {
+	url := "https://db.example.com"
+	entries := []password.PasswordEntry{
+		{EntryID: 1, Title: "db password", Username: "admin", EncryptedPassword: "s3cret", URL: &url},
+		{EntryID: 2, Title: "api-token", Username: "ci", EncryptedPassword: "t0ken"},
+		{EntryID: 3, Title: "DB Password", Username: "backup", EncryptedPassword: "other"},
+		{EntryID: 4, Title: "---", Username: "nobody", EncryptedPassword: "none"},
+	}
+
+	tests := []struct {
+		name     string
+		entries  []password.PasswordEntry
+		keyField string
+		want     string
+		wantErr  bool
+	}{
+		{name: "renders", entries: entries[:2], want: "API_TOKEN=\"t0ken\"\nDB_PASSWORD=\"s3cret\"\n"},
+		{name: "duplicate key", entries: entries[:3], wantErr: true},
+		{name: "empty key", entries: []password.PasswordEntry{entries[0], entries[3]}, wantErr: true},
+		{name: "empty key field", entries: entries[:2], keyField: utils.RenderFieldURL, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessLogs := &batchAccessLogRepository{}
+			service := &serviceAccountService{
+				ServiceAccountRepository:    renderServiceAccountRepository{entries: tt.entries},
+				PasswordGroupRepository:     fakeGroupRepository{},
+				PasswordAccessLogRepository: accessLogs,
+				EncryptionService:           plaintextEncryption{},
+			}
+
+			rendered, _, err := service.RenderSecrets(&in.RenderSecretsRequest{Group: "7", Format: render.FormatDotenv, KeyField: tt.keyField},
+				&apitoken.ServiceTokenClaims{ServiceAccountID: 3, UserID: 1}, in.RequestMetadata{RequestID: "request-1"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("RenderSecrets rendered %q", rendered)
+				}
+				if len(accessLogs.batches) != 0 {
+					t.Errorf("a failed render recorded %d export batches", len(accessLogs.batches))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderSecrets: %v", err)
+			}
+
+			if string(rendered) != tt.want {
+				t.Errorf("rendered %q, want %q", rendered, tt.want)
+			}
+			if len(accessLogs.batches) != 1 || len(accessLogs.batches[0]) != len(tt.entries) {
+				t.Fatalf("recorded %v, want one batch of %d exports", accessLogs.batches, len(tt.entries))
+			}
+			for i, accessLog := range accessLogs.batches[0] {
+				if accessLog.EntryID != tt.entries[i].EntryID || accessLog.Action != utils.AccessActionExport {
+					t.Errorf("export %d = entry %d %s, want entry %d %s", i, accessLog.EntryID, accessLog.Action, tt.entries[i].EntryID, utils.AccessActionExport)
+				}
+			}
+		})
+	}
+}
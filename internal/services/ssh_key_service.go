@@ -0,0 +1,222 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/password"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/entrytype"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/text"
+)
+
+// maxSignDataSize matches the largest message an OpenSSH agent accepts
+const maxSignDataSize = 256 * 1024
+
+type SSHKeyService interface {
+	Sign(entryID uint, req *in.SSHSignRequest, clientID string, vaultKey []byte, metadata in.RequestMetadata) (interface{}, error)
+	GetAuthorizedKey(entryID uint, clientID string) (string, error)
+}
+
+type sshKeyService struct {
+	UserRepository              repository.UserRepository
+	UserKeyRepository           repository.UserKeysRepository
+	PasswordEntryRepository     repository.PasswordEntryRepository
+	PasswordEntryKeyRepository  repository.PasswordEntryKeysRepository
+	PasswordAccessLogRepository repository.PasswordAccessLogRepository
+	EncryptionService           encryption.Encryption
+	Redis                       redis.RedisService
+}
+
+func NewSSHKeyService(
+	userRepository repository.UserRepository,
+	userKeyRepository repository.UserKeysRepository,
+	passwordEntryRepository repository.PasswordEntryRepository,
+	passwordEntryKeysRepository repository.PasswordEntryKeysRepository,
+	passwordAccessLogRepository repository.PasswordAccessLogRepository,
+	encryptionService encryption.Encryption,
+	redis redis.RedisService) SSHKeyService {
+	return &sshKeyService{
+		UserRepository:              userRepository,
+		UserKeyRepository:           userKeyRepository,
+		PasswordEntryRepository:     passwordEntryRepository,
+		PasswordEntryKeyRepository:  passwordEntryKeysRepository,
+		PasswordAccessLogRepository: passwordAccessLogRepository,
+		EncryptionService:           encryptionService,
+		Redis:                       redis,
+	}
+}
+
+// Sign signs the data with the entry's SSH private key the way an SSH agent does. The private key is only
+// decrypted in memory and every signature is recorded in the access log.
+func (s *sshKeyService) Sign(entryID uint, req *in.SSHSignRequest, clientID string, vaultKey []byte, metadata in.RequestMetadata) (interface{}, error) {
+	data, err := base64.StdEncoding.DecodeString(req.Data)
+	if err != nil {
+		return nil, errors.New("data must be base64 encoded")
+	}
+	if len(data) == 0 || len(data) > maxSignDataSize {
+		return nil, fmt.Errorf("data must be between 1 and %d bytes", maxSignDataSize)
+	}
+
+	owner, entry, err := s.getSSHKeyEntry(entryID, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vaultKey) == 0 {
+		log.Error().Str("clientID", clientID).Msg("Vault is locked")
+		return nil, errors.New("vault is locked")
+	}
+	privateKey, err := s.UserKeyRepository.GetPrivateKeyWithDerivedKey(owner.UserID, vaultKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user keys")
+		return nil, err
+	}
+	if privateKey == nil {
+		log.Error().Str("clientID", clientID).Msg("User private key not found")
+		return nil, errors.New("user private key not found")
+	}
+	passwordEntryKey, err := s.PasswordEntryKeyRepository.GetPasswordEntryKeyByEntryID(entry.EntryID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry key")
+		return nil, err
+	}
+	if passwordEntryKey == nil {
+		log.Error().Str("clientID", clientID).Msg("Password entry key not found")
+		return nil, errors.New("password entry key not found")
+	}
+
+	document, err := s.EncryptionService.DecryptPasswordEntryField(text.DerefString(entry.EncryptedData), passwordEntryKey.EncryptedSymmetricKey, privateKey)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decrypt SSH key")
+		return nil, err
+	}
+	var keyData map[string]string
+	if err := json.Unmarshal([]byte(document), &keyData); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to decode SSH key")
+		return nil, err
+	}
+	signer, err := entrytype.SSHSigner(keyData)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to open SSH key")
+		return nil, err
+	}
+
+	signature, err := signWithAlgorithm(signer, data, req.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	accessLog := password.PasswordAccessLog{
+		EntryID:   entry.EntryID,
+		UserID:    owner.UserID,
+		Action:    utils.AccessActionSSHSign,
+		Fields:    pq.StringArray{"private_key"},
+		RequestID: text.NilIfEmpty(metadata.RequestID),
+		IPAddress: text.NilIfEmpty(metadata.IPAddress),
+		UserAgent: text.NilIfEmpty(metadata.UserAgent),
+		CreatedBy: &clientID,
+	}
+	if err := s.PasswordAccessLogRepository.AddAccessLog(&accessLog); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to record SSH signature")
+		return nil, err
+	}
+
+	digest := sha256.Sum256(data)
+	fingerprint := ssh.FingerprintSHA256(signer.PublicKey())
+	log.Info().Str("clientID", clientID).Uint("entryID", entry.EntryID).Str("fingerprint", fingerprint).
+		Str("format", signature.Format).Str("dataSHA256", hex.EncodeToString(digest[:])).Msg("SSH data signed")
+
+	return out.SSHSignResponse{
+		EntryID:     entry.EntryID,
+		Format:      signature.Format,
+		Signature:   base64.StdEncoding.EncodeToString(ssh.Marshal(signature)),
+		Fingerprint: fingerprint,
+	}, nil
+}
+
+// GetAuthorizedKey returns the authorized_keys line recorded when the key was saved; no secret is decrypted
+func (s *sshKeyService) GetAuthorizedKey(entryID uint, clientID string) (string, error) {
+	_, entry, err := s.getSSHKeyEntry(entryID, clientID)
+	if err != nil {
+		return "", err
+	}
+
+	var metadata struct {
+		PublicKey string `json:"public_key"`
+	}
+	if err := json.Unmarshal([]byte(text.DerefString(entry.Metadata)), &metadata); err != nil || metadata.PublicKey == "" {
+		log.Error().Str("clientID", clientID).Uint("entryID", entry.EntryID).Msg("SSH public key not recorded")
+		return "", errors.New("SSH public key not found")
+	}
+	return metadata.PublicKey + "\n", nil
+}
+
+func (s *sshKeyService) getSSHKeyEntry(entryID uint, clientID string) (*user.Users, *password.PasswordEntry, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, nil, err
+	}
+	owner, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, nil, err
+	}
+	if owner == nil {
+		log.Error().Str("clientID", clientID).Msg("User not found")
+		return nil, nil, errors.New("user not found")
+	}
+
+	entry, err := s.PasswordEntryRepository.GetPasswordEntryByEntryIDAndUserID(entryID, owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve password entry")
+		return nil, nil, err
+	}
+	if entry == nil {
+		log.Error().Str("clientID", clientID).Msg("Password entry not found")
+		return nil, nil, errors.New("password entry not found")
+	}
+	if entry.EntryType != entrytype.SSHKey {
+		return nil, nil, errors.New("password entry is not an SSH key")
+	}
+	return owner, entry, nil
+}
+
+// signWithAlgorithm defaults RSA keys to rsa-sha2-512, since servers increasingly reject SHA-1 ssh-rsa signatures
+func signWithAlgorithm(signer ssh.Signer, data []byte, algorithm string) (*ssh.Signature, error) {
+	keyType := signer.PublicKey().Type()
+	if keyType != ssh.KeyAlgoRSA {
+		if algorithm != "" && algorithm != keyType {
+			return nil, fmt.Errorf("algorithm %s is not supported for %s keys", algorithm, keyType)
+		}
+		return signer.Sign(rand.Reader, data)
+	}
+
+	switch algorithm {
+	case "":
+		algorithm = ssh.KeyAlgoRSASHA512
+	case ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSA:
+	default:
+		return nil, fmt.Errorf("algorithm %s is not supported for %s keys", algorithm, keyType)
+	}
+
+	algorithmSigner, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, errors.New("SSH key does not support algorithm selection")
+	}
+	return algorithmSigner.SignWithAlgorithm(rand.Reader, data, algorithm)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/redis"
+)
+
+type UserDeviceService interface {
+	GetListDevices(clientID string) (interface{}, error)
+	RevokeDevice(deviceID uint, clientID string) error
+	ApproveDevice(deviceID uint, clientID string) error
+}
+
+type userDeviceService struct {
+	UserRepository       repository.UserRepository
+	UserDeviceRepository repository.UserDeviceRepository
+	Redis                redis.RedisService
+}
+
+func NewUserDeviceService(
+	userRepository repository.UserRepository,
+	userDeviceRepository repository.UserDeviceRepository,
+	redis redis.RedisService) UserDeviceService {
+	return &userDeviceService{
+		UserRepository:       userRepository,
+		UserDeviceRepository: userDeviceRepository,
+		Redis:                redis,
+	}
+}
+
+func (s *userDeviceService) GetListDevices(clientID string) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+
+	devices, err := s.UserDeviceRepository.GetDevicesByUserID(user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve devices")
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// RevokeDevice blocks the device and drops the active session when it belongs to that device
+func (s *userDeviceService) RevokeDevice(deviceID uint, clientID string) error {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return err
+	}
+
+	device, err := s.UserDeviceRepository.GetDeviceByIDAndUserID(deviceID, user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve device")
+		return err
+	}
+	if device.RevokedAt != nil {
+		return errors.New("device already revoked")
+	}
+
+	if err := s.UserDeviceRepository.RevokeDevice(device.DeviceID, clientID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to revoke device")
+		return err
+	}
+
+	// The password middleware trusts a recently seen device without reading it back, until this marker expires
+	if err := s.Redis.DeleteData(utils.DeviceSeen, device.ClientID+":"+device.DeviceIdentifier); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to clear device marker")
+		return err
+	}
+
+	if s.isCurrentSession(device) {
+		if err := s.Redis.DeleteToken(device.ClientID); err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to delete session token")
+			return err
+		}
+		if err := s.Redis.DeleteData(utils.VaultSession, device.ClientID); err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to lock vault session")
+			return err
+		}
+	}
+
+	log.Info().Str("clientID", clientID).Uint("deviceID", device.DeviceID).Msg("Device revoked")
+	return nil
+}
+
+// ApproveDevice lets a revoked device sign in again. It is called from another device of the user, since the
+// revoked one is refused by the password middleware.
+func (s *userDeviceService) ApproveDevice(deviceID uint, clientID string) error {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return err
+	}
+
+	device, err := s.UserDeviceRepository.GetDeviceByIDAndUserID(deviceID, user.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve device")
+		return err
+	}
+	if device.RevokedAt == nil {
+		return errors.New("device is not revoked")
+	}
+
+	if err := s.UserDeviceRepository.ApproveDevice(device.DeviceID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to approve device")
+		return err
+	}
+
+	log.Info().Str("clientID", clientID).Uint("deviceID", device.DeviceID).Msg("Device approved")
+	return nil
+}
+
+func (s *userDeviceService) isCurrentSession(device *user.UserDevice) bool {
+	if device.AccessUUID == nil {
+		return false
+	}
+
+	stored, err := s.Redis.GetToken(device.ClientID)
+	if err != nil || stored == "" {
+		return false
+	}
+
+	var tokenDetails user.TokenDetails
+	if err := json.Unmarshal([]byte(stored), &tokenDetails); err == nil && tokenDetails.AccessUUID != "" {
+		return tokenDetails.AccessUUID == *device.AccessUUID
+	}
+	return stored == *device.AccessUUID
+}
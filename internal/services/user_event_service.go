@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/event"
+	"password-management-service/internal/utils/redis"
+	"strconv"
+	"time"
+)
+
+// eventDoneTTL bounds how long handled event IDs are remembered for deduplication
+const eventDoneTTL = 7 * 24 * time.Hour
+
+type UserEventService interface {
+	HandleUserEvent(subject string, data []byte) error
+}
+
+type userEventService struct {
+	UserRepository  repository.UserRepository
+	InternalService InternalService
+	Redis           redis.RedisService
+	Subjects        map[string]string
+}
+
+// NewUserEventService maps the configured subjects to event types; an explicit type in the payload wins
+func NewUserEventService(
+	userRepository repository.UserRepository,
+	internalService InternalService,
+	redis redis.RedisService,
+	subjects map[string]string) UserEventService {
+	return &userEventService{
+		UserRepository:  userRepository,
+		InternalService: internalService,
+		Redis:           redis,
+		Subjects:        subjects,
+	}
+}
+
+func (s *userEventService) HandleUserEvent(subject string, data []byte) error {
+	var evt event.UserEvent
+	if err := json.Unmarshal(data, &evt); err != nil {
+		return event.Permanent(fmt.Errorf("invalid event payload: %v", err))
+	}
+	if evt.UserID == 0 {
+		return event.Permanent(errors.New("event is missing user_id"))
+	}
+
+	eventType := evt.Type
+	if eventType == "" {
+		eventType = s.Subjects[subject]
+	}
+	eventID := evt.EventID
+	if eventID == "" {
+		eventID = eventType + ":" + strconv.FormatUint(uint64(evt.UserID), 10) + ":" + strconv.FormatInt(evt.OccurredAt, 10)
+	}
+
+	if done, err := s.Redis.Exists(utils.EventDone, eventID); err == nil && done {
+		log.Info().Str("eventID", eventID).Str("type", eventType).Msg("Event already handled, skipping")
+		return nil
+	}
+
+	var err error
+	switch eventType {
+	case utils.EventUserCreated:
+		_, err = s.InternalService.BootstrapUserKey(evt.UserID, "nats")
+	case utils.EventUserDeleted:
+		_, err = s.InternalService.DeleteUser(evt.UserID, "nats")
+	case utils.EventUserRoleChanged:
+		err = s.invalidateSessions(evt)
+	default:
+		return event.Permanent(fmt.Errorf("unsupported event type %q on subject %s", eventType, subject))
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.Redis.SaveDataWithTTL(utils.EventDone, eventID, time.Now().Unix(), eventDoneTTL); err != nil {
+		log.Error().Str("eventID", eventID).Err(err).Msg("Failed to mark event as handled")
+	}
+	log.Info().Str("eventID", eventID).Str("type", eventType).Uint("userID", evt.UserID).Msg("Event handled")
+	return nil
+}
+
+// invalidateSessions forces the user to log in again so the new role is picked up, and locks the vault
+func (s *userEventService) invalidateSessions(evt event.UserEvent) error {
+	clientID := evt.ClientID
+	if clientID == "" {
+		user, err := s.UserRepository.GetUserByID(evt.UserID)
+		if err != nil {
+			return err
+		}
+		clientID = user.ClientID
+	}
+
+	if err := s.Redis.DeleteToken(clientID); err != nil {
+		return err
+	}
+	for _, key := range []string{utils.VaultSession, utils.PinVerify} {
+		if err := s.Redis.DeleteData(key, clientID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/encryption"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/vault"
+	"time"
+)
+
+type VaultService interface {
+	UnlockVault(clientID string) (interface{}, error)
+	LockVault(clientID string) error
+	RecheckUnlockedVaults() (int, error)
+}
+
+type vaultService struct {
+	UserRepository    repository.UserRepository
+	UserKeyRepository repository.UserKeysRepository
+	EncryptionService encryption.Encryption
+	BreachService     BreachService
+	Redis             redis.RedisService
+	IdleTimeout       time.Duration
+	MaxAge            time.Duration
+}
+
+func NewVaultService(
+	userRepository repository.UserRepository,
+	userKeyRepository repository.UserKeysRepository,
+	encryptionService encryption.Encryption,
+	breachService BreachService,
+	redis redis.RedisService,
+	idleTimeout time.Duration,
+	maxAge time.Duration) VaultService {
+	return &vaultService{
+		UserRepository:    userRepository,
+		UserKeyRepository: userKeyRepository,
+		EncryptionService: encryptionService,
+		BreachService:     breachService,
+		Redis:             redis,
+		IdleTimeout:       idleTimeout,
+		MaxAge:            maxAge,
+	}
+}
+
+// UnlockVault derives the key protecting the user's private key and parks it in Redis under a new
+// session token. Unlocking again replaces the previous session. The unlocked key is also handed to the
+// breach recheck, which only reads stored passwords while a vault is unlocked.
+func (s *vaultService) UnlockVault(clientID string) (interface{}, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	user, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+	if user == nil {
+		log.Error().Str("clientID", clientID).Msg("User not found")
+		return nil, errors.New("user not found")
+	}
+
+	if _, err := s.UserKeyRepository.GetUserKeys(user.UserID); errors.Is(err, gorm.ErrRecordNotFound) {
+		userKey, err := s.EncryptionService.GenerateUserKey(user)
+		if err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to generate user key pair")
+			return nil, err
+		}
+		if err := s.UserKeyRepository.AddUserKey(userKey); err != nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to add user key")
+			return nil, err
+		}
+	}
+
+	derivedKey, err := s.UserKeyRepository.GetDerivedKeyByUserID(user.UserID, user.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to derive vault key")
+		return nil, err
+	}
+
+	sessionToken, tokenHash, err := vault.GenerateToken()
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to generate vault session token")
+		return nil, err
+	}
+	encryptedKey, err := vault.Seal(derivedKey, sessionToken)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to seal vault key")
+		return nil, err
+	}
+
+	now := time.Now()
+	session := vault.Session{
+		TokenHash:    tokenHash,
+		EncryptedKey: encryptedKey,
+		CreatedAt:    now.Unix(),
+		LastUsedAt:   now.Unix(),
+	}
+	ttl := s.IdleTimeout
+	if s.MaxAge > 0 && s.MaxAge < ttl {
+		ttl = s.MaxAge
+	}
+	if err := s.Redis.SaveDataWithTTL(utils.VaultSession, clientID, session, ttl); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to save vault session")
+		return nil, err
+	}
+
+	var expiresAt int64
+	if s.MaxAge > 0 {
+		expiresAt = now.Add(s.MaxAge).Unix()
+	}
+
+	s.BreachService.RecheckEntries(user, derivedKey)
+
+	log.Info().Str("clientID", clientID).Msg("Vault unlocked")
+	return out.VaultSessionResponse{
+		SessionToken: sessionToken,
+		Header:       utils.XVaultSession,
+		IdleTimeout:  int64(s.IdleTimeout.Seconds()),
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+func (s *vaultService) LockVault(clientID string) error {
+	if err := s.Redis.DeleteData(utils.VaultSession, clientID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to lock vault")
+		return err
+	}
+
+	log.Info().Str("clientID", clientID).Msg("Vault locked")
+	return nil
+}
+
+// RecheckUnlockedVaults queues a breach recheck for every user with a live vault session, so entries of a vault
+// that stays unlocked are checked again after the recheck interval and not only when it is unlocked.
+func (s *vaultService) RecheckUnlockedVaults() (int, error) {
+	clientIDs, err := s.Redis.ScanClientIDs(utils.VaultSession)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list unlocked vaults")
+		return 0, err
+	}
+
+	queued := 0
+	for _, clientID := range clientIDs {
+		user, err := s.UserRepository.GetUserByClientID(clientID)
+		if err != nil || user == nil {
+			log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+			continue
+		}
+		s.BreachService.RecheckDueEntries(user)
+		queued++
+	}
+	return queued, nil
+}
@@ -0,0 +1,518 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/redis"
+	"password-management-service/internal/utils/webauthn"
+	"strings"
+	"time"
+)
+
+const (
+	challengeSize            = 32
+	defaultAuthenticatorName = "Security key"
+	maxCredentialIDLen       = 1023
+)
+
+// authenticatorTransports are the transport hints kept from a registration; unknown hints are dropped
+var authenticatorTransports = map[string]bool{
+	"usb": true, "nfc": true, "ble": true, "smart-card": true, "hybrid": true, "internal": true,
+}
+
+type WebAuthnService interface {
+	GetListAuthenticators(clientID string) (interface{}, error)
+	BeginRegistration(clientID string) (interface{}, error)
+	FinishRegistration(req *in.AuthenticatorRegistrationRequest, clientID string) (interface{}, error)
+	BeginAuthentication(clientID string) (interface{}, error)
+	FinishAuthentication(req *in.AuthenticatorAssertionRequest, clientID, requestID string) (interface{}, error)
+	DeleteAuthenticator(authenticatorID uint, clientID string) error
+	ResetAuthenticators(userID uint, clientID string) (interface{}, error)
+}
+
+type webAuthnService struct {
+	UserRepository              repository.UserRepository
+	UserAuthenticatorRepository repository.UserAuthenticatorRepository
+	Redis                       redis.RedisService
+	RelyingParty                webauthn.RelyingParty
+	Timeout                     time.Duration
+	StepUpMaxAge                time.Duration
+}
+
+func NewWebAuthnService(
+	userRepository repository.UserRepository,
+	userAuthenticatorRepository repository.UserAuthenticatorRepository,
+	redis redis.RedisService,
+	relyingParty webauthn.RelyingParty,
+	timeout time.Duration,
+	stepUpMaxAge time.Duration) WebAuthnService {
+	switch relyingParty.UserVerification {
+	case webauthn.UserVerificationRequired, webauthn.UserVerificationDiscouraged:
+	default:
+		relyingParty.UserVerification = webauthn.UserVerificationPreferred
+	}
+	origins := make([]string, 0, len(relyingParty.Origins))
+	for _, origin := range relyingParty.Origins {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	relyingParty.Origins = origins
+	// A verification has to expire even when step-up records are accepted at any age
+	if stepUpMaxAge <= 0 {
+		stepUpMaxAge = timeout
+	}
+
+	return &webAuthnService{
+		UserRepository:              userRepository,
+		UserAuthenticatorRepository: userAuthenticatorRepository,
+		Redis:                       redis,
+		RelyingParty:                relyingParty,
+		Timeout:                     timeout,
+		StepUpMaxAge:                stepUpMaxAge,
+	}
+}
+
+// webAuthnSession is the pending ceremony of a client. It is consumed by the finishing request, so a challenge
+// can only be answered once.
+type webAuthnSession struct {
+	Ceremony  string `json:"ceremony"`
+	Challenge string `json:"challenge"`
+	UserID    uint   `json:"user_id"`
+}
+
+func (s *webAuthnService) GetListAuthenticators(clientID string) (interface{}, error) {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticators, err := s.UserAuthenticatorRepository.GetAuthenticatorsByUserID(owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve authenticators")
+		return nil, err
+	}
+
+	responses := make([]out.AuthenticatorResponse, 0, len(authenticators))
+	for _, authenticator := range authenticators {
+		responses = append(responses, out.AuthenticatorResponse{
+			AuthenticatorID:   authenticator.AuthenticatorID,
+			Name:              authenticator.Name,
+			CredentialID:      webauthn.EncodeBase64URL(authenticator.CredentialID),
+			AAGUID:            authenticator.AAGUID,
+			Algorithm:         authenticator.Algorithm,
+			AttestationFormat: authenticator.AttestationFormat,
+			AttestationType:   authenticator.AttestationType,
+			Transports:        authenticator.Transports,
+			BackupEligible:    authenticator.BackupEligible,
+			BackupState:       authenticator.BackupState,
+			SignCount:         authenticator.SignCount,
+			CreatedAt:         authenticator.CreatedAt,
+			LastUsedAt:        authenticator.LastUsedAt,
+		})
+	}
+	return responses, nil
+}
+
+// BeginRegistration starts registering an authenticator. Authenticators already registered are excluded, so the
+// same one cannot be registered twice.
+func (s *webAuthnService) BeginRegistration(clientID string) (interface{}, error) {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+	authenticators, err := s.UserAuthenticatorRepository.GetAuthenticatorsByUserID(owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve authenticators")
+		return nil, err
+	}
+
+	challenge, err := s.startSession(clientID, webauthn.CeremonyCreate, owner.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := owner.FullName
+	if displayName == "" {
+		displayName = owner.Username
+	}
+	return out.CredentialCreationOptions{
+		RP: out.RelyingPartyEntity{ID: s.RelyingParty.ID, Name: s.RelyingParty.Name},
+		User: out.UserEntity{
+			ID:          webauthn.EncodeBase64URL(userHandle(owner.UserID)),
+			Name:        owner.Username,
+			DisplayName: displayName,
+		},
+		Challenge: challenge,
+		PubKeyCredParams: []out.CredentialParameter{
+			{Type: "public-key", Algorithm: webauthn.AlgES256},
+			{Type: "public-key", Algorithm: webauthn.AlgEdDSA},
+			{Type: "public-key", Algorithm: webauthn.AlgRS256},
+		},
+		Timeout:            s.Timeout.Milliseconds(),
+		ExcludeCredentials: credentialDescriptors(authenticators),
+		AuthenticatorSelection: out.AuthenticatorSelection{
+			ResidentKey:      "discouraged",
+			UserVerification: s.RelyingParty.UserVerification,
+		},
+		Attestation: "direct",
+	}, nil
+}
+
+// FinishRegistration verifies the attestation of a new authenticator and stores its credential
+func (s *webAuthnService) FinishRegistration(req *in.AuthenticatorRegistrationRequest, clientID string) (interface{}, error) {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.finishSession(clientID, webauthn.CeremonyCreate, owner.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataJSON, err := webauthn.DecodeBase64URL(req.ClientDataJSON)
+	if err != nil {
+		return nil, errors.New("client_data_json must be base64url encoded")
+	}
+	if _, err := webauthn.VerifyClientData(clientDataJSON, webauthn.CeremonyCreate, challenge, s.RelyingParty.Origins); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Invalid registration client data")
+		return nil, err
+	}
+	attestationObject, err := webauthn.DecodeBase64URL(req.AttestationObject)
+	if err != nil {
+		return nil, errors.New("attestation_object must be base64url encoded")
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	attestation, err := webauthn.VerifyAttestation(attestationObject, clientDataHash[:])
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Invalid attestation")
+		return nil, err
+	}
+
+	authData := attestation.AuthData
+	if err := s.checkAuthData(authData); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Invalid registration authenticator data")
+		return nil, err
+	}
+	credentialID, err := webauthn.DecodeBase64URL(req.ID)
+	if err != nil || string(credentialID) != string(authData.CredentialID) {
+		return nil, errors.New("credential ID does not match the attested credential")
+	}
+	if len(credentialID) > maxCredentialIDLen {
+		return nil, fmt.Errorf("credential ID must be at most %d bytes", maxCredentialIDLen)
+	}
+	algorithm, err := webauthn.Algorithm(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = defaultAuthenticatorName
+	}
+	transports := pq.StringArray{}
+	for _, transport := range req.Transports {
+		if authenticatorTransports[transport] {
+			transports = append(transports, transport)
+		}
+	}
+
+	authenticator := user.UserAuthenticator{
+		UserID:            owner.UserID,
+		Name:              name,
+		CredentialID:      credentialID,
+		PublicKey:         authData.PublicKey,
+		Algorithm:         algorithm,
+		SignCount:         authData.SignCount,
+		AAGUID:            formatAAGUID(authData.AAGUID),
+		AttestationFormat: attestation.Format,
+		AttestationType:   attestation.Type,
+		Transports:        transports,
+		BackupEligible:    authData.Has(webauthn.FlagBackupEligible),
+		BackupState:       authData.Has(webauthn.FlagBackupState),
+		CreatedAt:         time.Now(),
+		CreatedBy:         &clientID,
+	}
+	if err := s.UserAuthenticatorRepository.AddAuthenticator(&authenticator); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to add authenticator")
+		return nil, err
+	}
+
+	log.Info().Str("clientID", clientID).Uint("authenticatorID", authenticator.AuthenticatorID).
+		Str("format", attestation.Format).Str("aaguid", authenticator.AAGUID).Msg("Authenticator registered")
+	return out.AuthenticatorResponse{
+		AuthenticatorID:   authenticator.AuthenticatorID,
+		Name:              authenticator.Name,
+		CredentialID:      webauthn.EncodeBase64URL(authenticator.CredentialID),
+		AAGUID:            authenticator.AAGUID,
+		Algorithm:         authenticator.Algorithm,
+		AttestationFormat: authenticator.AttestationFormat,
+		AttestationType:   authenticator.AttestationType,
+		Transports:        authenticator.Transports,
+		BackupEligible:    authenticator.BackupEligible,
+		BackupState:       authenticator.BackupState,
+		SignCount:         authenticator.SignCount,
+		CreatedAt:         authenticator.CreatedAt,
+	}, nil
+}
+
+// BeginAuthentication starts an assertion with one of the user's authenticators
+func (s *webAuthnService) BeginAuthentication(clientID string) (interface{}, error) {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+	authenticators, err := s.UserAuthenticatorRepository.GetAuthenticatorsByUserID(owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve authenticators")
+		return nil, err
+	}
+	if len(authenticators) == 0 {
+		return nil, errors.New("no authenticator is registered")
+	}
+
+	challenge, err := s.startSession(clientID, webauthn.CeremonyGet, owner.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return out.CredentialRequestOptions{
+		Challenge:        challenge,
+		Timeout:          s.Timeout.Milliseconds(),
+		RPID:             s.RelyingParty.ID,
+		AllowCredentials: credentialDescriptors(authenticators),
+		UserVerification: s.RelyingParty.UserVerification,
+	}, nil
+}
+
+// FinishAuthentication verifies an assertion and records it as the step-up verification of the request ID, the
+// same record the PIN flow writes. A sign count that does not increase means the authenticator may be cloned, so
+// the assertion is refused.
+func (s *webAuthnService) FinishAuthentication(req *in.AuthenticatorAssertionRequest, clientID, requestID string) (interface{}, error) {
+	if requestID == "" {
+		return nil, fmt.Errorf("%s header is required", utils.XRequestID)
+	}
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.finishSession(clientID, webauthn.CeremonyGet, owner.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID, err := webauthn.DecodeBase64URL(req.ID)
+	if err != nil {
+		return nil, errors.New("id must be base64url encoded")
+	}
+	authenticator, err := s.UserAuthenticatorRepository.GetAuthenticatorByCredentialIDAndUserID(credentialID, owner.UserID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve authenticator")
+		return nil, errors.New("authenticator is not registered")
+	}
+	if req.UserHandle != "" {
+		handle, err := webauthn.DecodeBase64URL(req.UserHandle)
+		if err != nil || string(handle) != string(userHandle(owner.UserID)) {
+			return nil, errors.New("user handle does not match")
+		}
+	}
+
+	clientDataJSON, err := webauthn.DecodeBase64URL(req.ClientDataJSON)
+	if err != nil {
+		return nil, errors.New("client_data_json must be base64url encoded")
+	}
+	if _, err := webauthn.VerifyClientData(clientDataJSON, webauthn.CeremonyGet, challenge, s.RelyingParty.Origins); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Invalid assertion client data")
+		return nil, err
+	}
+	rawAuthData, err := webauthn.DecodeBase64URL(req.AuthenticatorData)
+	if err != nil {
+		return nil, errors.New("authenticator_data must be base64url encoded")
+	}
+	authData, err := webauthn.ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAuthData(authData); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Invalid assertion authenticator data")
+		return nil, err
+	}
+	if authData.Has(webauthn.FlagBackupEligible) != authenticator.BackupEligible {
+		return nil, errors.New("authenticator backup eligibility changed")
+	}
+	signature, err := webauthn.DecodeBase64URL(req.Signature)
+	if err != nil {
+		return nil, errors.New("signature must be base64url encoded")
+	}
+	if err := webauthn.VerifyAssertion(authenticator.PublicKey, rawAuthData, clientDataJSON, signature); err != nil {
+		log.Error().Str("clientID", clientID).Uint("authenticatorID", authenticator.AuthenticatorID).Err(err).Msg("Invalid assertion signature")
+		return nil, err
+	}
+
+	if (authData.SignCount != 0 || authenticator.SignCount != 0) && authData.SignCount <= authenticator.SignCount {
+		log.Warn().Str("clientID", clientID).Uint("authenticatorID", authenticator.AuthenticatorID).
+			Uint32("stored", authenticator.SignCount).Uint32("received", authData.SignCount).Msg("Authenticator sign count did not increase")
+		return nil, errors.New("authenticator sign count did not increase, the authenticator may be cloned")
+	}
+	if err := s.UserAuthenticatorRepository.UpdateSignCount(authenticator, authData.SignCount, authData.Has(webauthn.FlagBackupState)); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to update authenticator sign count")
+		return nil, err
+	}
+
+	verifiedAt := time.Now()
+	verify := out.VerifyPinCodeResponse{
+		ClientID:   clientID,
+		RequestID:  requestID,
+		Valid:      true,
+		VerifiedAt: verifiedAt.Unix(),
+		Method:     utils.StepUpMethodWebAuthn,
+	}
+	if err := s.Redis.SaveDataWithTTL(utils.PinVerify, clientID, verify, s.StepUpMaxAge); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to save verification")
+		return nil, err
+	}
+	_ = s.Redis.DeleteData(utils.PinAttempts, clientID)
+
+	log.Info().Str("clientID", clientID).Uint("authenticatorID", authenticator.AuthenticatorID).Msg("Authenticator assertion verified")
+	return out.StepUpVerificationResponse{
+		RequestID: requestID,
+		Header:    utils.XRequestID,
+		Method:    utils.StepUpMethodWebAuthn,
+		ExpiresAt: verifiedAt.Add(s.StepUpMaxAge).Unix(),
+	}, nil
+}
+
+func (s *webAuthnService) DeleteAuthenticator(authenticatorID uint, clientID string) error {
+	owner, err := s.getUser(clientID)
+	if err != nil {
+		return err
+	}
+	if err := s.UserAuthenticatorRepository.DeleteAuthenticator(authenticatorID, owner.UserID); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to delete authenticator")
+		return err
+	}
+
+	log.Info().Str("clientID", clientID).Uint("authenticatorID", authenticatorID).Msg("Authenticator deleted")
+	return nil
+}
+
+// ResetAuthenticators is the recovery path of a user who lost their only authenticator: an admin removes all of
+// them, so step-up falls back to the PIN until a new one is registered. A pending ceremony and any step-up
+// verification of the user are dropped with them.
+func (s *webAuthnService) ResetAuthenticators(userID uint, clientID string) (interface{}, error) {
+	owner, err := s.UserRepository.GetUserByID(userID)
+	if err != nil {
+		log.Error().Uint("userID", userID).Err(err).Msg("Failed to retrieve user by ID")
+		return nil, errors.New("user not found")
+	}
+
+	deleted, err := s.UserAuthenticatorRepository.ResetAuthenticators(owner.UserID, clientID)
+	if err != nil {
+		log.Error().Uint("userID", userID).Err(err).Msg("Failed to reset authenticators")
+		return nil, err
+	}
+	_ = s.Redis.DeleteData(utils.WebAuthnChallenge, owner.ClientID)
+	_ = s.Redis.DeleteData(utils.PinVerify, owner.ClientID)
+
+	log.Info().Str("clientID", clientID).Uint("userID", userID).Int64("authenticators", deleted).Msg("Authenticators reset")
+	return out.AuthenticatorResetResponse{
+		UserID:         owner.UserID,
+		Authenticators: deleted,
+	}, nil
+}
+
+// checkAuthData applies the checks shared by both ceremonies: the RP ID, user presence, user verification when
+// the relying party requires it, and consistent backup flags
+func (s *webAuthnService) checkAuthData(authData *webauthn.AuthData) error {
+	if err := authData.VerifyRPID(s.RelyingParty.ID); err != nil {
+		return err
+	}
+	if !authData.Has(webauthn.FlagUserPresent) {
+		return errors.New("user presence is required")
+	}
+	if s.RelyingParty.UserVerification == webauthn.UserVerificationRequired && !authData.Has(webauthn.FlagUserVerified) {
+		return errors.New("user verification is required")
+	}
+	if authData.Has(webauthn.FlagBackupState) && !authData.Has(webauthn.FlagBackupEligible) {
+		return errors.New("authenticator is backed up but not backup eligible")
+	}
+	return nil
+}
+
+func (s *webAuthnService) startSession(clientID, ceremony string, userID uint) (string, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+
+	session := webAuthnSession{
+		Ceremony:  ceremony,
+		Challenge: webauthn.EncodeBase64URL(challenge),
+		UserID:    userID,
+	}
+	if err := s.Redis.SaveDataWithTTL(utils.WebAuthnChallenge, clientID, session, s.Timeout); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to save WebAuthn challenge")
+		return "", err
+	}
+	return session.Challenge, nil
+}
+
+func (s *webAuthnService) finishSession(clientID, ceremony string, userID uint) ([]byte, error) {
+	var session webAuthnSession
+	if err := s.Redis.ConsumeData(utils.WebAuthnChallenge, clientID, &session); err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("No pending WebAuthn ceremony")
+		return nil, errors.New("no pending WebAuthn ceremony, start again")
+	}
+	if session.Ceremony != ceremony || session.UserID != userID {
+		return nil, errors.New("no pending WebAuthn ceremony, start again")
+	}
+	return webauthn.DecodeBase64URL(session.Challenge)
+}
+
+func (s *webAuthnService) getUser(clientID string) (*user.Users, error) {
+	data, err := redis.GetUserRedis(s.Redis, utils.User, clientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve data from Redis")
+		return nil, err
+	}
+	owner, err := s.UserRepository.GetUserByClientID(data.ClientID)
+	if err != nil {
+		log.Error().Str("clientID", clientID).Err(err).Msg("Failed to retrieve user by client ID")
+		return nil, err
+	}
+	if owner == nil || owner.UserID == 0 {
+		log.Error().Str("clientID", clientID).Msg("User not found")
+		return nil, errors.New("user not found")
+	}
+	return owner, nil
+}
+
+// userHandle is the WebAuthn user ID. It must not identify the person, so it is the internal user ID.
+func userHandle(userID uint) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
+
+func credentialDescriptors(authenticators []user.UserAuthenticator) []out.CredentialDescriptor {
+	descriptors := make([]out.CredentialDescriptor, 0, len(authenticators))
+	for _, authenticator := range authenticators {
+		descriptors = append(descriptors, out.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         webauthn.EncodeBase64URL(authenticator.CredentialID),
+			Transports: authenticator.Transports,
+		})
+	}
+	return descriptors
+}
+
+func formatAAGUID(aaguid [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", aaguid[0:4], aaguid[4:6], aaguid[6:8], aaguid[8:10], aaguid[10:16])
+}
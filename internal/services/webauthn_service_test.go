@@ -0,0 +1,366 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"password-management-service/internal/dto/in"
+	"password-management-service/internal/dto/out"
+	"password-management-service/internal/models/user"
+	"password-management-service/internal/repository"
+	"password-management-service/internal/utils"
+	"password-management-service/internal/utils/webauthn"
+	"testing"
+	"time"
+)
+
+// fakeAuthenticatorRepository keeps the registered authenticators in memory. UpdateSignCount only applies over the
+// count the assertion was checked against, as the database update does.
+type fakeAuthenticatorRepository struct {
+	repository.UserAuthenticatorRepository
+	authenticators []user.UserAuthenticator
+}
+
+func (f *fakeAuthenticatorRepository) AddAuthenticator(authenticator *user.UserAuthenticator) error {
+	authenticator.AuthenticatorID = uint(len(f.authenticators) + 1)
+	f.authenticators = append(f.authenticators, *authenticator)
+	return nil
+}
+
+func (f *fakeAuthenticatorRepository) GetAuthenticatorsByUserID(userID uint) ([]user.UserAuthenticator, error) {
+	return f.authenticators, nil
+}
+
+func (f *fakeAuthenticatorRepository) GetAuthenticatorByCredentialIDAndUserID(credentialID []byte, userID uint) (*user.UserAuthenticator, error) {
+	for _, authenticator := range f.authenticators {
+		if bytes.Equal(authenticator.CredentialID, credentialID) && authenticator.UserID == userID {
+			return &authenticator, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (f *fakeAuthenticatorRepository) UpdateSignCount(authenticator *user.UserAuthenticator, signCount uint32, backupState bool) error {
+	for i := range f.authenticators {
+		stored := &f.authenticators[i]
+		if stored.AuthenticatorID != authenticator.AuthenticatorID {
+			continue
+		}
+		if stored.SignCount != authenticator.SignCount {
+			return repository.ErrSignCountChanged
+		}
+		stored.SignCount = signCount
+		stored.BackupState = backupState
+		return nil
+	}
+	return repository.ErrSignCountChanged
+}
+
+func (f *fakeAuthenticatorRepository) ResetAuthenticators(userID uint, actor string) (int64, error) {
+	deleted := int64(len(f.authenticators))
+	f.authenticators = nil
+	return deleted, nil
+}
+
+// fakeSessionRedis stores what the WebAuthn ceremonies keep in Redis; the user lookups come from fakeRedis
+type fakeSessionRedis struct {
+	fakeRedis
+	data map[string][]byte
+}
+
+func (f *fakeSessionRedis) SaveDataWithTTL(key, clientID string, data interface{}, ttl time.Duration) error {
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	f.data[key+":"+clientID] = value
+	return nil
+}
+
+func (f *fakeSessionRedis) ConsumeData(key, clientID string, target interface{}) error {
+	value, found := f.data[key+":"+clientID]
+	if !found {
+		return errors.New("redis: nil")
+	}
+	delete(f.data, key+":"+clientID)
+	return json.Unmarshal(value, target)
+}
+
+func (f *fakeSessionRedis) DeleteData(key, clientID string) error {
+	delete(f.data, key+":"+clientID)
+	return nil
+}
+
+type fakeUserByIDRepository struct {
+	fakeUserRepository
+}
+
+func (f fakeUserByIDRepository) GetUserByID(id uint) (*user.Users, error) {
+	if id != 1 {
+		return nil, errors.New("record not found")
+	}
+	return &user.Users{UserID: 1, ClientID: testClientID}, nil
+}
+
+type authenticatorFixture struct {
+	service        WebAuthnService
+	authenticators *fakeAuthenticatorRepository
+	redis          *fakeSessionRedis
+	key            *ecdsa.PrivateKey
+	credentialID   []byte
+}
+
+func newAuthenticatorFixture(t *testing.T) authenticatorFixture {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+
+	authenticators := &fakeAuthenticatorRepository{}
+	sessions := &fakeSessionRedis{data: make(map[string][]byte)}
+	return authenticatorFixture{
+		service: NewWebAuthnService(
+			fakeUserByIDRepository{},
+			authenticators,
+			sessions,
+			webauthn.RelyingParty{ID: testRPID, Name: "Vault", Origins: []string{testOrigin}},
+			time.Minute,
+			0),
+		authenticators: authenticators,
+		redis:          sessions,
+		key:            key,
+		credentialID:   credentialID,
+	}
+}
+
+// store registers the credential of the fixture as if an earlier ceremony had left it at signCount
+func (f authenticatorFixture) store(t *testing.T, signCount uint32) {
+	t.Helper()
+
+	publicKey, err := webauthn.EncodeCOSEKey(&f.key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.authenticators.AddAuthenticator(&user.UserAuthenticator{
+		UserID:       1,
+		CredentialID: f.credentialID,
+		PublicKey:    publicKey,
+		Algorithm:    webauthn.AlgES256,
+		SignCount:    signCount,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f authenticatorFixture) clientData(t *testing.T, ceremony, challenge, origin string) []byte {
+	t.Helper()
+
+	clientDataJSON, err := json.Marshal(webauthn.ClientData{Type: ceremony, Challenge: challenge, Origin: origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientDataJSON
+}
+
+// register runs a registration ceremony with a none attestation made for rpID and origin
+func (f authenticatorFixture) register(t *testing.T, rpID, origin string) (interface{}, error) {
+	t.Helper()
+
+	response, err := f.service.BeginRegistration(testClientID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	options := response.(out.CredentialCreationOptions)
+
+	publicKey, err := webauthn.EncodeCOSEKey(&f.key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authData := webauthn.AuthenticatorData(rpID, webauthn.FlagUserPresent|webauthn.FlagUserVerified, 0,
+		webauthn.AttestedCredentialData([16]byte{}, f.credentialID, publicKey))
+	attestationObject, err := webauthn.NoneAttestationObject(authData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f.service.FinishRegistration(&in.AuthenticatorRegistrationRequest{
+		Name:              "Backup key",
+		ID:                webauthn.EncodeBase64URL(f.credentialID),
+		ClientDataJSON:    webauthn.EncodeBase64URL(f.clientData(t, webauthn.CeremonyCreate, options.Challenge, origin)),
+		AttestationObject: webauthn.EncodeBase64URL(attestationObject),
+	}, testClientID)
+}
+
+// authenticate runs an authentication ceremony in which the authenticator reports signCount
+func (f authenticatorFixture) authenticate(t *testing.T, rpID, origin string, signCount uint32) (interface{}, error) {
+	t.Helper()
+
+	response, err := f.service.BeginAuthentication(testClientID)
+	if err != nil {
+		t.Fatalf("BeginAuthentication: %v", err)
+	}
+	options := response.(out.CredentialRequestOptions)
+
+	clientDataJSON := f.clientData(t, webauthn.CeremonyGet, options.Challenge, origin)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	authData := webauthn.AuthenticatorData(rpID, webauthn.FlagUserPresent|webauthn.FlagUserVerified, signCount, nil)
+	signature, err := webauthn.SignAssertion(f.key, authData, clientDataHash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f.service.FinishAuthentication(&in.AuthenticatorAssertionRequest{
+		ID:                webauthn.EncodeBase64URL(f.credentialID),
+		ClientDataJSON:    webauthn.EncodeBase64URL(clientDataJSON),
+		AuthenticatorData: webauthn.EncodeBase64URL(authData),
+		Signature:         webauthn.EncodeBase64URL(signature),
+		UserHandle:        webauthn.EncodeBase64URL(userHandle(1)),
+	}, testClientID, "request-1")
+}
+
+func TestWebAuthnFinishRegistration(t *testing.T) {
+	tests := []struct {
+		name    string
+		rpID    string
+		origin  string
+		wantErr bool
+	}{
+		{name: "valid", rpID: testRPID, origin: testOrigin},
+		{name: "wrong origin", rpID: testRPID, origin: "https://evil.example", wantErr: true},
+		{name: "wrong RP ID hash", rpID: "evil.example", origin: testOrigin, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := newAuthenticatorFixture(t)
+
+			response, err := fixture.register(t, tt.rpID, tt.origin)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("FinishRegistration accepted the credential")
+				}
+				if len(fixture.authenticators.authenticators) != 0 {
+					t.Error("a rejected credential was stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FinishRegistration: %v", err)
+			}
+
+			authenticator := response.(out.AuthenticatorResponse)
+			if authenticator.AttestationFormat != webauthn.FormatNone || authenticator.AttestationType != webauthn.AttestationNone {
+				t.Errorf("attestation = %s/%s, want none/none", authenticator.AttestationFormat, authenticator.AttestationType)
+			}
+			if authenticator.Algorithm != webauthn.AlgES256 {
+				t.Errorf("algorithm = %d, want %d", authenticator.Algorithm, webauthn.AlgES256)
+			}
+			if len(fixture.authenticators.authenticators) != 1 {
+				t.Fatalf("stored %d authenticators, want 1", len(fixture.authenticators.authenticators))
+			}
+		})
+	}
+}
+
+func TestWebAuthnFinishAuthentication(t *testing.T) {
+	tests := []struct {
+		name      string
+		stored    uint32
+		received  uint32
+		rpID      string
+		origin    string
+		wantCount uint32
+		wantErr   bool
+	}{
+		{name: "increasing count", stored: 4, received: 5, rpID: testRPID, origin: testOrigin, wantCount: 5},
+		{name: "authenticator without a counter", stored: 0, received: 0, rpID: testRPID, origin: testOrigin},
+		{name: "replayed count", stored: 5, received: 5, rpID: testRPID, origin: testOrigin, wantCount: 5, wantErr: true},
+		{name: "decreasing count", stored: 9, received: 5, rpID: testRPID, origin: testOrigin, wantCount: 9, wantErr: true},
+		{name: "counter reset to zero", stored: 5, received: 0, rpID: testRPID, origin: testOrigin, wantCount: 5, wantErr: true},
+		{name: "wrong origin", stored: 4, received: 5, rpID: testRPID, origin: "https://evil.example", wantCount: 4, wantErr: true},
+		{name: "wrong RP ID hash", stored: 4, received: 5, rpID: "evil.example", origin: testOrigin, wantCount: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := newAuthenticatorFixture(t)
+			fixture.store(t, tt.stored)
+
+			response, err := fixture.authenticate(t, tt.rpID, tt.origin, tt.received)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("FinishAuthentication err = %v, want error %t", err, tt.wantErr)
+			}
+			if got := fixture.authenticators.authenticators[0].SignCount; got != tt.wantCount {
+				t.Errorf("stored sign count = %d, want %d", got, tt.wantCount)
+			}
+
+			_, verified := fixture.redis.data[utils.PinVerify+":"+testClientID]
+			if verified == tt.wantErr {
+				t.Errorf("step-up verification saved = %t, want %t", verified, !tt.wantErr)
+			}
+			if !tt.wantErr && response.(out.StepUpVerificationResponse).Method != utils.StepUpMethodWebAuthn {
+				t.Errorf("method = %s, want %s", response.(out.StepUpVerificationResponse).Method, utils.StepUpMethodWebAuthn)
+			}
+		})
+	}
+}
+
+// A challenge is single use, so replaying a whole assertion fails even before its sign count is compared
+func TestWebAuthnFinishAuthenticationConsumesChallenge(t *testing.T) {
+	fixture := newAuthenticatorFixture(t)
+	fixture.store(t, 0)
+
+	if _, err := fixture.authenticate(t, testRPID, testOrigin, 1); err != nil {
+		t.Fatalf("FinishAuthentication: %v", err)
+	}
+	if _, found := fixture.redis.data[utils.WebAuthnChallenge+":"+testClientID]; found {
+		t.Error("the challenge was kept after the ceremony finished")
+	}
+	if _, err := fixture.service.FinishAuthentication(&in.AuthenticatorAssertionRequest{
+		ID: webauthn.EncodeBase64URL(fixture.credentialID),
+	}, testClientID, "request-2"); err == nil {
+		t.Error("FinishAuthentication succeeded without a pending ceremony")
+	}
+}
+
+func TestWebAuthnResetAuthenticators(t *testing.T) {
+	fixture := newAuthenticatorFixture(t)
+	fixture.store(t, 3)
+	if _, err := fixture.authenticate(t, testRPID, testOrigin, 4); err != nil {
+		t.Fatalf("FinishAuthentication: %v", err)
+	}
+	if _, err := fixture.service.BeginAuthentication(testClientID); err != nil {
+		t.Fatalf("BeginAuthentication: %v", err)
+	}
+
+	response, err := fixture.service.ResetAuthenticators(1, "admin-1")
+	if err != nil {
+		t.Fatalf("ResetAuthenticators: %v", err)
+	}
+	if got := response.(out.AuthenticatorResetResponse); got.UserID != 1 || got.Authenticators != 1 {
+		t.Errorf("response = %+v, want user 1 with 1 authenticator", got)
+	}
+	if len(fixture.authenticators.authenticators) != 0 {
+		t.Error("authenticators were kept")
+	}
+	if len(fixture.redis.data) != 0 {
+		t.Errorf("Redis still holds %d ceremony or verification records", len(fixture.redis.data))
+	}
+	if _, err := fixture.service.BeginAuthentication(testClientID); err == nil {
+		t.Error("BeginAuthentication succeeded without an authenticator")
+	}
+
+	if _, err := fixture.service.ResetAuthenticators(2, "admin-1"); err == nil {
+		t.Error("ResetAuthenticators succeeded for an unknown user")
+	}
+}
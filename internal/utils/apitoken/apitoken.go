@@ -0,0 +1,75 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+const (
+	tokenPrefix     = "pms"
+	contextTokenKey = "service_token"
+)
+
+type ServiceTokenClaims struct {
+	ServiceAccountID uint   `json:"service_account_id"`
+	TokenID          uint   `json:"token_id"`
+	UserID           uint   `json:"user_id"`
+	Secret           string `json:"-"`
+}
+
+// Generate returns a new API token, its public lookup prefix and the hash stored in the database
+func Generate() (string, string, string, error) {
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	prefix := hex.EncodeToString(prefixBytes)
+	token := tokenPrefix + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return token, prefix, Hash(token), nil
+}
+
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Parse extracts an API token from an Authorization header value
+func Parse(header string) (string, error) {
+	token := strings.TrimSpace(header)
+	if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+
+	parts := strings.Split(token, "_")
+	if len(parts) != 3 || parts[0] != tokenPrefix || parts[1] == "" || parts[2] == "" {
+		return "", errors.New("malformed API token")
+	}
+	return token, nil
+}
+
+func SetServiceTokenClaims(c *gin.Context, claims *ServiceTokenClaims) {
+	c.Set(contextTokenKey, claims)
+}
+
+func ExtractServiceTokenClaims(c *gin.Context) (*ServiceTokenClaims, bool) {
+	tokenData, exists := c.Get(contextTokenKey)
+	if !exists {
+		return nil, false
+	}
+
+	claims, ok := tokenData.(*ServiceTokenClaims)
+	if !ok || claims == nil {
+		return nil, false
+	}
+	return claims, true
+}
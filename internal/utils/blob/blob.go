@@ -0,0 +1,185 @@
+package blob
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"os"
+	"password-management-service/internal/utils"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	BackendPostgres = "postgres"
+	BackendFile     = "file"
+	BackendS3       = "s3"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps attachment ciphertext. Blobs are opaque to the store, which never sees a key or plaintext.
+type Store interface {
+	Backend() string
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+// NewKey returns a random storage key that reveals nothing about the entry or the file name
+func NewKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func validKey(key string) bool {
+	if len(key) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+// postgresStore keeps blobs in the attachment_blobs table next to the metadata
+type postgresStore struct {
+	db gorm.DB
+}
+
+func NewPostgresStore(db gorm.DB) Store {
+	return &postgresStore{db: db}
+}
+
+type attachmentBlob struct {
+	StorageKey string `gorm:"column:storage_key;primaryKey"`
+	Data       []byte `gorm:"column:data"`
+}
+
+func (s *postgresStore) Backend() string {
+	return BackendPostgres
+}
+
+func (s *postgresStore) Put(key string, data []byte) error {
+	return s.db.Table(utils.TableAttachmentBlobName).Create(&attachmentBlob{StorageKey: key, Data: data}).Error
+}
+
+func (s *postgresStore) Get(key string) ([]byte, error) {
+	var blobs []attachmentBlob
+	if err := s.db.Table(utils.TableAttachmentBlobName).Where("storage_key = ?", key).Limit(1).Find(&blobs).Error; err != nil {
+		return nil, err
+	}
+	if len(blobs) == 0 {
+		return nil, ErrNotFound
+	}
+	return blobs[0].Data, nil
+}
+
+func (s *postgresStore) Delete(key string) error {
+	return s.db.Table(utils.TableAttachmentBlobName).Where("storage_key = ?", key).Delete(&attachmentBlob{}).Error
+}
+
+// fileStore writes one file per blob under <dir>/<first two key characters>/<key>
+type fileStore struct {
+	Dir string
+}
+
+func NewFileStore(dir string) (Store, error) {
+	if dir == "" {
+		return nil, errors.New("attachment directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &fileStore{Dir: dir}, nil
+}
+
+func (s *fileStore) Backend() string {
+	return BackendFile
+}
+
+func (s *fileStore) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid blob key: %s", key)
+	}
+	return filepath.Join(s.Dir, key[:2], key), nil
+}
+
+// Put writes to a temporary file first so a crash never leaves a truncated blob under the final name
+func (s *fileStore) Put(key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+key+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *fileStore) Get(key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *fileStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// S3Options configures an S3-compatible bucket such as AWS S3 or MinIO
+type S3Options struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Prefix    string
+}
+
+// NewStore builds the store for the configured backend
+func NewStore(backend string, db gorm.DB, dir string, s3 S3Options) (Store, error) {
+	switch strings.ToLower(backend) {
+	case "", BackendPostgres:
+		return NewPostgresStore(db), nil
+	case BackendFile:
+		return NewFileStore(dir)
+	case BackendS3:
+		return NewS3Store(s3)
+	default:
+		return nil, fmt.Errorf("unsupported attachment backend: %s", backend)
+	}
+}
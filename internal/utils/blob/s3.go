@@ -0,0 +1,169 @@
+package blob
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service   = "s3"
+	s3Algorithm = "AWS4-HMAC-SHA256"
+	amzDate     = "20060102T150405Z"
+	amzDay      = "20060102"
+)
+
+// s3Store talks to an S3-compatible API with path-style URLs and Signature Version 4, which MinIO and
+// other local stand-ins accept as well as AWS
+type s3Store struct {
+	Options S3Options
+	BaseURL *url.URL
+	Client  *http.Client
+	Now     func() time.Time
+}
+
+func NewS3Store(options S3Options) (Store, error) {
+	if options.Endpoint == "" || options.Bucket == "" {
+		return nil, errors.New("S3 endpoint and bucket are required")
+	}
+	if options.AccessKey == "" || options.SecretKey == "" {
+		return nil, errors.New("S3 access key and secret key are required")
+	}
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+
+	baseURL, err := url.Parse(strings.TrimSuffix(options.Endpoint, "/"))
+	if err != nil || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: %s", options.Endpoint)
+	}
+	return &s3Store{
+		Options: options,
+		BaseURL: baseURL,
+		Client:  &http.Client{Timeout: 60 * time.Second},
+		Now:     time.Now,
+	}, nil
+}
+
+func (s *s3Store) Backend() string {
+	return BackendS3
+}
+
+func (s *s3Store) Put(key string, data []byte) error {
+	resp, err := s.do(http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (s *s3Store) Get(key string) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error(resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *s3Store) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (s *s3Store) do(method, key string, body []byte) (*http.Response, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("invalid blob key: %s", key)
+	}
+
+	objectURL := *s.BaseURL
+	objectURL.Path = strings.TrimSuffix(objectURL.Path, "/") + "/" + s.Options.Bucket + "/" + s.Options.Prefix + key
+	req, err := http.NewRequest(method, objectURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	s.sign(req, body)
+	return s.Client.Do(req)
+}
+
+// sign adds a Signature Version 4 Authorization header covering the host, the payload hash and the date
+func (s *s3Store) sign(req *http.Request, body []byte) {
+	now := s.Now().UTC()
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", now.Format(amzDate))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + now.Format(amzDate) + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(amzDay), s.Options.Region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(amzDate),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.Options.SecretKey), now.Format(amzDay))
+	for _, part := range []string{s.Options.Region, s3Service, "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.Options.AccessKey, scope, signedHeaders, signature))
+}
+
+func s3Error(resp *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("unexpected status %d from S3: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,132 @@
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const PrefixLength = 5
+
+// Checker returns the Have-I-Been-Pwned range for a 5 character SHA-1 prefix as suffix -> count.
+// Only the prefix ever leaves the service, which is the k-anonymity model of the HIBP range API.
+type Checker interface {
+	Range(prefix string) (map[string]int, error)
+}
+
+// NewChecker prefers a local range dataset and falls back to the range API. It returns nil when
+// neither is configured, which disables breach detection.
+func NewChecker(rangeDir, apiURL string, timeout time.Duration) Checker {
+	if rangeDir != "" {
+		return &dirChecker{Dir: rangeDir}
+	}
+	if apiURL != "" {
+		return &apiChecker{
+			URL:    strings.TrimSuffix(apiURL, "/") + "/",
+			Client: &http.Client{Timeout: timeout},
+		}
+	}
+	return nil
+}
+
+// Hash splits the upper-case SHA-1 of a password into the range prefix and the suffix to look up
+func Hash(password string) (string, string) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return digest[:PrefixLength], digest[PrefixLength:]
+}
+
+// Count looks the suffix up in the range for prefix and returns how often it appeared in breaches
+func Count(checker Checker, prefix, suffix string) (int, error) {
+	hashes, err := checker.Range(prefix)
+	if err != nil {
+		return 0, err
+	}
+	return hashes[strings.ToUpper(suffix)], nil
+}
+
+// dirChecker reads one <PREFIX>.txt file per range, as written by the HIBP downloader
+type dirChecker struct {
+	Dir string
+}
+
+func (d *dirChecker) Range(prefix string) (map[string]int, error) {
+	if !validPrefix(prefix) {
+		return nil, fmt.Errorf("invalid range prefix: %s", prefix)
+	}
+
+	file, err := os.Open(filepath.Join(d.Dir, strings.ToUpper(prefix)+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		// A missing range file means no breached hash starts with this prefix
+		return map[string]int{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseRange(file)
+}
+
+// apiChecker queries <url><PREFIX>; padding is requested so the response size does not leak the prefix
+type apiChecker struct {
+	URL    string
+	Client *http.Client
+}
+
+func (a *apiChecker) Range(prefix string) (map[string]int, error) {
+	if !validPrefix(prefix) {
+		return nil, fmt.Errorf("invalid range prefix: %s", prefix)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, a.URL+strings.ToUpper(prefix), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from range API", resp.StatusCode)
+	}
+	return parseRange(io.LimitReader(resp.Body, 4<<20))
+}
+
+// parseRange reads SUFFIX:COUNT lines, skipping the zero-count padding entries
+func parseRange(reader io.Reader) (map[string]int, error) {
+	hashes := make(map[string]int)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		suffix, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n <= 0 {
+			continue
+		}
+		hashes[strings.ToUpper(suffix)] = n
+	}
+	return hashes, scanner.Err()
+}
+
+func validPrefix(prefix string) bool {
+	if len(prefix) != PrefixLength {
+		return false
+	}
+	_, err := hex.DecodeString(prefix + "0")
+	return err == nil
+}
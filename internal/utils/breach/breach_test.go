@@ -0,0 +1,141 @@
+package breach
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const (
+	passwordPrefix = "5BAA6"
+	passwordSuffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	rangeBody      = "003D68EB55068C33ACE09247EE4C639306B:3\r\n" +
+		"1e4c9b93f3f0682250b6cf8331b7ee68fd8:9659365\r\n" +
+		"\r\n" +
+		"01330C689E5D64F660D6947A93AD634EF8F:0\r\n" +
+		"malformed line\r\n" +
+		"0198748F3315F40B1A102BF18EEA0194CD9:abc\r\n"
+)
+
+func TestHash(t *testing.T) {
+	prefix, suffix := Hash("password")
+	if prefix != passwordPrefix || suffix != passwordSuffix {
+		t.Errorf("Hash = %s %s, want %s %s", prefix, suffix, passwordPrefix, passwordSuffix)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	hashes, err := parseRange(strings.NewReader(rangeBody))
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	want := map[string]int{
+		"003D68EB55068C33ACE09247EE4C639306B": 3,
+		passwordSuffix:                        9659365,
+	}
+	if !reflect.DeepEqual(hashes, want) {
+		t.Errorf("parseRange = %v, want %v", hashes, want)
+	}
+}
+
+func TestDirChecker(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, passwordPrefix+".txt"), []byte(rangeBody), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	checker := NewChecker(dir, "https://api.pwnedpasswords.com/range/", time.Second)
+
+	tests := []struct {
+		name    string
+		prefix  string
+		suffix  string
+		want    int
+		wantErr bool
+	}{
+		{name: "breached", prefix: passwordPrefix, suffix: passwordSuffix, want: 9659365},
+		{name: "lower case", prefix: "5baa6", suffix: strings.ToLower(passwordSuffix), want: 9659365},
+		{name: "padding entry", prefix: passwordPrefix, suffix: "01330C689E5D64F660D6947A93AD634EF8F"},
+		{name: "suffix not in range", prefix: passwordPrefix, suffix: "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF"},
+		{name: "missing range file", prefix: "00000", suffix: passwordSuffix},
+		{name: "path in prefix", prefix: "../5B", wantErr: true},
+		{name: "short prefix", prefix: "5BAA", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := Count(checker, tt.prefix, tt.suffix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Count error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if count != tt.want {
+				t.Errorf("Count = %d, want %d", count, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIChecker(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Error("range request without Add-Padding")
+		}
+		switch r.URL.Path {
+		case "/range/" + passwordPrefix:
+			_, _ = w.Write([]byte(rangeBody))
+		case "/range/00000":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	checker := NewChecker("", server.URL+"/range", time.Second)
+
+	tests := []struct {
+		name     string
+		prefix   string
+		suffix   string
+		want     int
+		wantErr  bool
+		wantPath string
+	}{
+		{name: "breached", prefix: "5baa6", suffix: passwordSuffix, want: 9659365, wantPath: "/range/" + passwordPrefix},
+		{name: "not breached", prefix: passwordPrefix, suffix: "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", wantPath: "/range/" + passwordPrefix},
+		{name: "error status", prefix: "00000", wantErr: true, wantPath: "/range/00000"},
+		{name: "invalid prefix is not sent", prefix: "5BAAZ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths = nil
+			count, err := Count(checker, tt.prefix, tt.suffix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Count error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if count != tt.want {
+				t.Errorf("Count = %d, want %d", count, tt.want)
+			}
+			var wantPaths []string
+			if tt.wantPath != "" {
+				wantPaths = []string{tt.wantPath}
+			}
+			if !reflect.DeepEqual(paths, wantPaths) {
+				t.Errorf("requested %v, want %v", paths, wantPaths)
+			}
+		})
+	}
+}
+
+func TestNewCheckerDisabled(t *testing.T) {
+	if checker := NewChecker("", "", time.Second); checker != nil {
+		t.Errorf("NewChecker = %T, want nil", checker)
+	}
+}
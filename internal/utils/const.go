@@ -6,16 +6,137 @@ const (
 	CredentialKey = "credential_key"
 	PageIndex     = "page_index"
 	PageSize      = "page_size"
+	RateLimit     = "rate_limit"
+	PinAttempts   = "pin_attempts"
+	PinLockout    = "pin_lockout"
+	VaultSession  = "vault_session"
+	EventDone     = "event_done"
+	// WebAuthnChallenge holds the pending WebAuthn ceremony of a client
+	WebAuthnChallenge = "webauthn_challenge"
+	// DeviceSeen marks a device whose last-seen time was recorded recently, keyed by client ID and device
+	DeviceSeen = "device_seen"
 )
 
 const (
-	XRequestID = "X-REQUEST-ID"
+	EventUserCreated     = "user.created"
+	EventUserDeleted     = "user.deleted"
+	EventUserRoleChanged = "user.role_changed"
 )
 
 const (
-	TablePasswordEntryName    = "password_entries"
-	TablePasswordEntryKeyName = "password_entry_keys"
-	TablePasswordEntryTagName = "password_entry_tags"
-	TablePasswordGroupName    = "password_groups"
-	TableUserKeyName          = "user_keys"
+	EventEntryCreated      = "entry.created"
+	EventEntryUpdated      = "entry.updated"
+	EventEntryDeleted      = "entry.deleted"
+	EventEntryShared       = "entry.shared"
+	EventEntryRevealed     = "entry.revealed"
+	EventEntryExported     = "entry.exported"
+	EventEntryExpired      = "entry.expired"
+	EventEntryCompromised  = "entry.compromised"
+	EventAttachmentAdded   = "entry.attachment_added"
+	EventAttachmentDeleted = "entry.attachment_deleted"
+	EventEntrySSHSigned    = "entry.ssh_signed"
+	EventEntryPasskeyUsed  = "entry.passkey_used"
+)
+
+const EventAuthenticatorsReset = "user.authenticators_reset"
+
+const (
+	XRequestID    = "X-REQUEST-ID"
+	XVaultSession = "X-VAULT-SESSION"
+)
+
+const (
+	StepUpActionReveal    = "reveal"
+	StepUpActionShare     = "share"
+	StepUpActionDelete    = "delete"
+	StepUpActionRotateKey = "rotate_key"
+	StepUpActionUnlock    = "unlock"
+	StepUpActionSSHSign   = "ssh_sign"
+	StepUpActionPasskey   = "passkey"
+	StepUpActionEnroll    = "enroll"
+	StepUpActionCreate    = "create"
+	StepUpActionApprove   = "approve_device"
+)
+
+// Step-up methods; once a user has registered an authenticator only a WebAuthn assertion satisfies step-up
+const (
+	StepUpMethodPin      = "pin"
+	StepUpMethodWebAuthn = "webauthn"
+)
+
+const (
+	RateLimitBucketRead      = "read"
+	RateLimitBucketReveal    = "reveal"
+	RateLimitBucketExport    = "export"
+	RateLimitBucketPinVerify = "pin_verify"
+)
+
+const (
+	AccessActionReveal      = "reveal"
+	AccessActionServiceRead = "service_read"
+	AccessActionExport      = "export"
+	AccessActionDownload    = "download"
+	AccessActionSSHSign     = "ssh_sign"
+	AccessActionPasskey     = "passkey"
+)
+
+const (
+	ServiceAccountPermissionRead = "read"
+)
+
+const (
+	SortFrecency = "frecency"
+)
+
+const (
+	BulkActionMove   = "move"
+	BulkActionCopy   = "copy"
+	BulkActionRemove = "remove"
+	BulkActionTag    = "tag"
+	BulkActionUntag  = "untag"
+	BulkActionDelete = "delete"
+)
+
+const (
+	RenderFieldTitle    = "title"
+	RenderFieldUsername = "username"
+	RenderFieldPassword = "password"
+	RenderFieldNotes    = "notes"
+	RenderFieldURL      = "url"
+)
+
+const (
+	RevealFieldUsername = "username"
+	RevealFieldPassword = "password"
+	RevealFieldNotes    = "notes"
+	RevealFieldData     = "data"
+	// RevealFieldCustom returns all custom fields with secret values masked; "custom_fields.<name>" unmasks one
+	RevealFieldCustom = "custom_fields"
+)
+
+const (
+	TablePasswordEntryName          = "password_entries"
+	TablePasswordEntryKeyName       = "password_entry_keys"
+	TablePasswordEntryTagName       = "password_entry_tags"
+	TablePasswordGroupName          = "password_groups"
+	TablePasswordEntryGroupName     = "password_entry_groups"
+	TablePasswordEntryURLName       = "password_entry_urls"
+	TableUserKeyName                = "user_keys"
+	TablePasswordAccessLogName      = "password_access_logs"
+	TableServiceAccountName         = "service_accounts"
+	TableServiceAccountScopeName    = "service_account_scopes"
+	TableServiceAccountTokenName    = "service_account_tokens"
+	TableServiceAccountEntryKeyName = "service_account_entry_keys"
+	TableUserDeviceName             = "user_devices"
+	TablePasswordTagName            = "password_tags"
+	TableSharedPasswordName         = "shared_passwords"
+	TablePasswordHistoryName        = "password_history"
+	TableOutboxEventName            = "outbox_events"
+	TablePasswordPolicyName         = "password_policies"
+	TablePasswordAttachmentName     = "password_attachments"
+	TableAttachmentBlobName         = "attachment_blobs"
+	TableAppDomainEquivalenceName   = "app_domain_equivalences"
+	TableEquivalentDomainGroupName  = "equivalent_domain_groups"
+	TableDomainGroupExclusionName   = "equivalent_domain_exclusions"
+	TableUserAuthenticatorName      = "user_authenticators"
 )
@@ -0,0 +1,182 @@
+package customfield
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	TypeText   = "text"
+	TypeHidden = "hidden"
+	TypeURL    = "url"
+	TypeEmail  = "email"
+	TypeDate   = "date"
+	TypeTOTP   = "totp"
+)
+
+const (
+	MaxFields    = 50
+	MaxNameLen   = 100
+	MaxValueLen  = 10000
+	DateLayout   = "2006-01-02"
+	otpauthProto = "otpauth"
+)
+
+// Field is one user-defined value of an entry. The whole list is stored as a single encrypted JSON document.
+type Field struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Value  string `json:"value,omitempty"`
+	Masked bool   `json:"masked,omitempty"`
+}
+
+// IsSecret reports whether the field value is hidden until it is revealed by name
+func (f Field) IsSecret() bool {
+	return f.Type == TypeHidden || f.Type == TypeTOTP
+}
+
+// Normalize validates the fields and returns them with trimmed names and canonical values
+func Normalize(fields []Field) ([]Field, error) {
+	if len(fields) > MaxFields {
+		return nil, fmt.Errorf("at most %d custom fields are allowed", MaxFields)
+	}
+
+	seen := make(map[string]bool, len(fields))
+	normalized := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		field.Name = strings.TrimSpace(field.Name)
+		field.Type = strings.ToLower(strings.TrimSpace(field.Type))
+		field.Masked = false
+		if field.Type == "" {
+			field.Type = TypeText
+		}
+
+		if field.Name == "" {
+			return nil, errors.New("custom field name is required")
+		}
+		if len(field.Name) > MaxNameLen {
+			return nil, fmt.Errorf("custom field name %q is too long", field.Name)
+		}
+		if seen[field.Name] {
+			return nil, fmt.Errorf("duplicate custom field: %s", field.Name)
+		}
+		seen[field.Name] = true
+
+		if len(field.Value) > MaxValueLen {
+			return nil, fmt.Errorf("custom field %q is too long", field.Name)
+		}
+		value, err := normalizeValue(field.Type, field.Value)
+		if err != nil {
+			return nil, fmt.Errorf("custom field %q: %v", field.Name, err)
+		}
+		field.Value = value
+		normalized = append(normalized, field)
+	}
+	return normalized, nil
+}
+
+func normalizeValue(fieldType, value string) (string, error) {
+	switch fieldType {
+	case TypeText, TypeHidden:
+		return value, nil
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return value, nil
+	}
+
+	switch fieldType {
+	case TypeURL:
+		parsed, err := url.ParseRequestURI(value)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return "", errors.New("invalid URL")
+		}
+		return value, nil
+	case TypeEmail:
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Address != value {
+			return "", errors.New("invalid email address")
+		}
+		return value, nil
+	case TypeDate:
+		if _, err := time.Parse(DateLayout, value); err != nil {
+			return "", errors.New("date must use the YYYY-MM-DD format")
+		}
+		return value, nil
+	case TypeTOTP:
+		return normalizeTOTP(value)
+	default:
+		return "", fmt.Errorf("unsupported type: %s", fieldType)
+	}
+}
+
+// normalizeTOTP accepts an otpauth:// URI, kept as is, or a bare base32 secret, stored without spaces in upper case
+func normalizeTOTP(value string) (string, error) {
+	if strings.HasPrefix(strings.ToLower(value), otpauthProto+"://") {
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Host != "totp" {
+			return "", errors.New("invalid otpauth URI")
+		}
+		if !validBase32(parsed.Query().Get("secret")) {
+			return "", errors.New("otpauth URI has no valid secret")
+		}
+		return value, nil
+	}
+
+	secret := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	if !validBase32(secret) {
+		return "", errors.New("TOTP secret must be base32 encoded")
+	}
+	return secret, nil
+}
+
+func validBase32(secret string) bool {
+	secret = strings.TrimRight(strings.ToUpper(secret), "=")
+	if secret == "" {
+		return false
+	}
+	_, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	return err == nil
+}
+
+func Marshal(fields []Field) (string, error) {
+	if len(fields) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func Unmarshal(document string) ([]Field, error) {
+	if document == "" {
+		return nil, nil
+	}
+	var fields []Field
+	if err := json.Unmarshal([]byte(document), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// Mask hides secret values except for the fields named in reveal
+func Mask(fields []Field, reveal map[string]bool) []Field {
+	masked := make([]Field, len(fields))
+	for i, field := range fields {
+		if field.IsSecret() && !reveal[field.Name] {
+			field.Value = ""
+			field.Masked = true
+		}
+		masked[i] = field
+	}
+	return masked
+}
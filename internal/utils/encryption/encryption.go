@@ -11,14 +11,39 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"io"
 	"password-management-service/internal/models/user"
 	"time"
 )
 
 type Encryption interface {
 	GenerateUserKey(user *user.Users) (*user.UserKey, error)
-	EncryptPasswordEntry(username, password, notes string, pubKey *rsa.PublicKey) (string, string, string, string, error)
+	EncryptPasswordEntry(fields EntryFields, pubKey *rsa.PublicKey) (EntryFields, string, error)
+	EncryptPasswordEntryFor(fields EntryFields, pubKey *rsa.PublicKey, recipients map[uint]*rsa.PublicKey) (EntryFields, string, map[uint]string, error)
 	DecryptPasswordEntry(encUsername, encPassword, encNotes, wrappedAESKey string, privateKey *rsa.PrivateKey) (string, string, string, error)
+	DecryptPasswordEntryField(encValue, wrappedAESKey string, privateKey *rsa.PrivateKey) (string, error)
+	UnwrapEntryKey(wrappedAESKey string, privateKey *rsa.PrivateKey) ([]byte, error)
+	WrapEntryKey(entryKey []byte, publicKey *rsa.PublicKey) (string, error)
+	DecryptEntryField(encValue string, entryKey []byte) (string, error)
+	EncryptPasswordEntryField(value, wrappedAESKey string, privateKey *rsa.PrivateKey) (string, error)
+	RewrapEntryKey(wrappedAESKey string, privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey) (string, error)
+	GenerateServiceAccountKey(ownerPublicKey *rsa.PublicKey) (string, string, string, error)
+	DecryptServiceAccountKey(encryptedPrivateKey, wrappedAESKey string, ownerPrivateKey *rsa.PrivateKey) (*rsa.PrivateKey, error)
+	SealPrivateKeyWithSecret(privateKey *rsa.PrivateKey, secret string) (string, string, error)
+	OpenPrivateKeyWithSecret(encryptedPrivateKey, salt, secret string) (*rsa.PrivateKey, error)
+	EncryptAttachment(data []byte, wrappedEntryKey string, privateKey *rsa.PrivateKey) ([]byte, string, error)
+	DecryptAttachment(ciphertext []byte, wrappedAttachmentKey, wrappedEntryKey string, privateKey *rsa.PrivateKey) ([]byte, error)
+	RewrapAttachmentKeys(wrappedAttachmentKeys []string, oldWrappedEntryKey, newWrappedEntryKey string, privateKey *rsa.PrivateKey) ([]string, error)
+}
+
+// EntryFields are the secret values of an entry. They are all encrypted under the same per-entry AES key.
+type EntryFields struct {
+	Username     string
+	Password     string
+	Notes        string
+	CustomFields string
+	Data         string
 }
 
 type encryption struct {
@@ -78,37 +103,66 @@ func (e *encryption) GenerateUserKey(data *user.Users) (*user.UserKey, error) {
 	}, nil
 }
 
-func (e *encryption) EncryptPasswordEntry(username, password, notes string, publicKey *rsa.PublicKey) (string, string, string, string, error) {
+// EncryptPasswordEntry encrypts the entry under a fresh AES key and wraps that key with the owner's public key.
+// Optional fields that are empty stay empty.
+func (e *encryption) EncryptPasswordEntry(fields EntryFields, publicKey *rsa.PublicKey) (EntryFields, string, error) {
+	encrypted, wrappedKey, _, err := e.EncryptPasswordEntryFor(fields, publicKey, nil)
+	return encrypted, wrappedKey, err
+}
+
+// EncryptPasswordEntryFor is EncryptPasswordEntry that also wraps the fresh key for every recipient, by recipient ID
+func (e *encryption) EncryptPasswordEntryFor(fields EntryFields, publicKey *rsa.PublicKey, recipients map[uint]*rsa.PublicKey) (EntryFields, string, map[uint]string, error) {
 	aesKey := make([]byte, 32)
 	_, err := rand.Read(aesKey)
 	if err != nil {
-		return "", "", "", "", err
+		return EntryFields{}, "", nil, err
 	}
 
-	encryptUsername, err := encryptWithAES([]byte(username), aesKey)
+	var encrypted EntryFields
+	encrypted.Username, err = encryptWithAES([]byte(fields.Username), aesKey)
 	if err != nil {
-		return "", "", "", "", err
+		return EntryFields{}, "", nil, err
 	}
 
-	encryptPassword, err := encryptWithAES([]byte(password), aesKey)
+	encrypted.Password, err = encryptWithAES([]byte(fields.Password), aesKey)
 	if err != nil {
-		return "", "", "", "", err
+		return EntryFields{}, "", nil, err
 	}
 
-	encryptNotes := ""
-	if notes != "" {
-		encryptNotes, err = encryptWithAES([]byte(notes), aesKey)
+	if fields.Notes != "" {
+		encrypted.Notes, err = encryptWithAES([]byte(fields.Notes), aesKey)
 		if err != nil {
-			return "", "", "", "", err
+			return EntryFields{}, "", nil, err
 		}
 	}
 
-	encryptedAESKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, aesKey, nil)
+	if fields.CustomFields != "" {
+		encrypted.CustomFields, err = encryptWithAES([]byte(fields.CustomFields), aesKey)
+		if err != nil {
+			return EntryFields{}, "", nil, err
+		}
+	}
+
+	if fields.Data != "" {
+		encrypted.Data, err = encryptWithAES([]byte(fields.Data), aesKey)
+		if err != nil {
+			return EntryFields{}, "", nil, err
+		}
+	}
+
+	wrappedKey, err := e.WrapEntryKey(aesKey, publicKey)
 	if err != nil {
-		return "", "", "", "", err
+		return EntryFields{}, "", nil, err
 	}
 
-	return encryptUsername, encryptPassword, encryptNotes, base64.StdEncoding.EncodeToString(encryptedAESKey), nil
+	recipientKeys := make(map[uint]string, len(recipients))
+	for id, recipient := range recipients {
+		recipientKeys[id], err = e.WrapEntryKey(aesKey, recipient)
+		if err != nil {
+			return EntryFields{}, "", nil, err
+		}
+	}
+	return encrypted, wrappedKey, recipientKeys, nil
 }
 
 func (e *encryption) DecryptPasswordEntry(encryptUsername, encryptPassword, encryptNotes, wrappedAESKey string, privateKey *rsa.PrivateKey) (string, string, string, error) {
@@ -137,21 +191,240 @@ func (e *encryption) DecryptPasswordEntry(encryptUsername, encryptPassword, encr
 	return decodeUsername, decodePass, decodeNotes, nil
 }
 
+// DecryptPasswordEntryField decrypts a single entry field so reveal requests only touch the requested secret
+func (e *encryption) DecryptPasswordEntryField(encValue, wrappedAESKey string, privateKey *rsa.PrivateKey) (string, error) {
+	if encValue == "" {
+		return "", nil
+	}
+
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, decode(wrappedAESKey), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return decryptAES(encValue, aesKey)
+}
+
+// UnwrapEntryKey opens an entry AES key once, for requests that decrypt several fields of the same entry
+func (e *encryption) UnwrapEntryKey(wrappedAESKey string, privateKey *rsa.PrivateKey) ([]byte, error) {
+	return rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, decode(wrappedAESKey), nil)
+}
+
+// WrapEntryKey wraps an entry key opened by UnwrapEntryKey for another recipient
+func (e *encryption) WrapEntryKey(entryKey []byte, publicKey *rsa.PublicKey) (string, error) {
+	encryptedAESKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, entryKey, nil)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encryptedAESKey), nil
+}
+
+// DecryptEntryField decrypts a field with an entry key opened by UnwrapEntryKey
+func (e *encryption) DecryptEntryField(encValue string, entryKey []byte) (string, error) {
+	if encValue == "" {
+		return "", nil
+	}
+	return decryptAES(encValue, entryKey)
+}
+
+// EncryptPasswordEntryField encrypts a value under the existing entry key, for secrets the service itself updates
+func (e *encryption) EncryptPasswordEntryField(value, wrappedAESKey string, privateKey *rsa.PrivateKey) (string, error) {
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, decode(wrappedAESKey), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptWithAES([]byte(value), aesKey)
+}
+
+// RewrapEntryKey unwraps an entry AES key with one key pair and wraps it for another recipient
+func (e *encryption) RewrapEntryKey(wrappedAESKey string, privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey) (string, error) {
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, decode(wrappedAESKey), nil)
+	if err != nil {
+		return "", err
+	}
+
+	encryptedAESKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, aesKey, nil)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encryptedAESKey), nil
+}
+
+// GenerateServiceAccountKey creates a service account key pair whose private key is encrypted for the owner.
+// It returns the public key, the encrypted private key and the owner-wrapped AES key.
+func (e *encryption) GenerateServiceAccountKey(ownerPublicKey *rsa.PublicKey) (string, string, string, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	aesKey := make([]byte, 32)
+	if _, err := rand.Read(aesKey); err != nil {
+		return "", "", "", err
+	}
+
+	encryptedPrivateKey, err := encryptWithAES(x509.MarshalPKCS1PrivateKey(privateKey), aesKey)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, ownerPublicKey, aesKey, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	publicKey := base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&privateKey.PublicKey))
+	return publicKey, encryptedPrivateKey, base64.StdEncoding.EncodeToString(wrappedKey), nil
+}
+
+func (e *encryption) DecryptServiceAccountKey(encryptedPrivateKey, wrappedAESKey string, ownerPrivateKey *rsa.PrivateKey) (*rsa.PrivateKey, error) {
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, ownerPrivateKey, decode(wrappedAESKey), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := decryptAES(encryptedPrivateKey, aesKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKCS1PrivateKey([]byte(der))
+}
+
+// SealPrivateKeyWithSecret encrypts a private key under a key derived from a high-entropy secret such as an API token
+func (e *encryption) SealPrivateKeyWithSecret(privateKey *rsa.PrivateKey, secret string) (string, string, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return "", "", fmt.Errorf("failed to generate secure salt: %w", err)
+	}
+
+	key, err := deriveSecretKey(secret, salt)
+	if err != nil {
+		return "", "", err
+	}
+
+	encryptedPrivateKey, err := encryptWithAES(x509.MarshalPKCS1PrivateKey(privateKey), key)
+	if err != nil {
+		return "", "", err
+	}
+	return encryptedPrivateKey, base64.StdEncoding.EncodeToString(salt), nil
+}
+
+func (e *encryption) OpenPrivateKeyWithSecret(encryptedPrivateKey, salt, secret string) (*rsa.PrivateKey, error) {
+	key, err := deriveSecretKey(secret, decode(salt))
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := decryptAES(encryptedPrivateKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKCS1PrivateKey([]byte(der))
+}
+
+// EncryptAttachment encrypts a file under a fresh AES key and wraps that key with the entry key, so anyone who
+// can open the entry can open its attachments. It returns the ciphertext and the wrapped attachment key.
+func (e *encryption) EncryptAttachment(data []byte, wrappedEntryKey string, privateKey *rsa.PrivateKey) ([]byte, string, error) {
+	entryKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, decode(wrappedEntryKey), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	attachmentKey := make([]byte, 32)
+	if _, err := rand.Read(attachmentKey); err != nil {
+		return nil, "", err
+	}
+
+	ciphertext, err := sealAES(data, attachmentKey)
+	if err != nil {
+		return nil, "", err
+	}
+	wrappedAttachmentKey, err := encryptWithAES(attachmentKey, entryKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return ciphertext, wrappedAttachmentKey, nil
+}
+
+func (e *encryption) DecryptAttachment(ciphertext []byte, wrappedAttachmentKey, wrappedEntryKey string, privateKey *rsa.PrivateKey) ([]byte, error) {
+	entryKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, decode(wrappedEntryKey), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	attachmentKey, err := openAES(decode(wrappedAttachmentKey), entryKey)
+	if err != nil {
+		return nil, err
+	}
+	return openAES(ciphertext, attachmentKey)
+}
+
+// RewrapAttachmentKeys moves attachment keys from a replaced entry key to its successor; both entry keys must be
+// wrapped for the same private key. The files themselves are not re-encrypted.
+func (e *encryption) RewrapAttachmentKeys(wrappedAttachmentKeys []string, oldWrappedEntryKey, newWrappedEntryKey string, privateKey *rsa.PrivateKey) ([]string, error) {
+	oldEntryKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, decode(oldWrappedEntryKey), nil)
+	if err != nil {
+		return nil, err
+	}
+	newEntryKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, decode(newWrappedEntryKey), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rewrapped := make([]string, len(wrappedAttachmentKeys))
+	for i, wrappedAttachmentKey := range wrappedAttachmentKeys {
+		attachmentKey, err := openAES(decode(wrappedAttachmentKey), oldEntryKey)
+		if err != nil {
+			return nil, err
+		}
+		rewrapped[i], err = encryptWithAES(attachmentKey, newEntryKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rewrapped, nil
+}
+
+func ParsePublicKey(b64PublicKey string) (*rsa.PublicKey, error) {
+	decoded, err := base64.StdEncoding.DecodeString(b64PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKCS1PublicKey(decoded)
+}
+
+func deriveSecretKey(secret string, salt []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), salt, []byte("service-account-key")), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 func encryptWithAES(plaintext, key []byte) (string, error) {
-	block, err := aes.NewCipher(key)
+	ciphertext, err := sealAES(plaintext, key)
 	if err != nil {
 		return "", err
 	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// sealAES encrypts with AES-GCM and prepends the random nonce to the ciphertext
+func sealAES(plaintext, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := rand.Read(nonce); err != nil {
-		return "", err
+		return nil, err
 	}
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
 }
 
 func decode(b64 string) []byte {
@@ -160,26 +433,29 @@ func decode(b64 string) []byte {
 }
 
 func decryptAES(b64cipher string, key []byte) (string, error) {
-	ciphertext := decode(b64cipher)
-	block, err := aes.NewCipher(key)
+	plain, err := openAES(decode(b64cipher), key)
 	if err != nil {
 		return "", err
 	}
+	return string(plain), nil
+}
+
+func openAES(ciphertext, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	nonceSize := gcm.NonceSize()
 	if len(ciphertext) < nonceSize {
-		return "", errors.New("ciphertext too short")
+		return nil, errors.New("ciphertext too short")
 	}
 
 	nonce := ciphertext[:nonceSize]
 	cipherData := ciphertext[nonceSize:]
-	plain, err := gcm.Open(nil, nonce, cipherData, nil)
-	if err != nil {
-		return "", err
-	}
-	return string(plain), nil
+	return gcm.Open(nil, nonce, cipherData, nil)
 }
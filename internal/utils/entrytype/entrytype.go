@@ -0,0 +1,413 @@
+package entrytype
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"net/mail"
+	"net/url"
+	"password-management-service/internal/utils/webauthn"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Login      = "login"
+	SecureNote = "secure_note"
+	CreditCard = "credit_card"
+	Identity   = "identity"
+	SSHKey     = "ssh_key"
+	APIKey     = "api_key"
+	Passkey    = "passkey"
+)
+
+const maxValueLen = 16384
+
+const (
+	minCredentialIDLen = 16
+	maxCredentialIDLen = 1023
+	maxUserHandleLen   = 64
+)
+
+var rpIDPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+type schema struct {
+	Required []string
+	Optional []string
+}
+
+// schemas lists the keys allowed in the encrypted data document of each type; logins keep using the
+// username and password columns
+var schemas = map[string]schema{
+	Login:      {},
+	SecureNote: {},
+	CreditCard: {
+		Required: []string{"cardholder_name", "number", "expiry_month", "expiry_year"},
+		Optional: []string{"cvv", "pin"},
+	},
+	Identity: {
+		Optional: []string{"title", "first_name", "middle_name", "last_name", "email", "phone", "company",
+			"address", "city", "state", "postal_code", "country", "date_of_birth", "passport_number",
+			"national_id", "license_number"},
+	},
+	SSHKey: {
+		Required: []string{"private_key"},
+		Optional: []string{"passphrase", "comment"},
+	},
+	APIKey: {
+		Required: []string{"key"},
+		Optional: []string{"secret", "endpoint"},
+	},
+	Passkey: {
+		Required: []string{"rp_id", "credential_id", "user_handle"},
+		Optional: []string{"private_key", "user_name", "user_display_name", "sign_count"},
+	},
+}
+
+// Result is the validated data document plus the non-secret metadata that may be stored in clear text
+type Result struct {
+	Data      map[string]string
+	Metadata  map[string]interface{}
+	ExpiresAt *time.Time
+}
+
+func IsValid(entryType string) bool {
+	_, found := schemas[entryType]
+	return found
+}
+
+// Normalize returns the canonical entry type, defaulting to login
+func Normalize(entryType string) (string, error) {
+	entryType = strings.ToLower(strings.TrimSpace(entryType))
+	if entryType == "" {
+		return Login, nil
+	}
+	if !IsValid(entryType) {
+		return "", fmt.Errorf("unsupported entry type: %s", entryType)
+	}
+	return entryType, nil
+}
+
+// Validate checks the data document against the schema of the type and applies the type-specific rules
+func Validate(entryType string, data map[string]string) (*Result, error) {
+	s, found := schemas[entryType]
+	if !found {
+		return nil, fmt.Errorf("unsupported entry type: %s", entryType)
+	}
+
+	allowed := make(map[string]bool, len(s.Required)+len(s.Optional))
+	for _, key := range append(append([]string{}, s.Required...), s.Optional...) {
+		allowed[key] = true
+	}
+
+	result := &Result{Data: make(map[string]string, len(data))}
+	for key, value := range data {
+		if !allowed[key] {
+			return nil, fmt.Errorf("field %q is not allowed for %s entries", key, entryType)
+		}
+		if len(value) > maxValueLen {
+			return nil, fmt.Errorf("field %q is too long", key)
+		}
+		if key != "private_key" && key != "passphrase" {
+			value = strings.TrimSpace(value)
+		}
+		if value != "" {
+			result.Data[key] = value
+		}
+	}
+	for _, key := range s.Required {
+		if result.Data[key] == "" {
+			return nil, fmt.Errorf("field %q is required for %s entries", key, entryType)
+		}
+	}
+
+	var err error
+	switch entryType {
+	case CreditCard:
+		err = validateCreditCard(result)
+	case Identity:
+		err = validateIdentity(result)
+	case SSHKey:
+		err = validateSSHKey(result)
+	case APIKey:
+		err = validateAPIKey(result)
+	case Passkey:
+		err = validatePasskey(result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		result.Data = nil
+	}
+	return result, nil
+}
+
+func validateCreditCard(result *Result) error {
+	number := strings.NewReplacer(" ", "", "-", "").Replace(result.Data["number"])
+	if len(number) < 12 || len(number) > 19 || !isDigits(number) {
+		return errors.New("card number must contain 12 to 19 digits")
+	}
+	if !luhn(number) {
+		return errors.New("card number fails the Luhn check")
+	}
+	result.Data["number"] = number
+
+	month, err := strconv.Atoi(result.Data["expiry_month"])
+	if err != nil || month < 1 || month > 12 {
+		return errors.New("expiry month must be between 1 and 12")
+	}
+	year, err := strconv.Atoi(result.Data["expiry_year"])
+	if err != nil || (len(result.Data["expiry_year"]) != 2 && len(result.Data["expiry_year"]) != 4) {
+		return errors.New("expiry year must have 2 or 4 digits")
+	}
+	if year < 100 {
+		year += 2000
+	}
+	result.Data["expiry_month"] = fmt.Sprintf("%02d", month)
+	result.Data["expiry_year"] = strconv.Itoa(year)
+
+	brand := cardBrand(number)
+	if cvv := result.Data["cvv"]; cvv != "" {
+		length := 3
+		if brand == "amex" {
+			length = 4
+		}
+		if len(cvv) != length || !isDigits(cvv) {
+			return fmt.Errorf("CVV must be %d digits", length)
+		}
+	}
+	if pin := result.Data["pin"]; pin != "" && (len(pin) < 4 || len(pin) > 12 || !isDigits(pin)) {
+		return errors.New("PIN must be 4 to 12 digits")
+	}
+
+	// A card is valid through the last day of its expiry month
+	expiresAt := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+	result.ExpiresAt = &expiresAt
+	result.Metadata = map[string]interface{}{
+		"brand":        brand,
+		"last4":        number[len(number)-4:],
+		"expiry_month": month,
+		"expiry_year":  year,
+	}
+	return nil
+}
+
+func validateIdentity(result *Result) error {
+	if result.Data["first_name"] == "" && result.Data["last_name"] == "" {
+		return errors.New("identity requires a first or last name")
+	}
+	if email := result.Data["email"]; email != "" {
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			return errors.New("invalid email address")
+		}
+	}
+	if dateOfBirth := result.Data["date_of_birth"]; dateOfBirth != "" {
+		parsed, err := time.Parse("2006-01-02", dateOfBirth)
+		if err != nil || parsed.After(time.Now()) {
+			return errors.New("date of birth must be a past date in the YYYY-MM-DD format")
+		}
+	}
+	return nil
+}
+
+// validateSSHKey parses the private key, using the passphrase when the key is encrypted, and records the
+// public key and its SHA256 fingerprint as metadata
+func validateSSHKey(result *Result) error {
+	signer, err := SSHSigner(result.Data)
+	if err != nil {
+		return err
+	}
+	publicKey := signer.PublicKey()
+
+	result.Metadata = map[string]interface{}{
+		"key_type":    publicKey.Type(),
+		"fingerprint": ssh.FingerprintSHA256(publicKey),
+		"public_key":  AuthorizedKey(publicKey, result.Data["comment"]),
+	}
+	return nil
+}
+
+// SSHSigner opens the private key of an SSH key data document
+func SSHSigner(data map[string]string) (ssh.Signer, error) {
+	privateKey := []byte(data["private_key"])
+
+	var (
+		key interface{}
+		err error
+	)
+	if passphrase := data["passphrase"]; passphrase != "" {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(privateKey, []byte(passphrase))
+	} else {
+		key, err = ssh.ParseRawPrivateKey(privateKey)
+	}
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, errors.New("SSH private key is encrypted, a passphrase is required")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH private key: %v", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported SSH key: %v", err)
+	}
+	return signer, nil
+}
+
+// AuthorizedKey formats a public key as an authorized_keys line
+func AuthorizedKey(publicKey ssh.PublicKey, comment string) string {
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey)))
+	if comment != "" {
+		authorizedKey += " " + comment
+	}
+	return authorizedKey
+}
+
+// validatePasskey normalizes the binary values to base64url and records the credential ID and COSE public key as
+// metadata. A passkey saved without a private key gets a new ES256 key.
+func validatePasskey(result *Result) error {
+	rpID := strings.ToLower(result.Data["rp_id"])
+	if len(rpID) > 253 || !rpIDPattern.MatchString(rpID) {
+		return errors.New("rp_id must be a domain, e.g. example.com")
+	}
+	result.Data["rp_id"] = rpID
+
+	credentialID, err := webauthn.DecodeBase64URL(result.Data["credential_id"])
+	if err != nil || len(credentialID) < minCredentialIDLen || len(credentialID) > maxCredentialIDLen {
+		return fmt.Errorf("credential_id must be %d to %d base64url encoded bytes", minCredentialIDLen, maxCredentialIDLen)
+	}
+	result.Data["credential_id"] = webauthn.EncodeBase64URL(credentialID)
+
+	userHandle, err := webauthn.DecodeBase64URL(result.Data["user_handle"])
+	if err != nil || len(userHandle) == 0 || len(userHandle) > maxUserHandleLen {
+		return fmt.Errorf("user_handle must be 1 to %d base64url encoded bytes", maxUserHandleLen)
+	}
+	result.Data["user_handle"] = webauthn.EncodeBase64URL(userHandle)
+
+	if signCount := result.Data["sign_count"]; signCount != "" {
+		if _, err := strconv.ParseUint(signCount, 10, 32); err != nil {
+			return errors.New("sign_count must be an unsigned 32-bit integer")
+		}
+	} else {
+		result.Data["sign_count"] = "0"
+	}
+
+	var privateKey *ecdsa.PrivateKey
+	if result.Data["private_key"] == "" {
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	} else {
+		privateKey, err = PasskeyKey(result.Data)
+	}
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	result.Data["private_key"] = webauthn.EncodeBase64URL(der)
+
+	publicKey, err := webauthn.EncodeCOSEKey(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	result.Metadata = map[string]interface{}{
+		"credential_id": result.Data["credential_id"],
+		"algorithm":     webauthn.AlgES256,
+		"public_key":    webauthn.EncodeBase64URL(publicKey),
+	}
+	return nil
+}
+
+// PasskeyKey opens the ES256 private key of a passkey data document: PKCS#8 or SEC 1, PEM or base64 encoded
+func PasskeyKey(data map[string]string) (*ecdsa.PrivateKey, error) {
+	encoded := strings.TrimSpace(data["private_key"])
+
+	var der []byte
+	if block, _ := pem.Decode([]byte(encoded)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := webauthn.DecodeBase64URL(encoded)
+		if err != nil {
+			return nil, errors.New("passkey private key must be PEM or base64 encoded")
+		}
+		der = decoded
+	}
+
+	var key interface{}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		key, err = x509.ParseECPrivateKey(der)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid passkey private key: %v", err)
+	}
+	privateKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || privateKey.Curve != elliptic.P256() {
+		return nil, errors.New("passkey private key must be an ES256 (P-256) key")
+	}
+	return privateKey, nil
+}
+
+func validateAPIKey(result *Result) error {
+	if endpoint := result.Data["endpoint"]; endpoint != "" {
+		parsed, err := url.ParseRequestURI(endpoint)
+		if err != nil || parsed.Host == "" {
+			return errors.New("invalid endpoint URL")
+		}
+	}
+	return nil
+}
+
+func luhn(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func cardBrand(number string) string {
+	switch {
+	case strings.HasPrefix(number, "4"):
+		return "visa"
+	case strings.HasPrefix(number, "34"), strings.HasPrefix(number, "37"):
+		return "amex"
+	case len(number) >= 2 && number[:2] >= "51" && number[:2] <= "55",
+		len(number) >= 4 && number[:4] >= "2221" && number[:4] <= "2720":
+		return "mastercard"
+	case strings.HasPrefix(number, "6011"), strings.HasPrefix(number, "65"):
+		return "discover"
+	case len(number) >= 4 && number[:4] >= "3528" && number[:4] <= "3589":
+		return "jcb"
+	default:
+		return "unknown"
+	}
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
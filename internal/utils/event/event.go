@@ -0,0 +1,224 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderSubject   = "Pms-Original-Subject"
+	HeaderError     = "Pms-Error"
+	HeaderDelivered = "Pms-Delivered"
+)
+
+type UserEvent struct {
+	EventID    string `json:"event_id"`
+	Type       string `json:"type"`
+	UserID     uint   `json:"user_id"`
+	ClientID   string `json:"client_id,omitempty"`
+	RoleID     uint   `json:"role_id,omitempty"`
+	OccurredAt int64  `json:"occurred_at,omitempty"`
+}
+
+// Handler processes one message. Returning an error schedules a retry unless it is wrapped with Permanent.
+type Handler func(subject string, data []byte) error
+
+type Options struct {
+	Subjects     []string
+	Queue        string
+	DeadLetter   string
+	MaxDeliver   int
+	RetryBackoff time.Duration
+}
+
+type Subscriber interface {
+	Start() error
+	Stop() error
+}
+
+type subscriber struct {
+	Conn    *nats.Conn
+	Options Options
+	Handler Handler
+	mu      sync.Mutex
+	subs    []*nats.Subscription
+	retries sync.WaitGroup
+	done    chan struct{}
+	stop    sync.Once
+}
+
+// NewSubscriber consumes the given subjects through JetStream when a stream covers them and falls back
+// to core NATS queue subscriptions otherwise. The connection is injected so an embedded server can be used.
+func NewSubscriber(conn *nats.Conn, options Options, handler Handler) Subscriber {
+	if options.MaxDeliver <= 0 {
+		options.MaxDeliver = 1
+	}
+	return &subscriber{
+		Conn:    conn,
+		Options: options,
+		Handler: handler,
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *subscriber) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	js, jsErr := s.Conn.JetStream()
+	for _, subject := range s.Options.Subjects {
+		subject = strings.TrimSpace(subject)
+		if subject == "" {
+			continue
+		}
+		queue := durableName(s.Options.Queue, subject)
+
+		if jsErr == nil {
+			if _, err := js.StreamNameBySubject(subject); err == nil {
+				sub, err := js.QueueSubscribe(subject, queue, s.handleJetStream,
+					nats.Durable(queue),
+					nats.ManualAck(),
+					nats.AckExplicit(),
+					nats.MaxDeliver(s.Options.MaxDeliver),
+				)
+				if err != nil {
+					return fmt.Errorf("failed to subscribe to %s: %v", subject, err)
+				}
+				s.subs = append(s.subs, sub)
+				log.Info().Str("subject", subject).Str("durable", queue).Msg("Subscribed to JetStream subject")
+				continue
+			}
+		}
+
+		sub, err := s.Conn.QueueSubscribe(subject, queue, s.handleCore)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %v", subject, err)
+		}
+		s.subs = append(s.subs, sub)
+		log.Info().Str("subject", subject).Str("queue", queue).Msg("Subscribed to NATS subject")
+	}
+	return nil
+}
+
+// Stop drains the subscriptions and waits for pending core retries, which are dead-lettered rather than dropped
+func (s *subscriber) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, sub := range s.subs {
+		if err := sub.Drain(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.subs = nil
+
+	s.stop.Do(func() { close(s.done) })
+	s.retries.Wait()
+	return errors.Join(errs...)
+}
+
+// handleJetStream lets the server redeliver with a growing delay and dead-letters the message once
+// the last allowed delivery fails
+func (s *subscriber) handleJetStream(msg *nats.Msg) {
+	err := s.Handler(msg.Subject, msg.Data)
+	if err == nil {
+		_ = msg.Ack()
+		return
+	}
+
+	delivered := 1
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		delivered = int(meta.NumDelivered)
+	}
+
+	if IsPermanent(err) || delivered >= s.Options.MaxDeliver {
+		s.deadLetter(msg, err, delivered)
+		_ = msg.Term()
+		return
+	}
+
+	log.Warn().Str("subject", msg.Subject).Int("delivered", delivered).Err(err).Msg("Event handling failed, retrying")
+	_ = msg.NakWithDelay(s.Options.RetryBackoff * time.Duration(delivered))
+}
+
+// handleCore retries in-process since core NATS has no redelivery. Retries wait on their own goroutine so a
+// failing message does not hold up dispatch of the ones behind it.
+func (s *subscriber) handleCore(msg *nats.Msg) {
+	s.attemptCore(msg, 1)
+}
+
+func (s *subscriber) attemptCore(msg *nats.Msg, attempt int) {
+	err := s.Handler(msg.Subject, msg.Data)
+	if err == nil {
+		return
+	}
+	if IsPermanent(err) || attempt >= s.Options.MaxDeliver {
+		s.deadLetter(msg, err, attempt)
+		return
+	}
+
+	log.Warn().Str("subject", msg.Subject).Int("delivered", attempt).Err(err).Msg("Event handling failed, retrying")
+	s.retries.Add(1)
+	go func() {
+		defer s.retries.Done()
+
+		timer := time.NewTimer(s.Options.RetryBackoff * time.Duration(attempt))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			s.attemptCore(msg, attempt+1)
+		case <-s.done:
+			s.deadLetter(msg, err, attempt)
+		}
+	}()
+}
+
+func (s *subscriber) deadLetter(msg *nats.Msg, cause error, delivered int) {
+	log.Error().Str("subject", msg.Subject).Int("delivered", delivered).Err(cause).Msg("Event moved to dead-letter subject")
+	if s.Options.DeadLetter == "" {
+		return
+	}
+
+	dead := nats.NewMsg(s.Options.DeadLetter)
+	dead.Data = msg.Data
+	dead.Header.Set(HeaderSubject, msg.Subject)
+	dead.Header.Set(HeaderError, cause.Error())
+	dead.Header.Set(HeaderDelivered, strconv.Itoa(delivered))
+	if err := s.Conn.PublishMsg(dead); err != nil {
+		log.Error().Str("subject", msg.Subject).Err(err).Msg("Failed to publish to dead-letter subject")
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (p permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p permanentError) Unwrap() error {
+	return p.err
+}
+
+// Permanent marks an error that retrying cannot fix, such as a malformed payload
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// durableName derives a consumer name per subject; durable names may not contain '.', '*' or '>'
+func durableName(queue, subject string) string {
+	return queue + "_" + strings.NewReplacer(".", "_", "*", "any", ">", "all").Replace(subject)
+}
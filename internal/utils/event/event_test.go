@@ -0,0 +1,241 @@
+package event
+
+import (
+	"errors"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testSubject    = "pms.test.user.created"
+	testDeadLetter = "pms.test.dead"
+)
+
+func runServer(t *testing.T, jetStream bool) *nats.Conn {
+	t.Helper()
+
+	opts := &server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		NoLog:     true,
+		NoSigs:    true,
+		JetStream: jetStream,
+		StoreDir:  t.TempDir(),
+	}
+	ns, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatalf("failed to create NATS server: %v", err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	t.Cleanup(ns.Shutdown)
+
+	conn, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	if jetStream {
+		js, err := conn.JetStream()
+		if err != nil {
+			t.Fatalf("failed to open JetStream: %v", err)
+		}
+		if _, err := js.AddStream(&nats.StreamConfig{Name: "PMS_TEST", Subjects: []string{testSubject}}); err != nil {
+			t.Fatalf("failed to add stream: %v", err)
+		}
+	}
+	return conn
+}
+
+func subscribeDeadLetter(t *testing.T, conn *nats.Conn) chan *nats.Msg {
+	t.Helper()
+
+	dead := make(chan *nats.Msg, 4)
+	sub, err := conn.ChanSubscribe(testDeadLetter, dead)
+	if err != nil {
+		t.Fatalf("failed to subscribe to dead-letter subject: %v", err)
+	}
+	t.Cleanup(func() { _ = sub.Unsubscribe() })
+	return dead
+}
+
+func startSubscriber(t *testing.T, conn *nats.Conn, handler Handler) Subscriber {
+	t.Helper()
+
+	sub := NewSubscriber(conn, Options{
+		Subjects:     []string{testSubject},
+		Queue:        "pms_test",
+		DeadLetter:   testDeadLetter,
+		MaxDeliver:   3,
+		RetryBackoff: 10 * time.Millisecond,
+	}, handler)
+	if err := sub.Start(); err != nil {
+		t.Fatalf("failed to start subscriber: %v", err)
+	}
+	t.Cleanup(func() { _ = sub.Stop() })
+	return sub
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+	var zero T
+	return zero
+}
+
+// publish sends through the Publisher when a stream stores the subject, and on core NATS for the core subscriber tests
+func publish(t *testing.T, conn *nats.Conn, jetStream bool, msgID, data string) {
+	t.Helper()
+
+	if jetStream {
+		if err := NewPublisher(conn, time.Second).Publish(testSubject, msgID, []byte(data)); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		return
+	}
+	msg := nats.NewMsg(testSubject)
+	msg.Data = []byte(data)
+	msg.Header.Set(nats.MsgIdHdr, msgID)
+	if err := conn.PublishMsg(msg); err != nil {
+		t.Fatalf("PublishMsg: %v", err)
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	for _, jetStream := range []bool{false, true} {
+		t.Run("jetstream="+strconv.FormatBool(jetStream), func(t *testing.T) {
+			conn := runServer(t, jetStream)
+
+			received := make(chan string, 1)
+			startSubscriber(t, conn, func(subject string, data []byte) error {
+				received <- subject + " " + string(data)
+				return nil
+			})
+
+			publish(t, conn, jetStream, "event-1", `{"user_id":1}`)
+			if got, want := receive(t, received), testSubject+` {"user_id":1}`; got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRetryThenDeadLetter(t *testing.T) {
+	for _, jetStream := range []bool{false, true} {
+		t.Run("jetstream="+strconv.FormatBool(jetStream), func(t *testing.T) {
+			conn := runServer(t, jetStream)
+			dead := subscribeDeadLetter(t, conn)
+
+			var attempts atomic.Int32
+			startSubscriber(t, conn, func(string, []byte) error {
+				attempts.Add(1)
+				return errors.New("unavailable")
+			})
+
+			publish(t, conn, jetStream, "event-2", "payload")
+			msg := receive(t, dead)
+			if got := attempts.Load(); got != 3 {
+				t.Errorf("attempts = %d, want 3", got)
+			}
+			if got := msg.Header.Get(HeaderDelivered); got != "3" {
+				t.Errorf("%s = %q, want 3", HeaderDelivered, got)
+			}
+			if got := msg.Header.Get(HeaderSubject); got != testSubject {
+				t.Errorf("%s = %q, want %q", HeaderSubject, got, testSubject)
+			}
+			if string(msg.Data) != "payload" {
+				t.Errorf("data = %q, want payload", msg.Data)
+			}
+		})
+	}
+}
+
+func TestPermanentErrorIsNotRetried(t *testing.T) {
+	conn := runServer(t, false)
+	dead := subscribeDeadLetter(t, conn)
+
+	var attempts atomic.Int32
+	startSubscriber(t, conn, func(string, []byte) error {
+		attempts.Add(1)
+		return Permanent(errors.New("malformed"))
+	})
+
+	publish(t, conn, false, "event-3", "{")
+	msg := receive(t, dead)
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+	if got := msg.Header.Get(HeaderError); got != "malformed" {
+		t.Errorf("%s = %q, want malformed", HeaderError, got)
+	}
+}
+
+// A message waiting for its retry must not hold up the messages published after it
+func TestCoreRetryDoesNotBlockDispatch(t *testing.T) {
+	conn := runServer(t, false)
+
+	received := make(chan string, 4)
+	sub := NewSubscriber(conn, Options{
+		Subjects:     []string{testSubject},
+		Queue:        "pms_test",
+		MaxDeliver:   2,
+		RetryBackoff: time.Hour,
+	}, func(_ string, data []byte) error {
+		received <- string(data)
+		if string(data) == "fail" {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	if err := sub.Start(); err != nil {
+		t.Fatalf("failed to start subscriber: %v", err)
+	}
+
+	for i, data := range []string{"fail", "ok"} {
+		publish(t, conn, false, "event-"+strconv.Itoa(i), data)
+	}
+	for _, want := range []string{"fail", "ok"} {
+		if got := receive(t, received); got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- sub.Stop() }()
+	if err := receive(t, stopped); err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+}
+
+// Without a stream storing the subject the publish fails, so the outbox keeps the event for a later retry
+func TestPublishRequiresStream(t *testing.T) {
+	for _, jetStream := range []bool{false, true} {
+		t.Run("jetstream="+strconv.FormatBool(jetStream), func(t *testing.T) {
+			conn := runServer(t, jetStream)
+			publisher := NewPublisher(conn, time.Second)
+
+			if err := publisher.Publish("pms.test.unstored", "event-4", []byte("payload")); err == nil {
+				t.Error("Publish succeeded without a stream")
+			}
+			if err := publisher.CheckStream("pms.test.unstored"); err == nil {
+				t.Error("CheckStream found a stream for an unstored subject")
+			}
+			if err := publisher.CheckStream("pms.test.>"); (err == nil) != jetStream {
+				t.Errorf("CheckStream(pms.test.>) = %v with jetstream=%t", err, jetStream)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"sync"
+	"time"
+)
+
+type Publisher interface {
+	Publish(subject, msgID string, data []byte) error
+	CheckStream(subject string) error
+}
+
+type publisher struct {
+	Conn    *nats.Conn
+	Timeout time.Duration
+	mu      sync.Mutex
+	streams map[string]bool
+}
+
+func NewPublisher(conn *nats.Conn, timeout time.Duration) Publisher {
+	return &publisher{
+		Conn:    conn,
+		Timeout: timeout,
+		streams: make(map[string]bool),
+	}
+}
+
+// Publish waits for a JetStream ack, using msgID for server-side deduplication. A subject no stream captures
+// is an error rather than a core NATS publish, so the caller keeps the event and retries it once a stream exists
+// instead of counting a message nobody stored as delivered.
+func (p *publisher) Publish(subject, msgID string, data []byte) error {
+	if p.Conn == nil || !p.Conn.IsConnected() {
+		return errors.New("NATS is not connected")
+	}
+
+	js, err := p.Conn.JetStream()
+	if err != nil {
+		return err
+	}
+	if err := p.findStream(js, subject); err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, msgID)
+	_, err = js.PublishMsg(msg, nats.AckWait(p.Timeout))
+	return err
+}
+
+// CheckStream reports whether a JetStream stream captures subject, which may be a wildcard
+func (p *publisher) CheckStream(subject string) error {
+	if p.Conn == nil || !p.Conn.IsConnected() {
+		return errors.New("NATS is not connected")
+	}
+
+	js, err := p.Conn.JetStream()
+	if err != nil {
+		return err
+	}
+	return p.findStream(js, subject)
+}
+
+// findStream caches the subjects a stream was found for; a missing stream is looked up again on the next call
+func (p *publisher) findStream(js nats.JetStreamContext, subject string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.streams[subject] {
+		return nil
+	}
+	if _, err := js.StreamNameBySubject(subject); err != nil {
+		return fmt.Errorf("no JetStream stream for subject %s: %v", subject, err)
+	}
+	p.streams[subject] = true
+	return nil
+}
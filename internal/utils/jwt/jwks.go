@@ -0,0 +1,250 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval throttles refreshes, whether triggered by an expired cache or an unknown kid, so neither forged
+// headers nor an unreachable JWKS endpoint turn every request into a fetch
+const minRefreshInterval = 30 * time.Second
+
+// KeySet resolves verification keys by kid from a JWKS document
+type KeySet interface {
+	GetKey(kid string) (crypto.PublicKey, error)
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type keySet struct {
+	Source      string
+	CacheTTL    time.Duration
+	Client      *http.Client
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	refreshedAt time.Time
+	inFlight    *refreshCall
+}
+
+// refreshCall is a refresh shared by every request that needs it; done is closed once err is set
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewKeySet loads keys from a JWKS file path or an http(s) URL. Keys are cached for cacheTTL and
+// refreshed early when a token references a kid that is not in the cache, which covers key rotation.
+func NewKeySet(source string, cacheTTL time.Duration) (KeySet, error) {
+	ks := &keySet{
+		Source:   source,
+		CacheTTL: cacheTTL,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     make(map[string]crypto.PublicKey),
+	}
+	ks.refreshedAt = time.Now()
+	if err := ks.refresh(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// GetKey serves cached keys and never waits on the JWKS source for a kid it already holds: a stale cache is
+// refreshed in the background and keeps being served if that refresh fails. Only an unknown kid waits, on the
+// refresh in flight or a new one when the last attempt is older than minRefreshInterval.
+func (k *keySet) GetKey(kid string) (crypto.PublicKey, error) {
+	k.mu.RLock()
+	key, found := k.keys[kid]
+	stale := k.CacheTTL > 0 && time.Since(k.fetchedAt) > k.CacheTTL
+	k.mu.RUnlock()
+
+	if found && !stale {
+		return key, nil
+	}
+
+	call := k.startRefresh()
+	if found {
+		return key, nil
+	}
+	if call == nil {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+
+	<-call.done
+	if call.err != nil {
+		return nil, call.err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if key, found = k.keys[kid]; !found {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	return key, nil
+}
+
+// startRefresh joins the refresh in flight or starts one, and returns nil when the last attempt was too recent
+func (k *keySet) startRefresh() *refreshCall {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.inFlight != nil {
+		return k.inFlight
+	}
+	if time.Since(k.refreshedAt) <= minRefreshInterval {
+		return nil
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	k.inFlight = call
+	k.refreshedAt = time.Now()
+	go func() {
+		call.err = k.refresh()
+		k.mu.Lock()
+		k.inFlight = nil
+		k.mu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// refresh replaces the cached keys with the current document; on failure the cached keys are kept
+func (k *keySet) refresh() error {
+	data, err := k.load()
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %v", err)
+	}
+
+	var doc jwks
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, key := range doc.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return fmt.Errorf("invalid JWK %q: %v", key.Kid, err)
+		}
+		keys[key.Kid] = publicKey
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS contains no signing keys")
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *keySet) load() ([]byte, error) {
+	if !strings.HasPrefix(k.Source, "http://") && !strings.HasPrefix(k.Source, "https://") {
+		return os.ReadFile(strings.TrimPrefix(k.Source, "file://"))
+	}
+
+	resp, err := k.Client.Get(k.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", j.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -1,6 +1,9 @@
 package jwt
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -20,23 +23,63 @@ type Service interface {
 type jwtService struct {
 	SecretKey         []byte
 	InternalSecretKey []byte
+	KeySet            KeySet
+	UserIssuer        string
+	UserAudience      string
+	InternalIssuer    string
+	InternalAudience  string
+	parser            *jwt.Parser
+	internalParser    *jwt.Parser
 }
 
-func NewJWTService(jwtSecret string) Service {
-	return jwtService{
-		SecretKey:         []byte(jwtSecret),
-		InternalSecretKey: []byte(jwtSecret),
+// Options configures token verification. HMAC tokens are accepted only when a secret is set and
+// asymmetric tokens (RS*, PS*, ES*, EdDSA) only when a key set is set. With a key set, user tokens
+// signed with Secret are rejected unless SecretWithKeySet is set. Internal tokens are HS256 signed
+// with InternalSecret only, which is required. Empty issuers and audiences are not checked.
+type Options struct {
+	Secret           string
+	InternalSecret   string
+	KeySet           KeySet
+	SecretWithKeySet bool
+	UserIssuer       string
+	UserAudience     string
+	InternalIssuer   string
+	InternalAudience string
+}
+
+func NewJWTService(options Options) (Service, error) {
+	if options.InternalSecret == "" {
+		return nil, errors.New("internal token secret is required")
+	}
+
+	secret := options.Secret
+	if options.KeySet != nil && !options.SecretWithKeySet {
+		secret = ""
+	}
+
+	var methods []string
+	if secret != "" {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if options.KeySet != nil {
+		methods = append(methods, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA")
 	}
+
+	return jwtService{
+		SecretKey:         []byte(secret),
+		InternalSecretKey: []byte(options.InternalSecret),
+		KeySet:            options.KeySet,
+		UserIssuer:        options.UserIssuer,
+		UserAudience:      options.UserAudience,
+		InternalIssuer:    options.InternalIssuer,
+		InternalAudience:  options.InternalAudience,
+		parser:            jwt.NewParser(jwt.WithValidMethods(methods)),
+		internalParser:    jwt.NewParser(jwt.WithValidMethods([]string{"HS256"})),
+	}, nil
 }
 
 func (j jwtService) ValidateToken(tokenString string) (*jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return j.SecretKey, nil
-	})
-
+	token, err := j.parser.Parse(tokenString, j.keyFunc(j.SecretKey))
 	if err != nil {
 		return nil, err
 	}
@@ -52,6 +95,16 @@ func (j jwtService) ValidateToken(tokenString string) (*jwt.MapClaims, error) {
 		}
 	}
 
+	if j.InternalIssuer != "" && j.InternalIssuer != j.UserIssuer && claims.VerifyIssuer(j.InternalIssuer, true) {
+		return nil, errors.New("internal token is not accepted")
+	}
+	if j.UserIssuer != "" && !claims.VerifyIssuer(j.UserIssuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if j.UserAudience != "" && !claims.VerifyAudience(j.UserAudience, true) {
+		return nil, errors.New("invalid token audience")
+	}
+
 	return &claims, nil
 }
 
@@ -116,7 +169,7 @@ func (j jwtService) GenerateInternalToken(serviceName string) (string, error) {
 	claims := InternalClaims{
 		Service: serviceName,
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "auth-service",
+			Issuer:    j.InternalIssuer,
 			Subject:   "internal-communication",
 			Audience:  []string{strings.ToLower(serviceName) + "-service"},
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
@@ -129,22 +182,68 @@ func (j jwtService) GenerateInternalToken(serviceName string) (string, error) {
 }
 
 func (j jwtService) ValidateInternalToken(tokenString string) (*InternalClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &InternalClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
+	// Internal tokens never go through the key set, so a token signed for users cannot pass as a service
+	token, err := j.internalParser.ParseWithClaims(tokenString, &InternalClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return j.InternalSecretKey, nil
 	})
-
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*InternalClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*InternalClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
 	}
 
-	return nil, jwt.ErrSignatureInvalid
+	if j.InternalIssuer != "" && !claims.VerifyIssuer(j.InternalIssuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if j.InternalAudience != "" && !claims.VerifyAudience(j.InternalAudience, true) {
+		return nil, errors.New("invalid token audience")
+	}
+
+	return claims, nil
+}
+
+// keyFunc picks the verification key for the token's algorithm: the given secret for HMAC,
+// or the JWKS key named by the kid header for asymmetric algorithms
+func (j jwtService) keyFunc(secret []byte) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			if len(secret) == 0 {
+				return nil, errors.New("HMAC tokens are not accepted")
+			}
+			return secret, nil
+		}
+
+		if j.KeySet == nil {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token is missing kid header")
+		}
+		key, err := j.KeySet.GetKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			if _, ok := key.(*rsa.PublicKey); ok {
+				return key, nil
+			}
+		case *jwt.SigningMethodECDSA:
+			if _, ok := key.(*ecdsa.PublicKey); ok {
+				return key, nil
+			}
+		case *jwt.SigningMethodEd25519:
+			if _, ok := key.(ed25519.PublicKey); ok {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("key %s does not match signing method %v", kid, token.Header["alg"])
+	}
 }
 
 type TokenClaims struct {
@@ -175,6 +274,23 @@ func ExtractTokenClaims(c *gin.Context) (*TokenClaims, bool) {
 	return tokenClaims, true
 }
 
+func SetInternalClaims(c *gin.Context, claims *InternalClaims) {
+	c.Set("internal_token", claims)
+}
+
+func ExtractInternalClaims(c *gin.Context) (*InternalClaims, bool) {
+	tokenData, exists := c.Get("internal_token")
+	if !exists {
+		return nil, false
+	}
+
+	claims, ok := tokenData.(*InternalClaims)
+	if !ok || claims == nil {
+		return nil, false
+	}
+	return claims, true
+}
+
 func HasPasswordResource(resource []string) bool {
 	for _, res := range resource {
 		if res == "password-management" {
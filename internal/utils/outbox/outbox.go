@@ -0,0 +1,92 @@
+package outbox
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"password-management-service/internal/models/password"
+	"time"
+)
+
+const (
+	Version = 1
+	Source  = "password-management-service"
+)
+
+// Envelope is the versioned JSON document published for every vault event. Consumers must
+// deduplicate on ID because delivery is at-least-once.
+type Envelope struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Version    int                    `json:"version"`
+	Source     string                 `json:"source"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	UserID     uint                   `json:"user_id,omitempty"`
+	EntryID    uint                   `json:"entry_id,omitempty"`
+	Actor      string                 `json:"actor,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
+
+// NewEvent builds the outbox row for an event; it never carries secrets, only identifiers and metadata
+func NewEvent(eventType string, userID, entryID uint, actor *string, data map[string]interface{}) (*password.OutboxEvent, error) {
+	eventID, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := Envelope{
+		ID:         eventID,
+		Type:       eventType,
+		Version:    Version,
+		Source:     Source,
+		OccurredAt: time.Now().UTC(),
+		UserID:     userID,
+		EntryID:    entryID,
+		Data:       data,
+	}
+	if actor != nil {
+		envelope.Actor = *actor
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &password.OutboxEvent{
+		EventID:       eventID,
+		EventType:     eventType,
+		EventVersion:  Version,
+		Payload:       string(payload),
+		NextAttemptAt: envelope.OccurredAt,
+	}
+	if userID != 0 {
+		event.UserID = &userID
+	}
+	if entryID != 0 {
+		event.AggregateID = &entryID
+	}
+	return event, nil
+}
+
+// RetryDelay backs off exponentially from one second up to ten minutes
+func RetryDelay(attempts int) time.Duration {
+	if attempts > 10 {
+		attempts = 10
+	}
+	delay := time.Second << attempts
+	if delay > 10*time.Minute {
+		delay = 10 * time.Minute
+	}
+	return delay
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
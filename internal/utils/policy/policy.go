@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"fmt"
+	"password-management-service/internal/models/password"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	ClassLowercase = 1 << iota
+	ClassUppercase
+	ClassDigit
+	ClassSymbol
+)
+
+const (
+	RuleMinLength         = "min_length"
+	RuleUppercase         = "require_uppercase"
+	RuleLowercase         = "require_lowercase"
+	RuleDigits            = "require_digits"
+	RuleSymbols           = "require_symbols"
+	RuleMaxAge            = "max_age"
+	RuleCompromised       = "forbid_compromised"
+	RuleUnknownComplexity = "unknown_complexity"
+)
+
+// Characteristics is everything policies need to know about a password; the password itself is never stored
+type Characteristics struct {
+	Length  int
+	Classes int
+}
+
+type Violation struct {
+	PolicyID   uint   `json:"policy_id"`
+	PolicyName string `json:"policy_name"`
+	Rule       string `json:"rule"`
+	Message    string `json:"message"`
+}
+
+// ViolationError is returned when a password does not satisfy the policies in effect
+type ViolationError struct {
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "password policy violation: " + strings.Join(messages, "; ")
+}
+
+func Inspect(plaintext string) Characteristics {
+	characteristics := Characteristics{}
+	for _, r := range plaintext {
+		characteristics.Length++
+		switch {
+		case unicode.IsLower(r):
+			characteristics.Classes |= ClassLowercase
+		case unicode.IsUpper(r):
+			characteristics.Classes |= ClassUppercase
+		case unicode.IsDigit(r):
+			characteristics.Classes |= ClassDigit
+		default:
+			characteristics.Classes |= ClassSymbol
+		}
+	}
+	return characteristics
+}
+
+// FromEntry returns the characteristics recorded for an entry, or nil for entries saved before they were tracked
+func FromEntry(entry *password.PasswordEntry) *Characteristics {
+	if entry.PasswordLength == nil || entry.PasswordClasses == nil {
+		return nil
+	}
+	return &Characteristics{Length: *entry.PasswordLength, Classes: *entry.PasswordClasses}
+}
+
+// Evaluate checks an entry against every policy in effect. Composition rules cannot be verified when the
+// characteristics are unknown, which is reported as its own violation rather than silently passing.
+func Evaluate(policies []password.PasswordPolicy, characteristics *Characteristics, changedAt time.Time, compromised bool, now time.Time) []Violation {
+	var violations []Violation
+	for _, p := range policies {
+		add := func(rule, message string) {
+			violations = append(violations, Violation{PolicyID: p.PolicyID, PolicyName: p.Name, Rule: rule, Message: message})
+		}
+
+		if characteristics == nil {
+			if p.MinLength > 0 || p.RequireUppercase || p.RequireLowercase || p.RequireDigits || p.RequireSymbols {
+				add(RuleUnknownComplexity, "password must be updated before its complexity can be verified")
+			}
+		} else {
+			if characteristics.Length < p.MinLength {
+				add(RuleMinLength, fmt.Sprintf("password must be at least %d characters", p.MinLength))
+			}
+			if p.RequireUppercase && characteristics.Classes&ClassUppercase == 0 {
+				add(RuleUppercase, "password must contain an uppercase letter")
+			}
+			if p.RequireLowercase && characteristics.Classes&ClassLowercase == 0 {
+				add(RuleLowercase, "password must contain a lowercase letter")
+			}
+			if p.RequireDigits && characteristics.Classes&ClassDigit == 0 {
+				add(RuleDigits, "password must contain a digit")
+			}
+			if p.RequireSymbols && characteristics.Classes&ClassSymbol == 0 {
+				add(RuleSymbols, "password must contain a symbol")
+			}
+		}
+
+		if p.MaxAgeDays > 0 && now.Sub(changedAt) > maxAge(p.MaxAgeDays) {
+			add(RuleMaxAge, fmt.Sprintf("password must be rotated every %d days", p.MaxAgeDays))
+		}
+		if p.ForbidCompromised && compromised {
+			add(RuleCompromised, "password appears in a known data breach")
+		}
+	}
+	return violations
+}
+
+// RotationDeadline returns when the strictest rotation rule requires a password changed at changedAt to be replaced
+func RotationDeadline(policies []password.PasswordPolicy, changedAt time.Time) *time.Time {
+	var deadline *time.Time
+	for _, p := range policies {
+		if p.MaxAgeDays <= 0 {
+			continue
+		}
+		expiresAt := changedAt.Add(maxAge(p.MaxAgeDays))
+		if deadline == nil || expiresAt.Before(*deadline) {
+			deadline = &expiresAt
+		}
+	}
+	return deadline
+}
+
+func maxAge(days int) time.Duration {
+	return time.Duration(days) * 24 * time.Hour
+}
@@ -0,0 +1,158 @@
+package policy
+
+import (
+	"password-management-service/internal/models/password"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		plaintext string
+		want      Characteristics
+	}{
+		{plaintext: "", want: Characteristics{}},
+		{plaintext: "abc", want: Characteristics{Length: 3, Classes: ClassLowercase}},
+		{plaintext: "Abc1", want: Characteristics{Length: 4, Classes: ClassLowercase | ClassUppercase | ClassDigit}},
+		{plaintext: "a b!", want: Characteristics{Length: 4, Classes: ClassLowercase | ClassSymbol}},
+		{plaintext: "Ünïcödé٣", want: Characteristics{Length: 8, Classes: ClassLowercase | ClassUppercase | ClassDigit}},
+		{plaintext: "密码🔑", want: Characteristics{Length: 3, Classes: ClassSymbol}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.plaintext, func(t *testing.T) {
+			if got := Inspect(tt.plaintext); got != tt.want {
+				t.Errorf("Inspect(%q) = %+v, want %+v", tt.plaintext, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromEntry(t *testing.T) {
+	length, classes := 12, ClassLowercase|ClassDigit
+
+	if got := FromEntry(&password.PasswordEntry{PasswordLength: &length}); got != nil {
+		t.Errorf("FromEntry without classes = %+v, want nil", got)
+	}
+	got := FromEntry(&password.PasswordEntry{PasswordLength: &length, PasswordClasses: &classes})
+	if got == nil || *got != (Characteristics{Length: length, Classes: classes}) {
+		t.Errorf("FromEntry = %+v, want length %d classes %d", got, length, classes)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	strict := password.PasswordPolicy{PolicyID: 1, Name: "strict", MinLength: 12, RequireUppercase: true, RequireLowercase: true, RequireDigits: true, RequireSymbols: true}
+	rotation := password.PasswordPolicy{PolicyID: 2, Name: "rotation", MaxAgeDays: 30, ForbidCompromised: true}
+	strong := &Characteristics{Length: 16, Classes: ClassLowercase | ClassUppercase | ClassDigit | ClassSymbol}
+
+	tests := []struct {
+		name            string
+		policies        []password.PasswordPolicy
+		characteristics *Characteristics
+		changedAt       time.Time
+		compromised     bool
+		want            []string
+	}{
+		{name: "no policies", characteristics: &Characteristics{Length: 1}, changedAt: now},
+		{name: "satisfied", policies: []password.PasswordPolicy{strict, rotation}, characteristics: strong, changedAt: now.AddDate(0, 0, -30)},
+		{
+			name:            "every composition rule",
+			policies:        []password.PasswordPolicy{strict},
+			characteristics: &Characteristics{Length: 4},
+			changedAt:       now,
+			want:            []string{RuleMinLength, RuleUppercase, RuleLowercase, RuleDigits, RuleSymbols},
+		},
+		{
+			name:            "one missing class",
+			policies:        []password.PasswordPolicy{strict},
+			characteristics: &Characteristics{Length: 12, Classes: ClassLowercase | ClassUppercase | ClassDigit},
+			changedAt:       now,
+			want:            []string{RuleSymbols},
+		},
+		{
+			name:      "unknown complexity is reported once per policy",
+			policies:  []password.PasswordPolicy{strict, {PolicyID: 3, MinLength: 8}},
+			changedAt: now,
+			want:      []string{RuleUnknownComplexity, RuleUnknownComplexity},
+		},
+		{name: "unknown complexity without composition rules", policies: []password.PasswordPolicy{rotation}, changedAt: now},
+		{
+			name:            "older than the max age",
+			policies:        []password.PasswordPolicy{rotation},
+			characteristics: strong,
+			changedAt:       now.AddDate(0, 0, -30).Add(-time.Second),
+			want:            []string{RuleMaxAge},
+		},
+		{
+			name:            "compromised",
+			policies:        []password.PasswordPolicy{rotation},
+			characteristics: strong,
+			changedAt:       now,
+			compromised:     true,
+			want:            []string{RuleCompromised},
+		},
+		{
+			name:            "compromised without a policy forbidding it",
+			policies:        []password.PasswordPolicy{strict},
+			characteristics: strong,
+			changedAt:       now,
+			compromised:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []string
+			for _, violation := range Evaluate(tt.policies, tt.characteristics, tt.changedAt, tt.compromised, now) {
+				rules = append(rules, violation.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("Evaluate rules = %v, want %v", rules, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateNamesThePolicy(t *testing.T) {
+	violations := Evaluate([]password.PasswordPolicy{{PolicyID: 7, Name: "team", MinLength: 20}}, &Characteristics{Length: 8}, time.Now(), false, time.Now())
+	want := []Violation{{PolicyID: 7, PolicyName: "team", Rule: RuleMinLength, Message: "password must be at least 20 characters"}}
+	if !reflect.DeepEqual(violations, want) {
+		t.Errorf("Evaluate = %+v, want %+v", violations, want)
+	}
+
+	err := &ViolationError{Violations: append(want, Violation{Message: "password must contain a digit"})}
+	if got := err.Error(); got != "password policy violation: password must be at least 20 characters; password must contain a digit" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestRotationDeadline(t *testing.T) {
+	changedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		policies []password.PasswordPolicy
+		want     *time.Time
+	}{
+		{name: "no policies"},
+		{name: "no rotation rule", policies: []password.PasswordPolicy{{MinLength: 12}, {MaxAgeDays: -1}}},
+		{name: "single rule", policies: []password.PasswordPolicy{{MaxAgeDays: 90}}, want: deadline(changedAt, 90)},
+		{name: "strictest wins", policies: []password.PasswordPolicy{{MaxAgeDays: 90}, {MaxAgeDays: 30}, {}}, want: deadline(changedAt, 30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RotationDeadline(tt.policies, changedAt)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("RotationDeadline = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func deadline(changedAt time.Time, days int) *time.Time {
+	t := changedAt.AddDate(0, 0, days)
+	return &t
+}
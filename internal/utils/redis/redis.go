@@ -7,12 +7,24 @@ import (
 	"fmt"
 	"github.com/redis/go-redis/v9"
 	"password-management-service/internal/models/user"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type RedisService interface {
 	SaveData(key, clientID string, data interface{}) error
 	GetData(key, clientID string, target interface{}) error
 	DeleteData(key, clientID string) error
+	ConsumeData(key, clientID string, target interface{}) error
+	ConsumeDataIf(key, clientID string, target interface{}, match func() bool) (bool, error)
+	SaveDataWithTTL(key, clientID string, data interface{}, ttl time.Duration) error
+	RefreshDataIf(key, clientID string, target interface{}, match func() bool, ttl time.Duration) (bool, error)
+	Exists(key, clientID string) (bool, error)
+	ScanClientIDs(key string) ([]string, error)
+	GetTTL(key, clientID string) (time.Duration, error)
+	Increment(key, clientID string, ttl time.Duration) (int64, error)
+	SlidingWindow(key, clientID string, limit int, window time.Duration) (bool, int, time.Duration, error)
 	GetToken(clientID string) (string, error)
 	DeleteToken(clientID string) error
 }
@@ -48,6 +60,170 @@ func (r redisService) DeleteData(key, clientID string) error {
 	return r.Client.Del(r.Ctx, key+":"+clientID).Err()
 }
 
+// ConsumeData reads and deletes the value atomically so it can only be used once
+func (r redisService) ConsumeData(key, clientID string, target interface{}) error {
+	jsonData, err := r.Client.GetDel(r.Ctx, key+":"+clientID).Result()
+	if errors.Is(err, redis.Nil) {
+		return fmt.Errorf("no data found for key: %s", key+":"+clientID)
+	} else if err != nil {
+		return fmt.Errorf("failed to consume data: %v", err)
+	}
+	return json.Unmarshal([]byte(jsonData), target)
+}
+
+// ConsumeDataIf reads the value into target and deletes it only when match accepts it. A value that is rejected
+// is left in place, and the delete fails if the value was changed or consumed in between.
+func (r redisService) ConsumeDataIf(key, clientID string, target interface{}, match func() bool) (bool, error) {
+	redisKey := key + ":" + clientID
+	matched := false
+	err := r.Client.Watch(r.Ctx, func(tx *redis.Tx) error {
+		jsonData, err := tx.Get(r.Ctx, redisKey).Result()
+		if errors.Is(err, redis.Nil) {
+			return fmt.Errorf("no data found for key: %s", redisKey)
+		} else if err != nil {
+			return fmt.Errorf("failed to consume data: %v", err)
+		}
+		if err := json.Unmarshal([]byte(jsonData), target); err != nil {
+			return err
+		}
+		if !match() {
+			return nil
+		}
+		_, err = tx.TxPipelined(r.Ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(r.Ctx, redisKey)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to consume data: %v", err)
+		}
+		matched = true
+		return nil
+	}, redisKey)
+	return matched, err
+}
+
+func (r redisService) SaveDataWithTTL(key, clientID string, data interface{}, ttl time.Duration) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %v", err)
+	}
+	return r.Client.Set(r.Ctx, key+":"+clientID, jsonData, ttl).Err()
+}
+
+// RefreshDataIf reads the value into target and, when match accepts it, writes target back with a new ttl.
+// The write only lands if the value is still the one that was read, and a missing key is never re-created,
+// so a refresh racing a delete or a replacement is dropped instead of restoring the old value.
+func (r redisService) RefreshDataIf(key, clientID string, target interface{}, match func() bool, ttl time.Duration) (bool, error) {
+	redisKey := key + ":" + clientID
+	refreshed := false
+	err := r.Client.Watch(r.Ctx, func(tx *redis.Tx) error {
+		jsonData, err := tx.Get(r.Ctx, redisKey).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to refresh data: %v", err)
+		}
+		if err := json.Unmarshal([]byte(jsonData), target); err != nil {
+			return err
+		}
+		if !match() {
+			return nil
+		}
+		updated, err := json.Marshal(target)
+		if err != nil {
+			return fmt.Errorf("failed to marshal data: %v", err)
+		}
+		_, err = tx.TxPipelined(r.Ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetXX(r.Ctx, redisKey, updated, ttl)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		refreshed = true
+		return nil
+	}, redisKey)
+	if errors.Is(err, redis.TxFailedErr) {
+		return false, nil
+	}
+	return refreshed, err
+}
+
+func (r redisService) Exists(key, clientID string) (bool, error) {
+	count, err := r.Client.Exists(r.Ctx, key+":"+clientID).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ScanClientIDs lists the client IDs holding a value under key, without blocking Redis the way KEYS would
+func (r redisService) ScanClientIDs(key string) ([]string, error) {
+	var clientIDs []string
+	iter := r.Client.Scan(r.Ctx, 0, key+":*", 100).Iterator()
+	for iter.Next(r.Ctx) {
+		clientIDs = append(clientIDs, strings.TrimPrefix(iter.Val(), key+":"))
+	}
+	return clientIDs, iter.Err()
+}
+
+func (r redisService) GetTTL(key, clientID string) (time.Duration, error) {
+	return r.Client.TTL(r.Ctx, key+":"+clientID).Result()
+}
+
+// Increment bumps a counter and starts its expiry on the first increment
+func (r redisService) Increment(key, clientID string, ttl time.Duration) (int64, error) {
+	redisKey := key + ":" + clientID
+	count, err := r.Client.Incr(r.Ctx, redisKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 && ttl > 0 {
+		if err := r.Client.Expire(r.Ctx, redisKey, ttl).Err(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// SlidingWindow records a hit in a sorted-set window and reports whether it fits in the limit,
+// the remaining budget and how long to wait before the oldest hit leaves the window.
+func (r redisService) SlidingWindow(key, clientID string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	redisKey := key + ":" + clientID
+	now := time.Now()
+	member := strconv.FormatInt(now.UnixNano(), 10)
+
+	var count *redis.IntCmd
+	_, err := r.Client.TxPipelined(r.Ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(r.Ctx, redisKey, "0", strconv.FormatInt(now.Add(-window).UnixMilli(), 10))
+		pipe.ZAdd(r.Ctx, redisKey, redis.Z{Score: float64(now.UnixMilli()), Member: member})
+		count = pipe.ZCard(r.Ctx, redisKey)
+		pipe.PExpire(r.Ctx, redisKey, window)
+		return nil
+	})
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	if int(count.Val()) <= limit {
+		return true, limit - int(count.Val()), 0, nil
+	}
+
+	if err := r.Client.ZRem(r.Ctx, redisKey, member).Err(); err != nil {
+		return false, 0, window, err
+	}
+
+	oldest, err := r.Client.ZRangeWithScores(r.Ctx, redisKey, 0, 0).Result()
+	if err != nil || len(oldest) == 0 {
+		return false, 0, window, err
+	}
+	retryAfter := time.UnixMilli(int64(oldest[0].Score)).Add(window).Sub(now)
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return false, 0, retryAfter, nil
+}
+
 func generateRedisKey(clientID string) string {
 	return "token:" + clientID
 }
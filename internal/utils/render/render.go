@@ -0,0 +1,101 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	FormatDotenv = "dotenv"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+)
+
+// Secrets renders key/value pairs in the requested format and returns the body with its content type.
+// Keys are sorted so the output is stable across calls.
+func Secrets(format string, secrets map[string]string) ([]byte, string, error) {
+	switch strings.ToLower(format) {
+	case "", FormatDotenv:
+		return dotenv(secrets), "text/plain; charset=utf-8", nil
+	case FormatJSON:
+		body, err := json.MarshalIndent(secrets, "", "  ")
+		if err != nil {
+			return nil, "", err
+		}
+		return append(body, '\n'), "application/json; charset=utf-8", nil
+	case FormatYAML:
+		body, err := yaml.Marshal(secrets)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, "application/yaml; charset=utf-8", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// SupportedFormat reports whether Secrets can render the format, so callers can reject a request before they
+// decrypt anything
+func SupportedFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case "", FormatDotenv, FormatJSON, FormatYAML:
+		return true
+	}
+	return false
+}
+
+// EnvKey converts a free-form name such as an entry title into an environment variable name
+func EnvKey(name string) string {
+	var b strings.Builder
+	lastUnderscore := true
+	for _, r := range strings.TrimSpace(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(unicode.ToUpper(r))
+			lastUnderscore = false
+			continue
+		}
+		if !lastUnderscore {
+			b.WriteByte('_')
+			lastUnderscore = true
+		}
+	}
+
+	key := strings.TrimRight(b.String(), "_")
+	if key != "" && key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return key
+}
+
+func dotenv(secrets map[string]string) []byte {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key)
+		b.WriteString("=")
+		b.WriteString(quoteDotenv(secrets[key]))
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
+
+func quoteDotenv(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"$", `\$`,
+		"`", "\\`",
+		"\n", `\n`,
+		"\r", `\r`,
+	)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -87,3 +87,15 @@ func DerefString(s *string) string {
 	}
 	return ""
 }
+
+// MaskString keeps the first and last characters of a value and hides the rest
+func MaskString(s string) string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return ""
+	}
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[0]) + strings.Repeat("*", len(runes)-2) + string(runes[len(runes)-1])
+}
@@ -0,0 +1,269 @@
+package urlmatch
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Match strategies, from the most to the least specific
+const (
+	MatchExact      = "exact"
+	MatchStartsWith = "starts_with"
+	MatchHost       = "host"
+	MatchBaseDomain = "base_domain"
+	MatchRegex      = "regex"
+)
+
+// MatchEquivalent labels a base domain match through an equivalent domain or a known app of the domain
+const MatchEquivalent = "equivalent"
+
+// App schemes identify a mobile app instead of a web site, e.g. androidapp://com.example.app
+const (
+	SchemeAndroidApp = "androidapp"
+	SchemeIOSApp     = "iosapp"
+)
+
+const (
+	DefaultMatch = MatchBaseDomain
+	MaxRules     = 20
+	MaxURLLen    = 2048
+)
+
+// scores ranks a match by the specificity of the strategy that produced it
+var scores = map[string]int{
+	MatchExact:      500,
+	MatchStartsWith: 400,
+	MatchHost:       300,
+	MatchBaseDomain: 200,
+	MatchRegex:      100,
+}
+
+const equivalentScore = 150
+
+var (
+	schemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+	appIDPattern  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*(\.[a-zA-Z0-9_-]+)+$`)
+	defaultPorts  = map[string]string{"http": "80", "https": "443"}
+)
+
+// Rule is one URL of an entry with the strategy used to match it. The URL of a regex rule is the pattern.
+type Rule struct {
+	URL   string `json:"url"`
+	Match string `json:"match,omitempty"`
+}
+
+// URI is a normalized URL: lower case scheme and host, IDNA host, no default port, user info or fragment, and a
+// path of at least "/". An app URI is only the scheme and the app ID, which is also its host and base domain.
+type URI struct {
+	Scheme     string
+	Host       string
+	Hostname   string
+	BaseDomain string
+	normalized string
+}
+
+func (u *URI) String() string {
+	return u.normalized
+}
+
+func (u *URI) IsApp() bool {
+	return u.Scheme == SchemeAndroidApp || u.Scheme == SchemeIOSApp
+}
+
+func IsValidMatch(match string) bool {
+	_, ok := scores[match]
+	return ok
+}
+
+// Parse normalizes a URL. A URL without a scheme is taken as https.
+func Parse(raw string) (*URI, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("URL is required")
+	}
+	if len(raw) > MaxURLLen {
+		return nil, errors.New("URL is too long")
+	}
+	if !schemePattern.MatchString(raw) {
+		raw = "https://" + raw
+	}
+	scheme, appID, _ := strings.Cut(raw, "://")
+	if scheme = strings.ToLower(scheme); scheme == SchemeAndroidApp || scheme == SchemeIOSApp {
+		return parseApp(scheme, appID)
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid URL: %s", raw)
+	}
+
+	scheme = strings.ToLower(parsed.Scheme)
+	hostname := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if net.ParseIP(hostname) == nil {
+		hostname, err = idna.Lookup.ToASCII(hostname)
+		if err != nil || hostname == "" {
+			return nil, fmt.Errorf("invalid URL host: %s", parsed.Hostname())
+		}
+	}
+
+	host := hostname
+	if port := parsed.Port(); port != "" && port != defaultPorts[scheme] {
+		host = net.JoinHostPort(hostname, port)
+	} else if strings.Contains(hostname, ":") {
+		host = "[" + hostname + "]"
+	}
+
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	normalized := scheme + "://" + host + path
+	if parsed.RawQuery != "" {
+		normalized += "?" + parsed.RawQuery
+	}
+
+	return &URI{
+		Scheme:     scheme,
+		Host:       host,
+		Hostname:   hostname,
+		BaseDomain: baseDomain(hostname),
+		normalized: normalized,
+	}, nil
+}
+
+// parseApp keeps the app ID as written; Android package names are case-sensitive
+func parseApp(scheme, appID string) (*URI, error) {
+	appID = strings.TrimSuffix(appID, "/")
+	if !appIDPattern.MatchString(appID) {
+		return nil, fmt.Errorf("invalid app ID: %s", appID)
+	}
+	normalized := scheme + "://" + appID
+	return &URI{
+		Scheme:     scheme,
+		Host:       appID,
+		Hostname:   appID,
+		BaseDomain: normalized,
+		normalized: normalized,
+	}, nil
+}
+
+// NormalizeDomain returns the base domain of a domain or URL, for equivalent domain groups
+func NormalizeDomain(raw string) (string, error) {
+	uri, err := Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if uri.IsApp() {
+		return "", fmt.Errorf("not a domain: %s", raw)
+	}
+	return uri.BaseDomain, nil
+}
+
+// baseDomain is the registrable domain of the host according to the public suffix list, e.g. example.co.uk for
+// login.example.co.uk. IP addresses and hosts that are themselves a public suffix are their own base domain.
+func baseDomain(hostname string) string {
+	if net.ParseIP(hostname) != nil {
+		return hostname
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		return hostname
+	}
+	return domain
+}
+
+// NormalizeRules validates the rules and returns them with normalized URLs, checked patterns and the
+// default strategy filled in. Duplicates are dropped.
+func NormalizeRules(rules []Rule) ([]Rule, error) {
+	if len(rules) > MaxRules {
+		return nil, fmt.Errorf("at most %d URLs are allowed", MaxRules)
+	}
+
+	seen := make(map[Rule]bool, len(rules))
+	normalized := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		rule.Match = strings.ToLower(strings.TrimSpace(rule.Match))
+		if rule.Match == "" {
+			rule.Match = DefaultMatch
+		}
+		if !IsValidMatch(rule.Match) {
+			return nil, fmt.Errorf("unsupported URL match: %s", rule.Match)
+		}
+
+		if rule.Match == MatchRegex {
+			pattern := strings.TrimSpace(rule.URL)
+			if pattern == "" {
+				return nil, errors.New("URL pattern is required")
+			}
+			if len(pattern) > MaxURLLen {
+				return nil, errors.New("URL pattern is too long")
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid URL pattern %q: %v", pattern, err)
+			}
+			rule.URL = pattern
+		} else {
+			uri, err := Parse(rule.URL)
+			if err != nil {
+				return nil, err
+			}
+			rule.URL = uri.String()
+		}
+
+		if seen[rule] {
+			continue
+		}
+		seen[rule] = true
+		normalized = append(normalized, rule)
+	}
+	return normalized, nil
+}
+
+// Score reports whether the rule matches the target and how specific the match is; a higher score ranks first.
+// Regex patterns are matched against the normalized target and are not anchored unless the pattern says so.
+func Score(rule Rule, target *URI) (int, bool) {
+	if rule.Match == MatchRegex {
+		pattern, err := regexp.Compile(rule.URL)
+		if err != nil {
+			return 0, false
+		}
+		return scores[MatchRegex], pattern.MatchString(target.String())
+	}
+
+	uri, err := Parse(rule.URL)
+	if err != nil {
+		return 0, false
+	}
+
+	var matched bool
+	switch rule.Match {
+	case MatchExact:
+		matched = uri.String() == target.String()
+	case MatchStartsWith:
+		matched = strings.HasPrefix(target.String(), uri.String())
+	case MatchHost:
+		matched = uri.Host == target.Host
+	case MatchBaseDomain:
+		matched = uri.BaseDomain == target.BaseDomain
+	}
+	return scores[rule.Match], matched
+}
+
+// ScoreEquivalent reports whether a base domain rule matches one of the keys, the base domains and app URIs
+// equivalent to the target. It ranks below a direct base domain match.
+func ScoreEquivalent(rule Rule, keys map[string]bool) (int, bool) {
+	if rule.Match != MatchBaseDomain {
+		return 0, false
+	}
+	uri, err := Parse(rule.URL)
+	if err != nil {
+		return 0, false
+	}
+	return equivalentScore, keys[uri.BaseDomain]
+}
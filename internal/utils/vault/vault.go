@@ -0,0 +1,107 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+)
+
+const (
+	tokenPrefix     = "vs"
+	contextVaultKey = "vault_key"
+)
+
+// Session is the unlocked vault state kept in Redis. The derived key is sealed under the
+// session token, which only the client holds, so a Redis dump alone cannot open the vault.
+type Session struct {
+	TokenHash    string `json:"token_hash"`
+	EncryptedKey string `json:"encrypted_key"`
+	CreatedAt    int64  `json:"created_at"`
+	LastUsedAt   int64  `json:"last_used_at"`
+}
+
+// GenerateToken returns a new vault session token and the hash stored in Redis
+func GenerateToken() (string, string, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+
+	token := tokenPrefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return token, Hash(token), nil
+}
+
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches compares a presented token against the stored hash in constant time
+func (s *Session) Matches(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(s.TokenHash), []byte(Hash(token))) == 1
+}
+
+// Seal encrypts the derived key under a key bound to the session token
+func Seal(derivedKey []byte, token string) (string, error) {
+	gcm, err := newGCM(token)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, derivedKey, []byte(tokenPrefix))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open recovers the derived key sealed by Seal
+func Open(encryptedKey, token string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("vault session is corrupted")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(tokenPrefix))
+}
+
+func newGCM(token string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("vault-session:" + token))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func SetVaultKey(c *gin.Context, derivedKey []byte) {
+	c.Set(contextVaultKey, derivedKey)
+}
+
+func ExtractVaultKey(c *gin.Context) ([]byte, bool) {
+	data, exists := c.Get(contextVaultKey)
+	if !exists {
+		return nil, false
+	}
+
+	derivedKey, ok := data.([]byte)
+	if !ok || len(derivedKey) == 0 {
+		return nil, false
+	}
+	return derivedKey, true
+}
@@ -0,0 +1,388 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fxamacker/cbor/v2"
+	"math/big"
+)
+
+// Client data types of the two ceremonies
+const (
+	CeremonyCreate = "webauthn.create"
+	CeremonyGet    = "webauthn.get"
+)
+
+// Attestation formats that can be verified
+const (
+	FormatNone   = "none"
+	FormatPacked = "packed"
+)
+
+// Attestation types, as recorded with a credential
+const (
+	AttestationNone  = "none"
+	AttestationSelf  = "self"
+	AttestationBasic = "basic"
+)
+
+// More COSE algorithms accepted from authenticators; passkeys of the vault are always ES256
+const (
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+const (
+	coseKeyTypeOKP   = 1
+	coseKeyTypeRSA   = 3
+	coseCurveEd25519 = 6
+	minRSAKeyBits    = 2048
+)
+
+// authDataMinLen is the RP ID hash, the flags and the sign count
+const authDataMinLen = 32 + 1 + 4
+
+// oidFIDOAAGUID is the certificate extension that carries the AAGUID of the authenticator model
+var oidFIDOAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// decMode rejects duplicate map keys, which would make a signed structure ambiguous
+var decMode, _ = cbor.DecOptions{DupMapKey: cbor.DupMapKeyEnforcedAPF}.DecMode()
+
+// AuthData is parsed authenticator data. The credential fields are only set when the attested credential data
+// flag is.
+type AuthData struct {
+	Raw          []byte
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       [16]byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func (a *AuthData) Has(flag byte) bool {
+	return a.Flags&flag == flag
+}
+
+// ClientData is the collected client data JSON the authenticator signed the hash of
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// Attestation is a verified attestation object
+type Attestation struct {
+	Format   string
+	Type     string
+	AuthData *AuthData
+}
+
+type rawAttestationObject struct {
+	Format    string          `cbor:"fmt"`
+	Statement cbor.RawMessage `cbor:"attStmt"`
+	AuthData  []byte          `cbor:"authData"`
+}
+
+type packedStatement struct {
+	Algorithm int      `cbor:"alg"`
+	Signature []byte   `cbor:"sig"`
+	X5C       [][]byte `cbor:"x5c"`
+}
+
+type coseKeyHeader struct {
+	KeyType   int `cbor:"1,keyasint"`
+	Algorithm int `cbor:"3,keyasint"`
+}
+
+type rsaCOSEKey struct {
+	N []byte `cbor:"-1,keyasint"`
+	E []byte `cbor:"-2,keyasint"`
+}
+
+// ParseAuthenticatorData splits authenticator data into its fields. Extension data is not interpreted.
+func ParseAuthenticatorData(data []byte) (*AuthData, error) {
+	if len(data) < authDataMinLen {
+		return nil, errors.New("authenticator data is too short")
+	}
+	authData := &AuthData{
+		Raw:       data,
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[authDataMinLen:]
+
+	if authData.Has(FlagAttestedCredentialData) {
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		copy(authData.AAGUID[:], rest[:16])
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || len(rest) < idLen {
+			return nil, errors.New("invalid credential ID length")
+		}
+		authData.CredentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		var publicKey cbor.RawMessage
+		remaining, err := decMode.UnmarshalFirst(rest, &publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %v", err)
+		}
+		authData.PublicKey = rest[:len(rest)-len(remaining)]
+		rest = remaining
+	}
+
+	if authData.Has(FlagExtensionData) {
+		var extensions map[string]interface{}
+		remaining, err := decMode.UnmarshalFirst(rest, &extensions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extension data: %v", err)
+		}
+		rest = remaining
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("authenticator data has trailing bytes")
+	}
+	return authData, nil
+}
+
+// VerifyRPID checks the authenticator data was produced for the relying party
+func (a *AuthData) VerifyRPID(rpID string) error {
+	expected := sha256.Sum256([]byte(rpID))
+	if subtle.ConstantTimeCompare(a.RPIDHash, expected[:]) != 1 {
+		return errors.New("authenticator data is for another relying party")
+	}
+	return nil
+}
+
+// VerifyClientData checks the client data of a ceremony against the challenge that was issued and the origins of
+// the relying party
+func VerifyClientData(raw []byte, ceremony string, challenge []byte, origins []string) (*ClientData, error) {
+	var clientData ClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, errors.New("invalid client data")
+	}
+	if clientData.Type != ceremony {
+		return nil, fmt.Errorf("client data type must be %s", ceremony)
+	}
+	received, err := DecodeBase64URL(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return nil, errors.New("challenge does not match")
+	}
+	if clientData.CrossOrigin {
+		return nil, errors.New("cross-origin ceremonies are not allowed")
+	}
+	for _, origin := range origins {
+		if clientData.Origin == origin {
+			return &clientData, nil
+		}
+	}
+	return nil, fmt.Errorf("origin %s is not allowed", clientData.Origin)
+}
+
+// VerifyAttestation parses an attestation object and verifies its statement over the authenticator data and the
+// client data hash. Packed certificates are checked against the FIDO requirements but not chained to a root, so a
+// basic attestation identifies the authenticator model without proving it.
+func VerifyAttestation(attestationObject, clientDataHash []byte) (*Attestation, error) {
+	var object rawAttestationObject
+	if err := decMode.Unmarshal(attestationObject, &object); err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %v", err)
+	}
+	authData, err := ParseAuthenticatorData(object.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if !authData.Has(FlagAttestedCredentialData) {
+		return nil, errors.New("attestation has no credential")
+	}
+
+	attestation := &Attestation{Format: object.Format, AuthData: authData}
+	switch object.Format {
+	case FormatNone:
+		var statement map[string]interface{}
+		if err := decMode.Unmarshal(object.Statement, &statement); err != nil || len(statement) > 0 {
+			return nil, errors.New("none attestation must have an empty statement")
+		}
+		attestation.Type = AttestationNone
+	case FormatPacked:
+		attestation.Type, err = verifyPacked(object.Statement, authData, clientDataHash)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported attestation format: %s", object.Format)
+	}
+	return attestation, nil
+}
+
+func verifyPacked(rawStatement cbor.RawMessage, authData *AuthData, clientDataHash []byte) (string, error) {
+	var statement packedStatement
+	if err := decMode.Unmarshal(rawStatement, &statement); err != nil {
+		return "", fmt.Errorf("invalid packed attestation statement: %v", err)
+	}
+	if len(statement.Signature) == 0 {
+		return "", errors.New("packed attestation has no signature")
+	}
+	signed := make([]byte, 0, len(authData.Raw)+len(clientDataHash))
+	signed = append(signed, authData.Raw...)
+	signed = append(signed, clientDataHash...)
+
+	if len(statement.X5C) == 0 {
+		// Self attestation is signed with the credential key itself
+		algorithm, err := Algorithm(authData.PublicKey)
+		if err != nil {
+			return "", err
+		}
+		if algorithm != statement.Algorithm {
+			return "", errors.New("self attestation algorithm does not match the credential")
+		}
+		if err := VerifySignature(authData.PublicKey, signed, statement.Signature); err != nil {
+			return "", fmt.Errorf("invalid self attestation signature: %v", err)
+		}
+		return AttestationSelf, nil
+	}
+
+	certificate, err := x509.ParseCertificate(statement.X5C[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid attestation certificate: %v", err)
+	}
+	if err := checkAttestationCertificate(certificate, authData.AAGUID); err != nil {
+		return "", err
+	}
+	signatureAlgorithm, ok := map[int]x509.SignatureAlgorithm{
+		AlgES256: x509.ECDSAWithSHA256,
+		AlgEdDSA: x509.PureEd25519,
+		AlgRS256: x509.SHA256WithRSA,
+	}[statement.Algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported attestation algorithm: %d", statement.Algorithm)
+	}
+	if err := certificate.CheckSignature(signatureAlgorithm, signed, statement.Signature); err != nil {
+		return "", fmt.Errorf("invalid attestation signature: %v", err)
+	}
+	return AttestationBasic, nil
+}
+
+// checkAttestationCertificate applies the packed attestation certificate requirements of the WebAuthn spec
+func checkAttestationCertificate(certificate *x509.Certificate, aaguid [16]byte) error {
+	subject := certificate.Subject
+	switch {
+	case certificate.Version != 3:
+		return errors.New("attestation certificate must be X.509 v3")
+	case len(subject.Country) == 0 || len(subject.Organization) == 0 || subject.CommonName == "":
+		return errors.New("attestation certificate subject is incomplete")
+	case len(subject.OrganizationalUnit) != 1 || subject.OrganizationalUnit[0] != "Authenticator Attestation":
+		return errors.New("attestation certificate must be issued for Authenticator Attestation")
+	case certificate.IsCA:
+		return errors.New("attestation certificate must not be a CA")
+	}
+
+	for _, extension := range certificate.Extensions {
+		if !extension.Id.Equal(oidFIDOAAGUID) {
+			continue
+		}
+		var certificateAAGUID []byte
+		if _, err := asn1.Unmarshal(extension.Value, &certificateAAGUID); err != nil || extension.Critical {
+			return errors.New("invalid AAGUID extension in attestation certificate")
+		}
+		if !bytes.Equal(certificateAAGUID, aaguid[:]) {
+			return errors.New("attestation certificate AAGUID does not match the authenticator")
+		}
+	}
+	return nil
+}
+
+// Algorithm returns the COSE algorithm of a credential public key, checking the key is one that can be verified
+func Algorithm(publicKey []byte) (int, error) {
+	_, algorithm, err := ParsePublicKey(publicKey)
+	return algorithm, err
+}
+
+// ParsePublicKey decodes a COSE_Key of an ES256, EdDSA (Ed25519) or RS256 credential
+func ParsePublicKey(publicKey []byte) (crypto.PublicKey, int, error) {
+	var header coseKeyHeader
+	if err := decMode.Unmarshal(publicKey, &header); err != nil {
+		return nil, 0, fmt.Errorf("invalid credential public key: %v", err)
+	}
+
+	switch {
+	case header.KeyType == coseKeyTypeEC2 && header.Algorithm == AlgES256:
+		var key coseKey
+		if err := decMode.Unmarshal(publicKey, &key); err != nil || key.Curve != coseCurveP256 ||
+			len(key.X) != coordinateSize || len(key.Y) != coordinateSize {
+			return nil, 0, errors.New("invalid ES256 public key")
+		}
+		point := make([]byte, 0, 1+2*coordinateSize)
+		point = append(append(append(point, 0x04), key.X...), key.Y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, 0, errors.New("ES256 public key is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(key.X), Y: new(big.Int).SetBytes(key.Y)}, AlgES256, nil
+	case header.KeyType == coseKeyTypeOKP && header.Algorithm == AlgEdDSA:
+		var key coseKey
+		if err := decMode.Unmarshal(publicKey, &key); err != nil || key.Curve != coseCurveEd25519 || len(key.X) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("invalid EdDSA public key")
+		}
+		return ed25519.PublicKey(key.X), AlgEdDSA, nil
+	case header.KeyType == coseKeyTypeRSA && header.Algorithm == AlgRS256:
+		var key rsaCOSEKey
+		if err := decMode.Unmarshal(publicKey, &key); err != nil || len(key.N) == 0 || len(key.E) == 0 || len(key.E) > 4 {
+			return nil, 0, errors.New("invalid RS256 public key")
+		}
+		rsaKey := &rsa.PublicKey{N: new(big.Int).SetBytes(key.N), E: int(new(big.Int).SetBytes(key.E).Int64())}
+		if rsaKey.N.BitLen() < minRSAKeyBits {
+			return nil, 0, fmt.Errorf("RS256 keys must have at least %d bits", minRSAKeyBits)
+		}
+		return rsaKey, AlgRS256, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported credential key type %d with algorithm %d", header.KeyType, header.Algorithm)
+}
+
+// VerifySignature verifies a signature made with the credential key of a COSE_Key
+func VerifySignature(publicKey, data, signature []byte) error {
+	key, _, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(data)
+	var valid bool
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	if !valid {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// VerifyAssertion verifies an assertion signature over the authenticator data and the hash of the client data JSON
+func VerifyAssertion(publicKey, authData, clientDataJSON, signature []byte) error {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := make([]byte, 0, len(authData)+len(clientDataHash))
+	signed = append(signed, authData...)
+	signed = append(signed, clientDataHash[:]...)
+	return VerifySignature(publicKey, signed, signature)
+}
@@ -0,0 +1,395 @@
+package webauthn
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// Vectors recorded from a software authenticator holding a P-256 credential for the example.com relying party. The
+// basic attestation is signed by a separate key whose self-issued certificate carries the authenticator AAGUID.
+const (
+	vectorRPID   = "example.com"
+	vectorOrigin = "https://example.com"
+
+	vectorCredentialID = "c26752c62d5b7c0e3cf3f3fe1c847673"
+	vectorAAGUID       = "103b05ae3d48edeeb43c4ac046250541"
+	vectorPublicKey    = "a5010203262001215820df599395ffab737106b494690ceb744fd54fea82f703c444b321a5daf43fcaca2258207d3edf3915" +
+		"da339f8dc9a224ac89cd32d6bbf2056b3dcb75802cad60c34efd9c"
+
+	vectorCreateChallenge  = "2d615f8f9920f8eb3a0f17e295da260da4e507e7bfe1e90cd055e0f7ac8ae36c"
+	vectorCreateClientData = `{"type":"webauthn.create","challenge":"LWFfj5kg-Os6DxfildomDaTlB-e_4ekM0FXg96yK42w","origin":"https://example.com","crossOrigin":false}`
+	vectorNoneAttestation  = "a363666d74646e6f6e656761747453746d74a06861757468446174615894a379a6f6eeafb9a55e378c118034e2751e682fab" +
+		"9f2d30ab13d2125586ce19474500000000000000000000000000000000000000000010c26752c62d5b7c0e3cf3f3fe1c8476" +
+		"73a5010203262001215820df599395ffab737106b494690ceb744fd54fea82f703c444b321a5daf43fcaca2258207d3edf39" +
+		"15da339f8dc9a224ac89cd32d6bbf2056b3dcb75802cad60c34efd9c"
+	vectorSelfAttestation = "a363666d74667061636b65646761747453746d74a263616c67266373696758473045022100d9b0d69f65cfd47b8a3a774638" +
+		"cd40dabc42aa4cd5e681f2d90eb82bd2f6f0e002202c6dd3fea584da0a68086186c70e7a6379c0ab45f34cb2e17adf81e31e" +
+		"b379cc6861757468446174615894a379a6f6eeafb9a55e378c118034e2751e682fab9f2d30ab13d2125586ce194745000000" +
+		"01103b05ae3d48edeeb43c4ac0462505410010c26752c62d5b7c0e3cf3f3fe1c847673a5010203262001215820df599395ff" +
+		"ab737106b494690ceb744fd54fea82f703c444b321a5daf43fcaca2258207d3edf3915da339f8dc9a224ac89cd32d6bbf205" +
+		"6b3dcb75802cad60c34efd9c"
+	vectorBasicAttestation = "a363666d74667061636b65646761747453746d74a363616c67266373696758483046022100ced792807a82b6a2b063453861" +
+		"25e4f992e55bd72b0bd52f4c8a49e031460b4f02210094ea18e98a964f7daa45b89dc939cb4336690af2f9a929fe2856e1d8" +
+		"532b7304637835638159020d30820209308201b0a003020102020101300a06082a8648ce3d0403023074310b300906035504" +
+		"0613025553311f301d060355040a13164578616d706c652041757468656e74696361746f727331223020060355040b131941" +
+		"757468656e74696361746f72204174746573746174696f6e3120301e060355040313174578616d706c65204b657920417474" +
+		"6573746174696f6e301e170d3234303130313030303030305a170d3434303130313030303030305a3074310b300906035504" +
+		"0613025553311f301d060355040a13164578616d706c652041757468656e74696361746f727331223020060355040b131941" +
+		"757468656e74696361746f72204174746573746174696f6e3120301e060355040313174578616d706c65204b657920417474" +
+		"6573746174696f6e3059301306072a8648ce3d020106082a8648ce3d03010703420004b2d3731c458b5b4b5c5213a00948c9" +
+		"253eb5f2f43dc44a4dfa14c51b658a7d0745c1b4af3b72307264d3b0baf4aaeffece0c438ee7669c0c1dfe2b2c98358dc6a3" +
+		"333031300c0603551d130101ff040230003021060b2b0601040182e51c01010404120410103b05ae3d48edeeb43c4ac04625" +
+		"0541300a06082a8648ce3d0403020347003044022060ebe1e0c3f7cbf1ff0048d68142e562582f54015d4f33168b576ae599" +
+		"14a8be02206caa920448a1ea20ee1f812f505de4039299e368f682efafd2ad0f122a56a7816861757468446174615894a379" +
+		"a6f6eeafb9a55e378c118034e2751e682fab9f2d30ab13d2125586ce19474500000001103b05ae3d48edeeb43c4ac0462505" +
+		"410010c26752c62d5b7c0e3cf3f3fe1c847673a5010203262001215820df599395ffab737106b494690ceb744fd54fea82f7" +
+		"03c444b321a5daf43fcaca2258207d3edf3915da339f8dc9a224ac89cd32d6bbf2056b3dcb75802cad60c34efd9c"
+
+	vectorGetChallenge      = "9c3aa9ccc39446ac279e673825585451b01f3b08b026b6a66fb7e80fe78f32ff"
+	vectorGetClientData     = `{"type":"webauthn.get","challenge":"nDqpzMOURqwnnmc4JVhUUbAfOwiwJramb7foD-ePMv8","origin":"https://example.com","crossOrigin":false}`
+	vectorAssertionAuthData = "a379a6f6eeafb9a55e378c118034e2751e682fab9f2d30ab13d2125586ce19470500000005"
+	vectorAssertionSig      = "3045022071f0f77abfeb516d18b4a90c1580c1ed3489e3b3a69eb0535938e45cd1c7c3ce022100bb5c4c8d5f2dd211e9985b" +
+		"4afe9b48a2d98e7cf9839e323721daaa851c135e74"
+)
+
+func decodeHex(t *testing.T, value string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func hashClientData(clientDataJSON string) []byte {
+	hash := sha256.Sum256([]byte(clientDataJSON))
+	return hash[:]
+}
+
+// reencode decodes a recorded attestation object, lets change alter it and encodes it again
+func reencode(t *testing.T, vector string, change func(object *attestationObject)) []byte {
+	t.Helper()
+	var object attestationObject
+	if err := decMode.Unmarshal(decodeHex(t, vector), &object); err != nil {
+		t.Fatal(err)
+	}
+	change(&object)
+	data, err := encMode.Marshal(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerifyAttestation(t *testing.T) {
+	tests := []struct {
+		name           string
+		object         func(t *testing.T) []byte
+		clientDataJSON string
+		wantFormat     string
+		wantType       string
+		wantAAGUID     string
+		wantSignCount  uint32
+		wantErr        bool
+	}{
+		{
+			name:           "none",
+			object:         func(t *testing.T) []byte { return decodeHex(t, vectorNoneAttestation) },
+			clientDataJSON: vectorCreateClientData,
+			wantFormat:     FormatNone,
+			wantType:       AttestationNone,
+			wantAAGUID:     "00000000000000000000000000000000",
+		},
+		{
+			name:           "packed self",
+			object:         func(t *testing.T) []byte { return decodeHex(t, vectorSelfAttestation) },
+			clientDataJSON: vectorCreateClientData,
+			wantFormat:     FormatPacked,
+			wantType:       AttestationSelf,
+			wantAAGUID:     vectorAAGUID,
+			wantSignCount:  1,
+		},
+		{
+			name:           "packed basic",
+			object:         func(t *testing.T) []byte { return decodeHex(t, vectorBasicAttestation) },
+			clientDataJSON: vectorCreateClientData,
+			wantFormat:     FormatPacked,
+			wantType:       AttestationBasic,
+			wantAAGUID:     vectorAAGUID,
+			wantSignCount:  1,
+		},
+		{
+			name: "none with a statement",
+			object: func(t *testing.T) []byte {
+				return reencode(t, vectorNoneAttestation, func(object *attestationObject) {
+					object.Statement = map[string]interface{}{"alg": AlgES256}
+				})
+			},
+			clientDataJSON: vectorCreateClientData,
+			wantErr:        true,
+		},
+		{
+			name:           "packed self over other client data",
+			object:         func(t *testing.T) []byte { return decodeHex(t, vectorSelfAttestation) },
+			clientDataJSON: vectorGetClientData,
+			wantErr:        true,
+		},
+		{
+			name:           "packed basic over other client data",
+			object:         func(t *testing.T) []byte { return decodeHex(t, vectorBasicAttestation) },
+			clientDataJSON: vectorGetClientData,
+			wantErr:        true,
+		},
+		{
+			name: "packed self with another algorithm",
+			object: func(t *testing.T) []byte {
+				return reencode(t, vectorSelfAttestation, func(object *attestationObject) {
+					object.Statement["alg"] = AlgEdDSA
+				})
+			},
+			clientDataJSON: vectorCreateClientData,
+			wantErr:        true,
+		},
+		{
+			name: "packed self with another relying party",
+			object: func(t *testing.T) []byte {
+				return reencode(t, vectorSelfAttestation, func(object *attestationObject) {
+					object.AuthData[0] ^= 0xff
+				})
+			},
+			clientDataJSON: vectorCreateClientData,
+			wantErr:        true,
+		},
+		{
+			name: "packed basic with a tampered signature",
+			object: func(t *testing.T) []byte {
+				return reencode(t, vectorBasicAttestation, func(object *attestationObject) {
+					signature := object.Statement["sig"].([]byte)
+					signature[len(signature)-1] ^= 0x01
+				})
+			},
+			clientDataJSON: vectorCreateClientData,
+			wantErr:        true,
+		},
+		{
+			name: "packed basic for another authenticator model",
+			object: func(t *testing.T) []byte {
+				return reencode(t, vectorBasicAttestation, func(object *attestationObject) {
+					object.AuthData[authDataMinLen] ^= 0xff
+				})
+			},
+			clientDataJSON: vectorCreateClientData,
+			wantErr:        true,
+		},
+		{
+			name: "unsupported format",
+			object: func(t *testing.T) []byte {
+				return reencode(t, vectorNoneAttestation, func(object *attestationObject) {
+					object.Format = "fido-u2f"
+				})
+			},
+			clientDataJSON: vectorCreateClientData,
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation, err := VerifyAttestation(tt.object(t), hashClientData(tt.clientDataJSON))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("VerifyAttestation accepted the attestation")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAttestation: %v", err)
+			}
+
+			if attestation.Format != tt.wantFormat || attestation.Type != tt.wantType {
+				t.Errorf("attestation = %s/%s, want %s/%s", attestation.Format, attestation.Type, tt.wantFormat, tt.wantType)
+			}
+			authData := attestation.AuthData
+			if err := authData.VerifyRPID(vectorRPID); err != nil {
+				t.Errorf("VerifyRPID: %v", err)
+			}
+			if got := hex.EncodeToString(authData.AAGUID[:]); got != tt.wantAAGUID {
+				t.Errorf("AAGUID = %s, want %s", got, tt.wantAAGUID)
+			}
+			if got := hex.EncodeToString(authData.CredentialID); got != vectorCredentialID {
+				t.Errorf("credential ID = %s, want %s", got, vectorCredentialID)
+			}
+			if got := hex.EncodeToString(authData.PublicKey); got != vectorPublicKey {
+				t.Errorf("public key = %s, want %s", got, vectorPublicKey)
+			}
+			if authData.SignCount != tt.wantSignCount {
+				t.Errorf("sign count = %d, want %d", authData.SignCount, tt.wantSignCount)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	tests := []struct {
+		name           string
+		authData       func(authData []byte)
+		clientDataJSON string
+		signature      func(signature []byte) []byte
+		wantErr        bool
+	}{
+		{
+			name:           "valid",
+			clientDataJSON: vectorGetClientData,
+		},
+		{
+			name:           "raised sign count",
+			authData:       func(authData []byte) { authData[len(authData)-1] = 6 },
+			clientDataJSON: vectorGetClientData,
+			wantErr:        true,
+		},
+		{
+			name:           "another relying party",
+			authData:       func(authData []byte) { authData[0] ^= 0xff },
+			clientDataJSON: vectorGetClientData,
+			wantErr:        true,
+		},
+		{
+			name:           "registration client data",
+			clientDataJSON: vectorCreateClientData,
+			wantErr:        true,
+		},
+		{
+			name:           "another origin",
+			clientDataJSON: `{"type":"webauthn.get","challenge":"nDqpzMOURqwnnmc4JVhUUbAfOwiwJramb7foD-ePMv8","origin":"https://evil.example","crossOrigin":false}`,
+			wantErr:        true,
+		},
+		{
+			name:           "truncated signature",
+			clientDataJSON: vectorGetClientData,
+			signature:      func(signature []byte) []byte { return signature[:len(signature)-1] },
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authData := decodeHex(t, vectorAssertionAuthData)
+			if tt.authData != nil {
+				tt.authData(authData)
+			}
+			signature := decodeHex(t, vectorAssertionSig)
+			if tt.signature != nil {
+				signature = tt.signature(signature)
+			}
+
+			err := VerifyAssertion(decodeHex(t, vectorPublicKey), authData, []byte(tt.clientDataJSON), signature)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("VerifyAssertion err = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAssertionAuthenticatorData(t *testing.T) {
+	authData, err := ParseAuthenticatorData(decodeHex(t, vectorAssertionAuthData))
+	if err != nil {
+		t.Fatalf("ParseAuthenticatorData: %v", err)
+	}
+	if authData.SignCount != 5 {
+		t.Errorf("sign count = %d, want 5", authData.SignCount)
+	}
+	if !authData.Has(FlagUserPresent|FlagUserVerified) || authData.Has(FlagAttestedCredentialData) {
+		t.Errorf("flags = %#x, want user present and verified without attested credential data", authData.Flags)
+	}
+
+	for rpID, wantErr := range map[string]bool{vectorRPID: false, "evil.example": true, "login.example.com": true} {
+		if err := authData.VerifyRPID(rpID); wantErr != (err != nil) {
+			t.Errorf("VerifyRPID(%s) err = %v, want error %t", rpID, err, wantErr)
+		}
+	}
+}
+
+func TestVerifyClientData(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		ceremony  string
+		challenge string
+		origins   []string
+		wantErr   bool
+	}{
+		{
+			name:      "registration",
+			raw:       vectorCreateClientData,
+			ceremony:  CeremonyCreate,
+			challenge: vectorCreateChallenge,
+			origins:   []string{vectorOrigin},
+		},
+		{
+			name:      "authentication",
+			raw:       vectorGetClientData,
+			ceremony:  CeremonyGet,
+			challenge: vectorGetChallenge,
+			origins:   []string{"https://login.example.com", vectorOrigin},
+		},
+		{
+			name:      "other ceremony",
+			raw:       vectorGetClientData,
+			ceremony:  CeremonyCreate,
+			challenge: vectorGetChallenge,
+			origins:   []string{vectorOrigin},
+			wantErr:   true,
+		},
+		{
+			name:      "other challenge",
+			raw:       vectorGetClientData,
+			ceremony:  CeremonyGet,
+			challenge: vectorCreateChallenge,
+			origins:   []string{vectorOrigin},
+			wantErr:   true,
+		},
+		{
+			name:      "wrong origin",
+			raw:       vectorGetClientData,
+			ceremony:  CeremonyGet,
+			challenge: vectorGetChallenge,
+			origins:   []string{"https://login.example.com"},
+			wantErr:   true,
+		},
+		{
+			name:      "cross origin",
+			raw:       `{"type":"webauthn.get","challenge":"nDqpzMOURqwnnmc4JVhUUbAfOwiwJramb7foD-ePMv8","origin":"https://example.com","crossOrigin":true}`,
+			ceremony:  CeremonyGet,
+			challenge: vectorGetChallenge,
+			origins:   []string{vectorOrigin},
+			wantErr:   true,
+		},
+		{
+			name:      "malformed",
+			raw:       `{"type":`,
+			ceremony:  CeremonyGet,
+			challenge: vectorGetChallenge,
+			origins:   []string{vectorOrigin},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientData, err := VerifyClientData([]byte(tt.raw), tt.ceremony, decodeHex(t, tt.challenge), tt.origins)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("VerifyClientData accepted %s", tt.raw)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyClientData: %v", err)
+			}
+			if clientData.Origin != vectorOrigin {
+				t.Errorf("origin = %s, want %s", clientData.Origin, vectorOrigin)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"github.com/fxamacker/cbor/v2"
+	"strings"
+)
+
+// Authenticator data flags
+const (
+	FlagUserPresent            byte = 0x01
+	FlagUserVerified           byte = 0x04
+	FlagBackupEligible         byte = 0x08
+	FlagBackupState            byte = 0x10
+	FlagAttestedCredentialData byte = 0x40
+	FlagExtensionData          byte = 0x80
+)
+
+// AlgES256 is the COSE algorithm of ECDSA P-256 with SHA-256, the only one passkeys are created with
+const AlgES256 = -7
+
+const (
+	coseKeyTypeEC2 = 2
+	coseCurveP256  = 1
+	coordinateSize = 32
+)
+
+// User verification requirements of the relying party
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+// RelyingParty is this service as a WebAuthn relying party. Origins are the web origins allowed to run ceremonies.
+type RelyingParty struct {
+	ID               string
+	Name             string
+	Origins          []string
+	UserVerification string
+}
+
+// encMode encodes CBOR in the CTAP2 canonical form authenticators use
+var encMode, _ = cbor.CTAP2EncOptions().EncMode()
+
+// coseKey is an EC2 COSE_Key; the integer labels are defined by RFC 9053
+type coseKey struct {
+	KeyType   int    `cbor:"1,keyasint"`
+	Algorithm int    `cbor:"3,keyasint"`
+	Curve     int    `cbor:"-1,keyasint"`
+	X         []byte `cbor:"-2,keyasint"`
+	Y         []byte `cbor:"-3,keyasint"`
+}
+
+type attestationObject struct {
+	Format    string                 `cbor:"fmt"`
+	Statement map[string]interface{} `cbor:"attStmt"`
+	AuthData  []byte                 `cbor:"authData"`
+}
+
+// EncodeCOSEKey encodes an ES256 public key as a COSE_Key
+func EncodeCOSEKey(publicKey *ecdsa.PublicKey) ([]byte, error) {
+	if publicKey.Curve != elliptic.P256() {
+		return nil, errors.New("only P-256 keys are supported")
+	}
+	ecdhKey, err := publicKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	// Uncompressed point: 0x04 || X || Y
+	point := ecdhKey.Bytes()
+	return encMode.Marshal(coseKey{
+		KeyType:   coseKeyTypeEC2,
+		Algorithm: AlgES256,
+		Curve:     coseCurveP256,
+		X:         point[1 : 1+coordinateSize],
+		Y:         point[1+coordinateSize:],
+	})
+}
+
+// AttestedCredentialData is the part of the registration authenticator data that carries the new credential
+func AttestedCredentialData(aaguid [16]byte, credentialID, publicKey []byte) []byte {
+	data := make([]byte, 0, len(aaguid)+2+len(credentialID)+len(publicKey))
+	data = append(data, aaguid[:]...)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(credentialID)))
+	data = append(data, credentialID...)
+	return append(data, publicKey...)
+}
+
+// AuthenticatorData builds the authenticator data of a ceremony. Attested credential data is only given on
+// registration and sets its flag.
+func AuthenticatorData(rpID string, flags byte, signCount uint32, attestedCredentialData []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	if len(attestedCredentialData) > 0 {
+		flags |= FlagAttestedCredentialData
+	}
+
+	data := make([]byte, 0, len(rpIDHash)+5+len(attestedCredentialData))
+	data = append(data, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, signCount)
+	return append(data, attestedCredentialData...)
+}
+
+// NoneAttestationObject wraps registration authenticator data in an attestation object of the "none" format
+func NoneAttestationObject(authData []byte) ([]byte, error) {
+	return encMode.Marshal(attestationObject{
+		Format:    "none",
+		Statement: map[string]interface{}{},
+		AuthData:  authData,
+	})
+}
+
+// SignAssertion signs the authenticator data and the client data hash with ES256, ASN.1 encoded as WebAuthn expects
+func SignAssertion(privateKey *ecdsa.PrivateKey, authData, clientDataHash []byte) ([]byte, error) {
+	signed := make([]byte, 0, len(authData)+len(clientDataHash))
+	signed = append(signed, authData...)
+	signed = append(signed, clientDataHash...)
+	digest := sha256.Sum256(signed)
+	return ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+}
+
+// EncodeBase64URL encodes without padding, as WebAuthn JSON does
+func EncodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeBase64URL also accepts padding and the standard alphabet, which clients send interchangeably
+func DecodeBase64URL(value string) ([]byte, error) {
+	value = strings.TrimRight(strings.TrimSpace(value), "=")
+	value = strings.NewReplacer("+", "-", "/", "_").Replace(value)
+	return base64.RawURLEncoding.DecodeString(value)
+}
@@ -0,0 +1,23 @@
+CREATE TABLE password_attachments
+(
+    attachment_id   SERIAL PRIMARY KEY,
+    entry_id        INT          NOT NULL REFERENCES password_entries (entry_id) ON DELETE CASCADE,
+    user_id         INT          NOT NULL,
+    file_name       VARCHAR(255) NOT NULL,
+    content_type    VARCHAR(255) NOT NULL,
+    size            BIGINT       NOT NULL,
+    checksum        VARCHAR(64)  NOT NULL, -- SHA-256 of the plaintext, verified on download
+    storage_backend VARCHAR(20)  NOT NULL,
+    storage_key     VARCHAR(64)  NOT NULL UNIQUE,
+    wrapped_key     TEXT         NOT NULL, -- Attachment AES key encrypted under the entry key
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by      VARCHAR(255)
+);
+CREATE INDEX idx_password_attachments_entry_id ON password_attachments (entry_id);
+
+-- Ciphertext for the postgres attachment backend
+CREATE TABLE attachment_blobs
+(
+    storage_key VARCHAR(64) PRIMARY KEY,
+    data        BYTEA NOT NULL
+);
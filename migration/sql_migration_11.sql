@@ -0,0 +1,9 @@
+-- Groups form a tree. path is the materialized breadcrumb (/work/aws/prod), rewritten for the whole subtree
+-- when a group is renamed or moved, so breadcrumbs and path lookups never need to walk the tree.
+ALTER TABLE password_groups
+    ADD COLUMN parent_group_id INT  NULL REFERENCES password_groups (group_id),
+    ADD COLUMN path            TEXT NULL;
+UPDATE password_groups SET path = '/' || name;
+ALTER TABLE password_groups ALTER COLUMN path SET NOT NULL;
+CREATE INDEX idx_password_groups_parent_group_id ON password_groups (parent_group_id);
+CREATE INDEX idx_password_groups_user_id_path ON password_groups (user_id, path) WHERE deleted_at IS NULL;
@@ -0,0 +1,16 @@
+-- Entries can belong to several groups. password_entries.group_id stays as the entry's primary group, the one
+-- shown as its breadcrumb, and is always one of its memberships.
+CREATE TABLE password_entry_groups
+(
+    entry_id   INT NOT NULL REFERENCES password_entries (entry_id) ON DELETE CASCADE,
+    group_id   INT NOT NULL REFERENCES password_groups (group_id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    PRIMARY KEY (entry_id, group_id)
+);
+CREATE INDEX idx_password_entry_groups_group_id ON password_entry_groups (group_id);
+
+INSERT INTO password_entry_groups (entry_id, group_id, created_by)
+SELECT entry_id, group_id, updated_by
+FROM password_entries
+WHERE group_id IS NOT NULL;
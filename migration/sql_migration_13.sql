@@ -0,0 +1,27 @@
+-- Tag names were unique across all users. They are now unique per user among live tags.
+ALTER TABLE password_tags DROP CONSTRAINT IF EXISTS password_tags_name_key;
+ALTER TABLE password_tags ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE password_tags
+    ADD COLUMN color VARCHAR(7)  NULL, -- #RRGGBB
+    ADD COLUMN icon  VARCHAR(50) NULL;
+
+-- Give users whose entries were linked to another user's tag a tag of their own with the same name
+INSERT INTO password_tags (user_id, name, color, icon, created_by, updated_by)
+SELECT DISTINCT ON (pe.user_id, pt.name) pe.user_id, pt.name, pt.color, pt.icon, pt.created_by, pt.updated_by
+FROM password_entry_tags pet
+JOIN password_entries pe ON pe.entry_id = pet.entry_id
+JOIN password_tags pt ON pt.tag_id = pet.tag_id
+WHERE pt.user_id <> pe.user_id AND pt.deleted_at IS NULL
+ORDER BY pe.user_id, pt.name, pt.tag_id;
+
+UPDATE password_entry_tags pet
+SET tag_id = own.tag_id
+FROM password_entries pe, password_tags pt, password_tags own
+WHERE pe.entry_id = pet.entry_id
+  AND pt.tag_id = pet.tag_id
+  AND pt.user_id <> pe.user_id
+  AND own.user_id = pe.user_id
+  AND own.name = pt.name
+  AND own.deleted_at IS NULL;
+
+CREATE UNIQUE INDEX idx_password_tags_user_id_name ON password_tags (user_id, name) WHERE deleted_at IS NULL;
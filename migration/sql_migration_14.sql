@@ -0,0 +1,8 @@
+ALTER TABLE password_entries
+    ADD COLUMN favorite  BOOLEAN   NOT NULL DEFAULT FALSE,
+    ADD COLUMN pinned_at TIMESTAMP NULL; -- Pinned entries are listed first, in the order they were pinned
+CREATE INDEX idx_password_entries_user_id_last_accessed_at ON password_entries (user_id, last_accessed_at DESC)
+    WHERE last_accessed_at IS NOT NULL AND deleted_at IS NULL;
+
+-- Frecency sums the user's recent accesses
+CREATE INDEX idx_password_access_logs_user_id_created_at ON password_access_logs (user_id, created_at);
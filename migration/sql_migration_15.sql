@@ -0,0 +1,21 @@
+-- Entries can have several URLs, each matched with its own strategy. password_entries.url stays as the entry's
+-- primary URL. host and base_domain are the normalized keys used to find candidates; they are NULL for regex
+-- patterns and for URLs copied below until the entry is saved again, and such rows are always scored.
+CREATE TABLE password_entry_urls
+(
+    url_id      SERIAL PRIMARY KEY,
+    entry_id    INT           NOT NULL REFERENCES password_entries (entry_id) ON DELETE CASCADE,
+    url         VARCHAR(2048) NOT NULL,
+    match_type  VARCHAR(20)   NOT NULL DEFAULT 'base_domain',
+    host        VARCHAR(255),
+    base_domain VARCHAR(255),
+    position    INT           NOT NULL DEFAULT 0,
+    created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_password_entry_urls_entry_id ON password_entry_urls (entry_id);
+CREATE INDEX idx_password_entry_urls_base_domain ON password_entry_urls (base_domain);
+
+INSERT INTO password_entry_urls (entry_id, url)
+SELECT entry_id, url
+FROM password_entries
+WHERE url IS NOT NULL AND url <> '';
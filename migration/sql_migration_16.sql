@@ -0,0 +1,80 @@
+-- Known mobile apps and the web domains their credentials belong to. app_id is the app URI, androidapp://<package>
+-- or iosapp://<bundle id>, and domain a base domain.
+CREATE TABLE app_domain_equivalences
+(
+    app_id VARCHAR(255) NOT NULL,
+    domain VARCHAR(255) NOT NULL,
+    PRIMARY KEY (app_id, domain)
+);
+CREATE INDEX idx_app_domain_equivalences_domain ON app_domain_equivalences (domain);
+
+INSERT INTO app_domain_equivalences (app_id, domain)
+VALUES ('androidapp://com.google.android.gm', 'google.com'),
+       ('androidapp://com.google.android.youtube', 'youtube.com'),
+       ('androidapp://com.facebook.katana', 'facebook.com'),
+       ('androidapp://com.facebook.orca', 'facebook.com'),
+       ('androidapp://com.instagram.android', 'instagram.com'),
+       ('androidapp://com.whatsapp', 'whatsapp.com'),
+       ('androidapp://com.twitter.android', 'twitter.com'),
+       ('androidapp://com.twitter.android', 'x.com'),
+       ('androidapp://com.linkedin.android', 'linkedin.com'),
+       ('androidapp://com.reddit.frontpage', 'reddit.com'),
+       ('androidapp://com.amazon.mShop.android.shopping', 'amazon.com'),
+       ('androidapp://com.netflix.mediaclient', 'netflix.com'),
+       ('androidapp://com.spotify.music', 'spotify.com'),
+       ('androidapp://com.paypal.android.p2pmobile', 'paypal.com'),
+       ('androidapp://com.github.android', 'github.com'),
+       ('androidapp://com.dropbox.android', 'dropbox.com'),
+       ('androidapp://com.microsoft.office.outlook', 'live.com'),
+       ('androidapp://com.microsoft.office.outlook', 'outlook.com'),
+       ('androidapp://com.slack', 'slack.com'),
+       ('androidapp://com.discord', 'discord.com'),
+       ('iosapp://com.google.Gmail', 'google.com'),
+       ('iosapp://com.google.ios.youtube', 'youtube.com'),
+       ('iosapp://com.facebook.Facebook', 'facebook.com'),
+       ('iosapp://com.burbn.instagram', 'instagram.com'),
+       ('iosapp://com.atebits.Tweetie2', 'twitter.com'),
+       ('iosapp://com.atebits.Tweetie2', 'x.com'),
+       ('iosapp://com.linkedin.LinkedIn', 'linkedin.com'),
+       ('iosapp://com.netflix.Netflix', 'netflix.com'),
+       ('iosapp://com.spotify.client', 'spotify.com'),
+       ('iosapp://com.yourcompany.PPClient', 'paypal.com'),
+       ('iosapp://com.github.stormbreaker.prod', 'github.com'),
+       ('iosapp://com.getdropbox.Dropbox', 'dropbox.com'),
+       ('iosapp://com.microsoft.Office.Outlook', 'outlook.com'),
+       ('iosapp://com.tinyspeck.chatlyio', 'slack.com');
+
+-- Domains that share the same accounts. Groups without a user apply to every user, who can exclude them; users
+-- add their own groups.
+CREATE TABLE equivalent_domain_groups
+(
+    group_id   SERIAL PRIMARY KEY,
+    user_id    INT,
+    domains    TEXT[] NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255),
+    deleted_at TIMESTAMP,
+    deleted_by VARCHAR(255)
+);
+CREATE INDEX idx_equivalent_domain_groups_user_id ON equivalent_domain_groups (user_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE equivalent_domain_exclusions
+(
+    user_id    INT NOT NULL,
+    group_id   INT NOT NULL REFERENCES equivalent_domain_groups (group_id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, group_id)
+);
+
+INSERT INTO equivalent_domain_groups (domains)
+VALUES ('{google.com,youtube.com,gmail.com}'),
+       ('{apple.com,icloud.com}'),
+       ('{microsoft.com,live.com,outlook.com,office.com,microsoftonline.com,xbox.com,skype.com}'),
+       ('{amazon.com,amazon.co.uk,amazon.de,amazon.fr,amazon.ca,amazon.co.jp,amazon.in}'),
+       ('{facebook.com,messenger.com}'),
+       ('{twitter.com,x.com}'),
+       ('{ebay.com,ebay.co.uk,ebay.de,ebay.fr,ebay.ca}'),
+       ('{paypal.com,paypal.me}'),
+       ('{atlassian.com,atlassian.net,bitbucket.org,trello.com}');
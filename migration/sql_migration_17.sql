@@ -0,0 +1,23 @@
+-- FIDO2 authenticators registered as the second factor of vault unlock and step-up. credential_id is the raw
+-- WebAuthn credential ID and public_key the COSE_Key of the attested credential data.
+CREATE TABLE user_authenticators
+(
+    authenticator_id   SERIAL PRIMARY KEY,
+    user_id            INT          NOT NULL,
+    name               VARCHAR(100) NOT NULL,
+    credential_id      BYTEA        NOT NULL,
+    public_key         BYTEA        NOT NULL,
+    algorithm          INT          NOT NULL,
+    sign_count         BIGINT       NOT NULL DEFAULT 0,
+    aaguid             VARCHAR(36)  NOT NULL,
+    attestation_format VARCHAR(32)  NOT NULL,
+    attestation_type   VARCHAR(32)  NOT NULL,
+    transports         TEXT[],
+    backup_eligible    BOOLEAN      NOT NULL DEFAULT FALSE,
+    backup_state       BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at         TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by         VARCHAR(255),
+    last_used_at       TIMESTAMP NULL,
+    CONSTRAINT unique_authenticator_credential UNIQUE (credential_id)
+);
+CREATE INDEX idx_user_authenticators_user_id ON user_authenticators (user_id);
@@ -0,0 +1,15 @@
+CREATE TABLE password_access_logs
+(
+    log_id     SERIAL PRIMARY KEY,
+    entry_id   INT          NOT NULL REFERENCES password_entries (entry_id) ON DELETE CASCADE,
+    user_id    INT          NOT NULL,
+    action     VARCHAR(50)  NOT NULL,
+    fields     TEXT[],
+    request_id VARCHAR(255),
+    ip_address VARCHAR(64),
+    user_agent TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255)
+);
+CREATE INDEX idx_password_access_logs_entry_id ON password_access_logs (entry_id);
+CREATE INDEX idx_password_access_logs_user_id ON password_access_logs (user_id);
@@ -0,0 +1,54 @@
+CREATE TABLE service_accounts
+(
+    service_account_id    SERIAL PRIMARY KEY,
+    user_id               INT          NOT NULL,
+    name                  VARCHAR(100) NOT NULL,
+    description           TEXT,
+    permission            VARCHAR(20)  NOT NULL DEFAULT 'read',
+    public_key            TEXT         NOT NULL,
+    encrypted_private_key TEXT         NOT NULL,
+    wrapped_key           TEXT         NOT NULL,
+    created_at            TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by            VARCHAR(255),
+    updated_at            TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by            VARCHAR(255),
+    deleted_at            TIMESTAMP NULL,
+    deleted_by            VARCHAR(255)
+);
+CREATE INDEX idx_service_accounts_user_id ON service_accounts (user_id);
+
+CREATE TABLE service_account_scopes
+(
+    scope_id           SERIAL PRIMARY KEY,
+    service_account_id INT NOT NULL REFERENCES service_accounts (service_account_id) ON DELETE CASCADE,
+    group_id           INT REFERENCES password_groups (group_id) ON DELETE CASCADE,
+    tag_id             INT REFERENCES password_tags (tag_id) ON DELETE CASCADE,
+    CONSTRAINT service_account_scope_target CHECK (group_id IS NOT NULL OR tag_id IS NOT NULL)
+);
+CREATE INDEX idx_service_account_scopes_service_account_id ON service_account_scopes (service_account_id);
+
+CREATE TABLE service_account_tokens
+(
+    token_id              SERIAL PRIMARY KEY,
+    service_account_id    INT          NOT NULL REFERENCES service_accounts (service_account_id) ON DELETE CASCADE,
+    name                  VARCHAR(100) NOT NULL,
+    token_prefix          VARCHAR(16)  NOT NULL,
+    token_hash            VARCHAR(64)  NOT NULL UNIQUE,
+    encrypted_private_key TEXT         NOT NULL,
+    salt                  TEXT         NOT NULL,
+    expires_at            TIMESTAMP,
+    last_used_at          TIMESTAMP,
+    created_at            TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by            VARCHAR(255)
+);
+CREATE INDEX idx_service_account_tokens_service_account_id ON service_account_tokens (service_account_id);
+
+CREATE TABLE service_account_entry_keys
+(
+    service_account_id      INT  NOT NULL REFERENCES service_accounts (service_account_id) ON DELETE CASCADE,
+    entry_id                INT  NOT NULL REFERENCES password_entries (entry_id) ON DELETE CASCADE,
+    encrypted_symmetric_key TEXT NOT NULL,
+    created_at              TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (service_account_id, entry_id)
+);
+CREATE INDEX idx_service_account_entry_keys_entry_id ON service_account_entry_keys (entry_id);
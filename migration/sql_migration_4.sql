@@ -0,0 +1,16 @@
+CREATE TABLE user_devices
+(
+    device_id         SERIAL PRIMARY KEY,
+    user_id           INT          NOT NULL,
+    client_id         VARCHAR(255) NOT NULL,
+    device_identifier VARCHAR(255) NOT NULL,
+    access_uuid       VARCHAR(255),
+    ip_address        VARCHAR(64),
+    user_agent        TEXT,
+    first_seen_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at        TIMESTAMP NULL,
+    revoked_by        VARCHAR(255),
+    CONSTRAINT unique_user_device UNIQUE (user_id, device_identifier)
+);
+CREATE INDEX idx_user_devices_user_id ON user_devices (user_id);
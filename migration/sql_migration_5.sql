@@ -0,0 +1,20 @@
+CREATE TABLE outbox_events
+(
+    outbox_id       SERIAL PRIMARY KEY,
+    event_id        VARCHAR(36)  NOT NULL UNIQUE,
+    event_type      VARCHAR(100) NOT NULL,
+    event_version   INT          NOT NULL DEFAULT 1,
+    aggregate_id    INT,
+    user_id         INT,
+    payload         JSONB        NOT NULL,
+    attempts        INT          NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    published_at    TIMESTAMP NULL,
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_outbox_events_pending ON outbox_events (next_attempt_at) WHERE published_at IS NULL;
+
+ALTER TABLE password_entries
+    ADD COLUMN expiry_notified_at TIMESTAMP NULL;
+CREATE INDEX idx_password_entries_expires_at ON password_entries (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE password_entries
+    ADD COLUMN compromised          BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN breach_count         INT     NOT NULL DEFAULT 0,
+    ADD COLUMN breach_checked_at    TIMESTAMP NULL;
+CREATE INDEX idx_password_entries_breach_checked_at ON password_entries (user_id, breach_checked_at) WHERE deleted_at IS NULL;
+CREATE INDEX idx_password_entries_compromised ON password_entries (user_id) WHERE compromised = TRUE;
@@ -0,0 +1,29 @@
+CREATE TABLE password_policies
+(
+    policy_id          SERIAL PRIMARY KEY,
+    name               VARCHAR(255) NOT NULL,
+    user_id            INT NULL,
+    group_id           INT NULL REFERENCES password_groups (group_id) ON DELETE CASCADE,
+    min_length         INT     NOT NULL DEFAULT 0,
+    require_uppercase  BOOLEAN NOT NULL DEFAULT FALSE,
+    require_lowercase  BOOLEAN NOT NULL DEFAULT FALSE,
+    require_digits     BOOLEAN NOT NULL DEFAULT FALSE,
+    require_symbols    BOOLEAN NOT NULL DEFAULT FALSE,
+    max_age_days       INT     NOT NULL DEFAULT 0,
+    forbid_compromised BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at         TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by         VARCHAR(255),
+    updated_at         TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by         VARCHAR(255),
+    deleted_at         TIMESTAMP NULL,
+    deleted_by         VARCHAR(255),
+    CONSTRAINT password_policies_single_scope CHECK (user_id IS NULL OR group_id IS NULL)
+);
+CREATE INDEX idx_password_policies_user_id ON password_policies (user_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_password_policies_group_id ON password_policies (group_id) WHERE deleted_at IS NULL;
+
+-- Password characteristics are recorded on save so policies can be evaluated without decrypting the vault
+ALTER TABLE password_entries
+    ADD COLUMN password_length     INT NULL,
+    ADD COLUMN password_classes    INT NULL,
+    ADD COLUMN password_changed_at TIMESTAMP NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE password_entries
+    ADD COLUMN encrypted_custom_fields TEXT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE password_entries
+    ADD COLUMN entry_type     VARCHAR(30) NOT NULL DEFAULT 'login',
+    ADD COLUMN encrypted_data TEXT  NULL,
+    ADD COLUMN metadata       JSONB NULL;
+CREATE INDEX idx_password_entries_user_id_entry_type ON password_entries (user_id, entry_type) WHERE deleted_at IS NULL;